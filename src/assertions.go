@@ -0,0 +1,346 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	collections "kalo/src/panels/collections"
+	request "kalo/src/panels/request"
+	response "kalo/src/panels/response"
+)
+
+// EvaluateAssertions checks the enabled assertions of an assert block against
+// a response. Body paths such as `res.body.items[0].id` are evaluated with jq
+// and {{variables}} in values are substituted from vars.
+func EvaluateAssertions(assertions []request.BruAssertion, resp *response.HTTPResponse, vars map[string]string) []TestResult {
+	if resp == nil {
+		return nil
+	}
+
+	var results []TestResult
+	for _, assertion := range assertions {
+		if !assertion.Enabled {
+			continue
+		}
+		results = append(results, evaluateAssertion(assertion, resp, vars))
+	}
+	return results
+}
+
+// evaluateAssertion checks one assertion. Its name keeps the value as written;
+// the expected value and failure message show it substituted.
+func evaluateAssertion(assertion request.BruAssertion, resp *response.HTTPResponse, vars map[string]string) TestResult {
+	result := TestResult{Name: fmt.Sprintf("%s: %s", assertion.Expression, assertion.Rule())}
+	assertion.Value = substituteTemplates(assertion.Value, vars)
+	result.Expected = assertion.Value
+
+	actual, err := resolveAssertionTarget(assertion.Expression, resp)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	result.Actual = actual
+
+	passed, err := applyAssertionOperator(assertion.Operator, actual, assertion.Value)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+
+	result.Passed = passed
+	if !passed {
+		result.Message = fmt.Sprintf("expected %s to %s but got %s", assertion.Expression, assertion.Rule(), formatAssertionValue(actual))
+	}
+	return result
+}
+
+// resolveAssertionTarget evaluates the left-hand side of an assertion
+func resolveAssertionTarget(expression string, resp *response.HTTPResponse) (interface{}, error) {
+	expression = strings.TrimSpace(expression)
+
+	switch {
+	case expression == "res.status":
+		return float64(resp.StatusCode), nil
+	case expression == "res.statusText":
+		return resp.Status, nil
+	case expression == "res.responseTime":
+		return float64(resp.ResponseTime.Microseconds()) / 1000, nil
+	case expression == "res.headers":
+		return scriptHeaders(resp.Headers), nil
+	case strings.HasPrefix(expression, "res.headers"):
+		name := strings.TrimPrefix(expression, "res.headers")
+		name = strings.TrimPrefix(name, ".")
+		name = strings.Trim(name, "[]\"'")
+		if value, ok := scriptHeaders(resp.Headers)[strings.ToLower(name)]; ok {
+			return value, nil
+		}
+		return nil, nil
+	case strings.HasPrefix(expression, "res.body"):
+		return resolveBodyPath(strings.TrimPrefix(expression, "res.body"), resp.Body)
+	default:
+		return nil, fmt.Errorf("unsupported assertion expression %q", expression)
+	}
+}
+
+// resolveBodyPath converts the remainder of a res.body expression to a jq
+// filter and runs it against the response body
+func resolveBodyPath(path string, body string) (interface{}, error) {
+	var data interface{}
+	if err := json.Unmarshal([]byte(body), &data); err != nil {
+		if strings.TrimSpace(path) == "" {
+			return body, nil
+		}
+		return nil, fmt.Errorf("response body is not JSON")
+	}
+
+	filter := strings.TrimSpace(path)
+	switch {
+	case filter == "":
+		filter = "."
+	case strings.HasPrefix(filter, "["), strings.HasPrefix(filter, "|"):
+		filter = "." + filter
+	}
+
+	return collections.RunJQ(filter, data)
+}
+
+// parseAssertionOperand decodes the right-hand side of an assertion. JSON
+// literals are decoded, single-quoted strings are unquoted and anything else
+// is used as a plain string.
+func parseAssertionOperand(raw string) interface{} {
+	raw = strings.TrimSpace(raw)
+	if len(raw) >= 2 && strings.HasPrefix(raw, "'") && strings.HasSuffix(raw, "'") {
+		return raw[1 : len(raw)-1]
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(raw), &value); err == nil {
+		return value
+	}
+	return raw
+}
+
+func applyAssertionOperator(operator string, actual interface{}, rawOperand string) (bool, error) {
+	operand := parseAssertionOperand(rawOperand)
+
+	switch operator {
+	case "eq":
+		return assertionValuesEqual(actual, operand), nil
+	case "neq":
+		return !assertionValuesEqual(actual, operand), nil
+	case "gt", "gte", "lt", "lte":
+		a, aOk := assertionNumber(actual)
+		b, bOk := assertionNumber(operand)
+		if !aOk || !bOk {
+			return false, fmt.Errorf("%s requires numbers, got %s and %s", operator, formatAssertionValue(actual), rawOperand)
+		}
+		switch operator {
+		case "gt":
+			return a > b, nil
+		case "gte":
+			return a >= b, nil
+		case "lt":
+			return a < b, nil
+		default:
+			return a <= b, nil
+		}
+	case "in", "notIn":
+		found := false
+		for _, item := range assertionList(operand, rawOperand) {
+			if assertionValuesEqual(actual, item) {
+				found = true
+				break
+			}
+		}
+		return found == (operator == "in"), nil
+	case "contains", "notContains":
+		contains := false
+		switch value := actual.(type) {
+		case string:
+			contains = strings.Contains(value, fmt.Sprint(operand))
+		case []interface{}:
+			for _, item := range value {
+				if assertionValuesEqual(item, operand) {
+					contains = true
+					break
+				}
+			}
+		case map[string]interface{}:
+			_, contains = value[fmt.Sprint(operand)]
+		}
+		return contains == (operator == "contains"), nil
+	case "length":
+		expected, ok := assertionNumber(operand)
+		if !ok {
+			return false, fmt.Errorf("length requires a number, got %s", rawOperand)
+		}
+		length, ok := assertionLength(actual)
+		return ok && float64(length) == expected, nil
+	case "matches", "notMatches":
+		pattern := fmt.Sprint(operand)
+		if strings.HasPrefix(pattern, "/") && strings.LastIndex(pattern, "/") > 0 {
+			pattern = pattern[1:strings.LastIndex(pattern, "/")]
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return false, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+		return re.MatchString(assertionString(actual)) == (operator == "matches"), nil
+	case "startsWith":
+		return strings.HasPrefix(assertionString(actual), fmt.Sprint(operand)), nil
+	case "endsWith":
+		return strings.HasSuffix(assertionString(actual), fmt.Sprint(operand)), nil
+	case "between":
+		bounds := assertionList(operand, rawOperand)
+		if len(bounds) != 2 {
+			return false, fmt.Errorf("between requires two bounds, got %s", rawOperand)
+		}
+		value, ok := assertionNumber(actual)
+		low, lowOk := assertionNumber(bounds[0])
+		high, highOk := assertionNumber(bounds[1])
+		if !ok || !lowOk || !highOk {
+			return false, fmt.Errorf("between requires numbers")
+		}
+		return value >= low && value <= high, nil
+	case "isEmpty", "isNotEmpty":
+		length, ok := assertionLength(actual)
+		empty := actual == nil || (ok && length == 0)
+		return empty == (operator == "isEmpty"), nil
+	case "isNull", "isUndefined":
+		return actual == nil, nil
+	case "isDefined":
+		return actual != nil, nil
+	case "isTruthy", "isFalsy":
+		return assertionTruthy(actual) == (operator == "isTruthy"), nil
+	case "isJson":
+		switch actual.(type) {
+		case map[string]interface{}, []interface{}:
+			return true, nil
+		}
+		return false, nil
+	case "isNumber":
+		_, ok := assertionNumber(actual)
+		_, isString := actual.(string)
+		return ok && !isString, nil
+	case "isString":
+		_, ok := actual.(string)
+		return ok, nil
+	case "isBoolean":
+		_, ok := actual.(bool)
+		return ok, nil
+	case "isArray":
+		_, ok := actual.([]interface{})
+		return ok, nil
+	default:
+		return false, fmt.Errorf("unknown assertion operator %q", operator)
+	}
+}
+
+// assertionValuesEqual compares values, treating numeric strings and numbers as equal
+func assertionValuesEqual(a, b interface{}) bool {
+	if aNum, ok := assertionNumber(a); ok {
+		if bNum, ok := assertionNumber(b); ok {
+			return aNum == bNum
+		}
+	}
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+	if a == nil || b == nil {
+		return false
+	}
+	return assertionString(a) == assertionString(b)
+}
+
+// assertionNumber converts JSON numbers, jq integers and numeric strings to float64
+func assertionNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(v).Float64()
+		return f, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func assertionLength(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case string:
+		return len(v), true
+	case []interface{}:
+		return len(v), true
+	case map[string]interface{}:
+		return len(v), true
+	default:
+		return 0, false
+	}
+}
+
+// assertionList returns the items of an array operand, or splits a plain
+// operand on commas
+func assertionList(operand interface{}, raw string) []interface{} {
+	if items, ok := operand.([]interface{}); ok {
+		return items
+	}
+
+	var items []interface{}
+	for _, part := range strings.Split(raw, ",") {
+		items = append(items, parseAssertionOperand(part))
+	}
+	return items
+}
+
+func assertionTruthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	default:
+		if n, ok := assertionNumber(v); ok {
+			return n != 0
+		}
+		return true
+	}
+}
+
+func assertionString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	case map[string]interface{}, []interface{}:
+		data, _ := json.Marshal(v)
+		return string(data)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func formatAssertionValue(value interface{}) string {
+	if value == nil {
+		return "null"
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
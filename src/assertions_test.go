@@ -0,0 +1,131 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	request "kalo/src/panels/request"
+	response "kalo/src/panels/response"
+)
+
+// assertionResponse is the response the assertion tests check
+var assertionResponse = &response.HTTPResponse{
+	StatusCode:   201,
+	Status:       "201 Created",
+	ResponseTime: 120_000_000, // 120ms
+	Headers:      response.Headers{"Content-Type": {"application/json; charset=utf-8"}},
+	Body:         `{"id": 42, "name": "Ada", "roles": ["admin", "dev"], "items": [{"id": 1}, {"id": 2}], "meta": {"next": null}}`,
+}
+
+func TestEvaluateAssertions(t *testing.T) {
+	tests := []struct {
+		line   string
+		passed bool
+	}{
+		{"res.status: eq 201", true},
+		{"res.status: eq 200", false},
+		{"res.status: neq 200", true},
+		{"res.status: neq 201", false},
+		{"res.status: gt 200", true},
+		{"res.status: gt 201", false},
+		{"res.responseTime: lt 500", true},
+		{"res.status: in 200, 201, 204", true},
+		{"res.status: in [200, 204]", false},
+		{"res.body.name: notIn 'Grace', 'Linus'", true},
+		{"res.headers.content-type: contains json", true},
+		{"res.body.roles: contains admin", true},
+		{"res.body.roles: contains guest", false},
+		{"res.body: contains roles", true},
+		{"res.body.name: notContains Grace", true},
+		{"res.body: isJson", true},
+		{"res.body.roles: isJson", true},
+		{"res.body.name: isJson", false},
+		{"res.body.roles: length 2", true},
+		{"res.body.name: length 4", false},
+		{"res.body.items[1].id: eq 2", true},
+		{"res.body.items | map(.id) | add: eq 3", true},
+		{`res.body.meta.next: isNull`, true},
+		{"res.body.missing: isUndefined", true},
+		{"res.body.name: eq Ada", true},
+		{"res.body.id: eq '42'", true},
+		{"res.statusText: startsWith 201", true},
+		{"res.body.name: matches /^A[a-z]+$/", true},
+		{"res.status: between 200, 299", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			assertions := parseAssertions(t, tt.line)
+			results := EvaluateAssertions(assertions, assertionResponse, nil)
+			if len(results) != 1 {
+				t.Fatalf("Expected one result, got %+v", results)
+			}
+			if results[0].Passed != tt.passed {
+				t.Errorf("Expected passed=%v, got %+v", tt.passed, results[0])
+			}
+			if !results[0].Passed && results[0].Message == "" {
+				t.Errorf("Expected a failure message, got %+v", results[0])
+			}
+		})
+	}
+}
+
+func TestEvaluateAssertionErrors(t *testing.T) {
+	tests := []struct {
+		line    string
+		message string
+	}{
+		{"res.body.name: gt 3", "gt requires numbers"},
+		{"res.body.roles: length many", "length requires a number"},
+		{"res.cookies: eq 1", "unsupported assertion expression"},
+		{"res.body.items[: eq 1", ""},
+	}
+
+	for _, tt := range tests {
+		results := EvaluateAssertions(parseAssertions(t, tt.line), assertionResponse, nil)
+		if len(results) != 1 || results[0].Passed || !strings.Contains(results[0].Message, tt.message) {
+			t.Errorf("%s: expected a failure mentioning %q, got %+v", tt.line, tt.message, results)
+		}
+	}
+
+	// A body that is not JSON cannot be queried
+	text := &response.HTTPResponse{StatusCode: 200, Body: "plain"}
+	results := EvaluateAssertions(parseAssertions(t, "res.body.id: eq 1\nres.body: eq plain"), text, nil)
+	if len(results) != 2 || results[0].Passed || results[0].Message != "response body is not JSON" || !results[1].Passed {
+		t.Errorf("Expected body paths to fail and the whole body to compare on a text response, got %+v", results)
+	}
+}
+
+func TestEvaluateAssertionsSubstitutesVariables(t *testing.T) {
+	vars := map[string]string{"userId": "42", "userName": "Ada", "okStatuses": "200, 201"}
+	assertions := parseAssertions(t, "res.body.id: eq {{userId}}\nres.body.name: eq '{{userName}}'\nres.status: in {{okStatuses}}\nres.body.id: neq {{userId}}\n~res.body.id: eq {{missing}}")
+
+	results := EvaluateAssertions(assertions, assertionResponse, vars)
+	if len(results) != 4 {
+		t.Fatalf("Expected disabled assertions to be skipped, got %+v", results)
+	}
+	for _, result := range results[:3] {
+		if !result.Passed {
+			t.Errorf("Expected %s to pass with variables substituted, got %+v", result.Name, result)
+		}
+	}
+
+	failed := results[3]
+	if failed.Name != "res.body.id: neq {{userId}}" {
+		t.Errorf("Expected the name to show the value as written, got %q", failed.Name)
+	}
+	if failed.Expected != "42" || !strings.Contains(failed.Message, "neq 42") {
+		t.Errorf("Expected the failure to show the substituted value, got %+v", failed)
+	}
+}
+
+// parseAssertions parses assert block lines into assertions
+func parseAssertions(t *testing.T, lines string) []request.BruAssertion {
+	t.Helper()
+	source := "get {\n  url: http://localhost\n}\n\nassert {\n  " + strings.ReplaceAll(lines, "\n", "\n  ") + "\n}\n"
+	bruReq, err := NewBruParser(strings.NewReader(source)).Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse assertions: %v", err)
+	}
+	return bruReq.Assertions
+}
@@ -0,0 +1,603 @@
+// Package bru implements a lossless document model for Bruno .bru files.
+//
+// A Document keeps every block of a file in order, including blocks kalo does
+// not understand, together with comments, blank lines, disabled `~` entries and
+// the original formatting. Only entries and blocks that are changed through the
+// API are re-rendered, so parsing and writing an unmodified file reproduces it
+// byte for byte.
+package bru
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// BlockKind describes how the contents of a block are structured
+type BlockKind int
+
+const (
+	// DictBlock holds `key: value` entries, e.g. meta, headers or auth:bearer
+	DictBlock BlockKind = iota
+	// TextBlock holds free-form text, e.g. body:json, tests or docs
+	TextBlock
+)
+
+// HTTPMethods lists the names of the blocks that hold a request's method and
+// URL. A ws block holds the URL of a WebSocket request, and an http block any
+// other method in its `method` entry.
+var HTTPMethods = []string{"get", "post", "put", "delete", "patch", "options", "head", "trace", "connect", "ws", CustomMethodBlock}
+
+// CustomMethodBlock is the method block used for methods without a block of
+// their own, such as PROPFIND or QUERY
+const CustomMethodBlock = "http"
+
+// Document is a parsed .bru file
+type Document struct {
+	Blocks []*Block
+
+	trailing     []string // blank and comment lines after the last block
+	finalNewline bool
+}
+
+// Block is a named `name { ... }` section of a .bru file
+type Block struct {
+	Name    string
+	Kind    BlockKind
+	Entries []*Entry // Entries of a DictBlock
+	Lines   []string // Raw lines of a TextBlock, without the closing brace
+
+	leading  []string // blank and comment lines above the block
+	header   string   // original header line, reused while the name is unchanged
+	origName string
+	trailing []string // blank and comment lines before the closing brace
+	footer   string
+	indent   string // indentation used for new entries
+}
+
+// Entry is a `key: value` line of a dictionary block. Values are stored
+// without surrounding single quotes; multi-line `'''` values are joined with
+// newlines.
+type Entry struct {
+	Key      string
+	Value    string
+	Disabled bool
+
+	comments     []string // blank and comment lines above the entry
+	raw          []string // original lines, reused while the entry is unchanged
+	origKey      string
+	origValue    string
+	origDisabled bool
+}
+
+// New returns an empty document
+func New() *Document {
+	return &Document{finalNewline: true}
+}
+
+// NewEntry creates a dictionary entry that has not been read from a file
+func NewEntry(key, value string, disabled bool) *Entry {
+	return &Entry{Key: key, Value: value, Disabled: disabled}
+}
+
+// KindOf returns the kind of block used for the given block name. Unknown
+// blocks are treated as text so that their contents are preserved verbatim.
+func KindOf(name string) BlockKind {
+	switch name {
+	case "meta", "headers", "query", "params:query", "params:path", "vars", "vars:pre-request", "vars:post-response", "assert", "settings",
+		"body:form-urlencoded", "body:multipart-form", "tls", "tls:client-certs", "proxy":
+		return DictBlock
+	}
+	if strings.HasPrefix(name, "auth:") || IsMethod(name) {
+		return DictBlock
+	}
+	return TextBlock
+}
+
+// IsMethod reports whether a block name is an HTTP method block
+func IsMethod(name string) bool {
+	for _, method := range HTTPMethods {
+		if name == method {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse reads a .bru document
+func Parse(reader io.Reader) (*Document, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{}
+	text := string(data)
+	if strings.HasSuffix(text, "\n") {
+		doc.finalNewline = true
+		text = strings.TrimSuffix(text, "\n")
+	}
+
+	lines := strings.Split(text, "\n")
+
+	var pending []string
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		// Anything that is not a block header is kept as-is between blocks
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || !strings.HasSuffix(trimmed, "{") {
+			pending = append(pending, line)
+			continue
+		}
+
+		name := strings.TrimSpace(strings.TrimSuffix(trimmed, "{"))
+		block := &Block{
+			Name:     name,
+			Kind:     KindOf(name),
+			leading:  pending,
+			header:   line,
+			origName: name,
+		}
+		pending = nil
+
+		var next int
+		var err error
+		if block.Kind == DictBlock {
+			next, err = block.parseDict(lines, i+1)
+		} else {
+			next, err = block.parseText(lines, i+1)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+
+		doc.Blocks = append(doc.Blocks, block)
+		i = next
+	}
+	doc.trailing = pending
+
+	return doc, nil
+}
+
+// parseDict reads entries up to the closing brace and returns its line index
+func (b *Block) parseDict(lines []string, start int) (int, error) {
+	var comments []string
+	for i := start; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		if trimmed == "}" {
+			b.trailing = comments
+			b.footer = line
+			return i, nil
+		}
+
+		disabled := strings.HasPrefix(trimmed, "~")
+		key, value, ok := splitEntry(strings.TrimPrefix(trimmed, "~"))
+		if !ok || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "//") {
+			comments = append(comments, line)
+			continue
+		}
+
+		if b.indent == "" {
+			b.indent = line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		}
+
+		raw := []string{line}
+		if value == "'''" {
+			// Multi-line value, terminated by a line containing only '''
+			var valueLines []string
+			closed := false
+			for i+1 < len(lines) {
+				i++
+				raw = append(raw, lines[i])
+				if strings.TrimSpace(lines[i]) == "'''" {
+					closed = true
+					break
+				}
+				valueLines = append(valueLines, lines[i])
+			}
+			if !closed {
+				return i, fmt.Errorf("unterminated multi-line value for %q", key)
+			}
+			value = strings.Join(dedent(valueLines), "\n")
+		} else {
+			value = unquote(value)
+		}
+
+		b.Entries = append(b.Entries, &Entry{
+			Key:          key,
+			Value:        value,
+			Disabled:     disabled,
+			comments:     comments,
+			raw:          raw,
+			origKey:      key,
+			origValue:    value,
+			origDisabled: disabled,
+		})
+		comments = nil
+	}
+	return len(lines), fmt.Errorf("unterminated %s block", b.Name)
+}
+
+// parseText reads raw lines up to a closing brace at the start of a line, as
+// Bruno does. Content lines are indented, so braces inside them, such as in
+// JSON strings or scripts, never end the block.
+func (b *Block) parseText(lines []string, start int) (int, error) {
+	for i := start; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimRight(line, " \t\r") == "}" {
+			b.footer = line
+			return i, nil
+		}
+		b.Lines = append(b.Lines, line)
+	}
+	return len(lines), fmt.Errorf("unterminated %s block", b.Name)
+}
+
+// splitEntry splits `key: value`. Keys such as assert expressions may contain
+// colons themselves, so ": " is preferred over the first colon.
+func splitEntry(line string) (string, string, bool) {
+	parts := strings.SplitN(line, ": ", 2)
+	if len(parts) != 2 {
+		parts = strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			return "", "", false
+		}
+	}
+	key := strings.TrimSpace(parts[0])
+	if key == "" {
+		return "", "", false
+	}
+	return key, strings.TrimSpace(parts[1]), true
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 && strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// dedent removes the indentation shared by all non-empty lines
+func dedent(lines []string) []string {
+	common := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if common < 0 || indent < common {
+			common = indent
+		}
+	}
+
+	result := make([]string, len(lines))
+	for i, line := range lines {
+		if len(line) >= common && common > 0 {
+			result[i] = line[common:]
+		} else {
+			result[i] = strings.TrimLeft(line, " \t")
+		}
+	}
+	return result
+}
+
+// String renders the document
+func (d *Document) String() string {
+	var lines []string
+	for _, block := range d.Blocks {
+		lines = append(lines, block.render()...)
+	}
+	lines = append(lines, d.trailing...)
+
+	content := strings.Join(lines, "\n")
+	if d.finalNewline {
+		content += "\n"
+	}
+	return content
+}
+
+func (b *Block) render() []string {
+	lines := append([]string(nil), b.leading...)
+
+	if b.header != "" && b.Name == b.origName {
+		lines = append(lines, b.header)
+	} else {
+		lines = append(lines, b.Name+" {")
+	}
+
+	if b.Kind == DictBlock {
+		for _, entry := range b.Entries {
+			lines = append(lines, entry.render(b.entryIndent())...)
+		}
+		lines = append(lines, b.trailing...)
+	} else {
+		lines = append(lines, b.Lines...)
+	}
+
+	if b.footer != "" {
+		lines = append(lines, b.footer)
+	} else {
+		lines = append(lines, "}")
+	}
+	return lines
+}
+
+func (b *Block) entryIndent() string {
+	if b.indent == "" {
+		return "  "
+	}
+	return b.indent
+}
+
+func (e *Entry) render(indent string) []string {
+	lines := append([]string(nil), e.comments...)
+	if e.raw != nil && !e.changed() {
+		return append(lines, e.raw...)
+	}
+
+	prefix := indent
+	if e.Disabled {
+		prefix += "~"
+	}
+
+	if strings.Contains(e.Value, "\n") {
+		lines = append(lines, prefix+e.Key+": '''")
+		for _, line := range strings.Split(e.Value, "\n") {
+			if line == "" {
+				lines = append(lines, "")
+			} else {
+				lines = append(lines, indent+"  "+line)
+			}
+		}
+		return append(lines, indent+"'''")
+	}
+
+	value := e.Value
+	if value != strings.TrimSpace(value) {
+		value = "'" + value + "'"
+	}
+	if value == "" {
+		return append(lines, prefix+e.Key+":")
+	}
+	return append(lines, prefix+e.Key+": "+value)
+}
+
+func (e *Entry) changed() bool {
+	return e.Key != e.origKey || e.Value != e.origValue || e.Disabled != e.origDisabled
+}
+
+// Block returns the first block with the given name, or nil
+func (d *Document) Block(name string) *Block {
+	for _, block := range d.Blocks {
+		if block.Name == name {
+			return block
+		}
+	}
+	return nil
+}
+
+// BlocksWithPrefix returns all blocks whose name starts with prefix, e.g. "body:"
+func (d *Document) BlocksWithPrefix(prefix string) []*Block {
+	var blocks []*Block
+	for _, block := range d.Blocks {
+		if strings.HasPrefix(block.Name, prefix) {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// MethodBlock returns the block holding the request's method and URL, or nil
+func (d *Document) MethodBlock() *Block {
+	for _, block := range d.Blocks {
+		if IsMethod(block.Name) {
+			return block
+		}
+	}
+	return nil
+}
+
+// EnsureBlock returns the block with the given name, adding an empty one in
+// the conventional position if the document does not have it yet
+func (d *Document) EnsureBlock(name string) *Block {
+	if block := d.Block(name); block != nil {
+		return block
+	}
+
+	block := &Block{Name: name, Kind: KindOf(name)}
+
+	rank := blockRank(name)
+	position := 0
+	for i, existing := range d.Blocks {
+		if existingRank := blockRank(existing.Name); existingRank >= 0 && existingRank <= rank {
+			position = i + 1
+		}
+	}
+
+	// Blocks are separated by a blank line
+	if position > 0 {
+		block.leading = []string{""}
+	} else if len(d.Blocks) > 0 && len(d.Blocks[0].leading) == 0 {
+		d.Blocks[0].leading = []string{""}
+	}
+
+	d.Blocks = append(d.Blocks, nil)
+	copy(d.Blocks[position+1:], d.Blocks[position:])
+	d.Blocks[position] = block
+	return block
+}
+
+// RemoveBlock removes the first block with the given name
+func (d *Document) RemoveBlock(name string) {
+	for i, block := range d.Blocks {
+		if block.Name == name {
+			d.Blocks = append(d.Blocks[:i], d.Blocks[i+1:]...)
+			return
+		}
+	}
+}
+
+// blockRank orders blocks the way Bruno writes them. Unknown blocks return -1.
+func blockRank(name string) int {
+	switch {
+	case name == "meta":
+		return 0
+	case name == "tags":
+		return 1
+	case IsMethod(name):
+		return 2
+	case name == "query" || name == "params:query":
+		return 3
+	case name == "params:path":
+		return 4
+	case name == "headers":
+		return 5
+	case strings.HasPrefix(name, "auth:"):
+		return 6
+	case strings.HasPrefix(name, "body:"):
+		return 7
+	case strings.HasPrefix(name, "vars"):
+		return 8
+	case name == "assert":
+		return 9
+	case name == "script:pre-request":
+		return 10
+	case name == "script:post-response":
+		return 11
+	case name == "tests":
+		return 12
+	case name == "settings":
+		return 13
+	case name == "docs":
+		return 14
+	default:
+		return -1
+	}
+}
+
+// Get returns the value of the last enabled entry with the given key
+func (b *Block) Get(key string) (string, bool) {
+	for i := len(b.Entries) - 1; i >= 0; i-- {
+		if b.Entries[i].Key == key && !b.Entries[i].Disabled {
+			return b.Entries[i].Value, true
+		}
+	}
+	return "", false
+}
+
+// Set updates the last enabled entry with the given key or appends a new one
+func (b *Block) Set(key, value string) {
+	for i := len(b.Entries) - 1; i >= 0; i-- {
+		if b.Entries[i].Key == key && !b.Entries[i].Disabled {
+			b.Entries[i].Value = value
+			return
+		}
+	}
+	b.Entries = append(b.Entries, NewEntry(key, value, false))
+}
+
+// Delete removes all enabled entries with the given key
+func (b *Block) Delete(key string) {
+	entries := b.Entries[:0]
+	for _, entry := range b.Entries {
+		if entry.Key == key && !entry.Disabled {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	b.Entries = entries
+}
+
+// Map returns the enabled entries as a map. Later duplicates win.
+func (b *Block) Map() map[string]string {
+	values := make(map[string]string)
+	for _, entry := range b.Entries {
+		if !entry.Disabled {
+			values[entry.Key] = entry.Value
+		}
+	}
+	return values
+}
+
+// SetMap makes the enabled entries match values. Existing entries keep their
+// position and formatting, disabled entries are left alone and new keys are
+// appended in sorted order.
+func (b *Block) SetMap(values map[string]string) {
+	current := b.Map()
+
+	for key := range current {
+		if _, ok := values[key]; !ok {
+			b.Delete(key)
+		}
+	}
+
+	var added []string
+	for key, value := range values {
+		if existing, ok := current[key]; ok {
+			if existing != value {
+				b.Set(key, value)
+			}
+			continue
+		}
+		added = append(added, key)
+	}
+
+	sort.Strings(added)
+	for _, key := range added {
+		b.Set(key, values[key])
+	}
+}
+
+// Content returns the text of a text block with the block indentation removed
+func (b *Block) Content() string {
+	lines := make([]string, len(b.Lines))
+	for i, line := range b.Lines {
+		lines[i] = strings.TrimPrefix(line, "  ")
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+// SetContent replaces the text of a text block, indenting it by two spaces.
+// The block is left untouched if its content is unchanged.
+func (b *Block) SetContent(content string) {
+	if content == b.Content() {
+		return
+	}
+
+	b.Lines = nil
+	if content == "" {
+		return
+	}
+	for _, line := range strings.Split(content, "\n") {
+		if line == "" {
+			b.Lines = append(b.Lines, "")
+		} else {
+			b.Lines = append(b.Lines, "  "+line)
+		}
+	}
+}
+
+// Clone returns a deep copy of the document
+func (d *Document) Clone() *Document {
+	clone := &Document{
+		trailing:     append([]string(nil), d.trailing...),
+		finalNewline: d.finalNewline,
+	}
+	for _, block := range d.Blocks {
+		copied := *block
+		copied.leading = append([]string(nil), block.leading...)
+		copied.trailing = append([]string(nil), block.trailing...)
+		copied.Lines = append([]string(nil), block.Lines...)
+		copied.Entries = nil
+		for _, entry := range block.Entries {
+			entryCopy := *entry
+			copied.Entries = append(copied.Entries, &entryCopy)
+		}
+		clone.Blocks = append(clone.Blocks, &copied)
+	}
+	return clone
+}
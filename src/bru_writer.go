@@ -0,0 +1,313 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"kalo/src/bru"
+	request "kalo/src/panels/request"
+)
+
+// FormatBruRequest renders a request as .bru content. Requests read from disk
+// are written back into their original document, so blocks, comments,
+// disabled entries and formatting that kalo does not model are preserved and
+// unchanged values keep their original lines.
+func FormatBruRequest(req *request.BruRequest) string {
+	if req.Document == nil {
+		req.Document = bru.New()
+	}
+	updateBruDocument(req.Document, req)
+	return req.Document.String()
+}
+
+// updateBruDocument writes the fields of a request into a document
+func updateBruDocument(doc *bru.Document, req *request.BruRequest) {
+	// Resolve the modes before any block is added or removed
+	previousBodyMode := bodyMode(doc)
+	previousAuthMode := authMode(doc)
+
+	meta := doc.EnsureBlock("meta")
+	setEntry(meta, "name", req.Meta.Name)
+	metaType := req.Meta.Type
+	if metaType == "" {
+		metaType = "http"
+	}
+	if req.IsWebSocket() {
+		metaType = "ws"
+	}
+	setEntry(meta, "type", metaType)
+	setEntry(meta, "seq", strconv.Itoa(req.Meta.Seq))
+
+	writeTags(doc, req.Tags)
+
+	method := strings.ToLower(req.HTTP.Method)
+	if method == "" {
+		method = "get"
+	}
+	blockName := method
+	if !bru.IsMethod(method) || method == bru.CustomMethodBlock {
+		blockName = bru.CustomMethodBlock
+	}
+	httpBlock := doc.MethodBlock()
+	if httpBlock == nil {
+		httpBlock = doc.EnsureBlock(blockName)
+	}
+	httpBlock.Name = blockName
+	writeCustomMethod(httpBlock, strings.ToUpper(method))
+	setEntry(httpBlock, "url", req.HTTP.URL)
+
+	queryBlock := "query"
+	if doc.Block("query") == nil && doc.Block("params:query") != nil {
+		queryBlock = "params:query"
+	}
+	writeParamsBlock(doc, queryBlock, req.Query)
+	writeParamsBlock(doc, "headers", req.Headers)
+
+	// Other auth and body blocks are kept so switching modes is not destructive
+	authType := req.Auth.Type
+	if authType == "" {
+		authType = "none"
+	}
+	setMode(httpBlock, "auth", authType, previousAuthMode)
+	if authType != "none" {
+		writeDictBlock(doc, "auth:"+authType, req.Auth.Values)
+	}
+
+	bodyType := req.Body.Type
+	if bodyType == "" {
+		bodyType = "none"
+	}
+	setMode(httpBlock, "body", bodyType, previousBodyMode)
+	if request.IsFormBody(bodyType) {
+		writeFormFields(doc, "body:"+bodyType, req.Body.Fields)
+	} else if bodyType != "none" {
+		if block := doc.Block("body:" + bodyType); block != nil || req.Body.Data != "" {
+			doc.EnsureBlock("body:" + bodyType).SetContent(req.Body.Data)
+		}
+	}
+	if bodyType == "graphql" {
+		writeTextBlock(doc, "body:graphql:vars", req.Body.Vars)
+	}
+
+	writeDictBlock(doc, "vars", req.Vars)
+	writeAssertions(doc, req.Assertions)
+	writeTextBlock(doc, "script:pre-request", req.Script.PreRequest)
+	writeTextBlock(doc, "script:post-response", req.Script.PostResponse)
+	writeTextBlock(doc, "tests", req.Tests)
+	writeDictBlock(doc, "settings", req.Settings)
+	writeTextBlock(doc, "docs", req.Docs)
+}
+
+// setEntry sets a value unless it is already present, so quoting and
+// formatting of unchanged values are kept
+func setEntry(block *bru.Block, key, value string) {
+	if existing, ok := block.Get(key); ok && existing == value {
+		return
+	}
+	block.Set(key, value)
+}
+
+// writeCustomMethod names the method in an http block, keeping it first like
+// the block name of other methods. Other method blocks have no method entry.
+func writeCustomMethod(block *bru.Block, method string) {
+	if block.Name != bru.CustomMethodBlock {
+		block.Delete("method")
+		return
+	}
+	if _, ok := block.Get("method"); ok {
+		setEntry(block, "method", method)
+		return
+	}
+	block.Entries = append([]*bru.Entry{bru.NewEntry("method", method, false)}, block.Entries...)
+}
+
+// setMode writes the body or auth mode of the method block. Files without the
+// key only get one when the mode differs from what they imply.
+func setMode(block *bru.Block, key, mode, previousMode string) {
+	if _, ok := block.Get(key); ok || mode != previousMode {
+		setEntry(block, key, mode)
+	}
+}
+
+// writeDictBlock syncs a key/value block, removing it once all of its entries
+// have been deleted
+func writeDictBlock(doc *bru.Document, name string, values map[string]string) {
+	block := doc.Block(name)
+	if block == nil {
+		if len(values) == 0 {
+			return
+		}
+		block = doc.EnsureBlock(name)
+	}
+
+	// Annotations such as `@contentType` are not part of the request values
+	merged := make(map[string]string, len(values))
+	for key, value := range block.Map() {
+		if strings.HasPrefix(key, "@") {
+			merged[key] = value
+		}
+	}
+	for key, value := range values {
+		merged[key] = value
+	}
+
+	hadEntries := len(block.Entries) > 0
+	block.SetMap(merged)
+	if hadEntries && len(block.Entries) == 0 {
+		doc.RemoveBlock(name)
+	}
+}
+
+// writeParamsBlock syncs a headers or query block with params, in order.
+// Disabled entries and annotations stay where they are, entries are reused
+// while their key is unchanged and the block is removed once it is empty.
+func writeParamsBlock(doc *bru.Document, name string, params request.BruParams) {
+	block := doc.Block(name)
+	if block == nil {
+		if len(params) == 0 {
+			return
+		}
+		block = doc.EnsureBlock(name)
+	}
+
+	var existing []*bru.Entry
+	for _, entry := range block.Entries {
+		if !entry.Disabled && !strings.HasPrefix(entry.Key, "@") {
+			existing = append(existing, entry)
+		}
+	}
+
+	// Prefer an identical entry, then one with the same key
+	var written []*bru.Entry
+	for _, param := range params {
+		var reused *bru.Entry
+		for _, sameValue := range []bool{true, false} {
+			for i, entry := range existing {
+				if entry.Key == param.Key && (!sameValue || entry.Value == param.Value) {
+					reused = entry
+					existing = append(existing[:i:i], existing[i+1:]...)
+					break
+				}
+			}
+			if reused != nil {
+				break
+			}
+		}
+		if reused == nil {
+			reused = bru.NewEntry(param.Key, param.Value, false)
+		}
+		reused.Value = param.Value
+		written = append(written, reused)
+	}
+
+	// Written entries take the places of the enabled ones, in order
+	var entries []*bru.Entry
+	for _, entry := range block.Entries {
+		if entry.Disabled || strings.HasPrefix(entry.Key, "@") {
+			entries = append(entries, entry)
+		} else if len(written) > 0 {
+			entries = append(entries, written[0])
+			written = written[1:]
+		}
+	}
+	entries = append(entries, written...)
+
+	hadEntries := len(block.Entries) > 0
+	block.Entries = entries
+	if hadEntries && len(entries) == 0 {
+		doc.RemoveBlock(name)
+	}
+}
+
+// writeTextBlock syncs a text block, removing it when the text is cleared
+func writeTextBlock(doc *bru.Document, name, content string) {
+	block := doc.Block(name)
+	if block == nil {
+		if content == "" {
+			return
+		}
+		block = doc.EnsureBlock(name)
+	}
+
+	if block.Content() == content {
+		return
+	}
+	if content == "" {
+		doc.RemoveBlock(name)
+		return
+	}
+	block.SetContent(content)
+}
+
+func writeTags(doc *bru.Document, tags []string) {
+	block := doc.Block("tags")
+	if block != nil && strings.Join(parseTagList(block.Content()), "\n") == strings.Join(tags, "\n") {
+		return
+	}
+	writeTextBlock(doc, "tags", strings.Join(tags, "\n"))
+}
+
+// writeAssertions replaces the assert entries, reusing existing entries that
+// still describe the same assertion so their original text is kept
+func writeAssertions(doc *bru.Document, assertions []request.BruAssertion) {
+	block := doc.Block("assert")
+	if block == nil {
+		if len(assertions) == 0 {
+			return
+		}
+		block = doc.EnsureBlock("assert")
+	}
+
+	hadEntries := len(block.Entries) > 0
+	existing := block.Entries
+	var entries []*bru.Entry
+	for _, assertion := range assertions {
+		var reused *bru.Entry
+		for i, entry := range existing {
+			if request.ParseAssertion(entry.Key, entry.Value, !entry.Disabled) == assertion {
+				reused = entry
+				existing = append(existing[:i:i], existing[i+1:]...)
+				break
+			}
+		}
+		if reused == nil {
+			reused = bru.NewEntry(assertion.Expression, assertion.Rule(), !assertion.Enabled)
+		}
+		entries = append(entries, reused)
+	}
+
+	block.Entries = entries
+	if hadEntries && len(entries) == 0 {
+		doc.RemoveBlock("assert")
+	}
+}
+
+// writeFormFields replaces the entries of a form body block, reusing existing
+// entries for unchanged fields
+func writeFormFields(doc *bru.Document, name string, fields []request.BruFormField) {
+	block := doc.Block(name)
+	if block == nil {
+		if len(fields) == 0 {
+			return
+		}
+		block = doc.EnsureBlock(name)
+	}
+
+	existing := block.Entries
+	var entries []*bru.Entry
+	for _, field := range fields {
+		var reused *bru.Entry
+		for i, entry := range existing {
+			if entry.Key == field.Key && entry.Value == field.Value && entry.Disabled == !field.Enabled {
+				reused = entry
+				existing = append(existing[:i:i], existing[i+1:]...)
+				break
+			}
+		}
+		if reused == nil {
+			reused = bru.NewEntry(field.Key, field.Value, !field.Enabled)
+		}
+		entries = append(entries, reused)
+	}
+	block.Entries = entries
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Reporter writes the results of a `kalo run` invocation in a specific format
+type Reporter interface {
+	Report(w io.Writer, results []*RunResult) error
+}
+
+// NewReporter returns the reporter for the given name. An empty name selects plain text.
+func NewReporter(name string) (Reporter, error) {
+	switch strings.ToLower(name) {
+	case "", "text":
+		return &TextReporter{}, nil
+	case "json":
+		return &JSONReporter{}, nil
+	case "junit":
+		return &JUnitReporter{}, nil
+	case "tap":
+		return &TAPReporter{}, nil
+	default:
+		return nil, fmt.Errorf("unknown reporter %q (expected json, junit or tap)", name)
+	}
+}
+
+// runSummary counts requests and tests across a run
+type runSummary struct {
+	Requests        int `json:"requests"`
+	FailedRequests  int `json:"failed_requests"`
+	SkippedRequests int `json:"skipped_requests"`
+	Tests           int `json:"tests"`
+	FailedTests     int `json:"failed_tests"`
+}
+
+func summarizeResults(results []*RunResult) runSummary {
+	summary := runSummary{Requests: len(results)}
+	for _, result := range results {
+		if result.Skipped != "" {
+			summary.SkippedRequests++
+		} else if result.Failed() {
+			summary.FailedRequests++
+		}
+		for _, test := range result.TestResults {
+			summary.Tests++
+			if !test.Passed {
+				summary.FailedTests++
+			}
+		}
+	}
+	return summary
+}
+
+// TextReporter prints a human readable summary
+type TextReporter struct{}
+
+func (r *TextReporter) Report(w io.Writer, results []*RunResult) error {
+	for _, result := range results {
+		status := "✓"
+		if result.Failed() {
+			status = "✗"
+		} else if result.Skipped != "" {
+			status = "-"
+		}
+
+		fmt.Fprintf(w, "%s %s %s (%s)", status, result.Method, result.Name, result.FilePath)
+		if result.Skipped != "" {
+			fmt.Fprintf(w, "\n    skipped: %s\n", result.Skipped)
+			continue
+		}
+		if result.Error != "" {
+			fmt.Fprintf(w, "\n    error: %s\n", result.Error)
+			writeScriptLogs(w, result.ScriptLogs)
+			continue
+		}
+		fmt.Fprintf(w, " → %d in %s\n", result.StatusCode, result.Duration.Round(time.Millisecond))
+
+		for _, test := range result.TestResults {
+			if test.Passed {
+				fmt.Fprintf(w, "    ✓ %s\n", test.Name)
+			} else {
+				fmt.Fprintf(w, "    ✗ %s\n", test.Name)
+				if test.Message != "" {
+					fmt.Fprintf(w, "      %s\n", test.Message)
+				}
+			}
+		}
+		writeScriptLogs(w, result.ScriptLogs)
+	}
+
+	summary := summarizeResults(results)
+	fmt.Fprintf(w, "\nRequests: %d passed, %d failed, %d skipped, %d total\n", summary.Requests-summary.FailedRequests-summary.SkippedRequests, summary.FailedRequests, summary.SkippedRequests, summary.Requests)
+	fmt.Fprintf(w, "Tests:    %d passed, %d failed, %d total\n", summary.Tests-summary.FailedTests, summary.FailedTests, summary.Tests)
+	return nil
+}
+
+// writeScriptLogs prints the console output of a request's scripts below its tests
+func writeScriptLogs(w io.Writer, scriptLogs []ScriptLog) {
+	for _, line := range scriptLogs {
+		if line.Level == "log" {
+			fmt.Fprintf(w, "    │ %s\n", line.Message)
+		} else {
+			fmt.Fprintf(w, "    │ %s: %s\n", line.Level, line.Message)
+		}
+	}
+}
+
+// JSONReporter prints all results as a single JSON document
+type JSONReporter struct{}
+
+func (r *JSONReporter) Report(w io.Writer, results []*RunResult) error {
+	output := struct {
+		Summary runSummary   `json:"summary"`
+		Results []*RunResult `json:"results"`
+	}{
+		Summary: summarizeResults(results),
+		Results: results,
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(output)
+}
+
+// JUnitReporter prints results as JUnit XML, one test suite per request
+type JUnitReporter struct{}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+func (r *JUnitReporter) Report(w io.Writer, results []*RunResult) error {
+	suites := junitTestSuites{}
+
+	for _, result := range results {
+		suite := junitTestSuite{
+			Name: result.Name,
+			Time: fmt.Sprintf("%.3f", result.Duration.Seconds()),
+		}
+
+		if result.Skipped != "" {
+			suite.Skipped++
+			suite.TestCases = append(suite.TestCases, junitTestCase{
+				Name:      result.Method + " " + result.URL,
+				ClassName: result.FilePath,
+				Time:      suite.Time,
+				Skipped:   &junitMessage{Message: result.Skipped},
+			})
+		} else if result.Error != "" {
+			suite.Errors++
+			suite.TestCases = append(suite.TestCases, junitTestCase{
+				Name:      result.Method + " " + result.URL,
+				ClassName: result.FilePath,
+				Time:      suite.Time,
+				Error:     &junitMessage{Message: result.Error, Body: result.Error},
+			})
+		} else if len(result.TestResults) == 0 {
+			// Requests without tests are reported as a single passing case
+			suite.TestCases = append(suite.TestCases, junitTestCase{
+				Name:      result.Method + " " + result.URL,
+				ClassName: result.FilePath,
+				Time:      suite.Time,
+			})
+		}
+
+		for _, test := range result.TestResults {
+			testCase := junitTestCase{
+				Name:      test.Name,
+				ClassName: result.FilePath,
+				Time:      "0.000",
+			}
+			if !test.Passed {
+				suite.Failures++
+				testCase.Failure = &junitMessage{Message: test.Message, Body: formatExpectedActual(test)}
+			}
+			suite.TestCases = append(suite.TestCases, testCase)
+		}
+
+		suite.Tests = len(suite.TestCases)
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Errors += suite.Errors
+		suites.Skipped += suite.Skipped
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// TAPReporter prints results in Test Anything Protocol version 13
+type TAPReporter struct{}
+
+func (r *TAPReporter) Report(w io.Writer, results []*RunResult) error {
+	type tapLine struct {
+		ok          bool
+		description string
+		message     string
+		skip        string
+	}
+
+	var lines []tapLine
+	for _, result := range results {
+		request := fmt.Sprintf("%s %s", result.Method, result.Name)
+		if result.Skipped != "" {
+			lines = append(lines, tapLine{ok: true, description: request, skip: result.Skipped})
+			continue
+		}
+		if result.Error != "" {
+			lines = append(lines, tapLine{ok: false, description: request, message: result.Error})
+			continue
+		}
+		if len(result.TestResults) == 0 {
+			lines = append(lines, tapLine{ok: true, description: request})
+			continue
+		}
+		for _, test := range result.TestResults {
+			lines = append(lines, tapLine{ok: test.Passed, description: request + " - " + test.Name, message: test.Message})
+		}
+	}
+
+	fmt.Fprintln(w, "TAP version 13")
+	fmt.Fprintf(w, "1..%d\n", len(lines))
+	for i, line := range lines {
+		status := "ok"
+		if !line.ok {
+			status = "not ok"
+		}
+		if line.skip != "" {
+			fmt.Fprintf(w, "%s %d %s # SKIP %s\n", status, i+1, line.description, line.skip)
+			continue
+		}
+		fmt.Fprintf(w, "%s %d %s\n", status, i+1, line.description)
+		if !line.ok && line.message != "" {
+			fmt.Fprintln(w, "  ---")
+			fmt.Fprintf(w, "  message: %q\n", line.message)
+			fmt.Fprintln(w, "  ...")
+		}
+	}
+	return nil
+}
+
+// formatExpectedActual describes a failed test's expected and actual values
+func formatExpectedActual(test TestResult) string {
+	if test.Expected == nil && test.Actual == nil {
+		return test.Message
+	}
+	return fmt.Sprintf("%s\nexpected: %v\nactual: %v", test.Message, test.Expected, test.Actual)
+}
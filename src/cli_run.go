@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	request "kalo/src/panels/request"
+)
+
+// RunResult holds the outcome of a single request executed by `kalo run`
+type RunResult struct {
+	Name        string        `json:"name"`
+	FilePath    string        `json:"file_path"`
+	Method      string        `json:"method"`
+	URL         string        `json:"url"`
+	StatusCode  int           `json:"status_code"`
+	Duration    time.Duration `json:"-"`
+	Error       string        `json:"error,omitempty"`
+	Skipped     string        `json:"skipped,omitempty"`
+	TestResults []TestResult  `json:"test_results,omitempty"`
+	ScriptLogs  []ScriptLog   `json:"script_logs,omitempty"`
+}
+
+// MarshalJSON writes the duration in milliseconds, as nanoseconds mean little to a CI report
+func (r *RunResult) MarshalJSON() ([]byte, error) {
+	type result RunResult
+	return json.Marshal(struct {
+		*result
+		DurationMS int64 `json:"duration_ms"`
+	}{
+		result:     (*result)(r),
+		DurationMS: r.Duration.Milliseconds(),
+	})
+}
+
+// Failed reports whether the request errored or any of its tests failed. Skipped requests never fail.
+func (r *RunResult) Failed() bool {
+	if r.Error != "" {
+		return true
+	}
+	for _, result := range r.TestResults {
+		if !result.Passed {
+			return true
+		}
+	}
+	return false
+}
+
+// runTarget is a request queued for execution together with its source file
+type runTarget struct {
+	request  *request.BruRequest
+	filePath string
+	env      *Environment
+}
+
+// runCLI implements `kalo run <collection-or-file> [--env name] [--reporter json|junit|tap]`
+// and returns the process exit code
+func runCLI(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	envName := flags.String("env", "", "environment to use for variable substitution")
+	reporterName := flags.String("reporter", "", "output format: json, junit or tap (default: plain text)")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: kalo run <collection-or-file> [--env name] [--reporter json|junit|tap]")
+		flags.PrintDefaults()
+	}
+
+	// Allow flags before and after the positional path
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return 2
+		}
+		if flags.NArg() == 0 {
+			break
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+
+	if len(positional) != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	reporter, err := NewReporter(*reporterName)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	targets, err := loadRunTargets(positional[0], *envName)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	if len(targets) == 0 {
+		fmt.Fprintf(stderr, "Error: no requests found in %s\n", positional[0])
+		return 2
+	}
+
+	client := NewHTTPClient()
+	if tokensPath, err := getOAuth2TokensPath(); err == nil {
+		client.OAuth2Tokens = NewOAuth2TokenStore(tokensPath)
+	}
+	// Without a TUI the authorization URL is also printed in case no browser opens
+	client.OpenBrowser = func(url string) error {
+		fmt.Fprintf(stderr, "Authorize in your browser: %s\n", url)
+		openBrowser(url)
+		return nil
+	}
+	runner := NewScriptRunner()
+	results := make([]*RunResult, 0, len(targets))
+
+	for _, target := range targets {
+		results = append(results, executeRunTarget(client, runner, target))
+	}
+
+	if err := reporter.Report(stdout, results); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	for _, result := range results {
+		if result.Failed() {
+			return 1
+		}
+	}
+	return 0
+}
+
+// loadRunTargets resolves a collection directory or single .bru file into an
+// ordered list of requests with their environments. A folder without the
+// named environment uses the one at the root of the run; when neither has it
+// the run fails rather than sending requests without their variables.
+func loadRunTargets(path, envName string) ([]*runTarget, error) {
+	path = filepath.Clean(path)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	root := path
+	if !info.IsDir() {
+		root = filepath.Dir(path)
+	}
+
+	var targets []*runTarget
+	environments := make(map[string][]*Environment)
+
+	if info.IsDir() {
+		data := LoadBruFiles(path, 0)
+		environments = data.Environments
+
+		for _, item := range data.Collections {
+			if item.IsFolder || item.IsTagGroup || item.RequestIndex < 0 || item.RequestIndex >= len(data.BruRequests) {
+				continue
+			}
+			// Tagged requests appear once per tag in the collections list
+			if containsRunTarget(targets, item.FilePath) {
+				continue
+			}
+			targets = append(targets, &runTarget{
+				request:  data.BruRequests[item.RequestIndex],
+				filePath: item.FilePath,
+			})
+		}
+	} else {
+		bruFile, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer bruFile.Close()
+
+		parsed, err := NewBruParser(bruFile).Parse()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+
+		dir := filepath.Dir(path)
+		parsed.Dir = dir
+		environments[dir] = LoadEnvironments(dir)
+		targets = append(targets, &runTarget{request: parsed, filePath: path})
+	}
+
+	// Execute requests folder by folder in meta.seq order
+	sort.SliceStable(targets, func(i, j int) bool {
+		dirI, dirJ := filepath.Dir(targets[i].filePath), filepath.Dir(targets[j].filePath)
+		if dirI != dirJ {
+			return dirI < dirJ
+		}
+		if targets[i].request.Meta.Seq != targets[j].request.Meta.Seq {
+			return targets[i].request.Meta.Seq < targets[j].request.Meta.Seq
+		}
+		return targets[i].filePath < targets[j].filePath
+	})
+
+	if envName != "" {
+		var missing []string
+		for _, target := range targets {
+			dir := filepath.Dir(target.filePath)
+			target.env = FindEnvironment(environments[dir], envName)
+			if target.env == nil {
+				target.env = FindEnvironment(environments[root], envName)
+			}
+			if target.env == nil && !slices.Contains(missing, dir) {
+				missing = append(missing, dir)
+			}
+		}
+		if len(missing) > 0 {
+			return nil, fmt.Errorf("environment %q not found in %s", envName, strings.Join(missing, ", "))
+		}
+	}
+
+	return targets, nil
+}
+
+func containsRunTarget(targets []*runTarget, filePath string) bool {
+	for _, target := range targets {
+		if target.filePath == filePath {
+			return true
+		}
+	}
+	return false
+}
+
+// executeRunTarget runs a single request with its scripts and tests
+func executeRunTarget(client *HTTPClient, runner *ScriptRunner, target *runTarget) *RunResult {
+	result := &RunResult{
+		Name:     target.request.Meta.Name,
+		FilePath: target.filePath,
+		Method:   target.request.HTTP.Method,
+		URL:      target.request.HTTP.URL,
+	}
+	if result.Name == "" {
+		result.Name = strings.TrimSuffix(filepath.Base(target.filePath), ".bru")
+	}
+
+	// A WebSocket conversation needs someone to type it, so it has nothing to run
+	if target.request.IsWebSocket() {
+		result.Skipped = "WebSocket requests can only be opened in the interactive console"
+		return result
+	}
+
+	execution := executeBruRequest(context.Background(), client, runner, target.request, target.env)
+	result.StatusCode = execution.Response.StatusCode
+	result.Duration = execution.Response.ResponseTime
+	result.Error = execution.Response.Error
+	result.TestResults = execution.TestResults
+	result.ScriptLogs = execution.ScriptLogs
+	// Tests have seen the body, so a temp file holding it is no longer needed
+	removeBodyFile(execution.Response)
+
+	return result
+}
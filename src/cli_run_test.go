@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeCollection writes files relative to a new collection directory
+func writeCollection(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	return dir
+}
+
+// runRequest is a .bru request for the collections the run tests build
+func runRequest(name string, seq int, url, tests string) string {
+	source := fmt.Sprintf("meta {\n  name: %s\n  type: http\n  seq: %d\n}\n\nget {\n  url: %s\n}\n", name, seq, url)
+	if tests != "" {
+		source += "\ntests {\n  " + tests + "\n}\n"
+	}
+	return source
+}
+
+func TestLoadRunTargetsOrder(t *testing.T) {
+	dir := writeCollection(t, map[string]string{
+		"z-login.bru":     runRequest("Login", 1, "http://localhost/login", ""),
+		"b-profile.bru":   runRequest("Profile", 2, "http://localhost/me", ""),
+		"a-settings.bru":  runRequest("Settings", 2, "http://localhost/settings", ""),
+		"users/list.bru":  runRequest("List Users", 2, "http://localhost/users", ""),
+		"users/first.bru": runRequest("First User", 1, "http://localhost/users/1", ""),
+	})
+
+	targets, err := loadRunTargets(dir, "")
+	if err != nil {
+		t.Fatalf("Failed to load targets: %v", err)
+	}
+
+	var names []string
+	for _, target := range targets {
+		names = append(names, target.request.Meta.Name)
+	}
+	// Folder by folder, then by meta.seq, then by file name
+	expected := "Login,Settings,Profile,First User,List Users"
+	if strings.Join(names, ",") != expected {
+		t.Errorf("Expected order %s, got %s", expected, strings.Join(names, ","))
+	}
+}
+
+func TestLoadRunTargetsEnvironments(t *testing.T) {
+	dir := writeCollection(t, map[string]string{
+		"environments/dev.bru":        "vars {\n  baseUrl: http://root.dev\n}\n",
+		"admin/environments/dev.bru":  "vars {\n  baseUrl: http://admin.dev\n}\n",
+		"admin/environments/prod.bru": "vars {\n  baseUrl: http://admin.prod\n}\n",
+		"health.bru":                  runRequest("Health", 1, "{{baseUrl}}/health", ""),
+		"admin/stats.bru":             runRequest("Stats", 1, "{{baseUrl}}/stats", ""),
+		"users/list.bru":              runRequest("List Users", 1, "{{baseUrl}}/users", ""),
+	})
+
+	targets, err := loadRunTargets(dir, "dev")
+	if err != nil {
+		t.Fatalf("Failed to load targets: %v", err)
+	}
+	expected := map[string]string{
+		"Health":     "http://root.dev",
+		"Stats":      "http://admin.dev",
+		"List Users": "http://root.dev", // users has no environments of its own
+	}
+	for _, target := range targets {
+		if target.env == nil || target.env.Vars["baseUrl"] != expected[target.request.Meta.Name] {
+			t.Errorf("Expected %s to use %s, got %+v", target.request.Meta.Name, expected[target.request.Meta.Name], target.env)
+		}
+	}
+
+	// Only admin defines prod, so the other folders are named in the error
+	_, err = loadRunTargets(dir, "prod")
+	if err == nil {
+		t.Fatal("Expected an error for folders without the environment")
+	}
+	if !strings.Contains(err.Error(), dir+",") || !strings.Contains(err.Error(), filepath.Join(dir, "users")) || strings.Contains(err.Error(), "admin") {
+		t.Errorf("Expected the error to name the root and users folders only, got %v", err)
+	}
+
+	if _, err := loadRunTargets(filepath.Join(dir, "admin", "stats.bru"), "prod"); err != nil {
+		t.Errorf("Expected a single file to use its folder's environments, got %v", err)
+	}
+}
+
+func TestRunCLIExitCodes(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	statusTest := `test("status", function() { expect(res.getStatus()).to.equal(200); });`
+	dir := writeCollection(t, map[string]string{
+		"environments/local.bru":      "vars {\n  baseUrl: " + server.URL + "\n}\n",
+		"ok.bru":                      runRequest("OK", 1, "{{baseUrl}}/ok", statusTest),
+		"fail.bru":                    runRequest("Fail", 2, "{{baseUrl}}/fail", statusTest),
+		"down.bru":                    runRequest("Down", 3, "http://127.0.0.1:1/down", ""),
+		"chat/environments/local.bru": "vars {\n  baseUrl: " + server.URL + "\n}\n",
+		"chat/chat.bru":               "meta {\n  name: Chat\n  type: ws\n  seq: 1\n}\n\nws {\n  url: {{baseUrl}}/chat\n}\n",
+		"chat/ok.bru":                 runRequest("OK", 2, "{{baseUrl}}/ok", statusTest),
+	})
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected int
+	}{
+		{"all tests pass", []string{filepath.Join(dir, "ok.bru"), "--env", "local"}, 0},
+		{"flags before the path", []string{"--env", "local", "--reporter", "tap", filepath.Join(dir, "ok.bru")}, 0},
+		{"a test fails", []string{filepath.Join(dir, "fail.bru"), "--env", "local"}, 1},
+		{"a request errors", []string{filepath.Join(dir, "down.bru")}, 1},
+		{"whole collection", []string{dir, "--env", "local"}, 1},
+		{"WebSocket requests are skipped", []string{filepath.Join(dir, "chat"), "--env", "local"}, 0},
+		{"no path", []string{"--env", "local"}, 2},
+		{"unknown reporter", []string{dir, "--reporter", "xml"}, 2},
+		{"unknown environment", []string{dir, "--env", "missing"}, 2},
+		{"missing path", []string{filepath.Join(dir, "nothing")}, 2},
+		{"no requests", []string{t.TempDir()}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := runCLI(tt.args, &stdout, &stderr); code != tt.expected {
+				t.Errorf("Expected exit code %d, got %d\nstdout: %s\nstderr: %s", tt.expected, code, stdout.String(), stderr.String())
+			}
+		})
+	}
+}
+
+// reporterResults covers a passing request, a failed test, a request error and a skipped WebSocket request
+var reporterResults = []*RunResult{
+	{
+		Name: "Login", FilePath: "api/login.bru", Method: "POST", URL: "http://localhost/login",
+		StatusCode: 200, Duration: 120 * time.Millisecond,
+		TestResults: []TestResult{{Name: "status", Passed: true}},
+	},
+	{
+		Name: "Users", FilePath: "api/users.bru", Method: "GET", URL: "http://localhost/users",
+		StatusCode: 200, Duration: 80 * time.Millisecond,
+		TestResults: []TestResult{
+			{Name: "status", Passed: true},
+			{Name: "count", Passed: false, Message: "expected 2 to equal 3", Expected: 3, Actual: 2},
+		},
+	},
+	{
+		Name: "Health", FilePath: "api/health.bru", Method: "GET", URL: "http://localhost/health",
+		Error: "connection refused",
+	},
+	{
+		Name: "Chat", FilePath: "api/chat.bru", Method: "WS", URL: "ws://localhost/chat",
+		Skipped: "WebSocket requests can only be opened in the interactive console",
+	},
+}
+
+func TestJSONReporter(t *testing.T) {
+	var output bytes.Buffer
+	if err := (&JSONReporter{}).Report(&output, reporterResults); err != nil {
+		t.Fatalf("Failed to report: %v", err)
+	}
+
+	var report struct {
+		Summary runSummary `json:"summary"`
+		Results []struct {
+			RunResult
+			DurationMS *int64 `json:"duration_ms"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(output.Bytes(), &report); err != nil {
+		t.Fatalf("Expected JSON output, got %s: %v", output.String(), err)
+	}
+	expected := runSummary{Requests: 4, FailedRequests: 2, SkippedRequests: 1, Tests: 3, FailedTests: 1}
+	if report.Summary != expected {
+		t.Errorf("Expected summary %+v, got %+v", expected, report.Summary)
+	}
+	if len(report.Results) != 4 || report.Results[2].Error != "connection refused" || report.Results[1].TestResults[1].Message != "expected 2 to equal 3" {
+		t.Errorf("Expected every result with its tests and errors, got %+v", report.Results)
+	}
+	if report.Results[3].Skipped == "" || report.Results[3].Error != "" {
+		t.Errorf("Expected the WebSocket request to be skipped, got %+v", report.Results[3])
+	}
+	if durationMS := report.Results[0].DurationMS; durationMS == nil || *durationMS != 120 {
+		t.Errorf("Expected the duration in milliseconds, got %s", output.String())
+	}
+}
+
+func TestJUnitReporter(t *testing.T) {
+	var output bytes.Buffer
+	if err := (&JUnitReporter{}).Report(&output, reporterResults); err != nil {
+		t.Fatalf("Failed to report: %v", err)
+	}
+
+	var suites junitTestSuites
+	if err := xml.Unmarshal(output.Bytes(), &suites); err != nil {
+		t.Fatalf("Expected JUnit XML, got %s: %v", output.String(), err)
+	}
+	if suites.Tests != 5 || suites.Failures != 1 || suites.Errors != 1 || suites.Skipped != 1 || len(suites.Suites) != 4 {
+		t.Errorf("Expected 5 tests with 1 failure, 1 error and 1 skipped in 4 suites, got %+v", suites)
+	}
+
+	failure := suites.Suites[1].TestCases[1].Failure
+	if failure == nil || failure.Message != "expected 2 to equal 3" || !strings.Contains(failure.Body, "3") {
+		t.Errorf("Expected the failed test to carry its message, got %+v", failure)
+	}
+	errored := suites.Suites[2].TestCases[0]
+	if errored.Error == nil || errored.Error.Message != "connection refused" || errored.Name != "GET http://localhost/health" {
+		t.Errorf("Expected the request error as an error case, got %+v", errored)
+	}
+	skipped := suites.Suites[3].TestCases[0]
+	if skipped.Skipped == nil || skipped.Error != nil || !strings.Contains(output.String(), "<skipped message=") {
+		t.Errorf("Expected the WebSocket request as a skipped case, got %+v", skipped)
+	}
+}
+
+func TestTAPReporter(t *testing.T) {
+	var output bytes.Buffer
+	if err := (&TAPReporter{}).Report(&output, reporterResults); err != nil {
+		t.Fatalf("Failed to report: %v", err)
+	}
+
+	expected := `TAP version 13
+1..5
+ok 1 POST Login - status
+ok 2 GET Users - status
+not ok 3 GET Users - count
+  ---
+  message: "expected 2 to equal 3"
+  ...
+not ok 4 GET Health
+  ---
+  message: "connection refused"
+  ...
+ok 5 WS Chat # SKIP WebSocket requests can only be opened in the interactive console
+`
+	if output.String() != expected {
+		t.Errorf("Unexpected TAP output:\n%s", output.String())
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"kalo/src/bru"
+)
+
+// CollectionConfig is what a collection's collection.bru configures for all
+// of its requests. It is read once per request and shared by the settings,
+// OAuth2 and transport lookups of that request.
+type CollectionConfig struct {
+	Dir      string
+	Settings map[string]string
+	TLS      *TLSConfig
+	tlsErr   error         // Reported when the transport is built, like other transport errors
+	doc      *bru.Document // The proxy block is resolved with each request's variables
+
+	modTime time.Time
+	size    int64
+}
+
+// LoadCollectionConfig reads the collection.bru of a collection. It returns
+// nil when the collection has none.
+func LoadCollectionConfig(collectionPath string) (*CollectionConfig, error) {
+	if collectionPath == "" {
+		return nil, nil
+	}
+
+	collectionFile, err := os.Open(filepath.Join(collectionPath, collectionFileName))
+	if err != nil {
+		return nil, nil
+	}
+	defer collectionFile.Close()
+
+	info, err := collectionFile.Stat()
+	if err != nil {
+		return nil, nil
+	}
+	doc, err := bru.Parse(collectionFile)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", collectionFileName, err)
+	}
+
+	config := &CollectionConfig{
+		Dir:      collectionPath,
+		Settings: make(map[string]string),
+		doc:      doc,
+		modTime:  info.ModTime(),
+		size:     info.Size(),
+	}
+	if block := doc.Block("settings"); block != nil {
+		for key, value := range block.Map() {
+			if !strings.HasPrefix(key, "@") {
+				config.Settings[key] = value
+			}
+		}
+	}
+	config.TLS, config.tlsErr = parseCollectionTLS(collectionPath, doc)
+	return config, nil
+}
+
+// proxy resolves the proxy configuration with a request's variables
+func (config *CollectionConfig) proxy(resolve func(string) string) (*ProxyConfig, error) {
+	if config == nil {
+		return nil, nil
+	}
+	return parseCollectionProxy(config.doc, resolve)
+}
+
+// unchanged reports whether collection.bru still has the size and
+// modification time it had when config was read
+func (config *CollectionConfig) unchanged(info os.FileInfo) bool {
+	return config.modTime.Equal(info.ModTime()) && config.size == info.Size()
+}
+
+// collectionConfig returns the configuration of a collection, reading its
+// collection.bru again only when the file has changed since it was cached
+func (c *HTTPClient) collectionConfig(collectionPath string) (*CollectionConfig, error) {
+	if collectionPath == "" {
+		return nil, nil
+	}
+	info, err := os.Stat(filepath.Join(collectionPath, collectionFileName))
+	if err != nil {
+		return nil, nil
+	}
+
+	c.collectionsMu.Lock()
+	defer c.collectionsMu.Unlock()
+
+	if cached, exists := c.collections[collectionPath]; exists && cached.unchanged(info) {
+		return cached, nil
+	}
+	config, err := LoadCollectionConfig(collectionPath)
+	if err != nil || config == nil {
+		delete(c.collections, collectionPath)
+		return nil, err
+	}
+	c.collections[collectionPath] = config
+	return config, nil
+}
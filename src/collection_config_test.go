@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCollectionConfigCache(t *testing.T) {
+	dir := t.TempDir()
+	collectionPath := filepath.Join(dir, collectionFileName)
+	writeConfig := func(content string, modTime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(collectionPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(collectionPath, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client := NewHTTPClient()
+	modTime := time.Now().Add(-time.Hour)
+
+	writeConfig("settings {\n  timeout: 100\n}\n", modTime)
+	first, err := client.collectionConfig(dir)
+	if err != nil || first == nil || first.Settings["timeout"] != "100" {
+		t.Fatalf("Expected the collection settings, got %+v, %v", first, err)
+	}
+	firstTransport, err := client.transportFor(first, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached, _ := client.collectionConfig(dir); cached != first {
+		t.Errorf("Expected an unchanged collection.bru to be read once")
+	}
+
+	writeConfig("settings {\n  timeout: 200\n}\n\ntls {\n  insecureSkipVerify: true\n}\n", modTime.Add(time.Minute))
+	second, err := client.collectionConfig(dir)
+	if err != nil || second == first || second.Settings["timeout"] != "200" {
+		t.Fatalf("Expected a modified collection.bru to be read again, got %+v, %v", second, err)
+	}
+	secondTransport, err := client.transportFor(second, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secondTransport == firstTransport || !secondTransport.TLSClientConfig.InsecureSkipVerify {
+		t.Errorf("Expected a new transport for the changed tls block")
+	}
+
+	writeConfig("settings {\n", modTime.Add(2*time.Minute))
+	if _, err := client.collectionConfig(dir); err == nil {
+		t.Errorf("Expected an invalid collection.bru to be reported")
+	}
+	if config, err := client.collectionConfig(t.TempDir()); config != nil || err != nil {
+		t.Errorf("Expected no config for a collection without collection.bru, got %+v, %v", config, err)
+	}
+}
@@ -56,11 +56,7 @@ func (h *CollectionsInputHandler) HandleInput(key tea.KeyMsg, m *model) (*model,
 				// Execute the selected request
 				m.currentReq = m.bruRequests[item.RequestIndex]
 				m.requestCursor = request.QuerySection
-				m.isLoading = true
-				return m, tea.Batch(func() tea.Msg {
-					resp, err := m.httpClient.ExecuteRequest(m.currentReq)
-					return httpResponseMsg{response: resp, err: err}
-				})
+				return m, m.executeRequest()
 			}
 		}
 		return m, nil
@@ -142,11 +138,7 @@ func (h *CollectionsInputHandler) HandleInput(key tea.KeyMsg, m *model) (*model,
 			item := m.collections[m.selectedReq]
 			if item.RequestIndex >= 0 && item.RequestIndex < len(m.bruRequests) {
 				m.currentReq = m.bruRequests[item.RequestIndex]
-				m.isLoading = true
-				return m, tea.Batch(func() tea.Msg {
-					resp, err := m.httpClient.ExecuteRequest(m.currentReq)
-					return httpResponseMsg{response: resp, err: err}
-				})
+				return m, m.executeRequest()
 			}
 		}
 		return m, nil
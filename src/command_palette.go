@@ -30,6 +30,16 @@ func NewCommandPalette() *CommandPalette {
 		{Name: "Import OpenAPI", Description: "Import OpenAPI 3.x specification", Action: "import_openapi"},
 		{Name: "Import Collection", Description: "Import Bruno collection", Action: "import_collection"},
 		{Name: "Switch Theme", Description: "Change the application theme", Action: "switch_theme"},
+		{Name: "Switch Environment", Description: "Select the active environment for this collection", Action: "switch_environment"},
+		{Name: "Request History", Description: "Browse past requests and re-open their responses", Action: "show_history"},
+		{Name: "Re-send From History", Description: "Send a past request again as it was sent, with fresh credentials", Action: "resend_history"},
+		{Name: "GraphQL Introspect", Description: "Fetch the schema of the current request's endpoint for completion", Action: "graphql_introspect"},
+		{Name: "Resume Stream", Description: "Reconnect to the last streamed response from its last event id", Action: "resume_stream"},
+		{Name: "Save Stream", Description: "Save the captured stream of the response to a file", Action: "save_stream"},
+		{Name: "Save Response Body", Description: "Save the response body as received to a file", Action: "save_body"},
+		{Name: "Cookies", Description: "Inspect, edit, delete and clear cookies of this collection and environment", Action: "manage_cookies"},
+		{Name: "Clear OAuth2 Tokens", Description: "Forget cached OAuth2 tokens for this collection and environment", Action: "clear_oauth2_tokens"},
+		{Name: "Prune History", Description: "Remove old history entries by age or size", Action: "prune_history"},
 		{Name: "Settings", Description: "Open application settings", Action: "settings"},
 	}
 
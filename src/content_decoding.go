@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/transform"
+)
+
+// acceptEncoding is sent when a request does not set Accept-Encoding itself.
+// Bodies are decoded by kalo rather than net/http so their size on the wire
+// is known.
+const acceptEncoding = "gzip, deflate"
+
+// bodyKind is what a response body turned out to contain
+type bodyKind string
+
+const (
+	bodyText   bodyKind = "text"
+	bodyJSON   bodyKind = "json"
+	bodyXML    bodyKind = "xml"
+	bodyHTML   bodyKind = "html"
+	bodyBinary bodyKind = "binary"
+)
+
+// countingReader counts the bytes read through it and keeps the error that
+// ended reading
+type countingReader struct {
+	reader io.Reader
+	count  int64
+	err    error
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.count += int64(n)
+	if err != nil {
+		r.err = err
+	}
+	return n, err
+}
+
+// decodingReader undoes the Content-Encoding of a body as it arrives. A body
+// that turns out to be corrupt ends early, keeping what was decoded and the
+// reason in err, while errors reading from the connection are passed on.
+type decodingReader struct {
+	decoder         io.Reader
+	wire            *countingReader
+	contentEncoding string
+	err             error
+}
+
+// newBodyDecoder decodes a response body read through wire. A body in an
+// encoding that is not supported is read as received, with the reason.
+func newBodyDecoder(wire *countingReader, contentEncoding string) (*decodingReader, error) {
+	decoder, err := newContentDecoder(wire, contentEncoding)
+	if err != nil {
+		decoder = wire
+	}
+	return &decodingReader{decoder: decoder, wire: wire, contentEncoding: contentEncoding}, err
+}
+
+func (r *decodingReader) Read(p []byte) (int, error) {
+	n, err := r.decoder.Read(p)
+	if err != nil && err != io.EOF && (r.wire.err == nil || r.wire.err == io.EOF) {
+		r.err = fmt.Errorf("failed to decode %s body: %v", r.contentEncoding, err)
+		return n, io.EOF
+	}
+	return n, err
+}
+
+// newContentDecoder undoes the Content-Encoding of a body as it is read.
+// Encodings are listed in the order they were applied, so they are undone
+// last to first.
+func newContentDecoder(body io.Reader, contentEncoding string) (io.Reader, error) {
+	encodings := strings.Split(contentEncoding, ",")
+	for i := len(encodings) - 1; i >= 0; i-- {
+		switch encoding := strings.ToLower(strings.TrimSpace(encodings[i])); encoding {
+		case "", "identity":
+		case "gzip", "x-gzip":
+			reader, err := gzip.NewReader(body)
+			if err != nil {
+				return nil, fmt.Errorf("invalid gzip body: %v", err)
+			}
+			body = reader
+		case "deflate":
+			body = newDeflateReader(body)
+		case "br":
+			body = brotli.NewReader(body)
+		case "zstd":
+			decoder, err := zstd.NewReader(body, zstd.WithDecoderConcurrency(1))
+			if err != nil {
+				return nil, fmt.Errorf("invalid zstd body: %v", err)
+			}
+			body = &zstdReader{decoder: decoder}
+		default:
+			return nil, fmt.Errorf("%s encoding is not supported", encoding)
+		}
+	}
+	return body, nil
+}
+
+// newDeflateReader reads a deflate body. The zlib wrapper required by HTTP
+// is often left out, so raw deflate data is accepted too.
+func newDeflateReader(body io.Reader) io.Reader {
+	buffered := bufio.NewReader(body)
+	header, _ := buffered.Peek(2)
+	if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		if reader, err := zlib.NewReader(buffered); err == nil {
+			return reader
+		}
+	}
+	return flate.NewReader(buffered)
+}
+
+// zstdReader releases its decoder once the body has been read, since bodies
+// are not always closed
+type zstdReader struct {
+	decoder *zstd.Decoder
+}
+
+func (r *zstdReader) Read(p []byte) (int, error) {
+	n, err := r.decoder.Read(p)
+	if err != nil {
+		r.decoder.Close()
+	}
+	return n, err
+}
+
+var (
+	metaCharsetPattern = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?\s*([\w.:-]+)`)
+	xmlEncodingPattern = regexp.MustCompile(`^<\?xml[^>]+encoding\s*=\s*["']([\w.:-]+)["']`)
+)
+
+// declaredCharset returns the charset of a body from its Content-Type, or
+// from the document itself for HTML and XML
+func declaredCharset(data []byte, contentType string) string {
+	if _, params, err := mime.ParseMediaType(contentType); err == nil && params["charset"] != "" {
+		return params["charset"]
+	}
+
+	head := data
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	if match := xmlEncodingPattern.FindSubmatch(bytes.TrimSpace(head)); match != nil {
+		return string(match[1])
+	}
+	if match := metaCharsetPattern.FindSubmatch(head); match != nil {
+		return string(match[1])
+	}
+	return ""
+}
+
+// decodeCharset converts a text body to UTF-8, returning the name of the
+// charset it was converted from. Unknown charsets are left as they are.
+func decodeCharset(data []byte, contentType string) (string, string) {
+	name := declaredCharset(data, contentType)
+	if name == "" {
+		return string(data), ""
+	}
+	encoding, err := htmlindex.Get(name)
+	if err != nil {
+		return string(data), ""
+	}
+	canonical, _ := htmlindex.Name(encoding)
+	if canonical == "utf-8" {
+		return string(data), canonical
+	}
+
+	decoded, _, err := transform.Bytes(encoding.NewDecoder(), data)
+	if err != nil {
+		return string(data), ""
+	}
+	return string(decoded), canonical
+}
+
+// newCharsetReader converts a streamed body to UTF-8 using the charset of
+// its Content-Type, returning the name of the charset it converts from
+func newCharsetReader(body io.Reader, contentType string) (io.Reader, string) {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil || params["charset"] == "" {
+		return body, ""
+	}
+	encoding, err := htmlindex.Get(params["charset"])
+	if err != nil {
+		return body, ""
+	}
+	canonical, _ := htmlindex.Name(encoding)
+	if canonical == "utf-8" {
+		return body, canonical
+	}
+	return transform.NewReader(body, encoding.NewDecoder()), canonical
+}
+
+// detectBodyKind classifies a decoded body. The Content-Type is trusted when
+// the body agrees with it; missing, generic or wrong types are sniffed.
+func detectBodyKind(contentType string, data []byte) bodyKind {
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	trimmed := bytes.TrimSpace(data)
+
+	switch {
+	case mediaType == "application/json" || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json"):
+		if json.Valid(trimmed) {
+			return bodyJSON
+		}
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		return bodyHTML
+	case mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml"):
+		return bodyXML
+	case strings.HasPrefix(mediaType, "image/") || strings.HasPrefix(mediaType, "audio/") || strings.HasPrefix(mediaType, "video/") || strings.HasPrefix(mediaType, "font/"):
+		return bodyBinary
+	}
+
+	if len(trimmed) == 0 {
+		return bodyText
+	}
+	if (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed) {
+		return bodyJSON
+	}
+	if params["charset"] == "" && looksBinary(data) {
+		return bodyBinary
+	}
+	switch sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(data)); sniffed {
+	case "text/html":
+		return bodyHTML
+	case "text/xml":
+		return bodyXML
+	}
+	return bodyText
+}
+
+// looksBinary reports whether data is not text in any charset
+func looksBinary(data []byte) bool {
+	sample := data
+	if len(sample) > 8192 {
+		sample = sample[:8192]
+	}
+	if bytes.IndexByte(sample, 0) >= 0 {
+		return true
+	}
+	return !utf8.Valid(sample) && !strings.HasPrefix(http.DetectContentType(sample), "text/")
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	response "kalo/src/panels/response"
+)
+
+// newEncodedBodyServer serves a body per path in a different encoding or charset
+func newEncodedBodyServer(t *testing.T) *httptest.Server {
+	return newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gzip":
+			if r.Header.Get("Accept-Encoding") != acceptEncoding {
+				t.Errorf("Expected the default Accept-Encoding, got %q", r.Header.Get("Accept-Encoding"))
+			}
+			// JSON sent as text/plain is sniffed
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("Content-Encoding", "gzip")
+			writer := gzip.NewWriter(w)
+			writer.Write([]byte(`{"name": "caf` + strings.Repeat("é", 200) + `"}`))
+			writer.Close()
+		case "/truncated":
+			var compressed bytes.Buffer
+			writer := gzip.NewWriter(&compressed)
+			writer.Write([]byte(strings.Repeat("line\n", 1000)))
+			writer.Close()
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(compressed.Bytes()[:compressed.Len()-8])
+		case "/deflate":
+			w.Header().Set("Content-Encoding", "deflate")
+			writer, _ := flate.NewWriter(w, flate.BestCompression)
+			writer.Write([]byte("<?xml version=\"1.0\"?><items/>"))
+			writer.Close()
+		case "/latin1":
+			w.Header().Set("Content-Type", "text/plain; charset=ISO-8859-1")
+			w.Write([]byte{'c', 'a', 'f', 0xe9})
+		case "/shift_jis":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><head><meta charset=\"Shift_JIS\"></head><body>\x93\xfa\x96\x7b</body></html>"))
+		case "/brotli":
+			if r.Header.Get("Accept-Encoding") != "br" {
+				t.Errorf("Expected the request's Accept-Encoding, got %q", r.Header.Get("Accept-Encoding"))
+			}
+			w.Header().Set("Content-Encoding", "br")
+			writer := brotli.NewWriter(w)
+			writer.Write([]byte(`{"encoding": "br"}`))
+			writer.Close()
+		case "/zstd":
+			w.Header().Set("Content-Encoding", "zstd")
+			writer, _ := zstd.NewWriter(w)
+			writer.Write([]byte(`{"encoding": "zstd"}`))
+			writer.Close()
+		case "/compress":
+			w.Header().Set("Content-Encoding", "compress")
+			w.Write([]byte{0x1f, 0x9d, 0x90, 0x61})
+		case "/png":
+			w.Write([]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"))
+		}
+	})
+}
+
+func TestContentDecoding(t *testing.T) {
+	server := newEncodedBodyServer(t)
+	tests := []struct {
+		path           string
+		acceptEncoding string
+		check          func(resp *response.HTTPResponse) bool
+	}{
+		{"/gzip", "", func(resp *response.HTTPResponse) bool {
+			// Pretty printed, with the compressed size below the decoded size
+			return resp.IsJSON && strings.Contains(resp.Body, `"name": "café`) &&
+				resp.ContentEncoding == "gzip" && resp.WireSize < resp.BodySize && response.FormatBodySize(resp) != ""
+		}},
+		{"/truncated", "", func(resp *response.HTTPResponse) bool {
+			// What could be decoded is kept with the reason
+			return strings.HasPrefix(resp.Body, "line\nline\n") && strings.HasPrefix(resp.DecodeError, "failed to decode gzip body")
+		}},
+		{"/deflate", "", func(resp *response.HTTPResponse) bool {
+			return resp.IsXML && resp.Body == "<?xml version=\"1.0\"?><items/>"
+		}},
+		{"/latin1", "", func(resp *response.HTTPResponse) bool {
+			return resp.Body == "café" && resp.Charset == "windows-1252"
+		}},
+		{"/shift_jis", "", func(resp *response.HTTPResponse) bool {
+			return resp.IsHTML && strings.Contains(resp.Body, "日本") && resp.Charset == "shift_jis"
+		}},
+		{"/brotli", "br", func(resp *response.HTTPResponse) bool {
+			return resp.IsJSON && strings.Contains(resp.Body, `"encoding": "br"`) && resp.ContentEncoding == "br"
+		}},
+		{"/zstd", "zstd", func(resp *response.HTTPResponse) bool {
+			return resp.IsJSON && strings.Contains(resp.Body, `"encoding": "zstd"`) && resp.DecodeError == ""
+		}},
+		{"/compress", "compress", func(resp *response.HTTPResponse) bool {
+			// Kept as received
+			return resp.DecodeError == "compress encoding is not supported" && resp.BodySize == 4
+		}},
+		{"/png", "", func(resp *response.HTTPResponse) bool {
+			return resp.IsBinary
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			sent := &HTTPRequestModel{Method: GET, URL: server.URL + test.path, Headers: response.Headers{}}
+			if test.acceptEncoding != "" {
+				sent.Headers.Set("Accept-Encoding", test.acceptEncoding)
+			}
+			resp := NewHTTPClient().SendRequest(context.Background(), sent)
+			if resp.Error != "" || !test.check(resp) {
+				t.Errorf("Unexpected response: %q %s (%s, %s)", resp.Body, resp.Error, resp.ContentEncoding, resp.Charset)
+			}
+		})
+	}
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// cookiesFileName is the file under ~/.kalo that stores cookie jars
+const cookiesFileName = "cookies.json"
+
+// StoredCookie is a cookie kept in a collection's jar
+type StoredCookie struct {
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Domain   string    `json:"domain"`
+	Path     string    `json:"path"`
+	Expires  time.Time `json:"expires,omitempty"` // Zero for session cookies
+	Secure   bool      `json:"secure,omitempty"`
+	HttpOnly bool      `json:"http_only,omitempty"`
+	HostOnly bool      `json:"host_only,omitempty"` // Set without a Domain attribute, so not sent to subdomains
+}
+
+func (c *StoredCookie) expired(now time.Time) bool {
+	return !c.Expires.IsZero() && !c.Expires.After(now)
+}
+
+// sameCookie reports whether two cookies have the same name, domain and
+// path, so one replaces the other
+func (c *StoredCookie) sameCookie(other *StoredCookie) bool {
+	return c.Name == other.Name && c.Domain == other.Domain && c.Path == other.Path
+}
+
+// matches reports whether the cookie should be sent to u
+func (c *StoredCookie) matches(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	if c.HostOnly {
+		if host != c.Domain {
+			return false
+		}
+	} else if host != c.Domain && !strings.HasSuffix(host, "."+c.Domain) {
+		return false
+	}
+
+	if c.Secure && u.Scheme != "https" && u.Scheme != "wss" {
+		return false
+	}
+
+	requestPath := u.EscapedPath()
+	if requestPath == "" {
+		requestPath = "/"
+	}
+	if requestPath == c.Path {
+		return true
+	}
+	return strings.HasPrefix(requestPath, c.Path) && (strings.HasSuffix(c.Path, "/") || requestPath[len(c.Path)] == '/')
+}
+
+// formatCookie renders a single line describing a cookie for the cookie manager
+func formatCookie(cookie *StoredCookie) string {
+	value := cookie.Value
+	if len(value) > 40 {
+		value = value[:37] + "..."
+	}
+
+	domain := cookie.Domain
+	if !cookie.HostOnly {
+		domain = "." + domain
+	}
+
+	expires := "session"
+	if !cookie.Expires.IsZero() {
+		expires = cookie.Expires.Local().Format("2006-01-02 15:04")
+	}
+
+	line := fmt.Sprintf("%s=%s  %s%s  %s", cookie.Name, value, domain, cookie.Path, expires)
+	if cookie.Secure {
+		line += "  Secure"
+	}
+	if cookie.HttpOnly {
+		line += "  HttpOnly"
+	}
+	return line
+}
+
+// CookieStore keeps a cookie jar per collection and environment. Jars are
+// written to ~/.kalo so logins survive restarts.
+type CookieStore struct {
+	path    string
+	cookies map[string][]*StoredCookie // Keyed by collectionScope
+	dirty   bool                       // Received cookies not written yet, see Flush
+	mu      sync.Mutex
+}
+
+// getCookiesPath returns the path of the cookie file, creating ~/.kalo if needed
+func getCookiesPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	kaloDir := filepath.Join(homeDir, ".kalo")
+	if err := os.MkdirAll(kaloDir, 0755); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(kaloDir, cookiesFileName), nil
+}
+
+// NewCookieStore creates a cookie store backed by the given file. An empty
+// path keeps cookies in memory only.
+func NewCookieStore(path string) *CookieStore {
+	store := &CookieStore{path: path, cookies: make(map[string][]*StoredCookie)}
+	if path != "" {
+		if data, err := os.ReadFile(path); err == nil {
+			json.Unmarshal(data, &store.cookies)
+		}
+	}
+	return store
+}
+
+// Cookies returns copies of the unexpired cookies of a scope, sorted by
+// domain, path and name
+func (s *CookieStore) Cookies(scope string) []*StoredCookie {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var cookies []*StoredCookie
+	for _, cookie := range s.cookies[scope] {
+		if !cookie.expired(now) {
+			copied := *cookie
+			cookies = append(cookies, &copied)
+		}
+	}
+
+	sort.Slice(cookies, func(i, j int) bool {
+		if cookies[i].Domain != cookies[j].Domain {
+			return cookies[i].Domain < cookies[j].Domain
+		}
+		if cookies[i].Path != cookies[j].Path {
+			return cookies[i].Path < cookies[j].Path
+		}
+		return cookies[i].Name < cookies[j].Name
+	})
+	return cookies
+}
+
+// Set adds a cookie to a scope, replacing one with the same name, domain and path
+func (s *CookieStore) Set(scope string, cookie *StoredCookie) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.set(scope, cookie)
+	return s.save()
+}
+
+func (s *CookieStore) set(scope string, cookie *StoredCookie) {
+	for i, existing := range s.cookies[scope] {
+		if existing.sameCookie(cookie) {
+			s.cookies[scope][i] = cookie
+			return
+		}
+	}
+	s.cookies[scope] = append(s.cookies[scope], cookie)
+}
+
+// Delete removes a cookie from a scope
+func (s *CookieStore) Delete(scope string, cookie *StoredCookie) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delete(scope, cookie)
+	return s.save()
+}
+
+func (s *CookieStore) delete(scope string, cookie *StoredCookie) {
+	cookies := s.cookies[scope]
+	for i, existing := range cookies {
+		if existing.sameCookie(cookie) {
+			s.cookies[scope] = append(cookies[:i], cookies[i+1:]...)
+			break
+		}
+	}
+	if len(s.cookies[scope]) == 0 {
+		delete(s.cookies, scope)
+	}
+}
+
+// Clear removes all cookies of a scope, returning how many were removed
+func (s *CookieStore) Clear(scope string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := len(s.cookies[scope])
+	delete(s.cookies, scope)
+	return removed, s.save()
+}
+
+// Flush writes the cookies received since the last write. Jars only record
+// cookies, so a response and each of its redirects do not rewrite the file;
+// the sender flushes once the request is done.
+func (s *CookieStore) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.dirty {
+		return nil
+	}
+	return s.save()
+}
+
+func (s *CookieStore) save() error {
+	s.dirty = false
+	if s.path == "" {
+		return nil
+	}
+
+	// Drop expired cookies so the file does not grow forever
+	now := time.Now()
+	for scope, cookies := range s.cookies {
+		kept := cookies[:0]
+		for _, cookie := range cookies {
+			if !cookie.expired(now) {
+				kept = append(kept, cookie)
+			}
+		}
+		if len(kept) == 0 {
+			delete(s.cookies, scope)
+		} else {
+			s.cookies[scope] = kept
+		}
+	}
+
+	data, err := json.MarshalIndent(s.cookies, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0600)
+}
+
+// Jar returns an http.CookieJar for a scope. Cookies received are always
+// stored; they are only sent when send is true.
+func (s *CookieStore) Jar(scope string, send bool) http.CookieJar {
+	return &scopedCookieJar{store: s, scope: scope, send: send}
+}
+
+type scopedCookieJar struct {
+	store *CookieStore
+	scope string
+	send  bool
+}
+
+// SetCookies stores the Set-Cookie headers of a response to u
+func (j *scopedCookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.store.mu.Lock()
+	defer j.store.mu.Unlock()
+
+	now := time.Now()
+	host := strings.ToLower(u.Hostname())
+	for _, cookie := range cookies {
+		stored := &StoredCookie{
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Domain:   host,
+			Path:     cookie.Path,
+			Secure:   cookie.Secure,
+			HttpOnly: cookie.HttpOnly,
+			HostOnly: true,
+		}
+
+		if cookie.Domain != "" {
+			domain := strings.TrimPrefix(strings.ToLower(cookie.Domain), ".")
+			// Servers may only set cookies for their own domain or a parent
+			// of it that is not a public suffix such as co.uk. IP addresses
+			// and public suffixes can only set cookies for themselves.
+			ipHost := net.ParseIP(host) != nil
+			publicSuffix := isPublicSuffix(domain)
+			if host != domain && (!strings.HasSuffix(host, "."+domain) || ipHost || publicSuffix) {
+				continue
+			}
+			if !ipHost && !publicSuffix {
+				stored.Domain = domain
+				stored.HostOnly = false
+			}
+		}
+
+		if stored.Path == "" || !strings.HasPrefix(stored.Path, "/") {
+			stored.Path = defaultCookiePath(u)
+		}
+
+		switch {
+		case cookie.MaxAge < 0:
+			stored.Expires = now
+		case cookie.MaxAge > 0:
+			stored.Expires = now.Add(time.Duration(cookie.MaxAge) * time.Second)
+		case !cookie.Expires.IsZero():
+			stored.Expires = cookie.Expires
+		}
+
+		if stored.expired(now) {
+			j.store.delete(j.scope, stored)
+			continue
+		}
+		j.store.set(j.scope, stored)
+	}
+	j.store.dirty = true
+}
+
+// Cookies returns the cookies to send with a request to u, longest path first
+func (j *scopedCookieJar) Cookies(u *url.URL) []*http.Cookie {
+	if !j.send {
+		return nil
+	}
+
+	var matched []*StoredCookie
+	for _, cookie := range j.store.Cookies(j.scope) {
+		if cookie.matches(u) {
+			matched = append(matched, cookie)
+		}
+	}
+	sort.SliceStable(matched, func(i, k int) bool {
+		return len(matched[i].Path) > len(matched[k].Path)
+	})
+
+	cookies := make([]*http.Cookie, 0, len(matched))
+	for _, cookie := range matched {
+		cookies = append(cookies, &http.Cookie{Name: cookie.Name, Value: cookie.Value})
+	}
+	return cookies
+}
+
+// isPublicSuffix reports whether cookies for domain would be shared by
+// unrelated sites, e.g. com, co.uk or github.io
+func isPublicSuffix(domain string) bool {
+	suffix, _ := publicsuffix.PublicSuffix(domain)
+	return suffix == domain
+}
+
+// defaultCookiePath is the directory of the request path, used when a cookie
+// does not set its own path
+func defaultCookiePath(u *url.URL) string {
+	dir := path.Dir(u.EscapedPath())
+	if !strings.HasPrefix(dir, "/") {
+		return "/"
+	}
+	return dir
+}
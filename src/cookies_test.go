@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	panels "kalo/src/panels/request"
+)
+
+// newCookieServer sets cookies on /login and echoes the cookies it receives
+func newCookieServer(t *testing.T) *httptest.Server {
+	return newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+			http.SetCookie(w, &http.Cookie{Name: "admin", Value: "1", Path: "/admin"})
+		default:
+			var names []string
+			for _, cookie := range r.Cookies() {
+				names = append(names, cookie.Name+"="+cookie.Value)
+			}
+			fmt.Fprint(w, strings.Join(names, ";"))
+		}
+	})
+}
+
+// sendWithCookies sends a GET request from the "collection" collection and
+// returns the response body
+func sendWithCookies(t *testing.T, client *HTTPClient, url string, env *Environment, settings map[string]string) string {
+	t.Helper()
+	bruReq := &panels.BruRequest{HTTP: panels.BruHTTP{Method: "GET", URL: url}, Settings: settings, Dir: "collection"}
+	resp := sendBruRequest(context.Background(), client, bruReq, env)
+	if resp.Error != "" {
+		t.Fatalf("Request to %s failed: %s", url, resp.Error)
+	}
+	return resp.Body
+}
+
+func TestCookieJarScopes(t *testing.T) {
+	server := newCookieServer(t)
+	client := NewHTTPClient()
+	client.Cookies = NewCookieStore(filepath.Join(t.TempDir(), cookiesFileName))
+	staging := &Environment{Name: "staging"}
+
+	sendWithCookies(t, client, server.URL+"/login", staging, nil)
+	tests := []struct {
+		name     string
+		path     string
+		env      *Environment
+		settings map[string]string
+		expected string
+	}{
+		{"path scoped", "/me", staging, nil, "session=abc"},
+		{"longest path first", "/admin/users", staging, nil, "admin=1;session=abc"},
+		{"other environment", "/me", nil, nil, ""},
+		{"sendCookies disabled", "/me", staging, map[string]string{"sendCookies": "false"}, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if body := sendWithCookies(t, client, server.URL+test.path, test.env, test.settings); body != test.expected {
+				t.Errorf("Expected cookies %q, got %q", test.expected, body)
+			}
+		})
+	}
+}
+
+func TestCookieStorePersistence(t *testing.T) {
+	server := newCookieServer(t)
+	cookiesPath := filepath.Join(t.TempDir(), cookiesFileName)
+	client := NewHTTPClient()
+	client.Cookies = NewCookieStore(cookiesPath)
+	staging := &Environment{Name: "staging"}
+	sendWithCookies(t, client, server.URL+"/login", staging, nil)
+
+	// Cookies survive a restart and can be edited
+	reloaded := NewCookieStore(cookiesPath)
+	scope := "collection|staging"
+	cookies := reloaded.Cookies(scope)
+	if len(cookies) != 2 || cookies[0].Name != "session" || !cookies[0].HostOnly {
+		t.Fatalf("Expected the stored cookies to be reloaded, got %+v", cookies)
+	}
+	cookies[0].Value = "edited"
+	reloaded.Set(scope, cookies[0])
+	reloaded.Delete(scope, cookies[1])
+	client.Cookies = reloaded
+	if body := sendWithCookies(t, client, server.URL+"/admin/users", staging, nil); body != "session=edited" {
+		t.Errorf("Expected the edited cookie, got %q", body)
+	}
+	if removed, _ := reloaded.Clear(scope); removed != 1 {
+		t.Errorf("Expected 1 cookie to be cleared, got %d", removed)
+	}
+}
+
+func TestCookieDomainAttribute(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		domain   string
+		stored   bool
+		hostOnly bool
+	}{
+		{"parent domain", "https://api.example.com/", "example.com", true, false},
+		{"leading dot", "https://api.example.com/", ".example.com", true, false},
+		{"own host", "https://api.example.com/", "api.example.com", true, false},
+		{"other site", "https://api.example.com/", "example.org", false, false},
+		{"subdomain of the host", "https://example.com/", "api.example.com", false, false},
+		{"public suffix", "https://shop.example.co.uk/", "co.uk", false, false},
+		{"private public suffix", "https://jane.github.io/", "github.io", false, false},
+		{"public suffix host", "https://co.uk/", "co.uk", true, true},
+		{"IP address parent", "http://192.168.1.10/", "168.1.10", false, false},
+		{"IP address host", "http://192.168.1.10/", "192.168.1.10", true, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := NewCookieStore("")
+			u, _ := url.Parse(test.url)
+			store.Jar("scope", true).SetCookies(u, []*http.Cookie{{Name: "id", Value: "1", Domain: test.domain}})
+
+			cookies := store.Cookies("scope")
+			if (len(cookies) == 1) != test.stored {
+				t.Fatalf("Expected stored to be %t, got %+v", test.stored, cookies)
+			}
+			if test.stored && cookies[0].HostOnly != test.hostOnly {
+				t.Errorf("Expected HostOnly to be %t, got %+v", test.hostOnly, cookies[0])
+			}
+		})
+	}
+}
+
+func TestCookieMatching(t *testing.T) {
+	store := NewCookieStore("")
+	jar := store.Jar("scope", true)
+	set := func(rawURL string, cookie *http.Cookie) {
+		u, _ := url.Parse(rawURL)
+		jar.SetCookies(u, []*http.Cookie{cookie})
+	}
+	set("https://api.example.com/login", &http.Cookie{Name: "host", Value: "1"})
+	set("https://api.example.com/", &http.Cookie{Name: "domain", Value: "1", Domain: "example.com"})
+	set("https://api.example.com/", &http.Cookie{Name: "admin", Value: "1", Path: "/admin"})
+	set("https://api.example.com/docs/v1/index.html", &http.Cookie{Name: "docs", Value: "1"})
+	set("https://api.example.com/", &http.Cookie{Name: "secure", Value: "1", Secure: true})
+
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"https://api.example.com/", "host,secure,domain"},
+		// Host-only cookies are not sent to other hosts of the domain
+		{"https://www.example.com/", "domain"},
+		{"https://example.com/", "domain"},
+		{"https://example.org/", ""},
+		{"http://api.example.com/", "host,domain"},
+		{"https://api.example.com/admin", "admin,host,secure,domain"},
+		{"https://api.example.com/admin/users", "admin,host,secure,domain"},
+		{"https://api.example.com/administrator", "host,secure,domain"},
+		// Without a Path the cookie belongs to the directory of the request
+		{"https://api.example.com/docs/v1/search", "docs,host,secure,domain"},
+		{"https://api.example.com/docs/v2", "host,secure,domain"},
+	}
+	for _, test := range tests {
+		u, _ := url.Parse(test.url)
+		var names []string
+		for _, cookie := range jar.Cookies(u) {
+			names = append(names, cookie.Name)
+		}
+		if got := strings.Join(names, ","); got != test.expected {
+			t.Errorf("Cookies for %s: expected %q, got %q", test.url, test.expected, got)
+		}
+	}
+}
+
+func TestCookieStoreSavesOncePerRequest(t *testing.T) {
+	cookiesPath := filepath.Join(t.TempDir(), cookiesFileName)
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+			http.Redirect(w, r, "/sso", http.StatusFound)
+		case "/sso":
+			// Cookies of earlier hops are not written while the request is running
+			if _, err := os.Stat(cookiesPath); err == nil {
+				http.Error(w, "cookies saved before the request finished", http.StatusInternalServerError)
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "sso", Value: "1", Path: "/"})
+		}
+	})
+	client := NewHTTPClient()
+	client.Cookies = NewCookieStore(cookiesPath)
+
+	bruReq := &panels.BruRequest{HTTP: panels.BruHTTP{Method: "GET", URL: server.URL + "/login"}, Dir: "collection"}
+	if resp := sendBruRequest(context.Background(), client, bruReq, nil); resp.Error != "" || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected the redirected login to succeed, got %d %s %s", resp.StatusCode, resp.Error, resp.Body)
+	}
+
+	if cookies := NewCookieStore(cookiesPath).Cookies("collection|"); len(cookies) != 2 {
+		t.Errorf("Expected the cookies of every hop to be saved once the request finished, got %+v", cookies)
+	}
+}
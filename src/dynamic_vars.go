@@ -0,0 +1,267 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	mathrand "math/rand/v2"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// dynamicVariables generate a new value each time they are used, with the
+// names Bruno and Postman use
+var dynamicVariables = map[string]func() string{
+	"$guid":       newUUID,
+	"$randomUUID": newUUID,
+	"$timestamp": func() string {
+		return strconv.FormatInt(time.Now().Unix(), 10)
+	},
+	"$isoTimestamp": func() string {
+		return time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+	},
+	"$randomInt": func() string {
+		return strconv.Itoa(mathrand.IntN(1001))
+	},
+	"$randomEmail": func() string {
+		names := []string{"alex", "sam", "jordan", "taylor", "casey", "morgan", "riley", "jamie"}
+		return fmt.Sprintf("%s%d@example.com", names[mathrand.IntN(len(names))], mathrand.IntN(10000))
+	},
+}
+
+// templateFilters transform the value before them in a pipeline such as
+// {{token | base64}}. Arguments follow the filter name.
+var templateFilters = map[string]func(value string, args []string) (string, error){
+	"base64": func(value string, args []string) (string, error) {
+		return base64.StdEncoding.EncodeToString([]byte(value)), nil
+	},
+	"urlencode": func(value string, args []string) (string, error) {
+		// Like encodeURIComponent, spaces become %20 rather than +
+		return strings.ReplaceAll(url.QueryEscape(value), "+", "%20"), nil
+	},
+	"sha256": func(value string, args []string) (string, error) {
+		sum := sha256.Sum256([]byte(value))
+		return hex.EncodeToString(sum[:]), nil
+	},
+	"hmac": func(value string, args []string) (string, error) {
+		if len(args) != 1 {
+			return "", fmt.Errorf("hmac needs a key")
+		}
+		mac := hmac.New(sha256.New, []byte(args[0]))
+		mac.Write([]byte(value))
+		return hex.EncodeToString(mac.Sum(nil)), nil
+	},
+	"date": func(value string, args []string) (string, error) {
+		if len(args) != 1 {
+			return "", fmt.Errorf("date needs a format")
+		}
+		parsed, err := parseTemplateTime(value)
+		if err != nil {
+			return "", err
+		}
+		return formatTemplateTime(parsed, args[0]), nil
+	},
+}
+
+// templateToken is a word of a {{ }} placeholder. Quoted words are literal text.
+type templateToken struct {
+	text   string
+	quoted bool
+}
+
+// evaluateTemplate resolves the inside of a {{ }} placeholder: a variable,
+// a dynamic variable such as $guid, process.env.NAME or a quoted string,
+// optionally piped through filters. ok is false when any part is unknown.
+func evaluateTemplate(expr string, vars map[string]string) (string, bool) {
+	name := strings.TrimSpace(expr)
+	if value, exists := vars[name]; exists {
+		return value, true
+	}
+
+	stages, ok := parseTemplate(name)
+	if !ok || len(stages[0]) != 1 {
+		return "", false
+	}
+	value, ok := resolveTemplateValue(stages[0][0], vars)
+	if !ok {
+		return "", false
+	}
+
+	for _, stage := range stages[1:] {
+		filter, exists := templateFilters[stage[0].text]
+		if stage[0].quoted || !exists {
+			return "", false
+		}
+		args := make([]string, 0, len(stage)-1)
+		for _, arg := range stage[1:] {
+			resolved, ok := resolveTemplateValue(arg, vars)
+			if !ok {
+				return "", false
+			}
+			args = append(args, resolved)
+		}
+
+		var err error
+		if value, err = filter(value, args); err != nil {
+			return "", false
+		}
+	}
+	return value, true
+}
+
+// resolveTemplateValue looks up a single word of a placeholder
+func resolveTemplateValue(token templateToken, vars map[string]string) (string, bool) {
+	if token.quoted {
+		return token.text, true
+	}
+	if value, exists := vars[token.text]; exists {
+		return value, true
+	}
+	if generate, exists := dynamicVariables[token.text]; exists {
+		return generate(), true
+	}
+	if name, found := strings.CutPrefix(token.text, "process.env."); found {
+		return os.LookupEnv(name)
+	}
+	return "", false
+}
+
+// parseTemplate splits a placeholder such as `$timestamp | date "YYYY-MM-DD"`
+// into its pipeline stages, each a list of words
+func parseTemplate(expr string) ([][]templateToken, bool) {
+	var stages [][]templateToken
+	var stage []templateToken
+	var word strings.Builder
+	inWord := false
+	quote := rune(0)
+
+	endWord := func() {
+		if inWord {
+			stage = append(stage, templateToken{text: word.String()})
+			word.Reset()
+			inWord = false
+		}
+	}
+
+	for _, r := range expr {
+		switch {
+		case quote != 0:
+			if r == quote {
+				stage = append(stage, templateToken{text: word.String(), quoted: true})
+				word.Reset()
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			if inWord {
+				return nil, false
+			}
+			quote = r
+		case r == '|':
+			endWord()
+			if len(stage) == 0 {
+				return nil, false
+			}
+			stages = append(stages, stage)
+			stage = nil
+		case unicode.IsSpace(r):
+			endWord()
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	endWord()
+	if quote != 0 || len(stage) == 0 {
+		return nil, false
+	}
+	return append(stages, stage), true
+}
+
+// parseTemplateTime reads the value piped into date: Unix seconds or
+// milliseconds, or an ISO 8601 timestamp
+func parseTemplateTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if number, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if len(value) > 11 {
+			return time.UnixMilli(number), nil
+		}
+		return time.Unix(number, 0), nil
+	}
+	if parsed, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return parsed, nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not a timestamp", value)
+}
+
+// templateDateTokens are the date format tokens of moment.js, which Bruno
+// users know, with their Go layouts. Longer tokens come first.
+var templateDateTokens = []struct {
+	token  string
+	layout string
+}{
+	{"YYYY", "2006"},
+	{"YY", "06"},
+	{"MM", "01"},
+	{"DD", "02"},
+	{"HH", "15"},
+	{"mm", "04"},
+	{"ss", "05"},
+	{"SSS", "000"},
+	{"Z", "Z07:00"},
+}
+
+// formatTemplateTime formats a time in UTC. "unix" and "iso" are accepted as
+// formats too.
+func formatTemplateTime(t time.Time, format string) string {
+	t = t.UTC()
+	switch format {
+	case "unix":
+		return strconv.FormatInt(t.Unix(), 10)
+	case "iso":
+		return t.Format("2006-01-02T15:04:05.000Z")
+	}
+	return formatTemplateDate(t, format)
+}
+
+// formatTemplateDate formats t one token at a time. Go layouts cannot escape
+// text, so everything between tokens is copied as written; formatting the
+// whole string as a layout would turn the 1 of "Day 1" into the month.
+func formatTemplateDate(t time.Time, format string) string {
+	var formatted strings.Builder
+	for format != "" {
+		matched := false
+		for _, token := range templateDateTokens {
+			if strings.HasPrefix(format, token.token) {
+				formatted.WriteString(t.Format(token.layout))
+				format = format[len(token.token):]
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			_, size := utf8.DecodeRuneInString(format)
+			formatted.WriteString(format[:size])
+			format = format[size:]
+		}
+	}
+	return formatted.String()
+}
+
+// newUUID returns a random version 4 UUID
+func newUUID() string {
+	data := make([]byte, 16)
+	rand.Read(data)
+	data[6] = data[6]&0x0f | 0x40
+	data[8] = data[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", data[0:4], data[4:6], data[6:8], data[8:10], data[10:16])
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDynamicVariables(t *testing.T) {
+	client := NewHTTPClient()
+
+	uuidPattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if guid := client.substituteVars("{{$guid}}", nil); !uuidPattern.MatchString(guid) || guid == client.substituteVars("{{$guid}}", nil) {
+		t.Errorf("Expected a new v4 UUID each time, got %q", guid)
+	}
+	if timestamp, err := strconv.ParseInt(client.substituteVars("{{$timestamp}}", nil), 10, 64); err != nil || time.Since(time.Unix(timestamp, 0)) > time.Minute {
+		t.Errorf("Expected the current Unix time, got %d (%v)", timestamp, err)
+	}
+	if iso := client.substituteVars("{{$isoTimestamp}}", nil); !regexp.MustCompile(`^\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{3}Z$`).MatchString(iso) {
+		t.Errorf("Expected an ISO timestamp, got %q", iso)
+	}
+	if number, err := strconv.Atoi(client.substituteVars("{{$randomInt}}", nil)); err != nil || number < 0 || number > 1000 {
+		t.Errorf("Expected a random int between 0 and 1000, got %d (%v)", number, err)
+	}
+	if email := client.substituteVars("{{$randomEmail}}", nil); !strings.HasSuffix(email, "@example.com") {
+		t.Errorf("Expected a random email, got %q", email)
+	}
+}
+
+func TestProcessEnvVariables(t *testing.T) {
+	t.Setenv("KALO_TEST_TOKEN", "from-env")
+	client := NewHTTPClient()
+
+	if got := client.substituteVars("Bearer {{process.env.KALO_TEST_TOKEN}}", nil); got != "Bearer from-env" {
+		t.Errorf("Expected the process environment variable, got %q", got)
+	}
+	if got := client.substituteVars("{{process.env.KALO_TEST_MISSING}}", nil); got != "{{process.env.KALO_TEST_MISSING}}" {
+		t.Errorf("Expected an unset variable to be kept as written, got %q", got)
+	}
+}
+
+func TestTemplateFilters(t *testing.T) {
+	vars := map[string]string{"user": "jane doe", "secret": "key", "when": "1700000000"}
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"{{ user | base64 }}", "amFuZSBkb2U="},
+		{"/users?name={{user|urlencode}}", "/users?name=jane%20doe"},
+		{"{{user | sha256}}", "ed37d99b1445238af3386f81a77a2caf3fffc0cd610be39b4f3ba53943dc66bf"},
+		{"{{user | hmac secret}}", "c4cd717a15562848e591d664961d1eab20d7df990edcb36fedf616691b3da5b5"},
+		{`{{user | hmac "key"}}`, "c4cd717a15562848e591d664961d1eab20d7df990edcb36fedf616691b3da5b5"},
+		{`{{when | date "YYYY-MM-DD HH:mm:ss"}}`, "2023-11-14 22:13:20"},
+		{`{{when | date "iso"}}`, "2023-11-14T22:13:20.000Z"},
+		// Text between tokens is not read as a Go layout
+		{`{{when | date "Day 1 of YYYY, Monday 2 Jan at HH:mm PM"}}`, "Day 1 of 2023, Monday 2 Jan at 22:13 PM"},
+		{`{{when | date "DD.MM.YY 15:04 Z"}}`, "14.11.23 15:04 Z"},
+		// Unknown filters, variables and values a filter rejects are kept as written
+		{"{{user | rot13}}", "{{user | rot13}}"},
+		{"{{missing | base64}}", "{{missing | base64}}"},
+		{`{{user | date "YYYY"}}`, `{{user | date "YYYY"}}`},
+	}
+	client := NewHTTPClient()
+	for _, test := range tests {
+		if got := client.substituteVars(test.input, vars); got != test.expected {
+			t.Errorf("Expected %s to become %q, got %q", test.input, test.expected, got)
+		}
+	}
+}
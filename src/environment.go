@@ -0,0 +1,128 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	request "kalo/src/panels/request"
+)
+
+// Environment represents a named set of variables loaded from a
+// collection's environments/*.bru files
+type Environment struct {
+	Name     string            `json:"name"`
+	FilePath string            `json:"file_path"`
+	Vars     map[string]string `json:"vars"`
+}
+
+// environmentsDirName is the folder inside a collection that holds environment files
+const environmentsDirName = "environments"
+
+// collectionFileName is the file inside a collection that holds settings
+// shared by all of its requests
+const collectionFileName = "collection.bru"
+
+// LoadEnvironments loads all environment files for a collection directory.
+// Environments are returned sorted by name.
+func LoadEnvironments(collectionPath string) []*Environment {
+	environments := []*Environment{}
+
+	envDir := filepath.Join(collectionPath, environmentsDirName)
+	entries, err := os.ReadDir(envDir)
+	if err != nil {
+		return environments
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".bru") {
+			continue
+		}
+
+		envPath := filepath.Join(envDir, entry.Name())
+		envFile, err := os.Open(envPath)
+		if err != nil {
+			continue
+		}
+
+		// Environment files only contain a vars block, so the request parser
+		// can read them directly
+		parser := NewBruParser(envFile)
+		parsed, err := parser.Parse()
+		envFile.Close()
+		if err != nil {
+			continue
+		}
+
+		environments = append(environments, &Environment{
+			Name:     strings.TrimSuffix(entry.Name(), ".bru"),
+			FilePath: envPath,
+			Vars:     parsed.Vars,
+		})
+	}
+
+	sort.Slice(environments, func(i, j int) bool {
+		return environments[i].Name < environments[j].Name
+	})
+
+	return environments
+}
+
+// requestSettings combines collection settings with the request's own
+// settings, which take precedence
+func requestSettings(bruReq *request.BruRequest, collection *CollectionConfig) map[string]string {
+	merged := make(map[string]string)
+	if collection != nil {
+		for key, value := range collection.Settings {
+			merged[key] = value
+		}
+	}
+	for key, value := range bruReq.Settings {
+		merged[key] = value
+	}
+	return merged
+}
+
+// collectionScope identifies the collection and environment that OAuth2
+// tokens and cookies are kept for
+func collectionScope(bruReq *request.BruRequest, env *Environment) string {
+	envName := ""
+	if env != nil {
+		envName = env.Name
+	}
+	return bruReq.Dir + "|" + envName
+}
+
+// FindEnvironment returns the environment with the given name, or nil
+func FindEnvironment(environments []*Environment, name string) *Environment {
+	for _, env := range environments {
+		if env.Name == name {
+			return env
+		}
+	}
+	return nil
+}
+
+// mergeVars combines environment, request and runtime variables. Runtime
+// variables set by scripts take precedence over request variables, which
+// take precedence over environment values.
+func mergeVars(env *Environment, requestVars map[string]string, runtimeVars map[string]string) map[string]string {
+	merged := make(map[string]string)
+
+	if env != nil {
+		for key, value := range env.Vars {
+			merged[key] = value
+		}
+	}
+
+	for key, value := range requestVars {
+		merged[key] = value
+	}
+
+	for key, value := range runtimeVars {
+		merged[key] = value
+	}
+
+	return merged
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadEnvironments(t *testing.T) {
+	dir := writeCollection(t, map[string]string{
+		"environments/staging.bru":  "vars {\n  baseUrl: https://staging.example.com\n  token: staging-token\n}\n",
+		"environments/dev.bru":      "vars {\n  baseUrl: http://localhost:3000\n}\n",
+		"environments/notes.txt":    "not an environment",
+		"environments/old/prod.bru": "vars {\n  baseUrl: https://example.com\n}\n",
+		"get-users.bru":             "get {\n  url: {{baseUrl}}/users\n}\n",
+		"other/environments/qa.bru": "vars {\n  baseUrl: https://qa.example.com\n}\n",
+	})
+
+	environments := LoadEnvironments(dir)
+	if len(environments) != 2 {
+		t.Fatalf("Expected the two .bru files in environments/, got %d", len(environments))
+	}
+
+	dev, staging := environments[0], environments[1]
+	if dev.Name != "dev" || staging.Name != "staging" {
+		t.Errorf("Expected environments sorted by name, got %s and %s", dev.Name, staging.Name)
+	}
+	if staging.FilePath != filepath.Join(dir, "environments", "staging.bru") {
+		t.Errorf("Expected the environment's file path, got %s", staging.FilePath)
+	}
+	if staging.Vars["baseUrl"] != "https://staging.example.com" || staging.Vars["token"] != "staging-token" {
+		t.Errorf("Expected the vars block to be loaded, got %v", staging.Vars)
+	}
+
+	if FindEnvironment(environments, "staging") != staging || FindEnvironment(environments, "prod") != nil {
+		t.Error("Expected FindEnvironment to look environments up by name")
+	}
+
+	if missing := LoadEnvironments(filepath.Join(dir, "missing")); missing == nil || len(missing) != 0 {
+		t.Errorf("Expected no environments for a collection without the folder, got %v", missing)
+	}
+}
+
+func TestMergeVarsPrecedence(t *testing.T) {
+	env := &Environment{Vars: map[string]string{"host": "env", "user": "env", "token": "env"}}
+	requestVars := map[string]string{"user": "request", "token": "request"}
+	runtimeVars := map[string]string{"token": "runtime"}
+
+	merged := mergeVars(env, requestVars, runtimeVars)
+	expected := map[string]string{"host": "env", "user": "request", "token": "runtime"}
+	for key, value := range expected {
+		if merged[key] != value {
+			t.Errorf("Expected %s from %s, got %q", key, value, merged[key])
+		}
+	}
+
+	if merged := mergeVars(nil, nil, nil); len(merged) != 0 {
+		t.Errorf("Expected no variables without any source, got %v", merged)
+	}
+	if env.Vars["token"] != "env" {
+		t.Error("Expected merging to leave the environment unchanged")
+	}
+}
+
+func TestVariablePrecedenceWhenSending(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.RawQuery))
+	})
+
+	bruReq := parseExecutorRequest(t, `get {
+  url: {{server}}/echo?host={{host}}&user={{user}}&token={{token}}
+}
+
+vars {
+  user: request-user
+  token: request-token
+}`, server.URL)
+	env := &Environment{Name: "dev", FilePath: "api/environments/dev.bru", Vars: map[string]string{"host": "env-host", "user": "env-user", "token": "env-token"}}
+
+	client := NewHTTPClient()
+	runner := NewScriptRunner()
+	execution := executeBruRequest(context.Background(), client, runner, bruReq, env)
+	if execution.Response.Body != "host=env-host&user=request-user&token=request-token" {
+		t.Errorf("Expected request vars to override the environment, got %q", execution.Response.Body)
+	}
+
+	// A runtime variable set by a script overrides both on later requests
+	bruReq.Script.PostResponse = `bru.setVar("token", "runtime-token");`
+	executeBruRequest(context.Background(), client, runner, bruReq, env)
+	execution = executeBruRequest(context.Background(), client, runner, bruReq, env)
+	if execution.Response.Body != "host=env-host&user=request-user&token=runtime-token" {
+		t.Errorf("Expected the runtime variable to take precedence, got %q", execution.Response.Body)
+	}
+}
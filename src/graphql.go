@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	request "kalo/src/panels/request"
+	response "kalo/src/panels/response"
+)
+
+// graphqlIntrospectionQuery asks for the types, fields and arguments used by
+// query completion
+const graphqlIntrospectionQuery = `query IntrospectionQuery {
+  __schema {
+    queryType { name }
+    mutationType { name }
+    subscriptionType { name }
+    types {
+      kind
+      name
+      fields(includeDeprecated: true) {
+        name
+        args { name type { ...TypeRef } }
+        type { ...TypeRef }
+      }
+      inputFields { name type { ...TypeRef } }
+    }
+  }
+}
+
+fragment TypeRef on __Type {
+  kind
+  name
+  ofType { kind name ofType { kind name ofType { kind name ofType { kind name } } } }
+}`
+
+type graphqlSchemaMsg struct {
+	requestID int
+	url       string
+	schema    *request.GraphQLSchema
+	response  *response.HTTPResponse
+	err       error
+}
+
+// introspectGraphQL sends the introspection query to the endpoint of a
+// request, reusing its URL, headers and auth
+func introspectGraphQL(ctx context.Context, client *HTTPClient, bruReq *request.BruRequest, vars map[string]string, env *Environment) (*request.GraphQLSchema, *response.HTTPResponse, error) {
+	req := bruReq.Clone()
+	req.HTTP.Method = "POST"
+	req.Body = request.BruBody{Type: "graphql", Data: graphqlIntrospectionQuery}
+
+	collection, err := client.collectionConfig(req.Dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := client.AuthorizeOAuth2(ctx, req, collection, vars, env); err != nil {
+		return nil, nil, err
+	}
+	sent, err := client.ResolveRequest(req, collection, vars)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sent.CookieScope = collectionScope(bruReq, env)
+
+	resp := client.SendRequest(ctx, sent)
+	if resp.Error != "" {
+		return nil, resp, fmt.Errorf("%s", resp.Error)
+	}
+	if resp.StatusCode >= 400 {
+		return nil, resp, fmt.Errorf("introspection returned %s", resp.Status)
+	}
+
+	schema, err := request.ParseGraphQLSchema([]byte(resp.Body))
+	if err != nil {
+		return nil, resp, err
+	}
+	return schema, resp, nil
+}
+
+// introspectCurrentRequest fetches the schema of the current request's endpoint
+func (m *model) introspectCurrentRequest() tea.Cmd {
+	if m.currentReq == nil {
+		return nil
+	}
+
+	ctx := m.startLoading()
+	requestID := m.loadingID
+	req := m.currentReq
+	env := m.activeEnvironment()
+	vars := mergeVars(env, req.Vars, m.scriptRunner.RuntimeVars(env))
+
+	cmd := func() tea.Msg {
+		schema, resp, err := introspectGraphQL(ctx, m.httpClient, req, vars, env)
+		return graphqlSchemaMsg{requestID: requestID, url: req.HTTP.URL, schema: schema, response: resp, err: err}
+	}
+
+	return tea.Batch(cmd, m.loadingTick())
+}
+
+// graphqlSchema returns the introspected schema for the current request
+func (m *model) graphqlSchema() *request.GraphQLSchema {
+	if m.currentReq == nil {
+		return nil
+	}
+	return m.graphqlSchemas[m.currentReq.HTTP.URL]
+}
+
+// updateGraphQLSuggestions recomputes completions for the GraphQL query being
+// edited, the same way jq suggestions follow the filter input
+func (m *model) updateGraphQLSuggestions() {
+	if m.currentReq == nil || m.currentReq.BodyEditState == nil {
+		return
+	}
+
+	editState := m.currentReq.BodyEditState
+	editState.Suggestions = nil
+	if m.currentReq.Body.Type != "graphql" || editState.EditingVars || editState.Mode != request.BodyTextEditMode {
+		return
+	}
+
+	offset := bodyCursorOffset(editState)
+	// Only suggest while a name is being typed
+	if request.GraphQLWordBefore(editState.Content, offset) == "" {
+		return
+	}
+	editState.Suggestions = request.GraphQLSuggestions(m.graphqlSchema(), editState.Content, offset)
+	if editState.SelectedSuggestion >= len(editState.Suggestions) {
+		editState.SelectedSuggestion = 0
+	}
+}
+
+// bodyCursorOffset converts the editor's line and column to an offset in its content
+func bodyCursorOffset(editState *request.BodyEditState) int {
+	lines := strings.Split(editState.Content, "\n")
+	offset := 0
+	for i := 0; i < editState.CursorLine && i < len(lines); i++ {
+		offset += len(lines[i]) + 1
+	}
+	if editState.CursorLine < len(lines) {
+		col := editState.CursorCol
+		if col > len(lines[editState.CursorLine]) {
+			col = len(lines[editState.CursorLine])
+		}
+		offset += col
+	}
+	return offset
+}
+
+// completeGraphQLSuggestion replaces the name before the cursor with the
+// selected suggestion
+func completeGraphQLSuggestion(editState *request.BodyEditState) {
+	if editState.SelectedSuggestion < 0 || editState.SelectedSuggestion >= len(editState.Suggestions) {
+		return
+	}
+
+	suggestion := editState.Suggestions[editState.SelectedSuggestion]
+	offset := bodyCursorOffset(editState)
+	word := request.GraphQLWordBefore(editState.Content, offset)
+	editState.Content = editState.Content[:offset-len(word)] + suggestion + editState.Content[offset:]
+	editState.CursorCol += len(suggestion) - len(word)
+	editState.Suggestions = nil
+	editState.SelectedSuggestion = 0
+}
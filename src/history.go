@@ -0,0 +1,822 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	response "kalo/src/panels/response"
+)
+
+// historyFileName is the JSONL file under ~/.kalo that stores executed requests
+const historyFileName = "history.jsonl"
+
+// historyMaxLineSize bounds a single history entry when reading the file back
+const historyMaxLineSize = 64 * 1024 * 1024
+
+// HistoryStore persists every executed request as one JSON line per
+// RequestResponsePair. Entries are kept in memory in execution order.
+type HistoryStore struct {
+	path    string
+	entries []*RequestResponsePair
+	mu      sync.Mutex
+}
+
+// getHistoryPath returns the path of the history file, creating ~/.kalo if needed
+func getHistoryPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	kaloDir := filepath.Join(homeDir, ".kalo")
+	if err := os.MkdirAll(kaloDir, 0755); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(kaloDir, historyFileName), nil
+}
+
+// NewHistoryStore creates a history store backed by the given file and loads it.
+// An empty path keeps history in memory only.
+func NewHistoryStore(path string) *HistoryStore {
+	store := &HistoryStore{path: path}
+	store.Load()
+	return store
+}
+
+// Load reads all entries from disk, skipping lines that cannot be decoded
+func (h *HistoryStore) Load() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.entries = nil
+	if h.path == "" {
+		return nil
+	}
+
+	file, err := os.Open(h.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), historyMaxLineSize)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var entry RequestResponsePair
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			continue
+		}
+		h.entries = append(h.entries, &entry)
+	}
+
+	return scanner.Err()
+}
+
+// Append records an entry in memory and appends it to the history file
+func (h *HistoryStore) Append(entry *RequestResponsePair) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.entries = append(h.entries, entry)
+	if h.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+// Entries returns all entries, newest first
+func (h *HistoryStore) Entries() []*RequestResponsePair {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	entries := make([]*RequestResponsePair, len(h.entries))
+	for i, entry := range h.entries {
+		entries[len(h.entries)-1-i] = entry
+	}
+	return entries
+}
+
+// Find returns the entry with the given ID, or nil
+func (h *HistoryStore) Find(id string) *RequestResponsePair {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, entry := range h.entries {
+		if entry.ID == id {
+			return entry
+		}
+	}
+	return nil
+}
+
+// Prune removes entries older than maxAge and then the oldest entries until the
+// file is no larger than maxBytes. A zero limit is ignored. Returns the number
+// of entries removed.
+func (h *HistoryStore) Prune(maxAge time.Duration, maxBytes int64) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	kept := h.entries
+	if maxAge > 0 {
+		cutoff := time.Now().Add(-maxAge)
+		kept = nil
+		for _, entry := range h.entries {
+			if !entry.ExecutedAt.Before(cutoff) {
+				kept = append(kept, entry)
+			}
+		}
+	}
+
+	// Encode once so size limits match what is written to disk
+	lines := make([][]byte, len(kept))
+	for i, entry := range kept {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return 0, err
+		}
+		lines[i] = append(data, '\n')
+	}
+
+	if maxBytes > 0 {
+		var total int64
+		start := len(lines)
+		for start > 0 && total+int64(len(lines[start-1])) <= maxBytes {
+			start--
+			total += int64(len(lines[start]))
+		}
+		kept = kept[start:]
+		lines = lines[start:]
+	}
+
+	removed := len(h.entries) - len(kept)
+	if removed == 0 {
+		return 0, nil
+	}
+
+	if h.path != "" {
+		tmpPath := h.path + ".tmp"
+		file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+		if err != nil {
+			return 0, err
+		}
+		for _, line := range lines {
+			if _, err := file.Write(line); err != nil {
+				file.Close()
+				os.Remove(tmpPath)
+				return 0, err
+			}
+		}
+		if err := file.Close(); err != nil {
+			os.Remove(tmpPath)
+			return 0, err
+		}
+		if err := os.Rename(tmpPath, h.path); err != nil {
+			return 0, err
+		}
+	}
+
+	h.entries = kept
+	return removed, nil
+}
+
+var statusClassFilterPattern = regexp.MustCompile(`^[1-5]xx$`)
+
+// FilterHistory returns the entries matching every whitespace separated term.
+// Terms like "2xx" or "4xx" match a status class, "error" matches failed
+// requests and any other term matches the name, method, URL, collection or
+// environment.
+func FilterHistory(entries []*RequestResponsePair, query string) []*RequestResponsePair {
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 {
+		return entries
+	}
+
+	var filtered []*RequestResponsePair
+	for _, entry := range entries {
+		if historyEntryMatches(entry, terms) {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
+func historyEntryMatches(entry *RequestResponsePair, terms []string) bool {
+	var haystack strings.Builder
+	if entry.Request != nil {
+		haystack.WriteString(strings.ToLower(entry.Request.Name + " " + string(entry.Request.Method) + " " + entry.Request.URL))
+	}
+	haystack.WriteString(" " + strings.ToLower(entry.Collection+" "+entry.Environment))
+
+	for _, term := range terms {
+		switch {
+		case statusClassFilterPattern.MatchString(term):
+			if entry.Response == nil || entry.Response.Error != "" || string(entry.Response.GetStatusClass()) != term {
+				return false
+			}
+		case term == "error":
+			if entry.Success {
+				return false
+			}
+		default:
+			if !strings.Contains(haystack.String(), term) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// redactedValue replaces credentials in recorded requests
+const redactedValue = "[redacted]"
+
+// credentialNamePattern matches the names of headers, query parameters and
+// body fields that usually carry credentials, such as Authorization, Cookie,
+// api_key or password
+var credentialNamePattern = regexp.MustCompile(`(?i)auth|token|secret|passw|api[-_]?key|session|cookie|signature`)
+
+// authSchemePattern matches the scheme that starts values like "Bearer <token>"
+var authSchemePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9-]* `)
+
+// redactCredentials returns a copy of a sent request for history, without the
+// values of credential headers, query parameters and body fields. Schemes
+// such as Bearer or Basic are kept so the entry still shows how the request
+// was authenticated.
+func redactCredentials(sent *HTTPRequestModel) *HTTPRequestModel {
+	isCredential := func(name string) bool {
+		isAuthKey := sent.Auth != nil && sent.Auth.KeyName != "" && strings.EqualFold(name, sent.Auth.KeyName)
+		return isAuthKey || credentialNamePattern.MatchString(name)
+	}
+
+	redacted := sent.Clone()
+	redacted.RedactedHeaders = nil
+	for name, values := range redacted.Headers {
+		if !isCredential(name) {
+			continue
+		}
+		for i, value := range values {
+			values[i] = authSchemePattern.FindString(value) + redactedValue
+		}
+		redacted.RedactedHeaders = append(redacted.RedactedHeaders, name)
+	}
+	sort.Strings(redacted.RedactedHeaders)
+
+	redacted.URL, redacted.RedactedQuery = redactURLQuery(sent.URL, isCredential)
+	for key := range redacted.QueryParams {
+		if isCredential(key) {
+			redacted.QueryParams[key] = redactedValue
+		}
+	}
+	redacted.RedactedBody = nil
+	if redacted.Body != nil {
+		redacted.RedactedBody = redactBody(redacted.Body, isCredential)
+	}
+	return redacted
+}
+
+// splitURLQuery splits a URL around its query string
+func splitURLQuery(rawURL string) (base, query, fragment string, found bool) {
+	base, query, found = strings.Cut(rawURL, "?")
+	if hash := strings.Index(query, "#"); hash >= 0 {
+		query, fragment = query[:hash], query[hash:]
+	}
+	return base, query, fragment, found
+}
+
+// redactURLQuery replaces the values of credential query parameters in a
+// URL, returning it and the names of the parameters
+func redactURLQuery(rawURL string, isCredential func(string) bool) (string, []string) {
+	base, query, fragment, found := splitURLQuery(rawURL)
+	if !found {
+		return rawURL, nil
+	}
+	query, names := redactEncodedPairs(query, isCredential)
+	return base + "?" + query + fragment, names
+}
+
+// refillURLQuery puts the values source has for the named query parameters
+// back into a URL redactURLQuery redacted
+func refillURLQuery(rawURL, source string, names []string) string {
+	base, query, fragment, found := splitURLQuery(rawURL)
+	if !found {
+		return rawURL
+	}
+	_, sourceQuery, _, _ := splitURLQuery(source)
+	return base + "?" + refillEncodedPairs(query, sourceQuery, names) + fragment
+}
+
+// redactEncodedPairs replaces the values of credential keys in a query string
+// or form body, returning it and the keys, in order and without repeats
+func redactEncodedPairs(encoded string, isCredential func(string) bool) (string, []string) {
+	var names []string
+	pairs := strings.Split(encoded, "&")
+	for i, pair := range pairs {
+		escapedKey, _, _ := strings.Cut(pair, "=")
+		key, err := url.QueryUnescape(escapedKey)
+		if err != nil || !isCredential(key) {
+			continue
+		}
+		pairs[i] = escapedKey + "=" + url.QueryEscape(redactedValue)
+		if !slices.Contains(names, key) {
+			names = append(names, key)
+		}
+	}
+	return strings.Join(pairs, "&"), names
+}
+
+// refillEncodedPairs puts the values source has for the named keys back into
+// a query string or form body redactEncodedPairs redacted, in order. Values
+// source no longer has are dropped.
+func refillEncodedPairs(encoded, source string, names []string) string {
+	values := make(map[string][]string)
+	for _, pair := range strings.Split(source, "&") {
+		escapedKey, value, _ := strings.Cut(pair, "=")
+		if key, err := url.QueryUnescape(escapedKey); err == nil && slices.Contains(names, key) {
+			values[key] = append(values[key], value)
+		}
+	}
+
+	var refilled []string
+	for _, pair := range strings.Split(encoded, "&") {
+		escapedKey, _, _ := strings.Cut(pair, "=")
+		key, err := url.QueryUnescape(escapedKey)
+		if err != nil || !slices.Contains(names, key) {
+			refilled = append(refilled, pair)
+			continue
+		}
+		if len(values[key]) > 0 {
+			refilled = append(refilled, escapedKey+"="+values[key][0])
+			values[key] = values[key][1:]
+		}
+	}
+	return strings.Join(refilled, "&")
+}
+
+// redactBody replaces the values of credential fields of a form, multipart
+// or JSON body in place. It returns the fields: their names in form bodies,
+// or dotted paths such as "user.password" in JSON.
+func redactBody(body *RequestBody, isCredential func(string) bool) []string {
+	switch body.Type {
+	case "form-urlencoded":
+		var names []string
+		body.Content, names = redactEncodedPairs(body.Content, isCredential)
+		for key := range body.FormData {
+			if isCredential(key) {
+				body.FormData[key] = redactedValue
+			}
+		}
+		return names
+	case "multipart-form":
+		var names []string
+		for i, part := range body.Parts {
+			if part.File == nil && isCredential(part.Name) {
+				body.Parts[i].Value = redactedValue
+				if !slices.Contains(names, part.Name) {
+					names = append(names, part.Name)
+				}
+			}
+		}
+		return names
+	}
+
+	spans, ok := jsonCredentialSpans(body.Content, isCredential)
+	if !ok {
+		return nil
+	}
+	var paths []string
+	for i := len(spans) - 1; i >= 0; i-- {
+		body.Content = body.Content[:spans[i].start] + `"` + redactedValue + `"` + body.Content[spans[i].end:]
+	}
+	for _, span := range spans {
+		paths = append(paths, span.path)
+	}
+	return paths
+}
+
+// refillBody puts the values source has for the fields redactBody redacted
+// back into body
+func refillBody(body, source *RequestBody, fields []string) {
+	switch body.Type {
+	case "form-urlencoded":
+		body.Content = refillEncodedPairs(body.Content, source.Content, fields)
+		for _, name := range fields {
+			if value, exists := source.FormData[name]; exists {
+				body.FormData[name] = value
+			} else {
+				delete(body.FormData, name)
+			}
+		}
+		return
+	case "multipart-form":
+		values := make(map[string][]string)
+		for _, part := range source.Parts {
+			if part.File == nil && slices.Contains(fields, part.Name) {
+				values[part.Name] = append(values[part.Name], part.Value)
+			}
+		}
+		for i, part := range body.Parts {
+			if part.File == nil && slices.Contains(fields, part.Name) && len(values[part.Name]) > 0 {
+				body.Parts[i].Value = values[part.Name][0]
+				values[part.Name] = values[part.Name][1:]
+			}
+		}
+		return
+	}
+
+	isField := func(string) bool { return true }
+	spans, ok := jsonCredentialSpans(body.Content, isField)
+	sourceSpans, sourceOK := jsonCredentialSpans(source.Content, isField)
+	if !ok || !sourceOK {
+		return
+	}
+	sourceValues := make(map[string]string)
+	for _, span := range sourceSpans {
+		sourceValues[span.path] = source.Content[span.start:span.end]
+	}
+	for i := len(spans) - 1; i >= 0; i-- {
+		value, exists := sourceValues[spans[i].path]
+		if exists && slices.Contains(fields, spans[i].path) {
+			body.Content = body.Content[:spans[i].start] + value + body.Content[spans[i].end:]
+		}
+	}
+}
+
+// jsonSpan is where a scalar value is in a JSON document, and its dotted path
+type jsonSpan struct {
+	path       string
+	start, end int
+}
+
+// jsonCredentialSpans finds the scalar values of credential keys in a JSON
+// document, including every value nested under them, in order. It returns
+// false for text that is not JSON.
+func jsonCredentialSpans(content string, isCredential func(string) bool) ([]jsonSpan, bool) {
+	if !json.Valid([]byte(content)) {
+		return nil, false
+	}
+	decoder := json.NewDecoder(strings.NewReader(content))
+	decoder.UseNumber()
+
+	var spans []jsonSpan
+	var walk func(path string, credential bool) error
+	walk = func(path string, credential bool) error {
+		// The decoder is positioned before the separators of the next value
+		start := int(decoder.InputOffset())
+		for start < len(content) && strings.IndexByte(" \t\r\n:,", content[start]) >= 0 {
+			start++
+		}
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+
+		switch token {
+		case json.Delim('{'):
+			for decoder.More() {
+				key, err := decoder.Token()
+				if err != nil {
+					return err
+				}
+				name, _ := key.(string)
+				if err := walk(joinJSONPath(path, name), credential || isCredential(name)); err != nil {
+					return err
+				}
+			}
+			_, err = decoder.Token()
+			return err
+		case json.Delim('['):
+			for i := 0; decoder.More(); i++ {
+				if err := walk(joinJSONPath(path, strconv.Itoa(i)), credential); err != nil {
+					return err
+				}
+			}
+			_, err = decoder.Token()
+			return err
+		}
+		if credential && token != nil {
+			spans = append(spans, jsonSpan{path: path, start: start, end: int(decoder.InputOffset())})
+		}
+		return nil
+	}
+
+	if err := walk("", false); err != nil {
+		return nil, false
+	}
+	return spans, true
+}
+
+func joinJSONPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// restoreCredentials fills in the headers, query parameters and body fields
+// history redacted by preparing the request's .bru file again with the named
+// environment, so scripts, OAuth2 and auth run as they would when sending it
+// from the collection. Everything else is sent as recorded.
+func restoreCredentials(ctx context.Context, client *HTTPClient, runner *ScriptRunner, recorded *HTTPRequestModel, envName string) (*HTTPRequestModel, error) {
+	if len(recorded.RedactedHeaders) == 0 && len(recorded.RedactedQuery) == 0 && len(recorded.RedactedBody) == 0 {
+		return recorded.Clone(), nil
+	}
+	if recorded.SourceFile == "" {
+		return nil, fmt.Errorf("the credentials of this request were not recorded")
+	}
+
+	bruFile, err := os.Open(recorded.SourceFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot restore credentials: %v", err)
+	}
+	bruReq, err := NewBruParser(bruFile).Parse()
+	bruFile.Close()
+	if err != nil {
+		return nil, fmt.Errorf("cannot restore credentials: %s: %v", recorded.SourceFile, err)
+	}
+	bruReq.Dir = filepath.Dir(recorded.SourceFile)
+
+	var env *Environment
+	if envName != "" {
+		if env = FindEnvironment(LoadEnvironments(bruReq.Dir), envName); env == nil {
+			return nil, fmt.Errorf("cannot restore credentials: environment %q not found", envName)
+		}
+	}
+	_, prepared, err := prepareBruRequest(ctx, client, runner, bruReq, env, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot restore credentials: %v", err)
+	}
+
+	restored := recorded.Clone()
+	restored.RedactedHeaders = nil
+	restored.RedactedQuery = nil
+	restored.RedactedBody = nil
+	for _, name := range recorded.RedactedHeaders {
+		if values, exists := prepared.Headers[name]; exists {
+			restored.Headers[name] = append([]string(nil), values...)
+		} else {
+			delete(restored.Headers, name)
+		}
+	}
+	if len(recorded.RedactedQuery) > 0 {
+		restored.URL = refillURLQuery(restored.URL, prepared.URL, recorded.RedactedQuery)
+		for _, key := range recorded.RedactedQuery {
+			if _, exists := restored.QueryParams[key]; exists {
+				restored.QueryParams[key] = prepared.QueryParams[key]
+			}
+		}
+	}
+	if len(recorded.RedactedBody) > 0 && restored.Body != nil && prepared.Body != nil {
+		refillBody(restored.Body, prepared.Body, recorded.RedactedBody)
+	}
+	return restored, nil
+}
+
+// newHistoryEntry builds a history entry for an executed request. Credential
+// headers are redacted, see redactCredentials.
+func newHistoryEntry(sent *HTTPRequestModel, resp *response.HTTPResponse, testResults []TestResult, environment, collection string) *RequestResponsePair {
+	executedAt := time.Now()
+	request := redactCredentials(sent)
+	responseModel := &HTTPResponseModel{
+		RequestURL: request.URL,
+		EndTime:    executedAt,
+	}
+
+	if resp != nil {
+		responseModel.StatusCode = resp.StatusCode
+		responseModel.Status = resp.Status
+		responseModel.Headers = resp.Headers
+		responseModel.Body = resp.Body
+		responseModel.ContentLength = resp.BodySize
+		responseModel.WireSize = resp.WireSize
+		responseModel.ContentEncoding = resp.ContentEncoding
+		responseModel.DecodeError = resp.DecodeError
+		responseModel.Charset = resp.Charset
+		responseModel.IsJSON = resp.IsJSON
+		responseModel.IsXML = resp.IsXML
+		responseModel.IsHTML = resp.IsHTML
+		responseModel.IsBinary = resp.IsBinary
+		responseModel.IsText = !resp.IsBinary
+		responseModel.ResponseTime = resp.ResponseTime
+		responseModel.Error = resp.Error
+		responseModel.ErrorType = ErrorType(resp.ErrorType)
+		responseModel.DNSTime = resp.DNSTime
+		responseModel.ConnectTime = resp.ConnectTime
+		responseModel.TLSTime = resp.TLSTime
+		responseModel.SendTime = resp.SendTime
+		responseModel.WaitTime = resp.WaitTime
+		responseModel.ReceiveTime = resp.ReceiveTime
+		responseModel.ConnReused = resp.ConnReused
+		responseModel.Protocol = resp.Protocol
+		responseModel.ServerAddr = resp.ServerAddr
+		responseModel.LocalAddr = resp.LocalAddr
+		responseModel.Proxy = resp.Proxy
+		responseModel.TLS = resp.TLS
+		responseModel.Streamed = resp.Streamed
+		responseModel.Events = resp.Events
+		responseModel.DroppedEvents = resp.DroppedEvents
+		responseModel.StreamEnd = resp.StreamEnd
+		// Redirects may carry credentials in their query strings as well
+		redactURL := func(rawURL string) string {
+			redacted, _ := redactURLQuery(rawURL, credentialNamePattern.MatchString)
+			return redacted
+		}
+		responseModel.FinalURL = redactURL(resp.FinalURL)
+		for _, redirect := range resp.Redirects {
+			responseModel.Redirects = append(responseModel.Redirects, RedirectInfo{
+				FromURL:    redactURL(redirect.URL),
+				ToURL:      redactURL(redirect.Location),
+				StatusCode: redirect.StatusCode,
+				Duration:   redirect.Duration,
+			})
+		}
+		responseModel.StartTime = executedAt.Add(-resp.ResponseTime)
+		responseModel.ContentType = responseModel.GetContentType()
+		if resp.Error == "" {
+			responseModel.StatusClass = responseModel.GetStatusClass()
+		}
+	}
+
+	success := resp != nil && resp.Error == ""
+	for _, result := range testResults {
+		if !result.Passed {
+			success = false
+		}
+	}
+
+	return &RequestResponsePair{
+		ID:          strconv.FormatInt(executedAt.UnixNano(), 36),
+		Request:     request,
+		Response:    responseModel,
+		Success:     success,
+		ExecutedAt:  executedAt,
+		Duration:    responseModel.ResponseTime,
+		TestResults: testResults,
+		Environment: environment,
+		Collection:  collection,
+	}
+}
+
+// historyResponse converts a stored response back into the response panel's model
+func historyResponse(entry *RequestResponsePair) *response.HTTPResponse {
+	if entry.Response == nil {
+		return &response.HTTPResponse{Error: "No response recorded"}
+	}
+
+	var redirects []response.Redirect
+	for _, redirect := range entry.Response.Redirects {
+		redirects = append(redirects, response.Redirect{
+			URL:        redirect.FromURL,
+			StatusCode: redirect.StatusCode,
+			Location:   redirect.ToURL,
+			Duration:   redirect.Duration,
+		})
+	}
+
+	return &response.HTTPResponse{
+		StatusCode:      entry.Response.StatusCode,
+		Status:          entry.Response.Status,
+		Headers:         entry.Response.Headers,
+		Body:            entry.Response.Body,
+		ResponseTime:    entry.Response.ResponseTime,
+		Error:           entry.Response.Error,
+		ErrorType:       string(entry.Response.ErrorType),
+		IsJSON:          entry.Response.IsJSON,
+		IsXML:           entry.Response.IsXML,
+		IsHTML:          entry.Response.IsHTML,
+		IsBinary:        entry.Response.IsBinary,
+		WireSize:        entry.Response.WireSize,
+		BodySize:        entry.Response.ContentLength,
+		ContentEncoding: entry.Response.ContentEncoding,
+		DecodeError:     entry.Response.DecodeError,
+		Charset:         entry.Response.Charset,
+		DNSTime:         entry.Response.DNSTime,
+		ConnectTime:     entry.Response.ConnectTime,
+		TLSTime:         entry.Response.TLSTime,
+		SendTime:        entry.Response.SendTime,
+		WaitTime:        entry.Response.WaitTime,
+		ReceiveTime:     entry.Response.ReceiveTime,
+		ConnReused:      entry.Response.ConnReused,
+		Protocol:        entry.Response.Protocol,
+		ServerAddr:      entry.Response.ServerAddr,
+		LocalAddr:       entry.Response.LocalAddr,
+		Proxy:           entry.Response.Proxy,
+		TLS:             entry.Response.TLS,
+		Streamed:        entry.Response.Streamed,
+		Events:          entry.Response.Events,
+		DroppedEvents:   entry.Response.DroppedEvents,
+		StreamEnd:       entry.Response.StreamEnd,
+		Redirects:       redirects,
+		FinalURL:        entry.Response.FinalURL,
+	}
+}
+
+// formatHistoryEntry renders a single line describing a history entry
+func formatHistoryEntry(entry *RequestResponsePair) string {
+	status := "ERR"
+	if entry.Response != nil && entry.Response.Error == "" {
+		status = strconv.Itoa(entry.Response.StatusCode)
+	}
+
+	method, name := "", ""
+	if entry.Request != nil {
+		method = string(entry.Request.Method)
+		name = entry.Request.Name
+		if name == "" {
+			name = entry.Request.URL
+		}
+	}
+
+	line := fmt.Sprintf("%s  %-3s  %-6s %s", entry.ExecutedAt.Local().Format("2006-01-02 15:04:05"), status, method, name)
+	if entry.Environment != "" {
+		line += " [" + entry.Environment + "]"
+	}
+	return line
+}
+
+// sizeUnits are the suffixes accepted by parseByteSize, longest first
+var sizeUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"gb", 1024 * 1024 * 1024},
+	{"mb", 1024 * 1024},
+	{"kb", 1024},
+	{"b", 1},
+}
+
+// parseByteSize parses a lowercase size like "10mb" or "512kb". ok is false
+// when value has no size suffix.
+func parseByteSize(value string) (size int64, ok bool, err error) {
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(value, unit.suffix) {
+			number, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(value, unit.suffix)), 64)
+			if err != nil || number <= 0 {
+				return 0, true, fmt.Errorf("invalid size %q", value)
+			}
+			return int64(number * float64(unit.multiplier)), true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// parsePruneLimit parses limits like "30d", "12h" or "10MB" for pruning history
+func parsePruneLimit(input string) (time.Duration, int64, error) {
+	value := strings.ToLower(strings.TrimSpace(input))
+	if value == "" {
+		return 0, 0, fmt.Errorf("empty prune limit")
+	}
+
+	if size, ok, err := parseByteSize(value); ok {
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid size %q", input)
+		}
+		return 0, size, nil
+	}
+
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.ParseFloat(strings.TrimSuffix(value, "d"), 64)
+		if err != nil || days <= 0 {
+			return 0, 0, fmt.Errorf("invalid age %q", input)
+		}
+		return time.Duration(days * float64(24*time.Hour)), 0, nil
+	}
+
+	age, err := time.ParseDuration(value)
+	if err != nil || age <= 0 {
+		return 0, 0, fmt.Errorf("invalid prune limit %q (use e.g. 30d, 12h or 10MB)", input)
+	}
+	return age, 0, nil
+}
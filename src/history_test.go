@@ -0,0 +1,348 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	response "kalo/src/panels/response"
+)
+
+// credentialRequest authenticates with a bearer token and sends other
+// credentials in headers
+const credentialRequest = `meta {
+  name: Profile
+  type: http
+  seq: 1
+}
+
+get {
+  url: {{baseUrl}}/me
+  auth: bearer
+}
+
+headers {
+  Accept: application/json
+  X-API-Key: {{apiKey}}
+  Cookie: session=abc123
+}
+
+auth:bearer {
+  token: {{token}}
+}
+`
+
+func TestHistoryRedactsCredentials(t *testing.T) {
+	bruReq, err := NewBruParser(strings.NewReader(credentialRequest)).Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse request: %v", err)
+	}
+	vars := map[string]string{"baseUrl": "http://localhost", "token": "secret-token", "apiKey": "secret-key"}
+	sent, err := NewHTTPClient().ResolveRequest(bruReq, nil, vars)
+	if err != nil {
+		t.Fatalf("Failed to resolve request: %v", err)
+	}
+
+	entry := newHistoryEntry(sent, &response.HTTPResponse{StatusCode: 200}, nil, "dev", "api")
+	headers := entry.Request.Headers
+	if headers.Get("Authorization") != "Bearer [redacted]" {
+		t.Errorf("Expected the bearer token to be redacted, got %q", headers.Get("Authorization"))
+	}
+	if headers.Get("X-Api-Key") != "[redacted]" || headers.Get("Cookie") != "[redacted]" {
+		t.Errorf("Expected API key and cookie headers to be redacted, got %q and %q", headers.Get("X-Api-Key"), headers.Get("Cookie"))
+	}
+	if headers.Get("Accept") != "application/json" {
+		t.Errorf("Expected other headers to be kept, got %q", headers.Get("Accept"))
+	}
+	if !slices.Equal(entry.Request.RedactedHeaders, []string{"Authorization", "Cookie", "X-Api-Key"}) {
+		t.Errorf("Expected the redacted headers to be listed, got %v", entry.Request.RedactedHeaders)
+	}
+	if entry.Request.Auth == nil || entry.Request.Auth.Type != AuthBearer {
+		t.Errorf("Expected the auth type to be recorded, got %+v", entry.Request.Auth)
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatalf("Failed to encode entry: %v", err)
+	}
+	for _, secret := range []string{"secret-token", "secret-key", "abc123"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("Expected %q to stay out of history, got %s", secret, data)
+		}
+	}
+
+	// The request that was sent keeps its credentials for resuming streams
+	if sent.Headers.Get("Authorization") != "Bearer secret-token" {
+		t.Errorf("Expected the sent request to be left alone, got %q", sent.Headers.Get("Authorization"))
+	}
+}
+
+func TestHistoryRedactsAPIKeyAuthHeader(t *testing.T) {
+	source := "get {\n  url: http://localhost/items\n  auth: apikey\n}\n\nauth:apikey {\n  key: X-Client\n  value: {{clientKey}}\n}\n"
+	bruReq, err := NewBruParser(strings.NewReader(source)).Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse request: %v", err)
+	}
+	sent, err := NewHTTPClient().ResolveRequest(bruReq, nil, map[string]string{"clientKey": "secret-key"})
+	if err != nil {
+		t.Fatalf("Failed to resolve request: %v", err)
+	}
+
+	entry := newHistoryEntry(sent, nil, nil, "", "")
+	if got := entry.Request.Headers.Get("X-Client"); got != "[redacted]" {
+		t.Errorf("Expected the API key header named by the auth block to be redacted, got %q", got)
+	}
+}
+
+func TestHistoryRedactsQueryAndBody(t *testing.T) {
+	sent := &HTTPRequestModel{
+		Method: POST,
+		URL:    "http://localhost/login?access_token=abc123&page=2#top",
+		Body:   &RequestBody{Type: "json", Content: "{\n  \"user\": {\"name\": \"ada\", \"password\": \"hunter2\"},\n  \"auth\": {\"key\": \"k-1\", \"ids\": [7, 8]},\n  \"note\": \"token}\"\n}"},
+	}
+	entry := newHistoryEntry(sent, &response.HTTPResponse{StatusCode: 200, FinalURL: sent.URL}, nil, "", "")
+	recorded := entry.Request
+
+	if recorded.URL != "http://localhost/login?access_token=%5Bredacted%5D&page=2#top" || entry.Response.FinalURL != recorded.URL {
+		t.Errorf("Expected the token query parameter to be redacted, got %q and %q", recorded.URL, entry.Response.FinalURL)
+	}
+	if !slices.Equal(recorded.RedactedQuery, []string{"access_token"}) {
+		t.Errorf("Expected the redacted query parameters to be listed, got %v", recorded.RedactedQuery)
+	}
+
+	expected := "{\n  \"user\": {\"name\": \"ada\", \"password\": \"[redacted]\"},\n  \"auth\": {\"key\": \"[redacted]\", \"ids\": [\"[redacted]\", \"[redacted]\"]},\n  \"note\": \"token}\"\n}"
+	if recorded.Body.Content != expected {
+		t.Errorf("Expected credential values to be redacted and the rest kept as written, got %s", recorded.Body.Content)
+	}
+	if !slices.Equal(recorded.RedactedBody, []string{"user.password", "auth.key", "auth.ids.0", "auth.ids.1"}) {
+		t.Errorf("Expected the redacted JSON paths to be listed, got %v", recorded.RedactedBody)
+	}
+	if sent.Body.Content == recorded.Body.Content || !strings.Contains(sent.URL, "abc123") {
+		t.Error("Expected the sent request to be left alone")
+	}
+
+	form := &HTTPRequestModel{Method: POST, URL: "http://localhost/token", Body: &RequestBody{
+		Type:     "form-urlencoded",
+		Content:  "grant_type=password&username=ada&password=hunter2&client_secret=hush%26hush",
+		FormData: map[string]string{"grant_type": "password", "username": "ada", "password": "hunter2", "client_secret": "hush&hush"},
+	}}
+	recorded = newHistoryEntry(form, nil, nil, "", "").Request
+	if recorded.Body.Content != "grant_type=password&username=ada&password=%5Bredacted%5D&client_secret=%5Bredacted%5D" || recorded.Body.FormData["password"] != "[redacted]" {
+		t.Errorf("Expected the form credentials to be redacted, got %q %v", recorded.Body.Content, recorded.Body.FormData)
+	}
+
+	data, _ := json.Marshal([]*HTTPRequestModel{newHistoryEntry(sent, nil, nil, "", "").Request, recorded})
+	for _, secret := range []string{"abc123", "hunter2", "k-1", "hush"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("Expected %q to stay out of history, got %s", secret, data)
+		}
+	}
+}
+
+func TestRestoreRedactedQueryAndBody(t *testing.T) {
+	dir := writeCollection(t, map[string]string{
+		"environments/dev.bru": "vars {\n  baseUrl: http://localhost\n  apiKey: dev-key\n  password: dev-password\n  secret: dev-secret\n}\n",
+		"login.bru":            "post {\n  url: {{baseUrl}}/login?api_key={{apiKey}}&page=1\n  body: json\n}\n\nbody:json {\n  {\"user\": \"ada\", \"password\": \"{{password}}\", \"remember\": true}\n}\n",
+		"token.bru":            "post {\n  url: {{baseUrl}}/token\n  body: form-urlencoded\n}\n\nbody:form-urlencoded {\n  client_id: kalo\n  client_secret: {{secret}}\n}\n",
+	})
+	client := NewHTTPClient()
+	runner := NewScriptRunner()
+
+	for _, name := range []string{"login.bru", "token.bru"} {
+		t.Run(name, func(t *testing.T) {
+			sourceFile := filepath.Join(dir, name)
+			targets, err := loadRunTargets(sourceFile, "dev")
+			if err != nil {
+				t.Fatalf("Failed to load request: %v", err)
+			}
+			_, sent, err := prepareBruRequest(context.Background(), client, runner, targets[0].request, targets[0].env, nil)
+			if err != nil {
+				t.Fatalf("Failed to prepare request: %v", err)
+			}
+			sent.SourceFile = sourceFile
+
+			recorded := newHistoryEntry(sent, nil, nil, "dev", filepath.Base(dir)).Request
+			if len(recorded.RedactedQuery)+len(recorded.RedactedBody) == 0 || strings.Contains(recorded.URL+recorded.Body.Content, "dev-") {
+				t.Fatalf("Expected credentials to be redacted, got %s %s", recorded.URL, recorded.Body.Content)
+			}
+
+			restored, err := restoreCredentials(context.Background(), client, runner, recorded, "dev")
+			if err != nil {
+				t.Fatalf("Failed to restore credentials: %v", err)
+			}
+			if restored.URL != sent.URL || restored.Body.Content != sent.Body.Content {
+				t.Errorf("Expected the request to be restored as sent:\n%s %s\ngot\n%s %s", sent.URL, sent.Body.Content, restored.URL, restored.Body.Content)
+			}
+			if len(restored.RedactedQuery)+len(restored.RedactedBody) != 0 {
+				t.Errorf("Expected nothing left redacted, got %v %v", restored.RedactedQuery, restored.RedactedBody)
+			}
+		})
+	}
+}
+
+func TestRestoreCredentials(t *testing.T) {
+	var authorization string
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+	})
+	dir := writeCollection(t, map[string]string{
+		"environments/dev.bru": "vars {\n  baseUrl: " + server.URL + "\n  token: dev-token\n  apiKey: dev-key\n}\n",
+		"profile.bru":          credentialRequest,
+	})
+	sourceFile := filepath.Join(dir, "profile.bru")
+
+	targets, err := loadRunTargets(sourceFile, "dev")
+	if err != nil {
+		t.Fatalf("Failed to load request: %v", err)
+	}
+	client := NewHTTPClient()
+	runner := NewScriptRunner()
+	_, sent, err := prepareBruRequest(context.Background(), client, runner, targets[0].request, targets[0].env, nil)
+	if err != nil {
+		t.Fatalf("Failed to prepare request: %v", err)
+	}
+	sent.SourceFile = sourceFile
+	recorded := newHistoryEntry(sent, nil, nil, "dev", filepath.Base(dir)).Request
+	recorded.SetHeader("Accept", "text/plain")
+
+	restored, err := restoreCredentials(context.Background(), client, runner, recorded, "dev")
+	if err != nil {
+		t.Fatalf("Failed to restore credentials: %v", err)
+	}
+	if restored.Headers.Get("Authorization") != "Bearer dev-token" || restored.Headers.Get("X-Api-Key") != "dev-key" {
+		t.Errorf("Expected the credentials to be resolved again, got %v", restored.Headers)
+	}
+	if restored.Headers.Get("Accept") != "text/plain" || len(restored.RedactedHeaders) != 0 {
+		t.Errorf("Expected the rest of the recorded request to be kept, got %+v", restored)
+	}
+
+	client.SendRequest(context.Background(), restored)
+	if authorization != "Bearer dev-token" {
+		t.Errorf("Expected the re-sent request to be authorized, got %q", authorization)
+	}
+
+	if _, err := restoreCredentials(context.Background(), client, runner, recorded, "prod"); err == nil || !strings.Contains(err.Error(), `"prod"`) {
+		t.Errorf("Expected an error for a missing environment, got %v", err)
+	}
+	recorded.SourceFile = ""
+	if _, err := restoreCredentials(context.Background(), client, runner, recorded, "dev"); err == nil {
+		t.Error("Expected an error without the source file")
+	}
+}
+
+// historyEntries builds entries executed the given number of hours ago
+func historyEntries(hoursAgo ...int) []*RequestResponsePair {
+	var entries []*RequestResponsePair
+	for i, hours := range hoursAgo {
+		entries = append(entries, &RequestResponsePair{
+			ID:         string(rune('a' + i)),
+			Request:    &HTTPRequestModel{Method: GET, URL: "http://localhost/items"},
+			Response:   &HTTPResponseModel{StatusCode: 200},
+			Success:    true,
+			ExecutedAt: time.Now().Add(-time.Duration(hours) * time.Hour),
+		})
+	}
+	return entries
+}
+
+func TestHistoryPrune(t *testing.T) {
+	path := filepath.Join(t.TempDir(), historyFileName)
+	store := NewHistoryStore(path)
+	for _, entry := range historyEntries(72, 48, 1, 0) {
+		if err := store.Append(entry); err != nil {
+			t.Fatalf("Failed to append: %v", err)
+		}
+	}
+
+	removed, err := store.Prune(24*time.Hour, 0)
+	if err != nil || removed != 2 {
+		t.Fatalf("Expected entries older than a day to be removed, got %d (%v)", removed, err)
+	}
+	if reloaded := NewHistoryStore(path).Entries(); len(reloaded) != 2 || reloaded[0].ID != "d" {
+		t.Errorf("Expected the file to keep the two newest entries, got %d", len(reloaded))
+	}
+
+	// A size limit keeps the newest entries that fit
+	line, _ := json.Marshal(store.Entries()[0])
+	removed, err = store.Prune(0, int64(len(line))+1)
+	if err != nil || removed != 1 {
+		t.Fatalf("Expected one entry over the size limit to be removed, got %d (%v)", removed, err)
+	}
+	if entries := NewHistoryStore(path).Entries(); len(entries) != 1 || entries[0].ID != "d" {
+		t.Errorf("Expected only the newest entry to be kept, got %+v", entries)
+	}
+
+	if removed, err := store.Prune(24*time.Hour, 0); err != nil || removed != 0 {
+		t.Errorf("Expected nothing to prune, got %d (%v)", removed, err)
+	}
+}
+
+func TestFilterHistory(t *testing.T) {
+	entries := []*RequestResponsePair{
+		{ID: "list", Request: &HTTPRequestModel{Name: "List Users", Method: GET, URL: "http://api/users"}, Response: &HTTPResponseModel{StatusCode: 200}, Success: true, Environment: "staging", Collection: "users"},
+		{ID: "create", Request: &HTTPRequestModel{Name: "Create User", Method: POST, URL: "http://api/users"}, Response: &HTTPResponseModel{StatusCode: 422}, Environment: "production", Collection: "users"},
+		{ID: "health", Request: &HTTPRequestModel{Name: "Health", Method: GET, URL: "http://api/health"}, Response: &HTTPResponseModel{Error: "connection refused"}, Collection: "ops"},
+	}
+
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{"", "list,create,health"},
+		{"2xx", "list"},
+		{"4xx", "create"},
+		{"error", "create,health"},
+		{"post users", "create"},
+		{"USERS get", "list"},
+		{"staging", "list"},
+		{"ops", "health"},
+		{"5xx", ""},
+	}
+
+	for _, tt := range tests {
+		var ids []string
+		for _, entry := range FilterHistory(entries, tt.query) {
+			ids = append(ids, entry.ID)
+		}
+		if strings.Join(ids, ",") != tt.expected {
+			t.Errorf("Filter %q: expected %q, got %q", tt.query, tt.expected, strings.Join(ids, ","))
+		}
+	}
+}
+
+func TestParsePruneLimit(t *testing.T) {
+	tests := []struct {
+		input    string
+		maxAge   time.Duration
+		maxBytes int64
+		wantErr  bool
+	}{
+		{"30d", 30 * 24 * time.Hour, 0, false},
+		{"1.5d", 36 * time.Hour, 0, false},
+		{"12h", 12 * time.Hour, 0, false},
+		{" 90m ", 90 * time.Minute, 0, false},
+		{"10MB", 0, 10 * 1024 * 1024, false},
+		{"512kb", 0, 512 * 1024, false},
+		{"100b", 0, 100, false},
+		{"", 0, 0, true},
+		{"-1d", 0, 0, true},
+		{"0mb", 0, 0, true},
+		{"soon", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		maxAge, maxBytes, err := parsePruneLimit(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: expected error %v, got %v", tt.input, tt.wantErr, err)
+			continue
+		}
+		if maxAge != tt.maxAge || maxBytes != tt.maxBytes {
+			t.Errorf("%q: expected %v and %d bytes, got %v and %d bytes", tt.input, tt.maxAge, tt.maxBytes, maxAge, maxBytes)
+		}
+	}
+}
@@ -2,161 +2,687 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
+	"mime/multipart"
+	"net"
 	"net/http"
+	"net/textproto"
 	"net/url"
+	"os"
+	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 	request "kalo/src/panels/request"
 	response "kalo/src/panels/response"
 )
 
 
+// defaultRequestTimeout applies to requests whose settings block does not set a timeout
+const defaultRequestTimeout = 30 * time.Second
+
+// defaultMaxRedirects matches the limit of Go's default redirect policy
+const defaultMaxRedirects = 10
+
+// defaultMaxBodySize is how much of a response body is kept in memory when
+// the settings block does not set maxBodySize. Larger bodies go to a temp file.
+const defaultMaxBodySize = 10 * 1024 * 1024
+
 type HTTPClient struct {
 	client *http.Client
+	
+	// OAuth2Tokens caches tokens of auth:oauth2 requests, in memory unless
+	// replaced with a store backed by a file
+	OAuth2Tokens *OAuth2TokenStore
+	// OpenBrowser shows the authorization page of the authorization code grant
+	OpenBrowser func(url string) error
+	// Cookies holds a cookie jar per collection and environment, in memory
+	// unless replaced with a store backed by a file
+	Cookies *CookieStore
+
+	// transports caches a transport per collection tls and proxy configuration
+	transports   map[string]*collectionTransport
+	transportsMu sync.Mutex
+	// collections caches each collection's collection.bru until the file changes
+	collections   map[string]*CollectionConfig
+	collectionsMu sync.Mutex
 }
 
 func NewHTTPClient() *HTTPClient {
 	return &HTTPClient{
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		// Timeouts are applied per request through the context
+		client:       &http.Client{},
+		OAuth2Tokens: NewOAuth2TokenStore(""),
+		OpenBrowser:  openBrowser,
+		Cookies:      NewCookieStore(""),
+		transports:   make(map[string]*collectionTransport),
+		collections:  make(map[string]*CollectionConfig),
 	}
 }
 
-func (c *HTTPClient) ExecuteRequest(bruReq *request.BruRequest) (*response.HTTPResponse, error) {
-	if bruReq == nil {
-		return nil, fmt.Errorf("request is nil")
+// ResolveRequest substitutes variables and applies authentication, producing
+// the exact request that will be sent over the wire
+func (c *HTTPClient) ResolveRequest(bruReq *request.BruRequest, collection *CollectionConfig, vars map[string]string) (*HTTPRequestModel, error) {
+	if bruReq.HTTP.Method != "" && !IsValidMethod(bruReq.HTTP.Method) {
+		return nil, fmt.Errorf("Invalid HTTP method %q", bruReq.HTTP.Method)
 	}
 
-	start := time.Now()
-
 	// Substitute environment variables
-	processedURL := c.substituteVars(bruReq.HTTP.URL, bruReq.Vars)
+	processedURL := c.substituteVars(bruReq.HTTP.URL, vars)
 	
 	// Parse URL and add query parameters
 	parsedURL, err := url.Parse(processedURL)
 	if err != nil {
-		return &response.HTTPResponse{Error: fmt.Sprintf("Invalid URL: %v", err)}, nil
+		return nil, fmt.Errorf("Invalid URL: %v", err)
 	}
 
-	// Add query parameters
+	// Add query parameters in order, after any already in the URL
 	if len(bruReq.Query) > 0 {
-		query := parsedURL.Query()
-		for key, value := range bruReq.Query {
-			processedValue := c.substituteVars(value, bruReq.Vars)
-			query.Add(key, processedValue)
+		var query []string
+		if parsedURL.RawQuery != "" {
+			query = append(query, parsedURL.RawQuery)
+		}
+		for _, param := range bruReq.Query {
+			processedValue := c.substituteVars(param.Value, vars)
+			query = append(query, url.QueryEscape(param.Key)+"="+url.QueryEscape(processedValue))
 		}
-		parsedURL.RawQuery = query.Encode()
+		parsedURL.RawQuery = strings.Join(query, "&")
 	}
 
 	// Prepare request body
-	var body io.Reader
-	if bruReq.Body.Type != "" && bruReq.Body.Data != "" {
-		processedBody := c.substituteVars(bruReq.Body.Data, bruReq.Vars)
-		
-		switch bruReq.Body.Type {
-		case "json":
-			// Wrap the body data in proper JSON structure if needed
-			if !strings.HasPrefix(strings.TrimSpace(processedBody), "{") {
-				processedBody = "{" + processedBody + "}"
-			}
-			body = bytes.NewBufferString(processedBody)
-		case "text", "raw":
-			body = bytes.NewBufferString(processedBody)
-		default:
-			body = bytes.NewBufferString(processedBody)
+	var body *RequestBody
+	switch {
+	case bruReq.Body.Type == "form-urlencoded":
+		body = c.resolveFormBody(bruReq.Body.Fields, vars)
+	case bruReq.Body.Type == "multipart-form":
+		body, err = c.resolveMultipartBody(bruReq.Body.Fields, bruReq.Dir, vars)
+		if err != nil {
+			return nil, err
 		}
+	case bruReq.Body.Type == "graphql":
+		body, err = c.resolveGraphQLBody(bruReq.Body, vars)
+		if err != nil {
+			return nil, err
+		}
+	case bruReq.Body.Type != "" && bruReq.Body.Data != "":
+		processedBody := c.substituteVars(bruReq.Body.Data, vars)
+		body = &RequestBody{Type: bruReq.Body.Type, Content: processedBody}
 	}
 
-	// Create HTTP request
-	req, err := http.NewRequest(bruReq.HTTP.Method, parsedURL.String(), body)
+	// Build the request so auth helpers can set headers the same way net/http would
+	req, err := http.NewRequest(bruReq.HTTP.Method, parsedURL.String(), nil)
 	if err != nil {
-		return &response.HTTPResponse{Error: fmt.Sprintf("Failed to create request: %v", err)}, nil
+		return nil, fmt.Errorf("Failed to create request: %v", err)
+	}
+
+	// Add headers, repeated ones are sent with every value
+	for _, param := range bruReq.Headers {
+		processedValue := c.substituteVars(param.Value, vars)
+		req.Header.Add(param.Key, processedValue)
 	}
 
-	// Add headers
-	for key, value := range bruReq.Headers {
-		processedValue := c.substituteVars(value, bruReq.Vars)
-		req.Header.Set(key, processedValue)
+	// Form bodies need a matching Content-Type unless one was set explicitly
+	if body != nil && body.ContentType != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", string(body.ContentType))
 	}
 
-	// Add authentication
+	// Add authentication, recording its type but none of its secrets
+	var auth *AuthConfig
 	if bruReq.Auth.Type != "" {
-		err := c.addAuth(req, bruReq.Auth, bruReq.Vars)
+		err := c.addAuth(req, bruReq.Auth, vars)
 		if err != nil {
-			return &response.HTTPResponse{Error: fmt.Sprintf("Auth error: %v", err)}, nil
+			return nil, fmt.Errorf("Auth error: %v", err)
+		}
+		if bruReq.Auth.Type != string(AuthNone) {
+			auth = &AuthConfig{Type: AuthType(bruReq.Auth.Type)}
+			if auth.Type == AuthAPIKey {
+				auth.KeyName = c.substituteVars(bruReq.Auth.Values["key"], vars)
+				auth.Location = "header"
+			}
 		}
 	}
 
+	settings := requestSettings(bruReq, collection)
+	timeout, err := parseRequestTimeout(settings["timeout"])
+	if err != nil {
+		return nil, err
+	}
+	disableRedirects, maxRedirects, err := parseRedirectSettings(settings)
+	if err != nil {
+		return nil, err
+	}
+	maxBodySize, err := parseMaxBodySize(settings["maxBodySize"])
+	if err != nil {
+		return nil, err
+	}
+	sendCookies := true
+	if value := strings.TrimSpace(settings["sendCookies"]); value != "" {
+		if sendCookies, err = strconv.ParseBool(value); err != nil {
+			return nil, fmt.Errorf("Invalid sendCookies setting %q", value)
+		}
+	}
+
+	return &HTTPRequestModel{
+		Method:           HTTPMethod(req.Method),
+		URL:              req.URL.String(),
+		Headers:          response.Headers(req.Header),
+		Body:             body,
+		Auth:             auth,
+		Name:             bruReq.Meta.Name,
+		Tags:             bruReq.Tags,
+		Timeout:          timeout,
+		DisableRedirects: disableRedirects,
+		MaxRedirects:     maxRedirects,
+		MaxBodySize:      maxBodySize,
+		DisableCookies:   !sendCookies,
+		CollectionDir:    bruReq.Dir,
+		CollectionVars:   vars,
+		Collection:       collection,
+		CreatedAt:        time.Now(),
+	}, nil
+}
+
+// SendRequest sends a fully resolved request and reads the response. The
+// request stops when ctx is cancelled or its timeout elapses. Streaming
+// responses sent with a stream observer are only subject to the timeout
+// until their headers arrive.
+func (c *HTTPClient) SendRequest(ctx context.Context, sent *HTTPRequestModel) *response.HTTPResponse {
+	start := time.Now()
+
+	timeout := sent.Timeout
+	if timeout <= 0 {
+		timeout = defaultRequestTimeout
+	}
+	// The deadline is a timer rather than a context deadline so streams can stop it
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	deadline := time.AfterFunc(timeout, func() { cancel(context.DeadlineExceeded) })
+	defer deadline.Stop()
+
+	var body io.Reader
+	multipartType := ""
+	if sent.Body != nil && sent.Body.Type == "multipart-form" {
+		buffer, bodyType, err := buildMultipartBody(sent.Body)
+		if err != nil {
+			return &response.HTTPResponse{Error: fmt.Sprintf("Failed to build multipart body: %v", err)}
+		}
+		body = buffer
+		multipartType = bodyType
+	} else if sent.Body != nil && sent.Body.Content != "" {
+		body = bytes.NewBufferString(sent.Body.Content)
+	}
+
+	// Create HTTP request, tracing each phase for the Timing tab
+	trace := &requestTrace{}
+	req, err := http.NewRequestWithContext(trace.withTrace(ctx), string(sent.Method), sent.URL, body)
+	if err != nil {
+		return &response.HTTPResponse{Error: fmt.Sprintf("Failed to create request: %v", err)}
+	}
+
+	for key, values := range sent.Headers {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	if multipartType != "" {
+		// The boundary is only known once the body has been written
+		req.Header.Set("Content-Type", multipartType)
+	}
+	if req.Header.Get("Accept-Encoding") == "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+
+	// Follow redirects on a copy of the client so each request records its own chain
+	client := *c.client
+	transport, err := c.sentTransport(sent)
+	if err != nil {
+		return &response.HTTPResponse{Error: err.Error(), ErrorType: string(ErrorValidation)}
+	}
+	client.Transport = transport
+	var redirects []response.Redirect
+	hopStart := start
+	maxRedirects := sent.MaxRedirects
+	if maxRedirects <= 0 {
+		maxRedirects = defaultMaxRedirects
+	}
+	if c.Cookies != nil && sent.CookieScope != "" {
+		client.Jar = c.Cookies.Jar(sent.CookieScope, !sent.DisableCookies)
+		defer c.Cookies.Flush()
+	}
+	client.CheckRedirect = func(next *http.Request, via []*http.Request) error {
+		if sent.DisableRedirects {
+			return http.ErrUseLastResponse
+		}
+
+		now := time.Now()
+		redirects = append(redirects, response.Redirect{
+			URL:        via[len(via)-1].URL.String(),
+			StatusCode: next.Response.StatusCode,
+			Location:   next.Response.Header.Get("Location"),
+			Duration:   now.Sub(hopStart),
+		})
+		hopStart = now
+		if len(redirects) > maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		trace.reset()
+		return nil
+	}
+
 	// Execute request
-	resp, err := c.client.Do(req)
-	responseTime := time.Since(start)
+	resp, err := client.Do(req)
 
 	if err != nil {
-		return &response.HTTPResponse{
-			Error:        fmt.Sprintf("Request failed: %v", err),
-			ResponseTime: responseTime,
-		}, nil
+		message, errorType := describeRequestError(ctx, err, timeout)
+		failed := &response.HTTPResponse{
+			Error:        message,
+			ErrorType:    string(errorType),
+			ResponseTime: time.Since(start),
+			Redirects:    redirects,
+			TLS:          describeFailedTLS(err, req.URL.Hostname()),
+			Proxy:        transport.proxyFor(req),
+		}
+		trace.apply(failed, time.Now())
+		return failed
 	}
 	defer resp.Body.Close()
 
-	// Read response body
-	bodyBytes, err := io.ReadAll(resp.Body)
+	// Keep every value, e.g. each Set-Cookie
+	headers := response.Headers(resp.Header)
+
+	// Read response body, streams chunk by chunk, undoing its Content-Encoding
+	var bodyBytes []byte
+	var events []response.StreamEvent
+	var droppedEvents int
+	var decoded *decodingReader
+	var decodeErr error
+	var bodySize int64
+	bodyFile := ""
+	charset := ""
+	wire := &countingReader{reader: resp.Body}
+	contentType := resp.Header.Get("Content-Type")
+	contentEncoding := resp.Header.Get("Content-Encoding")
+	decoded, decodeErr = newBodyDecoder(wire, contentEncoding)
+	streamed := isStreamingResponse(resp)
+	if streamed {
+		observer := streamObserverFrom(ctx)
+		if observer != nil {
+			deadline.Stop()
+			observer(streamUpdate{head: &response.HTTPResponse{StatusCode: resp.StatusCode, Status: resp.Status, Headers: headers}})
+		}
+		var stream io.Reader
+		stream, charset = newCharsetReader(decoded, contentType)
+		var kept streamBody
+		kept, err = readStream(ctx, stream, isEventStream(resp), bodySizeLimit(sent), observer)
+		bodyBytes, bodyFile, events, droppedEvents = kept.data, kept.file, kept.events, kept.dropped
+	} else {
+		// Bodies that grow past the size limit once decoded are read into a
+		// temp file with progress
+		bodyBytes, bodyFile, err = readBody(decoded, wire, resp.ContentLength, bodySizeLimit(sent), streamObserverFrom(ctx))
+	}
+	if err == nil && bodyFile != "" {
+		bodySize, bodyBytes, err = previewBodyFile(bodyFile)
+	}
+	if decoded.err != nil {
+		decodeErr = decoded.err
+	}
+	end := time.Now()
+	responseTime := end.Sub(start)
 	if err != nil {
+		if bodyFile != "" {
+			os.Remove(bodyFile)
+		}
+		message, errorType := fmt.Sprintf("Failed to read response body: %v", err), ErrorNetwork
+		if ctx.Err() != nil {
+			message, errorType = describeRequestError(ctx, err, timeout)
+		}
 		return &response.HTTPResponse{
 			StatusCode:   resp.StatusCode,
 			Status:       resp.Status,
-			Error:        fmt.Sprintf("Failed to read response body: %v", err),
+			Error:        message,
+			ErrorType:    string(errorType),
 			ResponseTime: responseTime,
-		}, nil
-	}
-
-	// Extract response headers
-	headers := make(map[string]string)
-	for key, values := range resp.Header {
-		if len(values) > 0 {
-			headers[key] = values[0]
 		}
 	}
 
-	// Format response body
+	// Format response body, converting text to UTF-8
+	kind := detectBodyKind(contentType, bodyBytes)
 	bodyStr := string(bodyBytes)
-	contentType := resp.Header.Get("Content-Type")
-	isJSON := strings.Contains(contentType, "application/json")
+	if kind != bodyBinary && !streamed {
+		bodyStr, charset = decodeCharset(bodyBytes, contentType)
+	}
 	
-	if isJSON {
+	if kind == bodyJSON {
 		// Pretty print JSON
 		var jsonObj interface{}
-		if err := json.Unmarshal(bodyBytes, &jsonObj); err == nil {
+		if err := json.Unmarshal([]byte(bodyStr), &jsonObj); err == nil {
 			if prettyBytes, err := json.MarshalIndent(jsonObj, "", "  "); err == nil {
 				bodyStr = string(prettyBytes)
 			}
 		}
 	}
 
-	return &response.HTTPResponse{
-		StatusCode:   resp.StatusCode,
-		Status:       resp.Status,
-		Headers:      headers,
-		Body:         bodyStr,
-		ResponseTime: responseTime,
-		IsJSON:       isJSON,
+	result := &response.HTTPResponse{
+		StatusCode:      resp.StatusCode,
+		Status:          resp.Status,
+		Headers:         headers,
+		Body:            bodyStr,
+		ResponseTime:    responseTime,
+		IsJSON:          kind == bodyJSON,
+		IsXML:           kind == bodyXML,
+		IsHTML:          kind == bodyHTML,
+		IsBinary:        kind == bodyBinary,
+		WireSize:        wire.count,
+		BodySize:        int64(len(bodyBytes)),
+		ContentEncoding: contentEncoding,
+		Charset:         charset,
+		RawBody:         bodyBytes,
+		Protocol:        resp.Proto,
+		Redirects:       redirects,
+		Proxy:           transport.proxyFor(resp.Request),
+		Streamed:        streamed,
+		Events:          events,
+		DroppedEvents:   droppedEvents,
+	}
+	if streamed {
+		result.StreamEnd = describeStreamEnd(ctx, timeout)
+	}
+	if bodyFile != "" {
+		result.BodyFile = bodyFile
+		result.BodySize = bodySize
+		result.BodyTruncated = true
+	}
+	if decodeErr != nil {
+		result.DecodeError = decodeErr.Error()
+	}
+	if len(redirects) > 0 {
+		result.FinalURL = resp.Request.URL.String()
+	}
+	if resp.TLS != nil {
+		result.TLS = describeTLSConnection(resp.TLS, resp.Request.URL.Hostname())
+	}
+	trace.apply(result, end)
+	return result
+}
+
+// describeRequestError explains why a request failed and classifies the
+// failure, telling cancelled and timed out requests and TLS failures apart
+// from other network errors
+func describeRequestError(ctx context.Context, err error, timeout time.Duration) (string, ErrorType) {
+	switch context.Cause(ctx) {
+	case context.Canceled:
+		return "Request cancelled", ErrorCancelled
+	case context.DeadlineExceeded:
+		return fmt.Sprintf("Request timed out after %s", timeout), ErrorTimeout
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "proxyconnect" {
+		return fmt.Sprintf("Request failed: could not connect to the proxy: %v", opErr.Err), ErrorNetwork
+	}
+	if message := describeTLSError(err); message != "" {
+		return message, ErrorTLS
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return fmt.Sprintf("Request failed: %v", err), ErrorDNS
+	}
+	return fmt.Sprintf("Request failed: %v", err), ErrorNetwork
+}
+
+// parseRequestTimeout reads the timeout of a settings block, given in
+// milliseconds as Bruno writes it or as a duration such as "5s". Zero or an
+// empty value keeps the default timeout.
+func parseRequestTimeout(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	if ms, err := strconv.Atoi(value); err == nil && ms >= 0 {
+		return time.Duration(ms) * time.Millisecond, nil
+	}
+	if duration, err := time.ParseDuration(value); err == nil && duration >= 0 {
+		return duration, nil
+	}
+	return 0, fmt.Errorf("Invalid timeout setting %q", value)
+}
+
+// bodySizeLimit is how much of a response body to the request is kept in memory
+func bodySizeLimit(sent *HTTPRequestModel) int64 {
+	if sent == nil || sent.MaxBodySize <= 0 {
+		return defaultMaxBodySize
+	}
+	return sent.MaxBodySize
+}
+
+// parseMaxBodySize reads the maxBodySize setting, in bytes or with a unit
+// such as 50MB
+func parseMaxBodySize(value string) (int64, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return 0, nil
+	}
+	if size, err := strconv.ParseInt(value, 10, 64); err == nil && size > 0 {
+		return size, nil
+	}
+	if size, ok, err := parseByteSize(value); ok && err == nil {
+		return size, nil
+	}
+	return 0, fmt.Errorf("Invalid maxBodySize setting %q", value)
+}
+
+// parseRedirectSettings reads followRedirects and maxRedirects from a
+// settings block. A maxRedirects of 0 disables redirects like
+// followRedirects: false.
+func parseRedirectSettings(settings map[string]string) (bool, int, error) {
+	disable := false
+	if value := strings.TrimSpace(settings["followRedirects"]); value != "" {
+		follow, err := strconv.ParseBool(value)
+		if err != nil {
+			return false, 0, fmt.Errorf("Invalid followRedirects setting %q", value)
+		}
+		disable = !follow
+	}
+
+	maxRedirects := 0
+	if value := strings.TrimSpace(settings["maxRedirects"]); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return false, 0, fmt.Errorf("Invalid maxRedirects setting %q", value)
+		}
+		if parsed == 0 {
+			disable = true
+		}
+		maxRedirects = parsed
+	}
+
+	return disable, maxRedirects, nil
+}
+
+// resolveFormBody encodes enabled fields as application/x-www-form-urlencoded,
+// keeping their order and duplicate keys
+func (c *HTTPClient) resolveFormBody(fields []request.BruFormField, vars map[string]string) *RequestBody {
+	var pairs []string
+	formData := make(map[string]string)
+	for _, field := range fields {
+		if !field.Enabled {
+			continue
+		}
+		key := c.substituteVars(field.Key, vars)
+		value := c.substituteVars(field.Value, vars)
+		pairs = append(pairs, url.QueryEscape(key)+"="+url.QueryEscape(value))
+		if _, exists := formData[key]; !exists {
+			formData[key] = value
+		}
+	}
+
+	return &RequestBody{
+		Type:        "form-urlencoded",
+		Content:     strings.Join(pairs, "&"),
+		FormData:    formData,
+		ContentType: ContentTypeForm,
+	}
+}
+
+// resolveGraphQLBody builds the standard {"query", "variables"} JSON payload.
+// Variables are substituted in the variables text before it is decoded.
+func (c *HTTPClient) resolveGraphQLBody(body request.BruBody, vars map[string]string) (*RequestBody, error) {
+	payload := map[string]interface{}{
+		"query": c.substituteVars(body.Data, vars),
+	}
+
+	if variables := strings.TrimSpace(c.substituteVars(body.Vars, vars)); variables != "" {
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(variables), &decoded); err != nil {
+			return nil, fmt.Errorf("Invalid GraphQL variables: %v", err)
+		}
+		payload["variables"] = decoded
+	}
+
+	content, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encode GraphQL body: %v", err)
+	}
+
+	return &RequestBody{
+		Type:        "graphql",
+		Content:     string(content),
+		ContentType: ContentTypeJSON,
 	}, nil
 }
 
+// resolveMultipartBody collects text fields and @file(...) uploads in the
+// order they are written. Relative file paths are resolved against the
+// directory of the .bru file.
+func (c *HTTPClient) resolveMultipartBody(fields []request.BruFormField, dir string, vars map[string]string) (*RequestBody, error) {
+	body := &RequestBody{
+		Type:        "multipart-form",
+		ContentType: ContentTypeMultipart,
+	}
+
+	for _, field := range fields {
+		if !field.Enabled {
+			continue
+		}
+		key := c.substituteVars(field.Key, vars)
+
+		paths, isFile := field.FilePaths()
+		if !isFile {
+			body.Parts = append(body.Parts, FormPart{Name: key, Value: c.substituteVars(field.Value, vars)})
+			continue
+		}
+
+		for _, path := range paths {
+			path = c.substituteVars(path, vars)
+			if !filepath.IsAbs(path) && dir != "" {
+				path = filepath.Join(dir, path)
+			}
+			if _, err := os.Stat(path); err != nil {
+				return nil, fmt.Errorf("File upload %q: %v", key, err)
+			}
+			body.Parts = append(body.Parts, FormPart{Name: key, File: &FileUpload{
+				FieldName: key,
+				Filename:  filepath.Base(path),
+				FilePath:  path,
+				MimeType:  mime.TypeByExtension(filepath.Ext(path)),
+			}})
+		}
+	}
+
+	return body, nil
+}
+
+// multipartParts returns the parts of a multipart body. Bodies recorded in
+// history before parts kept their order list text fields by name, then files.
+func multipartParts(body *RequestBody) []FormPart {
+	if len(body.Parts) > 0 {
+		return body.Parts
+	}
+
+	keys := make([]string, 0, len(body.FormData))
+	for key := range body.FormData {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]FormPart, 0, len(keys)+len(body.Files))
+	for _, key := range keys {
+		parts = append(parts, FormPart{Name: key, Value: body.FormData[key]})
+	}
+	for i := range body.Files {
+		parts = append(parts, FormPart{Name: body.Files[i].FieldName, File: &body.Files[i]})
+	}
+	return parts
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// buildMultipartBody writes form fields and files as multipart/form-data in
+// order and returns the body with its Content-Type, including the boundary
+func buildMultipartBody(body *RequestBody) (*bytes.Buffer, string, error) {
+	buffer := &bytes.Buffer{}
+	writer := multipart.NewWriter(buffer)
+
+	for _, part := range multipartParts(body) {
+		if part.File == nil {
+			if err := writer.WriteField(part.Name, part.Value); err != nil {
+				return nil, "", err
+			}
+			continue
+		}
+
+		upload := part.File
+		mimeType := upload.MimeType
+		if mimeType == "" {
+			mimeType = string(ContentTypeOctetStream)
+		}
+
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+			quoteEscaper.Replace(part.Name), quoteEscaper.Replace(upload.Filename)))
+		header.Set("Content-Type", mimeType)
+
+		writerPart, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, "", err
+		}
+
+		file, err := os.Open(upload.FilePath)
+		if err != nil {
+			return nil, "", err
+		}
+		_, err = io.Copy(writerPart, file)
+		file.Close()
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	return buffer, writer.FormDataContentType(), nil
+}
+
+// templatePattern matches a {{VARIABLE}} placeholder
+var templatePattern = regexp.MustCompile(`\{\{([^}]+)\}\}`)
+
 func (c *HTTPClient) substituteVars(text string, vars map[string]string) string {
-	// Replace {{VARIABLE}} patterns with actual values
-	re := regexp.MustCompile(`\{\{([^}]+)\}\}`)
-	
-	return re.ReplaceAllStringFunc(text, func(match string) string {
-		// Extract variable name (remove {{ and }})
-		varName := strings.TrimSpace(match[2 : len(match)-2])
-		
-		if value, exists := vars[varName]; exists {
+	return substituteTemplates(text, vars)
+}
+
+// substituteTemplates replaces {{VARIABLE}} patterns with actual values,
+// dynamic variables such as {{$guid}} and filters such as {{token | base64}}
+func substituteTemplates(text string, vars map[string]string) string {
+	return templatePattern.ReplaceAllStringFunc(text, func(match string) string {
+		if value, ok := evaluateTemplate(match[2:len(match)-2], vars); ok {
 			return value
 		}
 		
@@ -190,6 +716,17 @@ func (c *HTTPClient) addAuth(req *http.Request, auth request.BruAuth, vars map[s
 				req.Header.Set(processedKey, processedValue)
 			}
 		}
+	case "oauth2":
+		// The token is fetched by AuthorizeOAuth2 before the request is resolved
+		token, exists := auth.Values["access_token"]
+		if !exists || token == "" {
+			return fmt.Errorf("no OAuth2 access token")
+		}
+		prefix := c.substituteVars(auth.Values["token_header_prefix"], vars)
+		if prefix == "" {
+			prefix = "Bearer"
+		}
+		req.Header.Set("Authorization", prefix+" "+token)
 	}
 	
 	return nil
@@ -204,6 +741,23 @@ func (c *HTTPClient) FormatResponseForDisplay(httpResp *response.HTTPResponse) s
 		return fmt.Sprintf("Error: %s", httpResp.Error)
 	}
 
+	// Server-sent events are listed one by one
+	if len(httpResp.Events) > 0 {
+		return formatStreamEvents(httpResp.Events, httpResp.DroppedEvents)
+	}
+
+	// Binary bodies are shown in a hex view
+	raw := httpResp.RawBody
+	if raw == nil {
+		raw = []byte(httpResp.Body)
+	}
+	if httpResp.IsBinary {
+		return response.FormatHexDump(raw, httpResp.BodySize)
+	}
+
 	// Return only the response body since headers are displayed separately
+	if httpResp.BodyTruncated {
+		return httpResp.Body + "\n" + response.TruncatedNote(int64(len(raw)), httpResp.BodySize)
+	}
 	return httpResp.Body
 }
\ No newline at end of file
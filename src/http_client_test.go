@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	panels "kalo/src/panels/request"
+	response "kalo/src/panels/response"
+)
+
+// newTestServer starts a server for one test and closes it when the test ends
+func newTestServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// sendBruRequest sends a request the way the TUI and `kalo run` do, without scripts
+func sendBruRequest(ctx context.Context, client *HTTPClient, bruReq *panels.BruRequest, env *Environment) *response.HTTPResponse {
+	return executeBruRequest(ctx, client, NewScriptRunner(), bruReq, env).Response
+}
+
+// newSlowServer starts a server that only answers once the request is cancelled
+func newSlowServer(t *testing.T) *httptest.Server {
+	return newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+}
+
+func TestRequestTimeout(t *testing.T) {
+	server := newSlowServer(t)
+	bruReq := &panels.BruRequest{HTTP: panels.BruHTTP{Method: "GET", URL: server.URL}, Settings: map[string]string{"timeout": "50"}}
+
+	resp := sendBruRequest(context.Background(), NewHTTPClient(), bruReq, nil)
+	if resp.Error != "Request timed out after 50ms" {
+		t.Errorf("Expected a timeout error, got %q", resp.Error)
+	}
+}
+
+func TestRequestCancel(t *testing.T) {
+	server := newSlowServer(t)
+	bruReq := &panels.BruRequest{HTTP: panels.BruHTTP{Method: "GET", URL: server.URL}, Settings: map[string]string{"timeout": "10s"}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	resp := sendBruRequest(ctx, NewHTTPClient(), bruReq, nil)
+	if resp.Error != "Request cancelled" {
+		t.Errorf("Expected a cancelled request, got %q", resp.Error)
+	}
+}
+
+func TestInvalidTimeout(t *testing.T) {
+	bruReq := &panels.BruRequest{HTTP: panels.BruHTTP{Method: "GET", URL: "http://localhost"}, Settings: map[string]string{"timeout": "soon"}}
+	resp := sendBruRequest(context.Background(), NewHTTPClient(), bruReq, nil)
+	if !strings.Contains(resp.Error, "Invalid timeout") {
+		t.Errorf("Expected an invalid timeout to be rejected, got %q", resp.Error)
+	}
+}
+
+// newRedirectServer redirects /login to /sso and then to /home
+func newRedirectServer(t *testing.T) *httptest.Server {
+	return newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.Redirect(w, r, "/sso", http.StatusFound)
+		case "/sso":
+			http.Redirect(w, r, "/home", http.StatusMovedPermanently)
+		default:
+			fmt.Fprint(w, "home")
+		}
+	})
+}
+
+func TestFollowRedirects(t *testing.T) {
+	server := newRedirectServer(t)
+	bruReq := &panels.BruRequest{HTTP: panels.BruHTTP{Method: "GET", URL: server.URL + "/login"}, Dir: t.TempDir()}
+
+	resp := sendBruRequest(context.Background(), NewHTTPClient(), bruReq, nil)
+	if resp.Error != "" || resp.Body != "home" {
+		t.Fatalf("Expected redirects to be followed, got %q %q", resp.Error, resp.Body)
+	}
+	if len(resp.Redirects) != 2 || resp.Redirects[0].StatusCode != http.StatusFound || resp.Redirects[0].Location != "/sso" || resp.Redirects[1].StatusCode != http.StatusMovedPermanently {
+		t.Errorf("Unexpected redirect chain: %+v", resp.Redirects)
+	}
+	if resp.FinalURL != server.URL+"/home" {
+		t.Errorf("Expected final URL %s/home, got %q", server.URL, resp.FinalURL)
+	}
+}
+
+func TestCollectionRedirectLimit(t *testing.T) {
+	server := newRedirectServer(t)
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, collectionFileName), []byte("settings {\n  maxRedirects: 1\n}\n"), 0644)
+	bruReq := &panels.BruRequest{HTTP: panels.BruHTTP{Method: "GET", URL: server.URL + "/login"}, Dir: dir}
+
+	resp := sendBruRequest(context.Background(), NewHTTPClient(), bruReq, nil)
+	if !strings.Contains(resp.Error, "stopped after 1 redirects") || len(resp.Redirects) != 2 {
+		t.Errorf("Expected the collection's redirect limit to stop the request, got %q with %d redirects", resp.Error, len(resp.Redirects))
+	}
+}
+
+func TestRedirectsDisabled(t *testing.T) {
+	server := newRedirectServer(t)
+	dir := t.TempDir()
+	// The request overrides the collection settings
+	os.WriteFile(filepath.Join(dir, collectionFileName), []byte("settings {\n  followRedirects: true\n}\n"), 0644)
+	bruReq := &panels.BruRequest{
+		HTTP:     panels.BruHTTP{Method: "GET", URL: server.URL + "/login"},
+		Settings: map[string]string{"followRedirects": "false"},
+		Dir:      dir,
+	}
+
+	resp := sendBruRequest(context.Background(), NewHTTPClient(), bruReq, nil)
+	if resp.StatusCode != http.StatusFound || len(resp.Redirects) != 0 || resp.Headers.Get("Location") != "/sso" {
+		t.Errorf("Expected the first redirect to be returned, got %d with %d redirects", resp.StatusCode, len(resp.Redirects))
+	}
+}
+
+func TestSendCustomMethod(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Method", r.Method)
+		w.WriteHeader(http.StatusMultiStatus)
+	})
+
+	bruReq := &panels.BruRequest{HTTP: panels.BruHTTP{Method: "PROPFIND", URL: server.URL}}
+	resp := sendBruRequest(context.Background(), NewHTTPClient(), bruReq, nil)
+	if resp.Error != "" || resp.StatusCode != http.StatusMultiStatus || resp.Headers.Get("X-Method") != "PROPFIND" {
+		t.Errorf("Expected the PROPFIND to reach the server, got %d %q %v", resp.StatusCode, resp.Error, resp.Headers)
+	}
+}
+
+func TestInvalidMethod(t *testing.T) {
+	bruReq := &panels.BruRequest{HTTP: panels.BruHTTP{Method: "BAD METHOD", URL: "http://localhost"}}
+	if resp := sendBruRequest(context.Background(), NewHTTPClient(), bruReq, nil); !strings.Contains(resp.Error, "Invalid HTTP method") {
+		t.Errorf("Expected an invalid method error, got %q", resp.Error)
+	}
+}
+
+func TestSendRepeatedHeadersAndQuery(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Set-Cookie", "a=1")
+		w.Header().Add("Set-Cookie", "b=2")
+		w.Header().Set("X-Query", r.URL.RawQuery)
+		w.Header().Set("X-Accept", strings.Join(r.Header.Values("Accept"), ", "))
+	})
+
+	request, err := NewBruParser(strings.NewReader(repeatedParamsSource)).Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	env := &Environment{Vars: map[string]string{"baseUrl": server.URL}}
+	resp := sendBruRequest(context.Background(), NewHTTPClient(), request, env)
+	if resp.Error != "" {
+		t.Fatalf("Request failed: %s", resp.Error)
+	}
+	if query := resp.Headers.Get("X-Query"); query != "q=go&tag=b&tag=a&limit=10" {
+		t.Errorf("Expected query parameters in order, got %q", query)
+	}
+	if accept := resp.Headers.Get("X-Accept"); accept != "application/json, text/plain" {
+		t.Errorf("Expected both Accept headers, got %q", accept)
+	}
+	if cookies := resp.Headers.Values("Set-Cookie"); len(cookies) != 2 {
+		t.Errorf("Expected both Set-Cookie headers, got %v", cookies)
+	}
+	if formatted := response.FormatHeaders(resp.Headers); !strings.Contains(formatted, "Set-Cookie: a=1\nSet-Cookie: b=2\n") {
+		t.Errorf("Expected a line per Set-Cookie, got:\n%s", formatted)
+	}
+}
+
+func TestSendMultipartBody(t *testing.T) {
+	type receivedPart struct {
+		name, filename, content string
+	}
+	var received []receivedPart
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		reader, err := r.MultipartReader()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				break
+			}
+			content, _ := io.ReadAll(part)
+			received = append(received, receivedPart{part.FormName(), part.FileName(), string(content)})
+		}
+	})
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("hello"), 0644)
+	bruReq := &panels.BruRequest{
+		HTTP: panels.BruHTTP{Method: "POST", URL: server.URL},
+		Body: panels.BruBody{Type: "multipart-form", Fields: []panels.BruFormField{
+			{Key: "tag", Value: "b", Enabled: true},
+			{Key: "file", Value: "@file(notes.txt)", Enabled: true},
+			{Key: "tag", Value: "skipped", Enabled: false},
+			{Key: "tag", Value: "{{tag}}", Enabled: true},
+		}},
+		Dir: dir,
+	}
+	env := &Environment{Vars: map[string]string{"tag": "a"}}
+	resp := sendBruRequest(context.Background(), NewHTTPClient(), bruReq, env)
+	if resp.Error != "" || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Request failed: %d %s", resp.StatusCode, resp.Error)
+	}
+
+	// Repeated fields are all sent, in the order they are written
+	expected := []receivedPart{{"tag", "", "b"}, {"file", "notes.txt", "hello"}, {"tag", "", "a"}}
+	if fmt.Sprint(received) != fmt.Sprint(expected) {
+		t.Errorf("Expected parts %v, got %v", expected, received)
+	}
+}
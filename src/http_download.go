@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"time"
+
+	response "kalo/src/panels/response"
+)
+
+// bodyPreviewSize is how much of a body kept in a temp file is shown
+const bodyPreviewSize = 64 * 1024
+
+// downloadProgressInterval limits how often download progress is reported
+const downloadProgressInterval = 100 * time.Millisecond
+
+// downloadProgress is how much of a response body has arrived
+type downloadProgress struct {
+	received int64
+	total    int64 // -1 when the server sent no Content-Length
+}
+
+// spillBuffer keeps a body in memory until it grows past limit, and from
+// then on in a temp file
+type spillBuffer struct {
+	limit  int64
+	buffer bytes.Buffer
+	file   *os.File
+}
+
+// Write adds data to the body, moving it to a temp file once it grows past
+// the limit
+func (b *spillBuffer) Write(data []byte) (int, error) {
+	if b.file == nil && int64(b.buffer.Len()+len(data)) > b.limit {
+		file, err := os.CreateTemp("", "kalo-body-*")
+		if err != nil {
+			return 0, fmt.Errorf("failed to create temp file: %v", err)
+		}
+		b.file = file
+		if _, err := file.Write(b.buffer.Bytes()); err != nil {
+			return 0, fmt.Errorf("failed to write temp file: %v", err)
+		}
+		b.buffer = bytes.Buffer{}
+	}
+	if b.file == nil {
+		return b.buffer.Write(data)
+	}
+	n, err := b.file.Write(data)
+	if err != nil {
+		return n, fmt.Errorf("failed to write temp file: %v", err)
+	}
+	return n, nil
+}
+
+// path is the temp file the body is kept in, or "" while it is in memory
+func (b *spillBuffer) path() string {
+	if b.file == nil {
+		return ""
+	}
+	return b.file.Name()
+}
+
+// finish returns the body kept in memory, or the path of the temp file
+// holding it
+func (b *spillBuffer) finish() ([]byte, string, error) {
+	if b.file == nil {
+		return b.buffer.Bytes(), "", nil
+	}
+	if err := b.file.Close(); err != nil {
+		b.discard()
+		return nil, "", fmt.Errorf("failed to write temp file: %v", err)
+	}
+	return nil, b.file.Name(), nil
+}
+
+// discard deletes the temp file of a body that is not used
+func (b *spillBuffer) discard() {
+	if b.file != nil {
+		b.file.Close()
+		os.Remove(b.file.Name())
+	}
+}
+
+// readBody reads a decoded response body into memory until it grows past
+// limit, and from then on into a temp file whose path is returned instead.
+// Progress on the wire is passed to observer as the body arrives.
+func readBody(body io.Reader, wire *countingReader, total, limit int64, observer streamObserver) ([]byte, string, error) {
+	buffer := &spillBuffer{limit: limit}
+	lastReport := time.Now()
+	chunk := make([]byte, 32*1024)
+	for {
+		n, err := body.Read(chunk)
+		if n > 0 {
+			if _, writeErr := buffer.Write(chunk[:n]); writeErr != nil {
+				buffer.discard()
+				return nil, "", writeErr
+			}
+
+			if observer != nil && time.Since(lastReport) >= downloadProgressInterval {
+				observer(streamUpdate{progress: &downloadProgress{received: wire.count, total: total}})
+				lastReport = time.Now()
+			}
+		}
+		if err == io.EOF {
+			return buffer.finish()
+		}
+		if err != nil {
+			buffer.discard()
+			return nil, "", err
+		}
+	}
+}
+
+// previewBodyFile returns the size of a body kept in a temp file and its
+// first bodyPreviewSize bytes
+func previewBodyFile(bodyPath string) (int64, []byte, error) {
+	file, err := os.Open(bodyPath)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, nil, err
+	}
+	preview := make([]byte, bodyPreviewSize)
+	n, err := io.ReadFull(file, preview)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return 0, nil, err
+	}
+	return info.Size(), preview[:n], nil
+}
+
+// removeBodyFile deletes the temp file of a response that is no longer shown
+func removeBodyFile(resp *response.HTTPResponse) {
+	if resp != nil && resp.BodyFile != "" {
+		os.Remove(resp.BodyFile)
+	}
+}
+
+// writeResponseBody saves the body of a response as received, after undoing
+// its Content-Encoding, and returns the number of bytes written
+func writeResponseBody(resp *response.HTTPResponse, target string) (int64, error) {
+	file, err := os.Create(target)
+	if err != nil {
+		return 0, err
+	}
+	written, err := copyResponseBody(file, resp)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return written, err
+}
+
+// copyResponseBody writes the body of a response as received to w, from its
+// temp file if it has one
+func copyResponseBody(w io.Writer, resp *response.HTTPResponse) (int64, error) {
+	if resp.BodyFile != "" {
+		source, err := os.Open(resp.BodyFile)
+		if err != nil {
+			return 0, err
+		}
+		defer source.Close()
+		return io.Copy(w, source)
+	}
+
+	data := resp.RawBody
+	if data == nil {
+		data = []byte(resp.Body)
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// suggestBodyFileName names the file a response body is saved to: the name
+// the server suggests, the last segment of the URL, or one made up from the
+// time and the Content-Type
+func suggestBodyFileName(resp *response.HTTPResponse, requestURL string) string {
+	if _, params, err := mime.ParseMediaType(resp.Headers.Get("Content-Disposition")); err == nil {
+		if name := path.Base(params["filename"]); name != "." && name != "/" && params["filename"] != "" {
+			return name
+		}
+	}
+
+	if resp.FinalURL != "" {
+		requestURL = resp.FinalURL
+	}
+	if parsed, err := url.Parse(requestURL); err == nil {
+		if name := path.Base(parsed.Path); path.Ext(name) != "" {
+			return name
+		}
+	}
+
+	extension := ".txt"
+	switch {
+	case resp.IsJSON:
+		extension = ".json"
+	case resp.IsXML:
+		extension = ".xml"
+	case resp.IsHTML:
+		extension = ".html"
+	case resp.IsBinary:
+		extension = ".bin"
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Headers.Get("Content-Type"))
+	if extensions, err := mime.ExtensionsByType(mediaType); err == nil && len(extensions) > 0 && resp.IsBinary {
+		extension = extensions[0]
+	}
+	return fmt.Sprintf("response-%s%s", time.Now().Format("20060102-150405"), extension)
+}
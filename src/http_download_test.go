@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// largePayload is a 234.4 KB binary body starting with a PNG signature
+var largePayload = bytes.Repeat([]byte("\x89PNG\r\n\x1a\n\x00\x01\x02\x03"), 20000)
+
+// newDownloadServer serves largePayload gzipped, or chunked on /chunked
+func newDownloadServer(t *testing.T) *httptest.Server {
+	return newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		if r.URL.Path == "/chunked" {
+			// Flushing before the end makes the body chunked
+			w.Write(largePayload[:1024])
+			w.(http.Flusher).Flush()
+			w.Write(largePayload[1024:])
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		writer := gzip.NewWriter(w)
+		writer.Write(largePayload)
+		writer.Close()
+	})
+}
+
+func TestParseMaxBodySize(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected int64
+		valid    bool
+	}{
+		{"", 0, true},
+		{"64KB", 64 * 1024, true},
+		{"1048576", 1024 * 1024, true},
+		{"lots", 0, false},
+	}
+	for _, test := range tests {
+		size, err := parseMaxBodySize(test.value)
+		if (err == nil) != test.valid || (test.valid && size != test.expected) {
+			t.Errorf("parseMaxBodySize(%q): expected %d (valid %t), got %d (%v)", test.value, test.expected, test.valid, size, err)
+		}
+	}
+}
+
+func TestLargeResponseBodyFile(t *testing.T) {
+	server := newDownloadServer(t)
+	client := NewHTTPClient()
+	resp := client.SendRequest(context.Background(), &HTTPRequestModel{Method: GET, URL: server.URL + "/large", MaxBodySize: 64 * 1024})
+	if resp.Error != "" || resp.BodyFile == "" || !resp.BodyTruncated {
+		t.Fatalf("Expected the body to be kept in a temp file, got %q", resp.Error)
+	}
+	defer removeBodyFile(resp)
+	// The limit applies to the decoded body, not what was sent compressed
+	if resp.WireSize >= 64*1024 {
+		t.Errorf("Expected a compressed body below the limit, got %d bytes", resp.WireSize)
+	}
+	if resp.BodySize != int64(len(largePayload)) || len(resp.RawBody) != bodyPreviewSize || !resp.IsBinary {
+		t.Errorf("Expected a %d byte binary body with a preview, got %d bytes and a %d byte preview", len(largePayload), resp.BodySize, len(resp.RawBody))
+	}
+
+	display := client.FormatResponseForDisplay(resp)
+	if !strings.HasPrefix(display, "00000000  89 50 4e 47 0d 0a 1a 0a  00 01 02 03 89 50 4e 47  |.PNG.........PNG|") || !strings.Contains(display, "showing the first 64.0 KB of 234.4 KB") {
+		t.Errorf("Expected a truncated hex view, got %q", display[:100])
+	}
+}
+
+func TestSaveResponseBody(t *testing.T) {
+	server := newDownloadServer(t)
+	resp := NewHTTPClient().SendRequest(context.Background(), &HTTPRequestModel{Method: GET, URL: server.URL + "/large", MaxBodySize: 64 * 1024})
+	if resp.Error != "" {
+		t.Fatalf("Request failed: %s", resp.Error)
+	}
+
+	saved := filepath.Join(t.TempDir(), suggestBodyFileName(resp, server.URL+"/large"))
+	if written, err := writeResponseBody(resp, saved); err != nil || written != int64(len(largePayload)) {
+		t.Fatalf("Expected the whole body to be saved, wrote %d (%v)", written, err)
+	}
+	if data, _ := os.ReadFile(saved); !bytes.Equal(data, largePayload) || filepath.Ext(saved) != ".png" {
+		t.Errorf("Expected the decoded body to be saved as %s", saved)
+	}
+
+	removeBodyFile(resp)
+	if _, err := os.Stat(resp.BodyFile); !os.IsNotExist(err) {
+		t.Errorf("Expected the temp file to be removed")
+	}
+}
+
+func TestChunkedDownloadIsNotStreamed(t *testing.T) {
+	server := newDownloadServer(t)
+
+	// A chunked image is a download, not a stream
+	ctx := withStreamObserver(context.Background(), func(update streamUpdate) {})
+	resp := NewHTTPClient().SendRequest(ctx, &HTTPRequestModel{Method: GET, URL: server.URL + "/chunked"})
+	if resp.Streamed || resp.BodyFile != "" || !bytes.Equal(resp.RawBody, largePayload) {
+		t.Errorf("Expected the chunked image to be read as a body, streamed %v", resp.Streamed)
+	}
+}
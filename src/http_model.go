@@ -7,6 +7,9 @@ import (
 	"net/url"
 	"strings"
 	"time"
+	"unicode"
+
+	response "kalo/src/panels/response"
 )
 
 // HTTPMethod represents supported HTTP methods
@@ -20,6 +23,8 @@ const (
 	DELETE  HTTPMethod = "DELETE"
 	HEAD    HTTPMethod = "HEAD"
 	OPTIONS HTTPMethod = "OPTIONS"
+	TRACE   HTTPMethod = "TRACE"
+	CONNECT HTTPMethod = "CONNECT"
 )
 
 // ContentType represents common content types
@@ -52,7 +57,7 @@ type HTTPRequestModel struct {
 	// Basic request properties
 	Method      HTTPMethod            `json:"method"`
 	URL         string               `json:"url"`
-	Headers     map[string]string    `json:"headers,omitempty"`
+	Headers     response.Headers     `json:"headers,omitempty"`
 	QueryParams map[string]string    `json:"query_params,omitempty"`
 	
 	// Request body
@@ -71,6 +76,18 @@ type HTTPRequestModel struct {
 	
 	// Request configuration
 	Timeout     time.Duration        `json:"timeout,omitempty"`
+	DisableRedirects bool            `json:"disable_redirects,omitempty"`
+	MaxRedirects int                 `json:"max_redirects,omitempty"` // 0 uses defaultMaxRedirects
+	MaxBodySize int64                `json:"max_body_size,omitempty"` // 0 uses defaultMaxBodySize
+	CookieScope string               `json:"cookie_scope,omitempty"`  // Cookie jar used when sending, see collectionScope
+	DisableCookies bool              `json:"disable_cookies,omitempty"` // Store received cookies without sending any
+	CollectionDir string             `json:"collection_dir,omitempty"`  // Collection whose collection.bru configures TLS and proxies
+	CollectionVars map[string]string `json:"-"`                         // Variables the proxy credentials in collection.bru are resolved with
+	Collection *CollectionConfig     `json:"-"`                         // collection.bru as read when the request was resolved
+	SourceFile  string               `json:"source_file,omitempty"`      // .bru file the request was built from
+	RedactedHeaders []string         `json:"redacted_headers,omitempty"` // Headers whose values history does not keep, see redactCredentials
+	RedactedQuery []string           `json:"redacted_query,omitempty"`   // Query parameters whose values history does not keep
+	RedactedBody []string            `json:"redacted_body,omitempty"`    // Form fields or JSON paths whose values history does not keep
 	
 	// Timestamps
 	CreatedAt   time.Time            `json:"created_at,omitempty"`
@@ -83,9 +100,18 @@ type RequestBody struct {
 	Content     string            `json:"content,omitempty"`
 	FormData    map[string]string `json:"form_data,omitempty"`
 	Files       []FileUpload      `json:"files,omitempty"`
+	Parts       []FormPart        `json:"parts,omitempty"` // Multipart fields in the order they are sent
 	ContentType ContentType       `json:"content_type,omitempty"`
 }
 
+// FormPart is a field of a multipart body, holding either text or a file.
+// Names may repeat.
+type FormPart struct {
+	Name  string      `json:"name"`
+	Value string      `json:"value,omitempty"`
+	File  *FileUpload `json:"file,omitempty"`
+}
+
 // FileUpload represents a file to be uploaded
 type FileUpload struct {
 	FieldName string `json:"field_name"`
@@ -114,9 +140,13 @@ type HTTPResponseModel struct {
 	StatusClass  StatusClass       `json:"status_class"`
 	
 	// Response headers
-	Headers      map[string]string `json:"headers"`
+	Headers      response.Headers  `json:"headers"`
 	ContentType  string            `json:"content_type"`
 	ContentLength int64            `json:"content_length"`
+	WireSize     int64             `json:"wire_size,omitempty"`        // Bytes received, before Content-Encoding is undone
+	ContentEncoding string         `json:"content_encoding,omitempty"`
+	DecodeError  string            `json:"decode_error,omitempty"`
+	Charset      string            `json:"charset,omitempty"`          // Converted to UTF-8 from
 	
 	// Response body
 	Body         string            `json:"body"`
@@ -135,6 +165,7 @@ type HTTPResponseModel struct {
 	SendTime     time.Duration     `json:"send_time,omitempty"`
 	WaitTime     time.Duration     `json:"wait_time,omitempty"`
 	ReceiveTime  time.Duration     `json:"receive_time,omitempty"`
+	ConnReused   bool              `json:"conn_reused,omitempty"`
 	
 	// Request/Response metadata
 	RequestURL   string            `json:"request_url"`
@@ -156,6 +187,14 @@ type HTTPResponseModel struct {
 	Protocol     string            `json:"protocol,omitempty"`     // HTTP/1.1, HTTP/2, etc.
 	ServerAddr   string            `json:"server_addr,omitempty"`
 	LocalAddr    string            `json:"local_addr,omitempty"`
+	Proxy        string            `json:"proxy,omitempty"`        // Proxy the request went through, password redacted
+	TLS          *response.TLSInfo `json:"tls,omitempty"`
+
+	// Streaming responses
+	Streamed     bool                   `json:"streamed,omitempty"`
+	Events       []response.StreamEvent `json:"events,omitempty"`
+	DroppedEvents int                   `json:"dropped_events,omitempty"`
+	StreamEnd    string                 `json:"stream_end,omitempty"`
 }
 
 // StatusClass represents HTTP status code classes
@@ -176,6 +215,7 @@ const (
 	ErrorNone        ErrorType = "none"
 	ErrorNetwork     ErrorType = "network"
 	ErrorTimeout     ErrorType = "timeout"
+	ErrorCancelled   ErrorType = "cancelled"
 	ErrorDNS         ErrorType = "dns"
 	ErrorTLS         ErrorType = "tls"
 	ErrorAuth        ErrorType = "auth"
@@ -187,10 +227,11 @@ const (
 
 // RedirectInfo represents information about a redirect
 type RedirectInfo struct {
-	FromURL    string    `json:"from_url"`
-	ToURL      string    `json:"to_url"`
-	StatusCode int       `json:"status_code"`
-	Timestamp  time.Time `json:"timestamp"`
+	FromURL    string        `json:"from_url"`
+	ToURL      string        `json:"to_url"`
+	StatusCode int           `json:"status_code"`
+	Timestamp  time.Time     `json:"timestamp"`
+	Duration   time.Duration `json:"duration,omitempty"`
 }
 
 // RequestResponsePair represents a request-response pair for history/logging
@@ -206,6 +247,7 @@ type RequestResponsePair struct {
 	
 	// Test results (if any)
 	TestResults []TestResult      `json:"test_results,omitempty"`
+	ScriptLogs  []ScriptLog       `json:"script_logs,omitempty"`
 	
 	// Environment context
 	Environment string            `json:"environment,omitempty"`
@@ -221,22 +263,25 @@ type TestResult struct {
 	Actual   interface{} `json:"actual,omitempty"`
 }
 
+// ScriptLog is a line written by a script with console.log, console.warn or console.error
+type ScriptLog struct {
+	Level   string `json:"level"` // log, warn or error
+	Message string `json:"message"`
+}
+
 // Helper methods for HTTPRequestModel
 
-// SetHeader sets a header value
+// SetHeader sets a header value, replacing any others
 func (r *HTTPRequestModel) SetHeader(key, value string) {
 	if r.Headers == nil {
-		r.Headers = make(map[string]string)
+		r.Headers = make(response.Headers)
 	}
-	r.Headers[key] = value
+	r.Headers.Set(key, value)
 }
 
-// GetHeader gets a header value
+// GetHeader gets the first value of a header
 func (r *HTTPRequestModel) GetHeader(key string) string {
-	if r.Headers == nil {
-		return ""
-	}
-	return r.Headers[key]
+	return r.Headers.Get(key)
 }
 
 // SetQueryParam sets a query parameter
@@ -355,7 +400,7 @@ func (r *HTTPResponseModel) GetContentType() string {
 		return r.ContentType
 	}
 	
-	if contentType, exists := r.Headers["Content-Type"]; exists {
+	if contentType := r.Headers.Get("Content-Type"); contentType != "" {
 		// Extract just the media type, ignoring charset and other parameters
 		parts := strings.Split(contentType, ";")
 		return strings.TrimSpace(parts[0])
@@ -410,21 +455,27 @@ func (r *HTTPRequestModel) Validate() error {
 	}
 	
 	// Validate method
-	validMethods := []HTTPMethod{GET, POST, PUT, PATCH, DELETE, HEAD, OPTIONS}
-	validMethod := false
-	for _, method := range validMethods {
-		if r.Method == method {
-			validMethod = true
-			break
-		}
-	}
-	if !validMethod {
+	if !IsValidMethod(string(r.Method)) {
 		return fmt.Errorf("invalid HTTP method: %s", r.Method)
 	}
 	
 	return nil
 }
 
+// IsValidMethod reports whether method can be sent as an HTTP method. Besides
+// the standard methods, any token is allowed, e.g. PROPFIND or QUERY.
+func IsValidMethod(method string) bool {
+	if method == "" {
+		return false
+	}
+	for _, r := range method {
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("!#$%&'*+-.^_`|~", r)) {
+			return false
+		}
+	}
+	return true
+}
+
 // Clone creates a deep copy of the request model
 func (r *HTTPRequestModel) Clone() *HTTPRequestModel {
 	clone := &HTTPRequestModel{
@@ -433,16 +484,22 @@ func (r *HTTPRequestModel) Clone() *HTTPRequestModel {
 		Name:        r.Name,
 		Description: r.Description,
 		Timeout:     r.Timeout,
+		DisableRedirects: r.DisableRedirects,
+		MaxRedirects: r.MaxRedirects,
+		MaxBodySize: r.MaxBodySize,
+		CookieScope: r.CookieScope,
+		DisableCookies: r.DisableCookies,
+		CollectionDir: r.CollectionDir,
+		CollectionVars: r.CollectionVars,
+		Collection: r.Collection,
+		SourceFile:  r.SourceFile,
 		CreatedAt:   r.CreatedAt,
 		UpdatedAt:   r.UpdatedAt,
 	}
 	
 	// Clone headers
 	if r.Headers != nil {
-		clone.Headers = make(map[string]string)
-		for k, v := range r.Headers {
-			clone.Headers[k] = v
-		}
+		clone.Headers = r.Headers.Clone()
 	}
 	
 	// Clone query params
@@ -461,6 +518,19 @@ func (r *HTTPRequestModel) Clone() *HTTPRequestModel {
 		}
 	}
 	
+	if r.RedactedHeaders != nil {
+		clone.RedactedHeaders = make([]string, len(r.RedactedHeaders))
+		copy(clone.RedactedHeaders, r.RedactedHeaders)
+	}
+	if r.RedactedQuery != nil {
+		clone.RedactedQuery = make([]string, len(r.RedactedQuery))
+		copy(clone.RedactedQuery, r.RedactedQuery)
+	}
+	if r.RedactedBody != nil {
+		clone.RedactedBody = make([]string, len(r.RedactedBody))
+		copy(clone.RedactedBody, r.RedactedBody)
+	}
+	
 	// Clone tags
 	if r.Tags != nil {
 		clone.Tags = make([]string, len(r.Tags))
@@ -486,6 +556,11 @@ func (r *HTTPRequestModel) Clone() *HTTPRequestModel {
 			clone.Body.Files = make([]FileUpload, len(r.Body.Files))
 			copy(clone.Body.Files, r.Body.Files)
 		}
+
+		if r.Body.Parts != nil {
+			clone.Body.Parts = make([]FormPart, len(r.Body.Parts))
+			copy(clone.Body.Parts, r.Body.Parts)
+		}
 	}
 	
 	// Clone auth
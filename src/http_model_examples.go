@@ -13,9 +13,9 @@ func ExampleHTTPModels() {
 		Method: GET,
 		URL:    "https://api.github.com/users/octocat",
 		Name:   "Get GitHub User",
-		Headers: map[string]string{
-			"User-Agent": "Kalo HTTP Client",
-			"Accept":     "application/json",
+		Headers: map[string][]string{
+			"User-Agent": {"Kalo HTTP Client"},
+			"Accept":     {"application/json"},
 		},
 		Tags:      []string{"github", "api", "user"},
 		CreatedAt: time.Now(),
@@ -87,10 +87,10 @@ func ExampleHTTPModels() {
 		StatusCode:   200,
 		Status:       "200 OK",
 		StatusClass:  StatusSuccess,
-		Headers: map[string]string{
-			"Content-Type":   "application/json",
-			"Content-Length": "156",
-			"Server":         "nginx/1.18.0",
+		Headers: map[string][]string{
+			"Content-Type":   {"application/json"},
+			"Content-Length": {"156"},
+			"Server":         {"nginx/1.18.0"},
 		},
 		Body: `{
 			"id": 1,
@@ -113,9 +113,9 @@ func ExampleHTTPModels() {
 		StatusCode:   404,
 		Status:       "404 Not Found",
 		StatusClass:  StatusClientError,
-		Headers: map[string]string{
-			"Content-Type": "application/json",
-			"Server":       "nginx/1.18.0",
+		Headers: map[string][]string{
+			"Content-Type": {"application/json"},
+			"Server":       {"nginx/1.18.0"},
 		},
 		Body: `{
 			"message": "Not Found",
@@ -146,8 +146,8 @@ func ExampleHTTPModels() {
 		StatusCode:  200,
 		Status:      "200 OK",
 		StatusClass: StatusSuccess,
-		Headers: map[string]string{
-			"Content-Type": "text/html",
+		Headers: map[string][]string{
+			"Content-Type": {"text/html"},
 		},
 		Body:         "<html><body>Final destination</body></html>",
 		IsHTML:       true,
@@ -276,9 +276,9 @@ func ExampleRequestValidation() {
 func ExampleResponseHelpers() {
 	response := &HTTPResponseModel{
 		StatusCode:   201,
-		Headers: map[string]string{
-			"Content-Type": "application/json; charset=utf-8",
-			"Location":     "/users/123",
+		Headers: map[string][]string{
+			"Content-Type": {"application/json; charset=utf-8"},
+			"Location":     {"/users/123"},
 		},
 		Body:         `{"id": 123, "name": "John Doe"}`,
 		ResponseTime: 150 * time.Millisecond,
@@ -301,9 +301,9 @@ func ExampleRequestCloning() {
 	original := &HTTPRequestModel{
 		Method: POST,
 		URL:    "https://api.example.com/users",
-		Headers: map[string]string{
-			"Authorization": "Bearer token123",
-			"Content-Type":  "application/json",
+		Headers: map[string][]string{
+			"Authorization": {"Bearer token123"},
+			"Content-Type":  {"application/json"},
 		},
 		Tags: []string{"api", "users"},
 	}
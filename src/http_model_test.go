@@ -0,0 +1,17 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestLegacyHistoryHeaders(t *testing.T) {
+	// History written before headers kept every value still loads
+	var legacy HTTPResponseModel
+	if err := json.Unmarshal([]byte(`{"status_code": 200, "headers": {"Content-Type": "text/plain"}}`), &legacy); err != nil {
+		t.Fatalf("Failed to read legacy headers: %v", err)
+	}
+	if legacy.Headers.Get("Content-Type") != "text/plain" {
+		t.Errorf("Expected the legacy Content-Type, got %v", legacy.Headers)
+	}
+}
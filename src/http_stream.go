@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	response "kalo/src/panels/response"
+)
+
+// streamUpdate is part of a streaming response, delivered as it arrives.
+// The first update carries the status and headers in head.
+type streamUpdate struct {
+	head     *response.HTTPResponse
+	chunk    string
+	events   []response.StreamEvent
+	bodyFile string            // Set once the stream grows past the size limit and continues in this temp file
+	progress *downloadProgress // How much of a body that is not streamed has arrived
+}
+
+// maxStreamEvents is how many of the latest events of a stream are kept
+const maxStreamEvents = 1000
+
+// streamObserver receives a streaming response as it arrives. It is called
+// from the goroutine sending the request.
+type streamObserver func(update streamUpdate)
+
+type streamObserverKey struct{}
+
+// withStreamObserver attaches an observer to ctx. Streaming responses sent
+// with ctx are then read until they end or ctx is cancelled, instead of
+// until the request timeout.
+func withStreamObserver(ctx context.Context, observer streamObserver) context.Context {
+	return context.WithValue(ctx, streamObserverKey{}, observer)
+}
+
+func streamObserverFrom(ctx context.Context) streamObserver {
+	observer, _ := ctx.Value(streamObserverKey{}).(streamObserver)
+	return observer
+}
+
+// isEventStream reports whether a response is a Server-Sent Events stream
+func isEventStream(resp *http.Response) bool {
+	return isEventStreamType(resp.Header.Get("Content-Type"))
+}
+
+// isEventStreamType reports whether a Content-Type is a Server-Sent Events stream
+func isEventStreamType(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "text/event-stream"
+}
+
+// isStreamingResponse reports whether a response should be read as a stream:
+// an event stream, or a chunked body of unknown length that is not a file
+// download
+func isStreamingResponse(resp *http.Response) bool {
+	if isEventStream(resp) {
+		return true
+	}
+	if resp.ContentLength >= 0 || isDownload(resp) {
+		return false
+	}
+	for _, encoding := range resp.TransferEncoding {
+		if encoding == "chunked" {
+			return true
+		}
+	}
+	return false
+}
+
+// isDownload reports whether a response is a file rather than text, such as
+// an attachment or an image
+func isDownload(resp *http.Response) bool {
+	if disposition, _, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil && disposition == "attachment" {
+		return true
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch {
+	case mediaType == "application/octet-stream", mediaType == "application/zip", mediaType == "application/pdf", mediaType == "application/gzip":
+		return true
+	case strings.HasPrefix(mediaType, "image/"), strings.HasPrefix(mediaType, "audio/"), strings.HasPrefix(mediaType, "video/"), strings.HasPrefix(mediaType, "font/"):
+		return true
+	}
+	return false
+}
+
+// streamBody is what is kept of a streaming response
+type streamBody struct {
+	data    []byte // nil once the body is in file
+	file    string
+	events  []response.StreamEvent // The last maxStreamEvents events
+	dropped int                    // Earlier events that were not kept
+}
+
+// readStream reads a streaming body until it ends, ctx is cancelled or
+// reading fails, passing each chunk and the events parsed from it to
+// observer. The body moves to a temp file once it grows past limit.
+func readStream(ctx context.Context, body io.Reader, eventStream bool, limit int64, observer streamObserver) (streamBody, error) {
+	var kept streamBody
+	raw := &spillBuffer{limit: limit}
+	parser := &sseParser{}
+
+	buffer := make([]byte, 32*1024)
+	for {
+		n, err := body.Read(buffer)
+		if n > 0 {
+			chunk := string(buffer[:n])
+			spilled := raw.path() != ""
+			if _, writeErr := raw.Write(buffer[:n]); writeErr != nil {
+				raw.discard()
+				return streamBody{}, writeErr
+			}
+
+			var parsed []response.StreamEvent
+			if eventStream {
+				parsed = parser.feed(chunk)
+				kept.events = append(kept.events, parsed...)
+				// Trimmed once twice as many build up, to copy them rarely
+				if len(kept.events) >= 2*maxStreamEvents {
+					var dropped int
+					kept.events, dropped = trimStreamEvents(kept.events, maxStreamEvents)
+					kept.dropped += dropped
+				}
+			}
+			if observer != nil {
+				update := streamUpdate{chunk: chunk, events: parsed}
+				if !spilled {
+					update.bodyFile = raw.path()
+				}
+				observer(update)
+			}
+		}
+		if err != nil && err != io.EOF && ctx.Err() == nil {
+			raw.discard()
+			return streamBody{}, err
+		}
+		if err != nil {
+			// Ended, or stopped or timed out; what arrived so far is the response
+			var dropped int
+			kept.events, dropped = trimStreamEvents(kept.events, maxStreamEvents)
+			kept.dropped += dropped
+			kept.data, kept.file, err = raw.finish()
+			return kept, err
+		}
+	}
+}
+
+// trimStreamEvents keeps the last keep events, returning how many were dropped
+func trimStreamEvents(events []response.StreamEvent, keep int) ([]response.StreamEvent, int) {
+	if len(events) <= keep {
+		return events, 0
+	}
+	dropped := len(events) - keep
+	return append([]response.StreamEvent{}, events[dropped:]...), dropped
+}
+
+// describeStreamEnd tells why reading a stream stopped
+func describeStreamEnd(ctx context.Context, timeout time.Duration) string {
+	switch context.Cause(ctx) {
+	case nil:
+		return "ended"
+	case context.DeadlineExceeded:
+		return fmt.Sprintf("timed out after %s", timeout)
+	default:
+		return "stopped"
+	}
+}
+
+// sseParser parses a Server-Sent Events stream fed to it in arbitrary chunks
+type sseParser struct {
+	pending   string
+	eventType string
+	data      []string
+	hasData   bool
+	lastID    string // Carries over to later events, as in the EventSource spec
+}
+
+// feed parses a chunk, returning the events it completes
+func (p *sseParser) feed(chunk string) []response.StreamEvent {
+	p.pending += chunk
+
+	// A trailing \r may be the first half of a \r\n split across chunks
+	end := len(p.pending)
+	if strings.HasSuffix(p.pending, "\r") {
+		end--
+	}
+	text := strings.ReplaceAll(p.pending[:end], "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	lastNewline := strings.LastIndex(text, "\n")
+	if lastNewline < 0 {
+		return nil
+	}
+	p.pending = text[lastNewline+1:] + p.pending[end:]
+
+	var events []response.StreamEvent
+	for _, line := range strings.Split(text[:lastNewline], "\n") {
+		if line == "" {
+			if event, ok := p.dispatch(); ok {
+				events = append(events, event)
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue // Comment, often sent as a keep-alive
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			p.eventType = value
+		case "data":
+			p.data = append(p.data, value)
+			p.hasData = true
+		case "id":
+			if !strings.Contains(value, "\x00") {
+				p.lastID = value
+			}
+		}
+	}
+	return events
+}
+
+// dispatch completes the event being built at a blank line
+func (p *sseParser) dispatch() (response.StreamEvent, bool) {
+	defer func() {
+		p.eventType = ""
+		p.data = nil
+		p.hasData = false
+	}()
+
+	if !p.hasData {
+		return response.StreamEvent{}, false
+	}
+	eventType := p.eventType
+	if eventType == "" {
+		eventType = "message"
+	}
+	return response.StreamEvent{
+		ID:       p.lastID,
+		Event:    eventType,
+		Data:     strings.Join(p.data, "\n"),
+		Received: time.Now(),
+	}, true
+}
+
+// formatStreamEvents lists events for the response body viewport, numbered
+// after the dropped events that came before them
+func formatStreamEvents(events []response.StreamEvent, dropped int) string {
+	var content strings.Builder
+	if dropped > 0 {
+		content.WriteString(fmt.Sprintf("(%d earlier events not kept; save the stream for all of them)\n\n", dropped))
+	}
+	for i, event := range events {
+		content.WriteString(formatStreamEvent(dropped+i+1, event) + "\n\n")
+	}
+	return strings.TrimRight(content.String(), "\n")
+}
+
+// formatStreamEvent shows the event with the given number in the stream
+func formatStreamEvent(number int, event response.StreamEvent) string {
+	header := fmt.Sprintf("#%d %s", number, event.Event)
+	if event.ID != "" {
+		header += fmt.Sprintf("  id: %s", event.ID)
+	}
+	if !event.Received.IsZero() {
+		header += "  " + event.Received.Local().Format("15:04:05.000")
+	}
+	return header + "\n" + event.Data
+}
+
+// lastEventID is the id to resume a stream from with Last-Event-ID
+func lastEventID(events []response.StreamEvent) string {
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].ID != "" {
+			return events[i].ID
+		}
+	}
+	return ""
+}
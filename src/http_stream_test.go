@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	panels "kalo/src/panels/request"
+	response "kalo/src/panels/response"
+)
+
+// newEventStreamServer sends two events, or three when resuming with
+// Last-Event-ID, and keeps the stream open until the request is cancelled
+func newEventStreamServer(t *testing.T) *httptest.Server {
+	return newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, ": connected\n\nid: 1\nevent: greeting\ndata: hello\n\ndata: {\"n\": 2}\ndata: more\n\n")
+		if r.Header.Get("Last-Event-ID") != "" {
+			fmt.Fprintf(w, "id: %s-resumed\ndata: again\n\n", r.Header.Get("Last-Event-ID"))
+		}
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+}
+
+func TestEventStreamTimeout(t *testing.T) {
+	server := newEventStreamServer(t)
+
+	// Without an observer the timeout still ends the stream, keeping what arrived
+	bruReq := &panels.BruRequest{HTTP: panels.BruHTTP{Method: "GET", URL: server.URL}, Settings: map[string]string{"timeout": "200"}}
+	resp := sendBruRequest(context.Background(), NewHTTPClient(), bruReq, nil)
+	if resp.Error != "" || !resp.Streamed || resp.StreamEnd != "timed out after 200ms" {
+		t.Fatalf("Expected a stream that timed out, got %q (%q)", resp.Error, resp.StreamEnd)
+	}
+	if len(resp.Events) != 2 || resp.Events[0].Event != "greeting" || resp.Events[1].ID != "1" || resp.Events[1].Data != "{\"n\": 2}\nmore" {
+		t.Errorf("Unexpected events: %+v", resp.Events)
+	}
+}
+
+func TestEventStreamObserver(t *testing.T) {
+	server := newEventStreamServer(t)
+
+	// With an observer the stream runs until it is stopped
+	ctx, cancel := context.WithCancel(context.Background())
+	var received []response.StreamEvent
+	ctx = withStreamObserver(ctx, func(update streamUpdate) {
+		received = append(received, update.events...)
+		if len(received) == 3 {
+			cancel()
+		}
+	})
+	sent := &HTTPRequestModel{Method: GET, URL: server.URL, Headers: response.Headers{"Last-Event-Id": {"1"}}, Timeout: 100 * time.Millisecond}
+	resp := NewHTTPClient().SendRequest(ctx, sent)
+	if resp.Error != "" || resp.StreamEnd != "stopped" || len(resp.Events) != 3 || lastEventID(resp.Events) != "1-resumed" {
+		t.Errorf("Expected a stopped stream of 3 events, got %q (%q) %+v", resp.Error, resp.StreamEnd, resp.Events)
+	}
+}
+
+func TestSSEParserSplitLines(t *testing.T) {
+	// Lines may be split anywhere, including between \r and \n
+	parser := &sseParser{}
+	var events []response.StreamEvent
+	for _, chunk := range []string{"data: a\r", "\n\r\ndat", "a: b\r\n", "\r\n"} {
+		events = append(events, parser.feed(chunk)...)
+	}
+	if len(events) != 2 || events[0].Data != "a" || events[1].Data != "b" {
+		t.Errorf("Expected events a and b, got %+v", events)
+	}
+}
+
+func TestStreamBodyMovesToTempFile(t *testing.T) {
+	chunk := strings.Repeat("line of streamed text\n", 100)
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 5; i++ {
+			w.Write([]byte(chunk))
+			w.(http.Flusher).Flush()
+		}
+	})
+
+	var bodyFile string
+	ctx := withStreamObserver(context.Background(), func(update streamUpdate) {
+		if update.bodyFile != "" {
+			bodyFile = update.bodyFile
+		}
+	})
+	resp := NewHTTPClient().SendRequest(ctx, &HTTPRequestModel{Method: GET, URL: server.URL, MaxBodySize: int64(len(chunk)) * 2})
+	if resp.Error != "" || !resp.Streamed {
+		t.Fatalf("Expected a streamed response, got %q", resp.Error)
+	}
+	defer removeBodyFile(resp)
+
+	if resp.BodyFile == "" || resp.BodyFile != bodyFile || !resp.BodyTruncated {
+		t.Fatalf("Expected the stream to continue in the temp file the observer was told about, got %q and %q", resp.BodyFile, bodyFile)
+	}
+	if resp.BodySize != int64(len(chunk))*5 || len(resp.RawBody) > bodyPreviewSize {
+		t.Errorf("Expected %d bytes with a preview, got %d bytes and a %d byte preview", len(chunk)*5, resp.BodySize, len(resp.RawBody))
+	}
+	saved, err := os.ReadFile(resp.BodyFile)
+	if err != nil || string(saved) != strings.Repeat(chunk, 5) {
+		t.Errorf("Expected the whole stream in the temp file, got %d bytes (%v)", len(saved), err)
+	}
+}
+
+func TestStreamEventsAreCapped(t *testing.T) {
+	var source strings.Builder
+	total := 2*maxStreamEvents + 5
+	for i := 1; i <= total; i++ {
+		fmt.Fprintf(&source, "id: %d\ndata: event %d\n\n", i, i)
+	}
+
+	kept, err := readStream(context.Background(), strings.NewReader(source.String()), true, defaultMaxBodySize, nil)
+	if err != nil {
+		t.Fatalf("Failed to read stream: %v", err)
+	}
+	if len(kept.events) != maxStreamEvents || kept.dropped != total-maxStreamEvents {
+		t.Fatalf("Expected the last %d events and %d dropped, got %d and %d", maxStreamEvents, total-maxStreamEvents, len(kept.events), kept.dropped)
+	}
+	if lastEventID(kept.events) != fmt.Sprint(total) || string(kept.data) != source.String() {
+		t.Errorf("Expected the latest events and the whole body to be kept, got id %s", lastEventID(kept.events))
+	}
+
+	// Events are numbered by their place in the whole stream
+	formatted := formatStreamEvents(kept.events[:1], kept.dropped)
+	if !strings.HasPrefix(formatted, fmt.Sprintf("(%d earlier events not kept", kept.dropped)) || !strings.Contains(formatted, fmt.Sprintf("#%d message  id: %d", kept.dropped+1, kept.dropped+1)) {
+		t.Errorf("Unexpected formatting: %q", formatted)
+	}
+}
+
+func TestStreamViewRendersNewData(t *testing.T) {
+	m := &model{httpClient: NewHTTPClient()}
+	m.startLoading()
+	m.applyStreamUpdate(streamUpdate{head: &response.HTTPResponse{StatusCode: 200, Headers: response.Headers{"Content-Type": {"text/event-stream"}}}})
+	m.refreshStreamView()
+	if m.response != "Waiting for data..." {
+		t.Errorf("Expected to wait for the first event, got %q", m.response)
+	}
+
+	m.applyStreamUpdate(streamUpdate{chunk: "data: a\n\n", events: []response.StreamEvent{{Event: "message", Data: "a"}}})
+	m.applyStreamUpdate(streamUpdate{chunk: "data: b\n\n", events: []response.StreamEvent{{Event: "message", Data: "b"}}})
+	m.refreshStreamView()
+	if m.response != "#1 message\na\n\n#2 message\nb" || m.streamEventCount != 2 || m.streamSize != 18 {
+		t.Errorf("Expected both events rendered, got %q", m.response)
+	}
+
+	// Past the limit the start of the view is dropped, while the body is kept
+	// until the client moves it to a temp file
+	line := strings.Repeat("x", 1023) + "\n"
+	m.startLoading()
+	m.applyStreamUpdate(streamUpdate{head: &response.HTTPResponse{StatusCode: 200, Headers: response.Headers{"Content-Type": {"text/plain"}}}})
+	for i := 0; i < 2*streamViewLimit/len(line); i++ {
+		m.applyStreamUpdate(streamUpdate{chunk: line})
+	}
+	if len(m.streamView) > streamViewLimit || !m.streamViewTrimmed || !strings.HasPrefix(m.streamView, "x") {
+		t.Errorf("Expected the view to keep whole lines up to the limit, got %d bytes", len(m.streamView))
+	}
+	if len(m.streamBody) != 2*streamViewLimit {
+		t.Errorf("Expected the whole body to be kept, got %d bytes", len(m.streamBody))
+	}
+	m.applyStreamUpdate(streamUpdate{chunk: line, bodyFile: "/tmp/kalo-body-test"})
+	if m.streamBody != nil || m.streamFile != "/tmp/kalo-body-test" {
+		t.Errorf("Expected the body to be left to the temp file, got %d bytes", len(m.streamBody))
+	}
+}
+
+func TestMergeResumedStream(t *testing.T) {
+	m := &model{lastSent: &HTTPRequestModel{MaxBodySize: 10}}
+	m.streamPrevious = &response.HTTPResponse{Streamed: true, Body: "data: a\n\n", Events: []response.StreamEvent{{ID: "1", Data: "a"}}, DroppedEvents: 3}
+	resp := &response.HTTPResponse{Streamed: true, Body: "data: b\n\n", RawBody: []byte("data: b\n\n"), Events: []response.StreamEvent{{ID: "2", Data: "b"}}}
+
+	merged := m.mergeResumedStream(resp)
+	if merged.Error != "" {
+		t.Fatalf("Failed to merge: %s", merged.Error)
+	}
+	defer removeBodyFile(merged)
+	if len(merged.Events) != 2 || merged.DroppedEvents != 3 || lastEventID(merged.Events) != "2" {
+		t.Errorf("Expected the events appended after the dropped ones, got %+v (%d dropped)", merged.Events, merged.DroppedEvents)
+	}
+	// Together the bodies pass the size limit
+	saved, err := os.ReadFile(merged.BodyFile)
+	if err != nil || string(saved) != "data: a\n\ndata: b\n\n" || merged.BodySize != 18 || !merged.BodyTruncated {
+		t.Errorf("Expected the merged body in a temp file, got %q (%v)", saved, err)
+	}
+	if m.streamPrevious != nil {
+		t.Error("Expected the resumed stream to be cleared")
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	response "kalo/src/panels/response"
+)
+
+// requestTrace records when each phase of a request starts and ends. Dial
+// callbacks can run on other goroutines, so fields are guarded by mu.
+type requestTrace struct {
+	mu sync.Mutex
+
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	gotConn      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+
+	reused     bool
+	serverAddr string
+	localAddr  string
+}
+
+// withTrace attaches a requestTrace to ctx
+func (t *requestTrace) withTrace(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.record(&t.dnsStart)
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.record(&t.dnsDone)
+		},
+		ConnectStart: func(network, addr string) {
+			// Only the first of several parallel dials marks the start
+			t.mu.Lock()
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+			t.mu.Unlock()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			if err == nil {
+				t.record(&t.connectDone)
+			}
+		},
+		TLSHandshakeStart: func() {
+			t.record(&t.tlsStart)
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.record(&t.tlsDone)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.gotConn = time.Now()
+			t.reused = info.Reused
+			if info.Conn != nil {
+				t.serverAddr = info.Conn.RemoteAddr().String()
+				t.localAddr = info.Conn.LocalAddr().String()
+			}
+			t.mu.Unlock()
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.record(&t.wroteRequest)
+		},
+		GotFirstResponseByte: func() {
+			t.record(&t.firstByte)
+		},
+	})
+}
+
+// reset forgets the previous hop when a redirect is followed, so the phases
+// describe the request that produced the final response
+func (t *requestTrace) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.dnsStart, t.dnsDone = time.Time{}, time.Time{}
+	t.connectStart, t.connectDone = time.Time{}, time.Time{}
+	t.tlsStart, t.tlsDone = time.Time{}, time.Time{}
+	t.gotConn, t.wroteRequest, t.firstByte = time.Time{}, time.Time{}, time.Time{}
+	t.reused = false
+	t.serverAddr, t.localAddr = "", ""
+}
+
+func (t *requestTrace) record(field *time.Time) {
+	t.mu.Lock()
+	*field = time.Now()
+	t.mu.Unlock()
+}
+
+// apply fills in the timing breakdown of resp. end is when the body was read.
+func (t *requestTrace) apply(resp *response.HTTPResponse, end time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	resp.DNSTime = phaseDuration(t.dnsStart, t.dnsDone)
+	resp.ConnectTime = phaseDuration(t.connectStart, t.connectDone)
+	resp.TLSTime = phaseDuration(t.tlsStart, t.tlsDone)
+	resp.SendTime = phaseDuration(t.gotConn, t.wroteRequest)
+	resp.WaitTime = phaseDuration(t.wroteRequest, t.firstByte)
+	resp.ReceiveTime = phaseDuration(t.firstByte, end)
+	resp.ConnReused = t.reused
+	resp.ServerAddr = t.serverAddr
+	resp.LocalAddr = t.localAddr
+}
+
+// phaseDuration returns the time between two trace events, or zero when the
+// phase did not happen
+func phaseDuration(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
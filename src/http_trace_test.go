@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	response "kalo/src/panels/response"
+)
+
+func TestResponseTiming(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		fmt.Fprint(w, "ok")
+	})
+
+	client := NewHTTPClient()
+	sent := &HTTPRequestModel{Method: GET, URL: server.URL}
+
+	first := client.SendRequest(context.Background(), sent)
+	if first.Error != "" {
+		t.Fatalf("Request failed: %s", first.Error)
+	}
+	if first.ConnReused || first.ConnectTime <= 0 {
+		t.Errorf("Expected a new connection with connect time, got reused=%v connect=%v", first.ConnReused, first.ConnectTime)
+	}
+	if first.WaitTime < 10*time.Millisecond {
+		t.Errorf("Expected the server delay in the wait time, got %v", first.WaitTime)
+	}
+	if first.Protocol != "HTTP/1.1" || first.ServerAddr == "" {
+		t.Errorf("Expected protocol and server address, got %q %q", first.Protocol, first.ServerAddr)
+	}
+
+	second := client.SendRequest(context.Background(), sent)
+	if !second.ConnReused || second.ConnectTime != 0 {
+		t.Errorf("Expected the connection to be reused, got reused=%v connect=%v", second.ConnReused, second.ConnectTime)
+	}
+
+	waterfall := response.RenderTimingWaterfall(first, 80)
+	for _, label := range []string{"TCP Connect", "Waiting (TTFB)", "Total", "Connection:     new"} {
+		if !strings.Contains(waterfall, label) {
+			t.Errorf("Expected %q in the waterfall:\n%s", label, waterfall)
+		}
+	}
+}
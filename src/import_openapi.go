@@ -32,11 +32,14 @@ type OpenAPIServer struct {
 }
 
 type OpenAPIPath struct {
-	Get    *OpenAPIOperation `json:"get,omitempty"`
-	Post   *OpenAPIOperation `json:"post,omitempty"`
-	Put    *OpenAPIOperation `json:"put,omitempty"`
-	Patch  *OpenAPIOperation `json:"patch,omitempty"`
-	Delete *OpenAPIOperation `json:"delete,omitempty"`
+	Get     *OpenAPIOperation `json:"get,omitempty"`
+	Post    *OpenAPIOperation `json:"post,omitempty"`
+	Put     *OpenAPIOperation `json:"put,omitempty"`
+	Patch   *OpenAPIOperation `json:"patch,omitempty"`
+	Delete  *OpenAPIOperation `json:"delete,omitempty"`
+	Head    *OpenAPIOperation `json:"head,omitempty"`
+	Options *OpenAPIOperation `json:"options,omitempty"`
+	Trace   *OpenAPIOperation `json:"trace,omitempty"`
 }
 
 type OpenAPIOperation struct {
@@ -155,11 +158,14 @@ func convertOpenAPIToBruno(spec *OpenAPISpec, collectionName string) error {
 	requestCount := 0
 	for path, pathItem := range spec.Paths {
 		operations := map[string]*OpenAPIOperation{
-			"GET":    pathItem.Get,
-			"POST":   pathItem.Post,
-			"PUT":    pathItem.Put,
-			"PATCH":  pathItem.Patch,
-			"DELETE": pathItem.Delete,
+			"GET":     pathItem.Get,
+			"POST":    pathItem.Post,
+			"PUT":     pathItem.Put,
+			"PATCH":   pathItem.Patch,
+			"DELETE":  pathItem.Delete,
+			"HEAD":    pathItem.Head,
+			"OPTIONS": pathItem.Options,
+			"TRACE":   pathItem.Trace,
 		}
 
 		for method, operation := range operations {
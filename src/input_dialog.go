@@ -20,6 +20,9 @@ const (
 	MethodURLInput
 	OpenAPIImportInput
 	ThemeSelectionInput
+	EnvironmentSelectionInput
+	HistorySelectionInput
+	CookieManagerInput
 )
 
 type InputSpec struct {
@@ -41,6 +44,7 @@ type InputDialog struct {
 	nameInput       textinput.Model
 	tagsInput       textinput.Model
 	collectionInput textinput.Model
+	methodInput     textinput.Model // Custom method, typed when customMethod is selected
 	filePicker      filepicker.Model
 	confirmed       bool
 	selectedMethod  int
@@ -51,8 +55,21 @@ type InputDialog struct {
 	// Theme selection fields
 	themes          []string
 	selectedTheme   int
+	// Environment selection fields
+	environments        []string // First entry is always "No Environment"
+	selectedEnvironment int
+	// History selection fields
+	historyEntries  []*RequestResponsePair // All entries, newest first
+	historyFiltered []*RequestResponsePair // Entries matching the filter input
+	selectedHistory int
+	// Cookie manager fields
+	cookies         []*StoredCookie
+	selectedCookie  int
 }
 
+// customMethod is the method list entry for typing any other method
+const customMethod = "CUSTOM"
+
 func NewInputDialog() *InputDialog {
 	// Create text input for general use
 	ti := textinput.New()
@@ -73,6 +90,12 @@ func NewInputDialog() *InputDialog {
 	tagsTi.Placeholder = "tag1, tag2, tag3"
 	tagsTi.Width = 50
 	
+	// Create custom method input for method/URL dialog
+	methodTi := textinput.New()
+	methodTi.Placeholder = "PROPFIND"
+	methodTi.Width = 20
+	methodTi.Focus()
+	
 	// Create collection input for OpenAPI import
 	collectionTi := textinput.New()
 	collectionTi.Placeholder = "my-api-collection"
@@ -94,8 +117,9 @@ func NewInputDialog() *InputDialog {
 		urlInput:        urlTi,
 		tagsInput:       tagsTi,
 		collectionInput: collectionTi,
+		methodInput:     methodTi,
 		filePicker:      fp,
-		methods:         []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS", "QUERY"},
+		methods:         []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS", "TRACE", "CONNECT", "QUERY", "WS", customMethod},
 		selectedMethod:  0,
 		currentField:    0,
 		useFilePicker:   true, // Default to file picker for OpenAPI
@@ -117,16 +141,20 @@ func (id *InputDialog) Show(spec InputSpec) {
 	id.urlInput.SetValue("")
 	id.tagsInput.SetValue("")
 	id.collectionInput.SetValue("")
+	id.methodInput.SetValue("")
 	
 	// Pre-fill values if this is an edit operation
 	if spec.IsEdit && spec.PreFill != nil {
 		if spec.Type == MethodURLInput {
 			// Pre-fill method, name, and URL for request editing
 			if method, ok := spec.PreFill["method"].(string); ok {
-				// Find the method index
+				// Find the method index, typing methods not in the list
+				id.selectedMethod = len(id.methods) - 1
+				id.methodInput.SetValue(method)
 				for i, m := range id.methods {
 					if m == method {
 						id.selectedMethod = i
+						id.methodInput.SetValue("")
 						break
 					}
 				}
@@ -166,6 +194,50 @@ func (id *InputDialog) Show(spec InputSpec) {
 		// Re-initialize file picker to current directory
 		homeDir, _ := os.UserHomeDir()
 		id.filePicker.CurrentDirectory = homeDir
+	} else if spec.Type == EnvironmentSelectionInput {
+		// Environment selection - no text input needed
+		id.textInput.Blur()
+		id.nameInput.Blur()
+		id.urlInput.Blur()
+		id.tagsInput.Blur()
+		id.collectionInput.Blur()
+		id.environments = []string{"No Environment"}
+		id.selectedEnvironment = 0
+		if names, ok := spec.PreFill["environments"].([]string); ok {
+			id.environments = append(id.environments, names...)
+		}
+		// Highlight the currently active environment
+		if active, ok := spec.PreFill["active"].(string); ok && active != "" {
+			for i, name := range id.environments {
+				if i > 0 && name == active {
+					id.selectedEnvironment = i
+					break
+				}
+			}
+		}
+	} else if spec.Type == HistorySelectionInput {
+		// History selection - the text input filters the list
+		id.textInput.Focus()
+		id.nameInput.Blur()
+		id.urlInput.Blur()
+		id.tagsInput.Blur()
+		id.collectionInput.Blur()
+		id.textInput.Placeholder = "Filter by name, method, URL, 2xx, 4xx, error..."
+		id.historyEntries = nil
+		if entries, ok := spec.PreFill["entries"].([]*RequestResponsePair); ok {
+			id.historyEntries = entries
+		}
+		id.historyFiltered = id.historyEntries
+		id.selectedHistory = 0
+	} else if spec.Type == CookieManagerInput {
+		// Cookie manager - letter keys are actions, no text input needed
+		id.textInput.Blur()
+		id.nameInput.Blur()
+		id.urlInput.Blur()
+		id.tagsInput.Blur()
+		id.collectionInput.Blur()
+		cookies, _ := spec.PreFill["cookies"].([]*StoredCookie)
+		id.SetCookies(cookies)
 	} else if spec.Type == ThemeSelectionInput {
 		// Theme selection - no text input needed
 		id.textInput.Blur()
@@ -211,6 +283,13 @@ func (id *InputDialog) Hide() {
 	id.selectedFile = ""
 	id.useFilePicker = true
 	id.selectedTheme = 0
+	id.environments = nil
+	id.selectedEnvironment = 0
+	id.historyEntries = nil
+	id.historyFiltered = nil
+	id.selectedHistory = 0
+	id.cookies = nil
+	id.selectedCookie = 0
 	id.textInput.Blur()
 	id.nameInput.Blur()
 	id.urlInput.Blur()
@@ -244,8 +323,13 @@ func (id *InputDialog) GetResult() (string, string, map[string]interface{}, bool
 			}
 		}
 		
+		method := id.methods[id.selectedMethod]
+		if method == customMethod {
+			method = strings.ToUpper(strings.TrimSpace(id.methodInput.Value()))
+		}
+		
 		result := map[string]interface{}{
-			"method": id.methods[id.selectedMethod],
+			"method": method,
 			"name":   id.nameInput.Value(),
 			"url":    id.urlInput.Value(),
 			"tags":   tags,
@@ -280,6 +364,39 @@ func (id *InputDialog) GetResult() (string, string, map[string]interface{}, bool
 			}
 			return "", id.spec.Action, result, id.confirmed
 		}
+	} else if id.spec.Type == EnvironmentSelectionInput {
+		// For environment selection, an empty name means no environment
+		envName := ""
+		if id.selectedEnvironment > 0 && id.selectedEnvironment < len(id.environments) {
+			envName = id.environments[id.selectedEnvironment]
+		}
+		result := map[string]interface{}{
+			"environment": envName,
+		}
+		for k, v := range id.spec.ActionData {
+			result[k] = v
+		}
+		return "", id.spec.Action, result, id.confirmed
+	} else if id.spec.Type == HistorySelectionInput {
+		// For history selection, return the ID of the highlighted entry
+		entryID := ""
+		if id.selectedHistory < len(id.historyFiltered) {
+			entryID = id.historyFiltered[id.selectedHistory].ID
+		}
+		result := map[string]interface{}{
+			"id": entryID,
+		}
+		return "", id.spec.Action, result, id.confirmed
+	} else if id.spec.Type == CookieManagerInput {
+		// For the cookie manager, return the highlighted cookie and its scope
+		result := map[string]interface{}{}
+		for k, v := range id.spec.ActionData {
+			result[k] = v
+		}
+		if cookie := id.SelectedCookie(); cookie != nil {
+			result["cookie"] = cookie
+		}
+		return "", id.spec.Action, result, id.confirmed
 	}
 	return id.textInput.Value(), id.spec.Action, id.spec.ActionData, id.confirmed
 }
@@ -294,7 +411,7 @@ func (id *InputDialog) SwitchField() {
 		
 		switch id.currentField {
 		case 0:
-			// Focus on method selection (no text input)
+			// Focus on method selection, typed into methodInput for custom methods
 		case 1:
 			// Focus on name input
 			id.nameInput.Focus()
@@ -351,9 +468,55 @@ func (id *InputDialog) MoveMethodSelection(direction int) {
 		} else if id.selectedTheme >= len(id.themes) {
 			id.selectedTheme = 0
 		}
+	} else if id.spec.Type == EnvironmentSelectionInput {
+		id.selectedEnvironment += direction
+		if id.selectedEnvironment < 0 {
+			id.selectedEnvironment = len(id.environments) - 1
+		} else if id.selectedEnvironment >= len(id.environments) {
+			id.selectedEnvironment = 0
+		}
+	} else if id.spec.Type == HistorySelectionInput && len(id.historyFiltered) > 0 {
+		id.selectedHistory += direction
+		if id.selectedHistory < 0 {
+			id.selectedHistory = len(id.historyFiltered) - 1
+		} else if id.selectedHistory >= len(id.historyFiltered) {
+			id.selectedHistory = 0
+		}
+	} else if id.spec.Type == CookieManagerInput && len(id.cookies) > 0 {
+		id.selectedCookie += direction
+		if id.selectedCookie < 0 {
+			id.selectedCookie = len(id.cookies) - 1
+		} else if id.selectedCookie >= len(id.cookies) {
+			id.selectedCookie = 0
+		}
+	}
+}
+
+// IsCookieManager reports whether the dialog is the cookie manager
+func (id *InputDialog) IsCookieManager() bool {
+	return id.visible && id.spec.Type == CookieManagerInput
+}
+
+// SetCookies replaces the cookies listed by the cookie manager, keeping the
+// selection in range
+func (id *InputDialog) SetCookies(cookies []*StoredCookie) {
+	id.cookies = cookies
+	if id.selectedCookie >= len(id.cookies) {
+		id.selectedCookie = len(id.cookies) - 1
+	}
+	if id.selectedCookie < 0 {
+		id.selectedCookie = 0
 	}
 }
 
+// SelectedCookie returns the highlighted cookie, or nil when there are none
+func (id *InputDialog) SelectedCookie() *StoredCookie {
+	if id.selectedCookie < len(id.cookies) {
+		return id.cookies[id.selectedCookie]
+	}
+	return nil
+}
+
 func (id *InputDialog) SetURLInput(input string) {
 	if id.spec.Type == MethodURLInput {
 		id.urlInput.SetValue(input)
@@ -397,6 +560,11 @@ func (id *InputDialog) HandleFilePickerUpdate(msg tea.Msg) tea.Cmd {
 func (id *InputDialog) UpdateTextInputs(msg interface{}) {
 	if id.spec.Type == MethodURLInput {
 		switch id.currentField {
+		case 0:
+			// Typing only names a method once Custom is selected
+			if id.methods[id.selectedMethod] == customMethod {
+				id.methodInput, _ = id.methodInput.Update(msg)
+			}
 		case 1:
 			// Update name input when it's focused
 			id.nameInput, _ = id.nameInput.Update(msg)
@@ -419,6 +587,13 @@ func (id *InputDialog) UpdateTextInputs(msg interface{}) {
 			}
 		}
 		// Note: File picker is handled separately in HandleFilePickerUpdate
+	} else if id.spec.Type == HistorySelectionInput {
+		// Re-filter the history list as the filter text changes
+		id.textInput, _ = id.textInput.Update(msg)
+		id.historyFiltered = FilterHistory(id.historyEntries, id.textInput.Value())
+		if id.selectedHistory >= len(id.historyFiltered) {
+			id.selectedHistory = 0
+		}
 	} else {
 		// Update general text input for other types
 		id.textInput, _ = id.textInput.Update(msg)
@@ -525,7 +700,12 @@ func (id *InputDialog) Render(width, height int) string {
 			}
 		}
 		content.WriteString(methodDisplay)
-		content.WriteString("\n\n")
+		content.WriteString("\n")
+		if id.methods[id.selectedMethod] == customMethod {
+			content.WriteString(id.methodInput.View())
+			content.WriteString("\n")
+		}
+		content.WriteString("\n")
 
 		// Name input
 		content.WriteString(lipgloss.NewStyle().
@@ -640,6 +820,140 @@ func (id *InputDialog) Render(width, height int) string {
 		content.WriteString(lipgloss.NewStyle().
 			Foreground(lipgloss.Color("241")).
 			Render("↑↓: Navigate • Enter: Apply Theme • Esc: Cancel"))
+
+	case EnvironmentSelectionInput:
+		// Environment selection list
+		content.WriteString(lipgloss.NewStyle().
+			Foreground(lipgloss.Color("250")).
+			Render("Select Environment:"))
+		content.WriteString("\n\n")
+
+		for i, env := range id.environments {
+			if i == id.selectedEnvironment {
+				content.WriteString(lipgloss.NewStyle().
+					Background(lipgloss.Color("62")).
+					Foreground(lipgloss.Color("230")).
+					Padding(0, 1).
+					Render("▶ " + env))
+			} else {
+				content.WriteString("  " + env)
+			}
+			if i < len(id.environments)-1 {
+				content.WriteString("\n")
+			}
+		}
+
+		if len(id.environments) == 1 {
+			content.WriteString("\n\n")
+			content.WriteString(lipgloss.NewStyle().
+				Foreground(lipgloss.Color("241")).
+				Render("No environments found in this collection's environments/ folder"))
+		}
+
+		content.WriteString("\n\n")
+		content.WriteString(lipgloss.NewStyle().
+			Foreground(lipgloss.Color("241")).
+			Render("↑↓: Navigate • Enter: Select Environment • Esc: Cancel"))
+
+	case HistorySelectionInput:
+		// Filter input
+		content.WriteString(id.textInput.View())
+		content.WriteString("\n\n")
+
+		if len(id.historyFiltered) == 0 {
+			content.WriteString(lipgloss.NewStyle().
+				Foreground(lipgloss.Color("241")).
+				Render("No matching history entries"))
+		} else {
+			// Show a window of entries around the selection
+			visibleCount := height - 30
+			if visibleCount < 5 {
+				visibleCount = 5
+			}
+			start := 0
+			if id.selectedHistory >= visibleCount {
+				start = id.selectedHistory - visibleCount + 1
+			}
+			end := start + visibleCount
+			if end > len(id.historyFiltered) {
+				end = len(id.historyFiltered)
+			}
+
+			for i := start; i < end; i++ {
+				line := formatHistoryEntry(id.historyFiltered[i])
+				if i == id.selectedHistory {
+					content.WriteString(lipgloss.NewStyle().
+						Background(lipgloss.Color("62")).
+						Foreground(lipgloss.Color("230")).
+						Padding(0, 1).
+						Render("▶ " + line))
+				} else {
+					content.WriteString("  " + line)
+				}
+				if i < end-1 {
+					content.WriteString("\n")
+				}
+			}
+			content.WriteString("\n\n")
+			content.WriteString(lipgloss.NewStyle().
+				Foreground(lipgloss.Color("241")).
+				Render(fmt.Sprintf("%d of %d entries", len(id.historyFiltered), len(id.historyEntries))))
+		}
+
+		actionText := "Open Response"
+		if id.spec.Action == "resend_history" {
+			actionText = "Re-send Request"
+		}
+		content.WriteString("\n\n")
+		content.WriteString(lipgloss.NewStyle().
+			Foreground(lipgloss.Color("241")).
+			Render(fmt.Sprintf("Type to filter • ↑↓: Navigate • Enter: %s • Esc: Cancel", actionText)))
+
+	case CookieManagerInput:
+		if len(id.cookies) == 0 {
+			content.WriteString(lipgloss.NewStyle().
+				Foreground(lipgloss.Color("241")).
+				Render("No cookies stored for this collection and environment"))
+		} else {
+			// Show a window of cookies around the selection
+			visibleCount := height - 30
+			if visibleCount < 5 {
+				visibleCount = 5
+			}
+			start := 0
+			if id.selectedCookie >= visibleCount {
+				start = id.selectedCookie - visibleCount + 1
+			}
+			end := start + visibleCount
+			if end > len(id.cookies) {
+				end = len(id.cookies)
+			}
+
+			for i := start; i < end; i++ {
+				line := formatCookie(id.cookies[i])
+				if i == id.selectedCookie {
+					content.WriteString(lipgloss.NewStyle().
+						Background(lipgloss.Color("62")).
+						Foreground(lipgloss.Color("230")).
+						Padding(0, 1).
+						Render("▶ " + line))
+				} else {
+					content.WriteString("  " + line)
+				}
+				if i < end-1 {
+					content.WriteString("\n")
+				}
+			}
+			content.WriteString("\n\n")
+			content.WriteString(lipgloss.NewStyle().
+				Foreground(lipgloss.Color("241")).
+				Render(fmt.Sprintf("%d cookies", len(id.cookies))))
+		}
+
+		content.WriteString("\n\n")
+		content.WriteString(lipgloss.NewStyle().
+			Foreground(lipgloss.Color("241")).
+			Render("↑↓: Navigate • Enter: Edit value • d: Delete • x: Clear all • Esc: Close"))
 	}
 
 	return lipgloss.Place(width, height, lipgloss.Center, lipgloss.Center,
@@ -49,6 +49,19 @@ func (h *InputHandler) HandleKeyboardInput(m *model, msg tea.KeyMsg) (tea.Model,
 		return h.handleTabNavigation(m, msg)
 	case tea.KeyCtrlC:
 		return m, tea.Quit
+	case tea.KeyCtrlX:
+		// Stop the in-flight stream, cancel the in-flight request or close the WebSocket
+		if m.stopStream() || m.cancelLoading() || m.closeWebSocket() {
+			return m, nil
+		}
+	case tea.KeyEsc:
+		// Esc cancels a running request unless it is closing a filter or editor
+		if m.isLoading && !m.filterMode() && !(m.activePanel == requestPanel && h.requestHandler.IsInEditMode(m)) {
+			if !m.stopStream() {
+				m.cancelLoading()
+			}
+			return m, nil
+		}
 	case tea.KeyCtrlP:
 		// Open command palette
 		m.commandPalette.Show()
@@ -109,8 +122,11 @@ func (h *InputHandler) handleResponseInput(m *model, msg tea.KeyMsg) (tea.Model,
 // GetFooterText returns footer text for the active panel
 func (h *InputHandler) GetFooterText(m *model) string {
 	// Handle special states first
+	if m.streaming {
+		return "Streaming... • Esc/Ctrl+X: Stop • s: Save stream (response panel) • Tab: Switch panels • Ctrl+P: Command palette • Ctrl+C: Quit"
+	}
 	if m.isLoading {
-		return "Loading... • Tab: Switch panels • Ctrl+P: Command palette • Ctrl+C: Quit"
+		return "Loading... • Esc/Ctrl+X: Cancel • Tab: Switch panels • Ctrl+P: Command palette • Ctrl+C: Quit"
 	}
 
 	// Command palette takes priority
@@ -399,6 +415,10 @@ func (h *InputHandler) handleDialogInput(m *model, msg tea.KeyMsg) (tea.Model, t
 		m.inputDialog.MoveMethodSelection(1)
 		return m, nil
 	default:
+		// The cookie manager uses letter keys for its actions
+		if m.inputDialog.IsCookieManager() {
+			return h.handleCookieManagerInput(m, msg)
+		}
 		// Handle file picker updates for OpenAPI import
 		if cmd := m.inputDialog.HandleFilePickerUpdate(msg); cmd != nil {
 			return m, cmd
@@ -409,6 +429,26 @@ func (h *InputHandler) handleDialogInput(m *model, msg tea.KeyMsg) (tea.Model, t
 	}
 }
 
+// handleCookieManagerInput deletes the highlighted cookie or clears the jar
+func (h *InputHandler) handleCookieManagerInput(m *model, msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	_, _, actionData, _ := m.inputDialog.GetResult()
+	scope, _ := actionData["scope"].(string)
+
+	switch msg.String() {
+	case "d":
+		if cookie := m.inputDialog.SelectedCookie(); cookie != nil {
+			m.httpClient.Cookies.Delete(scope, cookie)
+		}
+	case "x":
+		m.httpClient.Cookies.Clear(scope)
+	default:
+		return m, nil
+	}
+
+	m.inputDialog.SetCookies(m.httpClient.Cookies.Cookies(scope))
+	return m, nil
+}
+
 // Placeholder methods - these need full implementations from the original file
 func (h *InputHandler) handleTextInputEscape(m *model, msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	// TODO: Implement proper escape handling for different text input modes
@@ -1,14 +1,15 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
-	"sort"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
@@ -28,10 +29,32 @@ const (
 )
 
 type httpResponseMsg struct {
-	response *response.HTTPResponse
-	err      error
+	requestID    int // Matches model.loadingID of the request that produced it
+	response     *response.HTTPResponse
+	testResults  []TestResult
+	scriptLogs   []ScriptLog
+	sent         *HTTPRequestModel    // Request as sent, with its credentials
+	historyEntry *RequestResponsePair // Recorded in history when set
+	err          error
 }
 
+// streamUpdateMsg delivers part of a streaming response as it arrives
+type streamUpdateMsg struct {
+	requestID int
+	update    streamUpdate
+	updates   <-chan streamUpdate
+}
+
+// loadingTickMsg advances the spinner while a request is in flight
+type loadingTickMsg struct {
+	requestID int
+}
+
+// loadingTickInterval is how often the response title refreshes while loading
+const loadingTickInterval = 100 * time.Millisecond
+
+var loadingSpinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
 type importCompleteMsg struct {
 	success bool
 	err     error
@@ -68,11 +91,36 @@ type model struct {
 	response         string
 	statusCode       int
 	httpClient       *HTTPClient
+	scriptRunner     *ScriptRunner
 	lastResponse     *response.HTTPResponse
 	isLoading        bool
+	loadingID        int                // Incremented for every request so stale results can be dropped
+	loadingStarted   time.Time
+	cancelRequest    context.CancelFunc // Cancels the in-flight request
+	streaming        bool               // The in-flight request is a stream whose headers have arrived
+	streamBody       []byte                 // In-flight stream, until it continues in streamFile
+	streamFile       string                 // Temp file the in-flight stream moved to past the size limit
+	streamSize       int64
+	streamEvents     []response.StreamEvent // The last maxStreamEvents events
+	streamEventCount int
+	streamIsEvents   bool
+	streamView       string // Rendered tail of the in-flight stream
+	streamViewTrimmed bool
+	streamDirty      bool   // streamView changed since the viewport was refreshed
+	streamPrevious   *response.HTTPResponse // Stream that the in-flight request resumes
+	download         *downloadProgress      // How much of the in-flight body has arrived
+	lastSent         *HTTPRequestModel      // Request that produced lastResponse, for resuming streams
+	lastSentEnv      string                 // Environment lastSent was sent with
+	wsConsole        *webSocketConsole      // Shown instead of the response for WebSocket requests
+	preview          *request.RequestPreview // Preview tab, built by refreshPreview
+	previewSource    previewSource
 	collectionsViewport viewport.Model
 	responseViewport viewport.Model
 	headersViewport  viewport.Model
+	testsViewport    viewport.Model
+	timingViewport   viewport.Model
+	connectionViewport viewport.Model
+	testResults      []TestResult
 	responseCursor   response.ResponseSection
 	responseActiveTab int
 	originalResponse string // Store original response for jq filtering
@@ -80,6 +128,11 @@ type model struct {
 	inputDialog      *InputDialog
 	filterManager    *collections.FilterManager
 	inputHandler     *InputHandler
+	environments     map[string][]*Environment // Environments keyed by collection directory
+	activeEnvironments map[string]string       // Active environment name keyed by collection directory
+	history          *HistoryStore
+	historyLabel     string // Set when the response panel shows a past response
+	graphqlSchemas   map[string]*request.GraphQLSchema // Introspected schemas keyed by request URL
 }
 
 // renderFilterCursor renders a solid colored cursor for filter input
@@ -126,6 +179,9 @@ func getCollectionsDir() (string, error) {
 }
 
 func initialModel() *model {
+	// History falls back to memory only if ~/.kalo is unavailable
+	historyPath, _ := getHistoryPath()
+
 	collectionsVP := viewport.New(30, 20)
 	collectionsVP.SetContent("Loading collections...")
 
@@ -137,6 +193,12 @@ func initialModel() *model {
 	headersVP := viewport.New(30, 5)
 	headersVP.SetContent("No headers available")
 
+	testsVP := viewport.New(30, 5)
+	testsVP.SetContent("No tests run")
+
+	timingVP := viewport.New(30, 5)
+	connectionVP := viewport.New(30, 5)
+
 	m := model{
 		activePanel:         collectionsPanel,
 		selectedReq:         0,
@@ -144,17 +206,30 @@ func initialModel() *model {
 		responseCursor:      response.ResponseBodySection,
 		statusCode:          200,
 		httpClient:          NewHTTPClient(),
+		scriptRunner:        NewScriptRunner(),
 		collectionsViewport: collectionsVP,
 		responseViewport:    responseVP,
 		headersViewport:     headersVP,
+		testsViewport:       testsVP,
+		timingViewport:      timingVP,
+		connectionViewport:  connectionVP,
 		commandPalette:      NewCommandPalette(),
 		inputDialog:         NewInputDialog(),
 		filterManager:       collections.NewFilterManager(),
 		inputHandler:        NewInputHandler(),
+		environments:        make(map[string][]*Environment),
+		activeEnvironments:  make(map[string]string),
+		history:             NewHistoryStore(historyPath),
+		graphqlSchemas:      make(map[string]*request.GraphQLSchema),
 		response: `{
   "message": "Select a request to see response"
 }`,
 	}
+	// Cached OAuth2 tokens fall back to memory only if ~/.kalo is unavailable
+	tokensPath, _ := getOAuth2TokensPath()
+	m.httpClient.OAuth2Tokens = NewOAuth2TokenStore(tokensPath)
+	cookiesPath, _ := getCookiesPath()
+	m.httpClient.Cookies = NewCookieStore(cookiesPath)
 	m.loadBruFiles()
 	return &m
 }
@@ -170,6 +245,7 @@ func (m *model) loadBruFiles() {
 	data := LoadBruFiles(collectionsDir, m.width)
 	m.collections = data.Collections
 	m.bruRequests = data.BruRequests
+	m.environments = data.Environments
 
 	if len(m.bruRequests) > 0 {
 		m.currentReq = m.bruRequests[0]
@@ -341,6 +417,8 @@ func (m *model) Init() tea.Cmd {
 }
 
 func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	defer m.refreshPreview()
+
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
@@ -349,41 +427,75 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		return m.inputHandler.HandleKeyboardInput(m, msg)
 		
+	case loadingTickMsg:
+		if m.isLoading && msg.requestID == m.loadingID {
+			m.refreshStreamView()
+			return m, m.loadingTick()
+		}
+		return m, nil
+	case webSocketOpenedMsg:
+		if !m.finishLoading(msg.requestID) {
+			if msg.session != nil {
+				msg.session.Close()
+			}
+			return m, nil
+		}
+		m.historyLabel = ""
+		if msg.err != nil {
+			removeBodyFile(m.lastResponse)
+			m.lastResponse = nil
+			m.displayError(fmt.Sprintf("Error: %v", msg.err))
+			return m, nil
+		}
+		return m, m.openWebSocketConsole(msg.session)
+	case webSocketEventMsg:
+		return m, m.handleWebSocketEvent(msg)
+	case webSocketSendFailedMsg:
+		if m.wsConsole != nil && m.wsConsole.session == msg.session {
+			m.wsConsole.note(msg.err.Error())
+		}
+		return m, nil
+	case streamUpdateMsg:
+		if !m.isLoading || msg.requestID != m.loadingID {
+			return m, nil
+		}
+		m.applyStreamUpdate(msg.update)
+		return m, waitForStreamUpdate(msg.requestID, msg.updates)
 	case httpResponseMsg:
-		m.isLoading = false
+		if !m.finishLoading(msg.requestID) {
+			removeBodyFile(msg.response)
+			return m, nil
+		}
+		// Scripts may have set runtime variables the preview shows
+		m.preview = nil
+		if msg.historyEntry != nil {
+			m.history.Append(msg.historyEntry)
+			m.lastSent = msg.sent
+			m.lastSentEnv = msg.historyEntry.Environment
+		}
+		m.historyLabel = ""
 		if msg.err != nil {
-			m.response = fmt.Sprintf("Error: %v", msg.err)
-			m.statusCode = 0
-			m.responseViewport.SetContent(m.response)
-			m.headersViewport.SetContent("No headers available")
+			m.displayError(fmt.Sprintf("Error: %v", msg.err))
 		} else {
-			m.lastResponse = msg.response
-			m.originalResponse = msg.response.Body
-			m.response = m.httpClient.FormatResponseForDisplay(msg.response)
-			m.statusCode = msg.response.StatusCode
-			m.responseViewport.SetContent(m.response)
-			m.responseViewport.GotoTop()
-			m.setAppliedJQFilter("") // Clear applied jq filter for new response
-
-			// Format headers for display
-			var headersContent strings.Builder
-			if len(msg.response.Headers) > 0 {
-				// Sort headers alphabetically
-				var keys []string
-				for key := range msg.response.Headers {
-					keys = append(keys, key)
-				}
-				sort.Strings(keys)
-				
-				for _, key := range keys {
-					headersContent.WriteString(fmt.Sprintf("%s: %s\n", key, msg.response.Headers[key]))
-				}
-			} else {
-				headersContent.WriteString("No headers received")
+			m.displayResponse(m.mergeResumedStream(msg.response), msg.testResults, msg.scriptLogs)
+		}
+		return m, nil
+	case graphqlSchemaMsg:
+		if !m.finishLoading(msg.requestID) {
+			return m, nil
+		}
+		m.historyLabel = ""
+		if msg.err != nil {
+			m.response = fmt.Sprintf("GraphQL introspection error: %v", msg.err)
+			m.statusCode = 0
+			if msg.response != nil {
+				m.statusCode = msg.response.StatusCode
 			}
-			m.headersViewport.SetContent(headersContent.String())
-			m.headersViewport.GotoTop()
+			m.responseViewport.SetContent(m.response)
+			return m, nil
 		}
+		m.graphqlSchemas[msg.url] = msg.schema
+		m.displayResponse(msg.response, nil, nil)
 		return m, nil
 	case importCompleteMsg:
 		if msg.success {
@@ -427,6 +539,36 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// displayResponse shows a response, its test results and script output in the response panel
+func (m *model) displayResponse(resp *response.HTTPResponse, testResults []TestResult, scriptLogs []ScriptLog) {
+	if m.lastResponse != resp {
+		removeBodyFile(m.lastResponse)
+	}
+	m.lastResponse = resp
+	m.originalResponse = resp.Body
+	m.response = m.httpClient.FormatResponseForDisplay(resp)
+	m.statusCode = resp.StatusCode
+	m.responseViewport.SetContent(m.response)
+	m.responseViewport.GotoTop()
+	m.setAppliedJQFilter("") // Clear applied jq filter for new response
+
+	// Format headers for display, after the redirect chain that led to them
+	var headersContent strings.Builder
+	headersContent.WriteString(formatRedirectChain(resp))
+	if len(resp.Headers) > 0 {
+		headersContent.WriteString(response.FormatHeaders(resp.Headers))
+	} else {
+		headersContent.WriteString("No headers received")
+	}
+	m.headersViewport.SetContent(headersContent.String())
+	m.headersViewport.GotoTop()
+
+	m.testResults = testResults
+	m.testsViewport.SetContent(formatTestResults(testResults, scriptLogs))
+	m.testsViewport.GotoTop()
+	m.connectionViewport.GotoTop()
+}
+
 func (m *model) updateCurrentRequest() {
 	if m.selectedReq <= 0 || len(m.collections) == 0 {
 		return
@@ -448,13 +590,509 @@ func (m *model) executeRequest() tea.Cmd {
 	if m.currentReq == nil {
 		return nil
 	}
+	if m.currentReq.IsWebSocket() {
+		return m.connectWebSocket()
+	}
+	m.closeWebSocket()
+	m.wsConsole = nil
+
+	ctx := m.startLoading()
+	requestID := m.loadingID
+	req := m.currentReq
+	env := m.activeEnvironment()
+	collection := filepath.Base(m.currentRequestCollectionPath())
+	filePath := getCurrentRequestFilePath(m)
+	ctx, waitForStream, streamDone := m.observeStream(ctx)
+
+	cmd := func() tea.Msg {
+		defer streamDone()
+		execution := executeBruRequest(ctx, m.httpClient, m.scriptRunner, req, env)
+		if execution.Sent == nil {
+			return httpResponseMsg{requestID: requestID, response: execution.Response, scriptLogs: execution.ScriptLogs}
+		}
 
-	m.isLoading = true
+		envName := ""
+		if env != nil {
+			envName = env.Name
+		}
+		execution.Sent.SourceFile = filePath
+		entry := newHistoryEntry(execution.Sent, execution.Response, execution.TestResults, envName, collection)
+		entry.ScriptLogs = execution.ScriptLogs
+		return httpResponseMsg{requestID: requestID, response: execution.Response, testResults: execution.TestResults, scriptLogs: execution.ScriptLogs, sent: execution.Sent, historyEntry: entry}
+	}
+
+	return tea.Batch(cmd, m.loadingTick(), waitForStream)
+}
+
+// resendHistoryEntry sends a past request again as it was recorded, with the
+// credentials history redacted resolved again
+func (m *model) resendHistoryEntry(entry *RequestResponsePair) tea.Cmd {
+	if entry.Request == nil {
+		return nil
+	}
+
+	ctx := m.startLoading()
+	requestID := m.loadingID
+	recorded := entry.Request
+	ctx, waitForStream, streamDone := m.observeStream(ctx)
+
+	cmd := func() tea.Msg {
+		defer streamDone()
+		sent, err := restoreCredentials(ctx, m.httpClient, m.scriptRunner, recorded, entry.Environment)
+		if err != nil {
+			return httpResponseMsg{requestID: requestID, response: &response.HTTPResponse{Error: fmt.Sprintf("Cannot re-send: %v", err)}}
+		}
+		response := m.httpClient.SendRequest(ctx, sent)
+		historyEntry := newHistoryEntry(sent, response, nil, entry.Environment, entry.Collection)
+		return httpResponseMsg{requestID: requestID, response: response, sent: sent, historyEntry: historyEntry}
+	}
+
+	return tea.Batch(cmd, m.loadingTick(), waitForStream)
+}
+
+// resumeStream reconnects to the stream of the last response, sending the
+// last event id so the server can continue where it stopped. New data is
+// appended to what was already captured.
+func (m *model) resumeStream() tea.Cmd {
+	if m.isLoading || m.lastResponse == nil || !m.lastResponse.Streamed || m.lastSent == nil {
+		return nil
+	}
 
+	previous := m.lastResponse
+	recorded := m.lastSent
+	envName := m.lastSentEnv
+	lastID := lastEventID(previous.Events)
+
+	ctx := m.startLoading()
+	requestID := m.loadingID
+	m.streamPrevious = previous
+	m.streamSize = previous.BodySize
+	m.streamEvents = append([]response.StreamEvent{}, previous.Events...)
+	m.streamEventCount = len(previous.Events) + previous.DroppedEvents
+	m.streamIsEvents = len(previous.Events) > 0
+	m.appendStreamView(m.httpClient.FormatResponseForDisplay(previous) + "\n\n")
+	collection := filepath.Base(m.currentRequestCollectionPath())
+	ctx, waitForStream, streamDone := m.observeStream(ctx)
+
+	cmd := func() tea.Msg {
+		defer streamDone()
+		// A stream shown from history was recorded without its credentials
+		sent, err := restoreCredentials(ctx, m.httpClient, m.scriptRunner, recorded, envName)
+		if err != nil {
+			return httpResponseMsg{requestID: requestID, response: &response.HTTPResponse{Error: fmt.Sprintf("Cannot resume: %v", err)}}
+		}
+		if lastID != "" {
+			sent.SetHeader("Last-Event-ID", lastID)
+		}
+		response := m.httpClient.SendRequest(ctx, sent)
+		historyEntry := newHistoryEntry(sent, response, nil, envName, collection)
+		return httpResponseMsg{requestID: requestID, response: response, sent: sent, historyEntry: historyEntry}
+	}
+
+	return tea.Batch(cmd, m.loadingTick(), waitForStream)
+}
+
+// observeStream makes streaming responses of the current request show up as
+// they arrive. The returned done func must be called once the request ends.
+func (m *model) observeStream(ctx context.Context) (context.Context, tea.Cmd, func()) {
+	requestID := m.loadingID
+	updates := make(chan streamUpdate)
+	observed := withStreamObserver(ctx, func(update streamUpdate) {
+		select {
+		case updates <- update:
+		case <-ctx.Done():
+		}
+	})
+	return observed, waitForStreamUpdate(requestID, updates), func() { close(updates) }
+}
+
+// waitForStreamUpdate waits for the next part of a streaming response
+func waitForStreamUpdate(requestID int, updates <-chan streamUpdate) tea.Cmd {
 	return func() tea.Msg {
-		response, err := m.httpClient.ExecuteRequest(m.currentReq)
-		return httpResponseMsg{response: response, err: err}
+		update, ok := <-updates
+		if !ok {
+			return nil
+		}
+		return streamUpdateMsg{requestID: requestID, update: update, updates: updates}
+	}
+}
+
+// streamViewLimit is how much of an in-flight stream the response viewport
+// shows. Earlier data is only kept in streamBody or streamFile.
+const streamViewLimit = 256 * 1024
+
+// applyStreamUpdate adds newly arrived data to the in-flight stream. Only the
+// new events are rendered, and the viewport picks them up on the next tick.
+func (m *model) applyStreamUpdate(update streamUpdate) {
+	if update.progress != nil {
+		m.download = update.progress
+		return
+	}
+	if update.head != nil {
+		m.streaming = true
+		m.statusCode = update.head.StatusCode
+		m.streamIsEvents = isEventStreamType(update.head.Headers.Get("Content-Type"))
+		m.headersViewport.SetContent(response.FormatHeaders(update.head.Headers))
+		m.streamDirty = true
+	}
+	if update.bodyFile != "" {
+		// The file starts with what was kept in memory so far
+		m.streamFile = update.bodyFile
+		m.streamBody = nil
+	}
+	if m.streamFile == "" {
+		m.streamBody = append(m.streamBody, update.chunk...)
+	}
+	m.streamSize += int64(len(update.chunk))
+
+	if !m.streamIsEvents {
+		m.appendStreamView(update.chunk)
+		return
+	}
+	m.streamEvents = append(m.streamEvents, update.events...)
+	if len(m.streamEvents) >= 2*maxStreamEvents {
+		m.streamEvents, _ = trimStreamEvents(m.streamEvents, maxStreamEvents)
+	}
+	for _, event := range update.events {
+		m.streamEventCount++
+		m.appendStreamView(formatStreamEvent(m.streamEventCount, event) + "\n\n")
+	}
+}
+
+// appendStreamView adds text to the rendered stream, dropping whole lines
+// from its start once it grows past streamViewLimit
+func (m *model) appendStreamView(text string) {
+	if text == "" {
+		return
+	}
+	m.streamView += text
+	m.streamDirty = true
+	if len(m.streamView) <= streamViewLimit {
+		return
+	}
+
+	cut := len(m.streamView) - streamViewLimit
+	if newline := strings.IndexByte(m.streamView[cut:], '\n'); newline >= 0 {
+		cut += newline + 1
+	} else {
+		for cut < len(m.streamView) && !utf8.RuneStart(m.streamView[cut]) {
+			cut++
+		}
+	}
+	m.streamView = m.streamView[cut:]
+	m.streamViewTrimmed = true
+}
+
+// refreshStreamView shows what has arrived of the in-flight stream, following
+// the end of the stream unless the user scrolled away from it
+func (m *model) refreshStreamView() {
+	if !m.streamDirty {
+		return
+	}
+	m.streamDirty = false
+
+	m.response = strings.TrimRight(m.streamView, "\n")
+	if m.response == "" {
+		m.response = "Waiting for data..."
+	} else if m.streamViewTrimmed {
+		m.response = "(earlier data not shown; save the stream for all of it)\n\n" + m.response
+	}
+	following := m.responseViewport.AtBottom()
+	m.responseViewport.SetContent(m.response)
+	if following {
+		m.responseViewport.GotoBottom()
+	}
+}
+
+// saveStreamCapture writes the in-flight stream as far as it has arrived,
+// after the stream it resumes
+func (m *model) saveStreamCapture(target string) (int64, error) {
+	file, err := os.Create(target)
+	if err != nil {
+		return 0, err
+	}
+	var written int64
+	if m.streamPrevious != nil {
+		written, err = copyResponseBody(file, m.streamPrevious)
+	}
+	if err == nil {
+		var n int64
+		n, err = copyResponseBody(file, &response.HTTPResponse{RawBody: m.streamBody, BodyFile: m.streamFile})
+		written += n
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return written, err
+}
+
+// mergeResumedStream prepends the stream a resumed request continued. The
+// merged body goes to a temp file when it grows past the size limit.
+func (m *model) mergeResumedStream(resp *response.HTTPResponse) *response.HTTPResponse {
+	previous := m.streamPrevious
+	m.streamPrevious = nil
+	if previous == nil || resp == nil || !resp.Streamed {
+		return resp
+	}
+	defer removeBodyFile(resp)
+
+	merged := *resp
+	events, dropped := trimStreamEvents(append(append([]response.StreamEvent{}, previous.Events...), resp.Events...), maxStreamEvents)
+	merged.Events = events
+	merged.DroppedEvents = previous.DroppedEvents + resp.DroppedEvents + dropped
+
+	body := &spillBuffer{limit: bodySizeLimit(m.lastSent)}
+	_, err := copyResponseBody(body, previous)
+	if err == nil {
+		_, err = copyResponseBody(body, resp)
+	}
+	var data []byte
+	if err == nil {
+		data, merged.BodyFile, err = body.finish()
+	} else {
+		body.discard()
+	}
+	merged.BodySize = int64(len(data))
+	merged.BodyTruncated = false
+	if err == nil && merged.BodyFile != "" {
+		merged.BodyTruncated = true
+		if merged.BodySize, data, err = previewBodyFile(merged.BodyFile); err != nil {
+			os.Remove(merged.BodyFile)
+		}
+	}
+	if err != nil {
+		return &response.HTTPResponse{Error: fmt.Sprintf("Failed to keep the resumed stream: %v", err)}
+	}
+	merged.Body = string(data)
+	merged.RawBody = data
+	return &merged
+}
+
+// stopStream closes the in-flight stream. Unlike cancelling, what arrived so
+// far is kept and shown as the response.
+func (m *model) stopStream() bool {
+	if !m.isLoading || !m.streaming {
+		return false
+	}
+	m.cancelRequest()
+	return true
+}
+
+// streamSummary counts the events of a streamed response for the response title
+func (m *model) streamSummary() string {
+	if !m.lastResponse.Streamed {
+		return ""
+	}
+	if len(m.lastResponse.Events) > 0 {
+		return fmt.Sprintf(" • stream: %d events, %s", len(m.lastResponse.Events)+m.lastResponse.DroppedEvents, m.lastResponse.StreamEnd)
+	}
+	return fmt.Sprintf(" • stream %s", m.lastResponse.StreamEnd)
+}
+
+// startLoading marks a new request as in flight, cancelling any request still
+// running, and returns the context that cancels it
+func (m *model) startLoading() context.Context {
+	if m.cancelRequest != nil {
+		m.cancelRequest()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.loadingID++
+	m.isLoading = true
+	m.loadingStarted = time.Now()
+	m.cancelRequest = cancel
+	m.streaming = false
+	m.streamBody = nil
+	m.streamFile = ""
+	m.streamSize = 0
+	m.streamEvents = nil
+	m.streamEventCount = 0
+	m.streamIsEvents = false
+	m.streamView = ""
+	m.streamViewTrimmed = false
+	m.streamDirty = false
+	m.streamPrevious = nil
+	m.download = nil
+	return ctx
+}
+
+// loadingTick schedules the next spinner frame for the current request
+func (m *model) loadingTick() tea.Cmd {
+	requestID := m.loadingID
+	return tea.Tick(loadingTickInterval, func(time.Time) tea.Msg {
+		return loadingTickMsg{requestID: requestID}
+	})
+}
+
+// finishLoading stops loading when a result arrives. It returns false for
+// results of requests that were cancelled or replaced by a newer one.
+func (m *model) finishLoading(requestID int) bool {
+	if requestID != m.loadingID {
+		return false
+	}
+	if m.cancelRequest != nil {
+		m.cancelRequest()
+		m.cancelRequest = nil
+	}
+	m.isLoading = false
+	m.streaming = false
+	return true
+}
+
+// cancelLoading aborts the in-flight request. Its result is dropped when it arrives.
+func (m *model) cancelLoading() bool {
+	if !m.isLoading {
+		return false
+	}
+
+	m.cancelRequest()
+	m.cancelRequest = nil
+	m.loadingID++
+	m.isLoading = false
+	m.streaming = false
+	m.historyLabel = ""
+	removeBodyFile(m.lastResponse)
+	m.lastResponse = nil
+	m.displayError("Request cancelled")
+	return true
+}
+
+// displayError shows a message in place of a response
+func (m *model) displayError(message string) {
+	m.response = message
+	m.statusCode = 0
+	m.responseViewport.SetContent(m.response)
+	m.headersViewport.SetContent("No headers available")
+	m.testResults = nil
+	m.testsViewport.SetContent("No tests run")
+}
+
+// formatRedirectChain lists the redirects followed before the final response
+func formatRedirectChain(resp *response.HTTPResponse) string {
+	if len(resp.Redirects) == 0 {
+		return ""
+	}
+
+	var content strings.Builder
+	content.WriteString(currentTheme.SectionStyle.Render(fmt.Sprintf("Redirects (%d)", len(resp.Redirects))))
+	content.WriteString("\n")
+	for i, redirect := range resp.Redirects {
+		content.WriteString(fmt.Sprintf("%d. %d %s  %s\n", i+1, redirect.StatusCode, http.StatusText(redirect.StatusCode), redirect.URL))
+		content.WriteString(fmt.Sprintf("   → %s  (%s)\n", redirect.Location, response.FormatDuration(redirect.Duration)))
+	}
+	if resp.FinalURL != "" {
+		content.WriteString(fmt.Sprintf("Final URL: %s\n", resp.FinalURL))
+	}
+	content.WriteString("\n")
+	return content.String()
+}
+
+// formatTestResults renders test results for the Tests tab, followed by what
+// the request's scripts wrote to the console
+func formatTestResults(results []TestResult, scriptLogs []ScriptLog) string {
+	consoleOutput := formatScriptLogs(scriptLogs)
+	if len(results) == 0 {
+		return "No tests run" + consoleOutput
+	}
+
+	var content strings.Builder
+	passed := 0
+	for _, result := range results {
+		if result.Passed {
+			passed++
+			content.WriteString(currentTheme.StatusOkStyle.Render("✓ " + result.Name))
+			content.WriteString("\n")
+			continue
+		}
+
+		content.WriteString(currentTheme.ErrorStyle.Render("✗ " + result.Name))
+		content.WriteString("\n")
+		if result.Message != "" {
+			content.WriteString(fmt.Sprintf("    %s\n", result.Message))
+		}
+	}
+
+	summary := fmt.Sprintf("%d passed, %d failed, %d total", passed, len(results)-passed, len(results))
+	return summary + "\n\n" + content.String() + consoleOutput
+}
+
+// formatScriptLogs renders script console output as a section of the Tests tab
+func formatScriptLogs(scriptLogs []ScriptLog) string {
+	if len(scriptLogs) == 0 {
+		return ""
+	}
+
+	var content strings.Builder
+	content.WriteString("\n\n")
+	content.WriteString(currentTheme.SectionStyle.Render("Console"))
+	content.WriteString("\n")
+	for _, line := range scriptLogs {
+		switch line.Level {
+		case "error":
+			content.WriteString(currentTheme.ErrorStyle.Render("error " + line.Message))
+		case "warn":
+			content.WriteString(currentTheme.WarningStyle.Render("warn  " + line.Message))
+		default:
+			content.WriteString(line.Message)
+		}
+		content.WriteString("\n")
+	}
+	return content.String()
+}
+
+// testsTabSummary returns a short pass/fail count for the response title
+func (m *model) testsTabSummary() string {
+	if len(m.testResults) == 0 {
+		return ""
+	}
+
+	passed := 0
+	for _, result := range m.testResults {
+		if result.Passed {
+			passed++
+		}
+	}
+	return fmt.Sprintf(" • tests: %d/%d", passed, len(m.testResults))
+}
+
+// activeResponseViewport returns the viewport for the selected response tab
+func (m *model) activeResponseViewport() *viewport.Model {
+	switch response.GetResponseTabSection(m.responseActiveTab) {
+	case response.ResponseHeadersSection:
+		return &m.headersViewport
+	case response.ResponseTestsSection:
+		return &m.testsViewport
+	case response.ResponseTimingSection:
+		return &m.timingViewport
+	case response.ResponseConnectionSection:
+		return &m.connectionViewport
+	default:
+		return &m.responseViewport
+	}
+}
+
+// currentRequestCollectionPath returns the collection directory of the current request
+func (m *model) currentRequestCollectionPath() string {
+	if m.currentReq != nil {
+		for _, item := range m.collections {
+			if item.RequestIndex >= 0 && item.RequestIndex < len(m.bruRequests) && m.bruRequests[item.RequestIndex] == m.currentReq {
+				return filepath.Dir(item.FilePath)
+			}
+		}
 	}
+
+	// Fall back to the collection of the selected item
+	return getCurrentCollectionPath(m)
+}
+
+// activeEnvironment returns the selected environment for the current request's collection
+func (m *model) activeEnvironment() *Environment {
+	collectionPath := m.currentRequestCollectionPath()
+	name, exists := m.activeEnvironments[collectionPath]
+	if !exists {
+		return nil
+	}
+	return FindEnvironment(m.environments[collectionPath], name)
 }
 
 func getCurrentRequestFilePath(m *model) string {
@@ -566,6 +1204,118 @@ func (m *model) executeCommand(action string) tea.Cmd {
 		}
 		m.inputDialog.Show(spec)
 		return nil
+	case "switch_environment":
+		collectionPath := m.currentRequestCollectionPath()
+		var names []string
+		for _, env := range m.environments[collectionPath] {
+			names = append(names, env.Name)
+		}
+		spec := InputSpec{
+			Type:   EnvironmentSelectionInput,
+			Title:  "Switch Environment",
+			Action: action,
+			PreFill: map[string]interface{}{
+				"environments": names,
+				"active":       m.activeEnvironments[collectionPath],
+			},
+			ActionData: map[string]interface{}{
+				"collection": collectionPath,
+			},
+		}
+		m.inputDialog.Show(spec)
+		return nil
+	case "show_history", "resend_history":
+		title := "Request History"
+		if action == "resend_history" {
+			title = "Re-send From History"
+		}
+		spec := InputSpec{
+			Type:   HistorySelectionInput,
+			Title:  title,
+			Action: action,
+			PreFill: map[string]interface{}{
+				"entries": m.history.Entries(),
+			},
+		}
+		m.inputDialog.Show(spec)
+		return nil
+	case "graphql_introspect":
+		return m.introspectCurrentRequest()
+	case "clear_oauth2_tokens":
+		if m.currentReq != nil {
+			removed, err := m.httpClient.OAuth2Tokens.Clear(collectionScope(m.currentReq, m.activeEnvironment()))
+			if err != nil {
+				m.response = fmt.Sprintf("Failed to clear OAuth2 tokens: %v", err)
+			} else {
+				m.response = fmt.Sprintf("Cleared %d OAuth2 token(s) for this collection and environment", removed)
+			}
+			m.responseViewport.SetContent(m.response)
+		}
+		return nil
+	case "manage_cookies":
+		if m.currentReq == nil {
+			return nil
+		}
+		scope := collectionScope(m.currentReq, m.activeEnvironment())
+		spec := InputSpec{
+			Type:   CookieManagerInput,
+			Title:  "Cookies",
+			Action: action,
+			PreFill: map[string]interface{}{
+				"cookies": m.httpClient.Cookies.Cookies(scope),
+			},
+			ActionData: map[string]interface{}{
+				"scope": scope,
+			},
+		}
+		m.inputDialog.Show(spec)
+		return nil
+	case "resume_stream":
+		return m.resumeStream()
+	case "save_stream":
+		if !m.streaming && (m.lastResponse == nil || !m.lastResponse.Streamed) {
+			return nil
+		}
+		spec := InputSpec{
+			Type:   TextInput,
+			Title:  "Save Stream",
+			Prompt: "File to save the captured stream to:",
+			Action: action,
+			PreFill: map[string]interface{}{
+				"value": fmt.Sprintf("stream-%s.txt", time.Now().Format("20060102-150405")),
+			},
+		}
+		m.inputDialog.Show(spec)
+		return nil
+	case "save_body":
+		if m.isLoading || m.lastResponse == nil || m.lastResponse.Error != "" {
+			return nil
+		}
+		requestURL := ""
+		if m.lastSent != nil {
+			requestURL = m.lastSent.URL
+		}
+		spec := InputSpec{
+			Type:   TextInput,
+			Title:  "Save Response Body",
+			Prompt: "File to save the response body to:",
+			Action: action,
+			PreFill: map[string]interface{}{
+				"value": suggestBodyFileName(m.lastResponse, requestURL),
+			},
+		}
+		m.inputDialog.Show(spec)
+		return nil
+	case "prune_history":
+		spec := InputSpec{
+			Type:        TextInput,
+			Title:       "Prune History",
+			Prompt:      "Remove entries older than an age (e.g. 30d, 12h) or shrink history to a size (e.g. 10MB):",
+			Placeholder: "30d",
+			Action:      action,
+		}
+		m.inputDialog.Show(spec)
+		return nil
 	default:
 		return nil
 	}
@@ -616,6 +1366,10 @@ func (m *model) executeInputCommand(action string, input string, actionData map[
 			urlStr, urlOk := actionData["url"].(string)
 			name, _ := actionData["name"].(string)
 			tags, _ := actionData["tags"].([]string)
+			if methodOk && !IsValidMethod(method) {
+				m.responseViewport.SetContent(fmt.Sprintf("Invalid HTTP method %q", method))
+				return nil
+			}
 			
 			if methodOk && urlOk && urlStr != "" {
 				// Get the current collection path (folder or root)
@@ -646,7 +1400,7 @@ func (m *model) executeInputCommand(action string, input string, actionData map[
 					}
 					
 					// Generate complete .bru file content
-					bruContent := m.generateBruContent(newReq)
+					bruContent := FormatBruRequest(newReq)
 					
 					err := os.WriteFile(filePath, []byte(bruContent), 0644)
 					if err == nil {
@@ -663,6 +1417,10 @@ func (m *model) executeInputCommand(action string, input string, actionData map[
 			name, _ := actionData["name"].(string)
 			tags, _ := actionData["tags"].([]string)
 			filePath, filePathOk := actionData["filePath"].(string)
+			if methodOk && !IsValidMethod(method) {
+				m.responseViewport.SetContent(fmt.Sprintf("Invalid HTTP method %q", method))
+				return nil
+			}
 			
 			if methodOk && urlOk && filePathOk && urlStr != "" && filePath != "" {
 				// Use display name if provided, otherwise generate from method and URL
@@ -681,7 +1439,7 @@ func (m *model) executeInputCommand(action string, input string, actionData map[
 					}
 					
 					// Generate complete .bru file content preserving all existing data
-					bruContent := m.generateBruContent(m.currentReq)
+					bruContent := FormatBruRequest(m.currentReq)
 					
 					err := os.WriteFile(filePath, []byte(bruContent), 0644)
 					if err == nil {
@@ -723,6 +1481,111 @@ func (m *model) executeInputCommand(action string, input string, actionData map[
 			}
 		}
 		return nil
+	case "switch_environment":
+		if actionData != nil {
+			collectionPath, collectionOk := actionData["collection"].(string)
+			envName, _ := actionData["environment"].(string)
+			if collectionOk {
+				if envName == "" {
+					delete(m.activeEnvironments, collectionPath)
+				} else {
+					m.activeEnvironments[collectionPath] = envName
+				}
+			}
+		}
+		return nil
+	case "show_history":
+		if entryID, ok := actionData["id"].(string); ok && entryID != "" {
+			if entry := m.history.Find(entryID); entry != nil {
+				m.displayResponse(historyResponse(entry), entry.TestResults, entry.ScriptLogs)
+				m.lastSent = entry.Request
+				m.lastSentEnv = entry.Environment
+				m.historyLabel = entry.ExecutedAt.Local().Format("2006-01-02 15:04:05")
+				m.activePanel = responsePanel
+			}
+		}
+		return nil
+	case "resend_history":
+		if entryID, ok := actionData["id"].(string); ok && entryID != "" {
+			if entry := m.history.Find(entryID); entry != nil {
+				return m.resendHistoryEntry(entry)
+			}
+		}
+		return nil
+	case "manage_cookies":
+		// Enter on a cookie edits its value
+		scope, _ := actionData["scope"].(string)
+		cookie, ok := actionData["cookie"].(*StoredCookie)
+		if !ok {
+			return nil
+		}
+		spec := InputSpec{
+			Type:   TextInput,
+			Title:  "Edit Cookie",
+			Prompt: fmt.Sprintf("Value of %s (%s%s):", cookie.Name, cookie.Domain, cookie.Path),
+			Action: "edit_cookie",
+			IsEdit: true,
+			PreFill: map[string]interface{}{
+				"value": cookie.Value,
+			},
+			ActionData: map[string]interface{}{
+				"scope":  scope,
+				"cookie": cookie,
+			},
+		}
+		m.inputDialog.Show(spec)
+		return nil
+	case "edit_cookie":
+		scope, _ := actionData["scope"].(string)
+		if cookie, ok := actionData["cookie"].(*StoredCookie); ok {
+			cookie.Value = input
+			if err := m.httpClient.Cookies.Set(scope, cookie); err != nil {
+				m.responseViewport.SetContent(fmt.Sprintf("Failed to save cookie: %v", err))
+				return nil
+			}
+		}
+		return m.executeCommand("manage_cookies")
+	case "save_stream":
+		// A stream still in flight is saved as far as it has arrived
+		var written int64
+		var err error
+		if m.streaming {
+			written, err = m.saveStreamCapture(input)
+		} else if m.lastResponse != nil {
+			written, err = writeResponseBody(m.lastResponse, input)
+		}
+		if err != nil {
+			m.responseViewport.SetContent(fmt.Sprintf("Failed to save stream: %v", err))
+			return nil
+		}
+		m.responseViewport.SetContent(fmt.Sprintf("%s\n\nSaved %d bytes to %s", m.response, written, input))
+		m.responseViewport.GotoBottom()
+		return nil
+	case "save_body":
+		if m.lastResponse == nil {
+			return nil
+		}
+		written, err := writeResponseBody(m.lastResponse, input)
+		if err != nil {
+			m.responseViewport.SetContent(fmt.Sprintf("Failed to save response body: %v", err))
+			return nil
+		}
+		m.responseViewport.SetContent(fmt.Sprintf("%s\n\nSaved %s to %s", m.response, response.FormatSize(written), input))
+		m.responseViewport.GotoBottom()
+		return nil
+	case "prune_history":
+		maxAge, maxBytes, err := parsePruneLimit(input)
+		if err != nil {
+			m.responseViewport.SetContent(fmt.Sprintf("Prune Error: %v", err))
+			return nil
+		}
+		removed, err := m.history.Prune(maxAge, maxBytes)
+		if err != nil {
+			m.responseViewport.SetContent(fmt.Sprintf("Prune Error: %v", err))
+			return nil
+		}
+		m.responseViewport.SetContent(fmt.Sprintf("Removed %d history entries", removed))
+		return nil
 	default:
 		return nil
 	}
@@ -754,7 +1617,7 @@ func (m *model) View() string {
 		main,
 	)
 
-	header := currentTheme.TitleStyle.Width(m.width - 2).Render("Kalo - Bruno API Client")
+	header := currentTheme.TitleStyle.Width(m.width - 2).Render("Kalo - Bruno API Client" + m.renderEnvironmentIndicator())
 
 	// Get footer text from input handler (which delegates to appropriate panel)
 	footerText := m.inputHandler.GetFooterText(m)
@@ -800,8 +1663,12 @@ func (m *model) renderMainArea(width, height int) string {
 	requestTitle := m.renderRequestTitle(width)
 	responseTitle := m.renderResponseTitle(width)
 
-	request := request.RenderRequest(width, requestHeight, m.currentReq, m.activePanel == requestPanel, m.requestCursor, m.requestActiveTab, currentTheme.FocusedStyle, currentTheme.BlurredStyle, currentTheme.TitleStyle, currentTheme.CursorStyle, currentTheme.MethodStyle, currentTheme.URLStyle, currentTheme.SectionStyle, currentTheme.TextCursorStyle)
-	response := response.RenderResponse(width, responseHeight, m.activePanel == responsePanel, m.isLoading, m.lastResponse, m.statusCode, m.responseCursor, m.responseActiveTab, &m.headersViewport, &m.responseViewport, currentTheme.FocusedStyle, currentTheme.BlurredStyle, currentTheme.TitleStyle, currentTheme.CursorStyle, currentTheme.SectionStyle, currentTheme.StatusOkStyle, m.appliedJQFilter())
+	request := request.RenderRequest(width, requestHeight, m.currentReq, m.preview, m.activePanel == requestPanel, m.requestCursor, m.requestActiveTab, currentTheme.FocusedStyle, currentTheme.BlurredStyle, currentTheme.TitleStyle, currentTheme.CursorStyle, currentTheme.MethodStyle, currentTheme.URLStyle, currentTheme.SectionStyle, currentTheme.TextCursorStyle)
+	if m.wsConsole != nil && !m.isLoading {
+		console := response.RenderWebSocketConsole(width, responseHeight, m.activePanel == responsePanel, m.wsConsole.messages, &m.wsConsole.log, m.wsConsole.input.View(), currentTheme.FocusedStyle, currentTheme.BlurredStyle)
+		return lipgloss.JoinVertical(lipgloss.Left, requestTitle, request, responseTitle, console)
+	}
+	response := response.RenderResponse(width, responseHeight, m.activePanel == responsePanel, m.isLoading, m.lastResponse, m.statusCode, m.responseCursor, m.responseActiveTab, &m.headersViewport, &m.responseViewport, &m.testsViewport, &m.timingViewport, &m.connectionViewport, currentTheme.FocusedStyle, currentTheme.BlurredStyle, currentTheme.TitleStyle, currentTheme.CursorStyle, currentTheme.SectionStyle, currentTheme.StatusOkStyle, m.appliedJQFilter())
 
 	return lipgloss.JoinVertical(lipgloss.Left, requestTitle, request, responseTitle, response)
 }
@@ -831,11 +1698,27 @@ func (m *model) renderResponseTitle(width int) string {
 	var titleContent string
 	
 	if m.isLoading {
-		titleContent = " Response ⏳ Loading..."
+		elapsed := time.Since(m.loadingStarted)
+		frame := loadingSpinnerFrames[int(elapsed/loadingTickInterval)%len(loadingSpinnerFrames)]
+		if m.streaming {
+			received := fmt.Sprintf("%d bytes", m.streamSize)
+			if m.streamEventCount > 0 {
+				received = fmt.Sprintf("%d events", m.streamEventCount)
+			}
+			titleContent = fmt.Sprintf(" Response %s Streaming... %s %.1fs", frame, received, elapsed.Seconds())
+		} else if m.download != nil {
+			received := response.FormatSize(m.download.received)
+			if m.download.total > 0 {
+				received = fmt.Sprintf("%s of %s (%d%%)", received, response.FormatSize(m.download.total), m.download.received*100/m.download.total)
+			}
+			titleContent = fmt.Sprintf(" Response %s Downloading... %s %.1fs", frame, received, elapsed.Seconds())
+		} else {
+			titleContent = fmt.Sprintf(" Response %s Loading... %.1fs", frame, elapsed.Seconds())
+		}
 	} else if m.lastResponse != nil {
 		// Extract MIME type from Content-Type header
 		contentType := ""
-		if ct, exists := m.lastResponse.Headers["Content-Type"]; exists {
+		if ct := m.lastResponse.Headers.Get("Content-Type"); ct != "" {
 			// Extract just the MIME type part (before semicolon if present)
 			if idx := strings.Index(ct, ";"); idx != -1 {
 				contentType = strings.TrimSpace(ct[:idx])
@@ -872,6 +1755,15 @@ func (m *model) renderResponseTitle(width int) string {
 		if contentType != "" {
 			mimeInfo = fmt.Sprintf(" • %s", contentType)
 		}
+		if size := response.FormatBodySize(m.lastResponse); size != "" {
+			mimeInfo += fmt.Sprintf(" • %s", size)
+		}
+		
+		// Add active environment info
+		envInfo := ""
+		if env := m.activeEnvironment(); env != nil {
+			envInfo = fmt.Sprintf(" • env: %s", env.Name)
+		}
 		
 		titleContent = lipgloss.JoinHorizontal(
 			lipgloss.Left,
@@ -879,138 +1771,69 @@ func (m *model) renderResponseTitle(width int) string {
 			statusStyle.Render(statusText),
 			timing,
 			mimeInfo,
+			envInfo,
+			m.redirectsSummary(),
+			m.streamSummary(),
+			m.testsTabSummary(),
+			m.historyInfo(),
 			" ",
 		)
 	} else {
 		titleContent = " Response " + currentTheme.StatusOkStyle.Render("200 OK") + " • Mock Response"
 	}
+	if m.wsConsole != nil && !m.isLoading {
+		titleContent = m.webSocketTitle()
+	}
 	
 	return currentTheme.TitleStyle.Width(width-2).Render(titleContent)
 }
 
-func (m *model) renderCollectionsTitle(width int) string {
-	return currentTheme.TitleStyle.Width(width-2).Render(" Collections ")
+// redirectsSummary counts the redirects followed for the response title
+func (m *model) redirectsSummary() string {
+	if len(m.lastResponse.Redirects) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" • redirects: %d", len(m.lastResponse.Redirects))
 }
 
-// generateBruContent creates a complete .bru file content from a BruRequest,
-// preserving all existing data including tags, headers, query params, body, auth, etc.
-func (m *model) generateBruContent(request *request.BruRequest) string {
-	var content strings.Builder
-	
-	// Meta block
-	content.WriteString("meta {\n")
-	content.WriteString(fmt.Sprintf("  name: %s\n", request.Meta.Name))
-	content.WriteString("  type: http\n")
-	content.WriteString(fmt.Sprintf("  seq: %d\n", request.Meta.Seq))
-	content.WriteString("}\n\n")
-	
-	// Tags block (if tags exist)
-	if len(request.Tags) > 0 {
-		content.WriteString("tags {\n")
-		for _, tag := range request.Tags {
-			content.WriteString(fmt.Sprintf("  %s\n", tag))
-		}
-		content.WriteString("}\n\n")
-	}
-	
-	// HTTP method block
-	content.WriteString(fmt.Sprintf("%s {\n", strings.ToLower(request.HTTP.Method)))
-	content.WriteString(fmt.Sprintf("  url: %s\n", request.HTTP.URL))
-	content.WriteString("}\n")
-	
-	// Query parameters
-	if len(request.Query) > 0 {
-		content.WriteString("\nquery {\n")
-		// Sort keys for consistent output
-		keys := make([]string, 0, len(request.Query))
-		for key := range request.Query {
-			keys = append(keys, key)
-		}
-		sort.Strings(keys)
-		for _, key := range keys {
-			content.WriteString(fmt.Sprintf("  %s: %s\n", key, request.Query[key]))
-		}
-		content.WriteString("}\n")
-	}
-	
-	// Headers
-	if len(request.Headers) > 0 {
-		content.WriteString("\nheaders {\n")
-		// Sort keys for consistent output
-		keys := make([]string, 0, len(request.Headers))
-		for key := range request.Headers {
-			keys = append(keys, key)
-		}
-		sort.Strings(keys)
-		for _, key := range keys {
-			content.WriteString(fmt.Sprintf("  %s: %s\n", key, request.Headers[key]))
-		}
-		content.WriteString("}\n")
-	}
-	
-	// Auth
-	if request.Auth.Type != "" {
-		content.WriteString("\nauth {\n")
-		content.WriteString(fmt.Sprintf("  mode: %s\n", request.Auth.Type))
-		if len(request.Auth.Values) > 0 {
-			// Sort keys for consistent output
-			keys := make([]string, 0, len(request.Auth.Values))
-			for key := range request.Auth.Values {
-				keys = append(keys, key)
-			}
-			sort.Strings(keys)
-			for _, key := range keys {
-				content.WriteString(fmt.Sprintf("  %s: %s\n", key, request.Auth.Values[key]))
-			}
-		}
-		content.WriteString("}\n")
-	}
-	
-	// Body
-	if request.Body.Type != "" && request.Body.Data != "" {
-		content.WriteString(fmt.Sprintf("\nbody:%s {\n", request.Body.Type))
-		content.WriteString(request.Body.Data)
-		content.WriteString("\n}\n")
-	}
-	
-	// Variables
-	if len(request.Vars) > 0 {
-		content.WriteString("\nvars {\n")
-		// Sort keys for consistent output
-		keys := make([]string, 0, len(request.Vars))
-		for key := range request.Vars {
-			keys = append(keys, key)
-		}
-		sort.Strings(keys)
-		for _, key := range keys {
-			content.WriteString(fmt.Sprintf("  %s: %s\n", key, request.Vars[key]))
-		}
-		content.WriteString("}\n")
+// historyInfo labels the response title when a past response is shown
+func (m *model) historyInfo() string {
+	if m.historyLabel == "" {
+		return ""
 	}
-	
-	// Tests
-	if request.Tests != "" {
-		content.WriteString("\ntests {\n")
-		content.WriteString(request.Tests)
-		content.WriteString("\n}\n")
+	return fmt.Sprintf(" • history: %s", m.historyLabel)
+}
+
+// renderEnvironmentIndicator renders the active environment for the header bar
+func (m *model) renderEnvironmentIndicator() string {
+	if env := m.activeEnvironment(); env != nil {
+		return fmt.Sprintf(" • Environment: %s", env.Name)
 	}
-	
-	// Docs
-	if request.Docs != "" {
-		content.WriteString("\ndocs {\n")
-		content.WriteString(request.Docs)
-		content.WriteString("\n}\n")
+	if len(m.environments[m.currentRequestCollectionPath()]) > 0 {
+		return " • No Environment"
 	}
-	
-	return content.String()
+	return ""
+}
+
+func (m *model) renderCollectionsTitle(width int) string {
+	return currentTheme.TitleStyle.Width(width-2).Render(" Collections ")
 }
 
 func main() {
+	// Headless mode for CI pipelines
+	if len(os.Args) > 1 && os.Args[1] == "run" {
+		os.Exit(runCLI(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	// Initialize theme system
 	currentTheme = LoadTheme("default") // Can be configurable later
 	
-	p := tea.NewProgram(initialModel(), tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
+	m := initialModel()
+	p := tea.NewProgram(m, tea.WithAltScreen())
+	_, err := p.Run()
+	// The body of the last large response only lives as long as kalo
+	removeBodyFile(m.lastResponse)
+	if err != nil {
 		fmt.Printf("Error: %v", err)
 		os.Exit(1)
 	}
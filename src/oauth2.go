@@ -0,0 +1,549 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	request "kalo/src/panels/request"
+)
+
+// oauth2TokensFileName is the file under ~/.kalo that caches OAuth2 tokens
+const oauth2TokensFileName = "oauth2-tokens.json"
+
+// defaultOAuth2CallbackURL is the loopback redirect used by the authorization
+// code grant when auth:oauth2 does not set callback_url
+const defaultOAuth2CallbackURL = "http://127.0.0.1:8765/callback"
+
+// oauth2RefreshMargin renews tokens shortly before they expire so a request
+// does not race the expiry
+const oauth2RefreshMargin = 30 * time.Second
+
+// oauth2AuthorizeTimeout bounds how long the authorization code grant waits
+// for the browser to redirect back
+const oauth2AuthorizeTimeout = 5 * time.Minute
+
+// OAuth2Token is an access token obtained for an auth:oauth2 request
+type OAuth2Token struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	TokenType    string    `json:"token_type,omitempty"`
+	Scope        string    `json:"scope,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at,omitempty"`
+}
+
+// needsRefresh reports whether the token has expired or is about to
+func (t *OAuth2Token) needsRefresh() bool {
+	return !t.ExpiresAt.IsZero() && time.Now().Add(oauth2RefreshMargin).After(t.ExpiresAt)
+}
+
+// OAuth2TokenStore caches tokens per collection and environment. Tokens are
+// written to ~/.kalo so authorization code logins survive restarts.
+type OAuth2TokenStore struct {
+	path   string
+	tokens map[string]*OAuth2Token
+	mu     sync.Mutex
+	flows  map[string]chan struct{} // Per cache key, so concurrent requests for a token share one login
+}
+
+// getOAuth2TokensPath returns the path of the token cache, creating ~/.kalo if needed
+func getOAuth2TokensPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	kaloDir := filepath.Join(homeDir, ".kalo")
+	if err := os.MkdirAll(kaloDir, 0755); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(kaloDir, oauth2TokensFileName), nil
+}
+
+// NewOAuth2TokenStore creates a token cache backed by the given file. An
+// empty path keeps tokens in memory only.
+func NewOAuth2TokenStore(path string) *OAuth2TokenStore {
+	store := &OAuth2TokenStore{path: path, tokens: make(map[string]*OAuth2Token)}
+	if path != "" {
+		if data, err := os.ReadFile(path); err == nil {
+			json.Unmarshal(data, &store.tokens)
+		}
+	}
+	return store
+}
+
+// lockFlow waits until no other token request for key is running, or ctx is
+// done. Requests for other keys, such as another collection waiting for a
+// browser login, do not block it. The returned func releases the lock.
+func (s *OAuth2TokenStore) lockFlow(ctx context.Context, key string) (func(), error) {
+	s.mu.Lock()
+	if s.flows == nil {
+		s.flows = make(map[string]chan struct{})
+	}
+	flow, exists := s.flows[key]
+	if !exists {
+		flow = make(chan struct{}, 1)
+		s.flows[key] = flow
+	}
+	s.mu.Unlock()
+
+	select {
+	case flow <- struct{}{}:
+		return func() { <-flow }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Get returns the cached token for a key
+func (s *OAuth2TokenStore) Get(key string) *OAuth2Token {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tokens[key]
+}
+
+// Set caches a token and writes the cache to disk
+func (s *OAuth2TokenStore) Set(key string, token *OAuth2Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[key] = token
+	return s.save()
+}
+
+// Clear removes the tokens of a collection and environment, returning how
+// many were removed
+func (s *OAuth2TokenStore) Clear(scope string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	for key := range s.tokens {
+		if strings.HasPrefix(key, scope+"\n") {
+			delete(s.tokens, key)
+			removed++
+		}
+	}
+	return removed, s.save()
+}
+
+func (s *OAuth2TokenStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(s.tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0600)
+}
+
+// oauth2Config is an auth:oauth2 block with variables substituted
+type oauth2Config struct {
+	GrantType            string
+	AccessTokenURL       string
+	AuthorizationURL     string
+	CallbackURL          string
+	ClientID             string
+	ClientSecret         string
+	Scope                string
+	State                string
+	Username             string
+	Password             string
+	PKCE                 bool
+	CredentialsPlacement string            // "body" or "basic_auth_header"
+	Transport            http.RoundTripper // The collection's transport, for its proxy and TLS settings
+}
+
+func (c *HTTPClient) resolveOAuth2Config(values map[string]string, vars map[string]string) oauth2Config {
+	get := func(key string) string {
+		return strings.TrimSpace(c.substituteVars(values[key], vars))
+	}
+
+	config := oauth2Config{
+		GrantType:            get("grant_type"),
+		AccessTokenURL:       get("access_token_url"),
+		AuthorizationURL:     get("authorization_url"),
+		CallbackURL:          get("callback_url"),
+		ClientID:             get("client_id"),
+		ClientSecret:         get("client_secret"),
+		Scope:                get("scope"),
+		State:                get("state"),
+		Username:             get("username"),
+		Password:             get("password"),
+		CredentialsPlacement: get("credentials_placement"),
+	}
+	config.PKCE, _ = strconv.ParseBool(get("pkce"))
+	if config.GrantType == "" {
+		config.GrantType = "client_credentials"
+	}
+	if config.CallbackURL == "" {
+		config.CallbackURL = defaultOAuth2CallbackURL
+	}
+	return config
+}
+
+// cacheKey identifies a token within a scope, so changing the client or user
+// fetches a new token
+func (config oauth2Config) cacheKey(scope string) string {
+	return strings.Join([]string{scope, config.GrantType, config.AccessTokenURL, config.ClientID, config.Scope, config.Username}, "\n")
+}
+
+// AuthorizeOAuth2 makes sure an auth:oauth2 request has an access token,
+// reusing the cached one, refreshing it when it is about to expire or
+// running the configured grant. The token is stored in the request's auth
+// values for addAuth, so callers pass a copy of the loaded request.
+func (c *HTTPClient) AuthorizeOAuth2(ctx context.Context, bruReq *request.BruRequest, collection *CollectionConfig, vars map[string]string, env *Environment) error {
+	if bruReq.Auth.Type != "oauth2" {
+		return nil
+	}
+
+	config := c.resolveOAuth2Config(bruReq.Auth.Values, vars)
+	key := config.cacheKey(collectionScope(bruReq, env))
+	transport, err := c.transportFor(collection, vars)
+	if err != nil {
+		return err
+	}
+	config.Transport = transport
+
+	unlock, err := c.OAuth2Tokens.lockFlow(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	token := c.OAuth2Tokens.Get(key)
+	if token != nil && token.needsRefresh() {
+		refreshed, err := c.refreshOAuth2Token(ctx, config, token)
+		if err != nil {
+			token = nil // Fall back to the full grant
+		} else {
+			token = refreshed
+			c.OAuth2Tokens.Set(key, token)
+		}
+	}
+
+	if token == nil {
+		var err error
+		token, err = c.fetchOAuth2Token(ctx, config)
+		if err != nil {
+			return fmt.Errorf("OAuth2 %s grant failed: %v", config.GrantType, err)
+		}
+		c.OAuth2Tokens.Set(key, token)
+	}
+
+	if bruReq.Auth.Values == nil {
+		bruReq.Auth.Values = make(map[string]string)
+	}
+	bruReq.Auth.Values["access_token"] = token.AccessToken
+	return nil
+}
+
+// fetchOAuth2Token runs the configured grant
+func (c *HTTPClient) fetchOAuth2Token(ctx context.Context, config oauth2Config) (*OAuth2Token, error) {
+	switch config.GrantType {
+	case "client_credentials":
+		form := url.Values{"grant_type": {"client_credentials"}}
+		if config.Scope != "" {
+			form.Set("scope", config.Scope)
+		}
+		return c.requestOAuth2Token(ctx, config, form)
+	case "password":
+		form := url.Values{
+			"grant_type": {"password"},
+			"username":   {config.Username},
+			"password":   {config.Password},
+		}
+		if config.Scope != "" {
+			form.Set("scope", config.Scope)
+		}
+		return c.requestOAuth2Token(ctx, config, form)
+	case "authorization_code":
+		return c.authorizeOAuth2Code(ctx, config)
+	default:
+		return nil, fmt.Errorf("unsupported grant type %q", config.GrantType)
+	}
+}
+
+func (c *HTTPClient) refreshOAuth2Token(ctx context.Context, config oauth2Config, token *OAuth2Token) (*OAuth2Token, error) {
+	if token.RefreshToken == "" {
+		return nil, fmt.Errorf("no refresh token")
+	}
+
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {token.RefreshToken},
+	}
+	if config.Scope != "" {
+		form.Set("scope", config.Scope)
+	}
+	refreshed, err := c.requestOAuth2Token(ctx, config, form)
+	if err != nil {
+		return nil, err
+	}
+	// Servers may keep the refresh token and omit it from the response
+	if refreshed.RefreshToken == "" {
+		refreshed.RefreshToken = token.RefreshToken
+	}
+	return refreshed, nil
+}
+
+// requestOAuth2Token posts a token request, sending the client credentials
+// in the body unless credentials_placement asks for a basic auth header
+func (c *HTTPClient) requestOAuth2Token(ctx context.Context, config oauth2Config, form url.Values) (*OAuth2Token, error) {
+	if config.AccessTokenURL == "" {
+		return nil, fmt.Errorf("access_token_url is not set")
+	}
+
+	if config.CredentialsPlacement != "basic_auth_header" {
+		form.Set("client_id", config.ClientID)
+		if config.ClientSecret != "" {
+			form.Set("client_secret", config.ClientSecret)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, defaultRequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", config.AccessTokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", string(ContentTypeForm))
+	req.Header.Set("Accept", string(ContentTypeJSON))
+	if config.CredentialsPlacement == "basic_auth_header" {
+		req.SetBasicAuth(url.QueryEscape(config.ClientID), url.QueryEscape(config.ClientSecret))
+	}
+
+	// Token requests go through the same proxy and TLS settings as the request
+	client := *c.client
+	if config.Transport != nil {
+		client.Transport = config.Transport
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return parseOAuth2TokenResponse(resp.StatusCode, resp.Header.Get("Content-Type"), body)
+}
+
+// parseOAuth2TokenResponse decodes a token endpoint response. Some servers
+// answer with form encoding instead of JSON.
+func parseOAuth2TokenResponse(statusCode int, contentType string, body []byte) (*OAuth2Token, error) {
+	var fields struct {
+		AccessToken      string      `json:"access_token"`
+		RefreshToken     string      `json:"refresh_token"`
+		TokenType        string      `json:"token_type"`
+		Scope            string      `json:"scope"`
+		ExpiresIn        json.Number `json:"expires_in"`
+		Error            string      `json:"error"`
+		ErrorDescription string      `json:"error_description"`
+	}
+
+	if strings.Contains(contentType, "application/x-www-form-urlencoded") {
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, fmt.Errorf("invalid token response: %v", err)
+		}
+		fields.AccessToken = values.Get("access_token")
+		fields.RefreshToken = values.Get("refresh_token")
+		fields.TokenType = values.Get("token_type")
+		fields.Scope = values.Get("scope")
+		fields.ExpiresIn = json.Number(values.Get("expires_in"))
+		fields.Error = values.Get("error")
+		fields.ErrorDescription = values.Get("error_description")
+	} else if err := json.Unmarshal(body, &fields); err != nil {
+		if statusCode >= 400 {
+			return nil, fmt.Errorf("token endpoint returned %d: %s", statusCode, strings.TrimSpace(string(body)))
+		}
+		return nil, fmt.Errorf("invalid token response: %v", err)
+	}
+
+	if fields.Error != "" {
+		if fields.ErrorDescription != "" {
+			return nil, fmt.Errorf("%s: %s", fields.Error, fields.ErrorDescription)
+		}
+		return nil, fmt.Errorf("%s", fields.Error)
+	}
+	if statusCode >= 400 {
+		return nil, fmt.Errorf("token endpoint returned %d", statusCode)
+	}
+	if fields.AccessToken == "" {
+		return nil, fmt.Errorf("token response has no access_token")
+	}
+
+	token := &OAuth2Token{
+		AccessToken:  fields.AccessToken,
+		RefreshToken: fields.RefreshToken,
+		TokenType:    fields.TokenType,
+		Scope:        fields.Scope,
+	}
+	if seconds, err := fields.ExpiresIn.Int64(); err == nil && seconds > 0 {
+		token.ExpiresAt = time.Now().Add(time.Duration(seconds) * time.Second)
+	}
+	return token, nil
+}
+
+// authorizeOAuth2Code runs the authorization code grant: the authorization
+// URL is opened in a browser and a listener on the loopback callback_url
+// waits for the redirect carrying the code
+func (c *HTTPClient) authorizeOAuth2Code(ctx context.Context, config oauth2Config) (*OAuth2Token, error) {
+	if config.AuthorizationURL == "" {
+		return nil, fmt.Errorf("authorization_url is not set")
+	}
+
+	callback, err := url.Parse(config.CallbackURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid callback_url: %v", err)
+	}
+	if callback.Scheme != "http" || !isLoopbackHost(callback.Hostname()) {
+		return nil, fmt.Errorf("callback_url must be an http loopback address such as %s", defaultOAuth2CallbackURL)
+	}
+
+	state := config.State
+	if state == "" {
+		state = randomURLSafeString(16)
+	}
+
+	authURL, err := url.Parse(config.AuthorizationURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid authorization_url: %v", err)
+	}
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", config.ClientID)
+	query.Set("redirect_uri", config.CallbackURL)
+	query.Set("state", state)
+	if config.Scope != "" {
+		query.Set("scope", config.Scope)
+	}
+
+	verifier := ""
+	if config.PKCE {
+		verifier = randomURLSafeString(32)
+		challenge := sha256.Sum256([]byte(verifier))
+		query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+		query.Set("code_challenge_method", "S256")
+	}
+	authURL.RawQuery = query.Encode()
+
+	listener, err := net.Listen("tcp", callback.Host)
+	if err != nil {
+		return nil, fmt.Errorf("cannot listen on %s: %v", callback.Host, err)
+	}
+
+	type callbackResult struct {
+		code string
+		err  error
+	}
+	results := make(chan callbackResult, 1)
+
+	callbackPath := callback.Path
+	if callbackPath == "" {
+		callbackPath = "/"
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(callbackPath, func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		result := callbackResult{code: params.Get("code")}
+		switch {
+		case params.Get("error") != "":
+			result.err = fmt.Errorf("authorization denied: %s %s", params.Get("error"), params.Get("error_description"))
+		case params.Get("state") != state:
+			result.err = fmt.Errorf("state mismatch in authorization response")
+		case result.code == "":
+			result.err = fmt.Errorf("authorization response has no code")
+		}
+
+		if result.err != nil {
+			fmt.Fprintf(w, "Authorization failed: %v. You can close this window.", result.err)
+		} else {
+			fmt.Fprint(w, "Authorization complete. You can close this window and return to kalo.")
+		}
+		select {
+		case results <- result:
+		default:
+		}
+	})
+
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	defer server.Shutdown(context.Background())
+
+	if err := c.OpenBrowser(authURL.String()); err != nil {
+		return nil, fmt.Errorf("cannot open a browser, visit %s: %v", authURL.String(), err)
+	}
+
+	var result callbackResult
+	select {
+	case result = <-results:
+	case <-time.After(oauth2AuthorizeTimeout):
+		return nil, fmt.Errorf("timed out waiting for the authorization redirect")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if result.err != nil {
+		return nil, result.err
+	}
+
+	form := url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {result.code},
+		"redirect_uri": {config.CallbackURL},
+	}
+	if verifier != "" {
+		form.Set("code_verifier", verifier)
+	}
+	return c.requestOAuth2Token(ctx, config, form)
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// randomURLSafeString returns n random bytes encoded for use in URLs, as
+// used for PKCE verifiers and state values
+func randomURLSafeString(n int) string {
+	data := make([]byte, n)
+	rand.Read(data)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// openBrowser opens a URL with the platform's default browser
+func openBrowser(target string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", target)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	default:
+		cmd = exec.Command("xdg-open", target)
+	}
+	return cmd.Start()
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	panels "kalo/src/panels/request"
+)
+
+func TestOAuth2ClientCredentials(t *testing.T) {
+	var grants []string
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			r.ParseForm()
+			grants = append(grants, r.PostForm.Get("grant_type"))
+			if r.PostForm.Get("client_id") != "kalo" || r.PostForm.Get("client_secret") != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				fmt.Fprint(w, `{"error": "invalid_client"}`)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			// Expires within the refresh margin, so the next request refreshes it
+			fmt.Fprintf(w, `{"access_token": "token-%d", "token_type": "Bearer", "expires_in": 10, "refresh_token": "refresh"}`, len(grants))
+		default:
+			fmt.Fprint(w, r.Header.Get("Authorization"))
+		}
+	})
+
+	bruReq := &panels.BruRequest{
+		HTTP: panels.BruHTTP{Method: "GET", URL: server.URL + "/resource"},
+		Auth: panels.BruAuth{Type: "oauth2", Values: map[string]string{
+			"grant_type":       "client_credentials",
+			"access_token_url": "{{authUrl}}/token",
+			"client_id":        "kalo",
+			"client_secret":    "secret",
+		}},
+	}
+	env := &Environment{Name: "test", Vars: map[string]string{"authUrl": server.URL}}
+	client := NewHTTPClient()
+
+	for i, expected := range []string{"Bearer token-1", "Bearer token-2"} {
+		resp := sendBruRequest(context.Background(), client, bruReq, env)
+		if resp.Error != "" {
+			t.Fatalf("Request %d failed: %s", i+1, resp.Error)
+		}
+		if resp.Body != expected {
+			t.Errorf("Request %d: expected Authorization %q, got %q", i+1, expected, resp.Body)
+		}
+	}
+
+	if strings.Join(grants, ",") != "client_credentials,refresh_token" {
+		t.Errorf("Expected a client_credentials grant followed by a refresh, got %v", grants)
+	}
+	if _, exists := bruReq.Auth.Values["access_token"]; exists {
+		t.Errorf("Expected the loaded request to be left without a token")
+	}
+
+	removed, _ := client.OAuth2Tokens.Clear(collectionScope(bruReq, env))
+	if removed != 1 {
+		t.Errorf("Expected one cached token to be cleared, got %d", removed)
+	}
+}
+
+func TestOAuth2FlowLockPerKey(t *testing.T) {
+	store := NewOAuth2TokenStore("")
+	unlock, err := store.lockFlow(context.Background(), "collection-a")
+	if err != nil {
+		t.Fatalf("Failed to lock: %v", err)
+	}
+
+	// A login waiting in one collection does not hold up another
+	unlockOther, err := store.lockFlow(context.Background(), "collection-b")
+	if err != nil {
+		t.Fatalf("Expected another key to lock at once, got %v", err)
+	}
+	unlockOther()
+
+	// The same key waits, until the request gives up
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := store.lockFlow(ctx, "collection-a"); err != context.DeadlineExceeded {
+		t.Errorf("Expected the same key to wait until the context is done, got %v", err)
+	}
+
+	unlock()
+	if unlock, err := store.lockFlow(context.Background(), "collection-a"); err != nil {
+		t.Errorf("Expected the key to lock once released, got %v", err)
+	} else {
+		unlock()
+	}
+}
+
+// newOAuth2Server passes requests to /token to handleToken and echoes the
+// Authorization header of any other request
+func newOAuth2Server(t *testing.T, handleToken http.HandlerFunc) *httptest.Server {
+	return newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			r.ParseForm()
+			handleToken(w, r)
+			return
+		}
+		fmt.Fprint(w, r.Header.Get("Authorization"))
+	})
+}
+
+// oauth2Request is a request to serverURL authorized by an auth:oauth2 block
+// with the given values
+func oauth2Request(serverURL string, values map[string]string) *panels.BruRequest {
+	values["access_token_url"] = serverURL + "/token"
+	return &panels.BruRequest{
+		HTTP: panels.BruHTTP{Method: "GET", URL: serverURL + "/resource"},
+		Auth: panels.BruAuth{Type: "oauth2", Values: values},
+	}
+}
+
+// loopbackCallbackURL is a callback_url on a free loopback port
+func loopbackCallbackURL(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	defer listener.Close()
+	return "http://" + listener.Addr().String() + "/callback"
+}
+
+func TestOAuth2PasswordGrant(t *testing.T) {
+	server := newOAuth2Server(t, func(w http.ResponseWriter, r *http.Request) {
+		form := r.PostForm
+		if form.Get("grant_type") != "password" || form.Get("username") != "ada" || form.Get("password") != "hunter2" || form.Get("scope") != "read" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error": "invalid_grant", "error_description": "wrong credentials"}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token": "password-token", "token_type": "Bearer", "expires_in": 3600}`)
+	})
+
+	values := map[string]string{"grant_type": "password", "client_id": "kalo", "username": "{{user}}", "password": "{{password}}", "scope": "read"}
+	env := &Environment{Name: "test", Vars: map[string]string{"user": "ada", "password": "hunter2"}}
+	execution := executeBruRequest(context.Background(), NewHTTPClient(), NewScriptRunner(), oauth2Request(server.URL, values), env)
+	if execution.Response.Error != "" || execution.Response.Body != "Bearer password-token" {
+		t.Errorf("Expected the password grant token to be sent, got %q (%s)", execution.Response.Body, execution.Response.Error)
+	}
+
+	env.Vars["password"] = "wrong"
+	execution = executeBruRequest(context.Background(), NewHTTPClient(), NewScriptRunner(), oauth2Request(server.URL, values), env)
+	if !strings.Contains(execution.Response.Error, "OAuth2 password grant failed: invalid_grant: wrong credentials") {
+		t.Errorf("Expected the token endpoint's error, got %q", execution.Response.Error)
+	}
+}
+
+func TestOAuth2ClientAuthentication(t *testing.T) {
+	tests := []struct {
+		placement string
+		basic     bool
+	}{
+		{"", false},
+		{"body", false},
+		{"basic_auth_header", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.placement, func(t *testing.T) {
+			server := newOAuth2Server(t, func(w http.ResponseWriter, r *http.Request) {
+				user, password, basic := r.BasicAuth()
+				inBody := r.PostForm.Get("client_id") != "" || r.PostForm.Get("client_secret") != ""
+				if basic != tt.basic || inBody == tt.basic {
+					w.WriteHeader(http.StatusUnauthorized)
+					fmt.Fprint(w, `{"error": "invalid_client"}`)
+					return
+				}
+				// RFC 6749 form-encodes the credentials before base64
+				if basic && (user != "kalo+app" || password != "s3cret%26") {
+					t.Errorf("Expected the form-encoded client credentials in the header, got %q and %q", user, password)
+				}
+				if !basic && (r.PostForm.Get("client_id") != "kalo app" || r.PostForm.Get("client_secret") != "s3cret&") {
+					t.Errorf("Expected the client credentials in the body, got %v", r.PostForm)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, `{"access_token": "client-token"}`)
+			})
+
+			values := map[string]string{"grant_type": "client_credentials", "client_id": "kalo app", "client_secret": "s3cret&", "credentials_placement": tt.placement}
+			execution := executeBruRequest(context.Background(), NewHTTPClient(), NewScriptRunner(), oauth2Request(server.URL, values), nil)
+			if execution.Response.Body != "Bearer client-token" {
+				t.Errorf("Expected the client to authenticate, got %q (%s)", execution.Response.Body, execution.Response.Error)
+			}
+		})
+	}
+}
+
+func TestParseOAuth2TokenResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		token       string
+		err         string
+	}{
+		{"json", 200, "application/json", `{"access_token": "a", "expires_in": "60"}`, "a", ""},
+		{"form encoded", 200, "application/x-www-form-urlencoded; charset=utf-8", "access_token=b&token_type=bearer&expires_in=60&refresh_token=r", "b", ""},
+		{"form encoded error", 400, "application/x-www-form-urlencoded", "error=invalid_grant&error_description=expired+code", "", "invalid_grant: expired code"},
+		{"text error", 500, "text/plain", "down for maintenance", "", "token endpoint returned 500: down for maintenance"},
+		{"no token", 200, "application/json", `{"token_type": "Bearer"}`, "", "token response has no access_token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := parseOAuth2TokenResponse(tt.status, tt.contentType, []byte(tt.body))
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Errorf("Expected error %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil || token.AccessToken != tt.token {
+				t.Fatalf("Expected token %q, got %+v (%v)", tt.token, token, err)
+			}
+			if token.ExpiresAt.Before(time.Now().Add(50*time.Second)) || token.ExpiresAt.After(time.Now().Add(70*time.Second)) {
+				t.Errorf("Expected the token to expire in a minute, got %v", token.ExpiresAt)
+			}
+		})
+	}
+}
+
+func TestOAuth2AuthorizationCodePKCE(t *testing.T) {
+	callbackURL := loopbackCallbackURL(t)
+	var authorization url.Values
+	var callbackPage string
+	server := newOAuth2Server(t, func(w http.ResponseWriter, r *http.Request) {
+		form := r.PostForm
+		// The verifier must hash to the challenge sent to the authorization page
+		hash := sha256.Sum256([]byte(form.Get("code_verifier")))
+		if form.Get("grant_type") != "authorization_code" || form.Get("code") != "the-code" || form.Get("redirect_uri") != callbackURL ||
+			base64.RawURLEncoding.EncodeToString(hash[:]) != authorization.Get("code_challenge") {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error": "invalid_grant"}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token": "code-token", "refresh_token": "refresh"}`)
+	})
+
+	client := NewHTTPClient()
+	client.OpenBrowser = func(target string) error {
+		authURL, err := url.Parse(target)
+		if err != nil {
+			return err
+		}
+		authorization = authURL.Query()
+		// The browser follows the redirect back to the loopback listener
+		resp, err := http.Get(authorization.Get("redirect_uri") + "?code=the-code&state=" + url.QueryEscape(authorization.Get("state")))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		page, _ := io.ReadAll(resp.Body)
+		callbackPage = string(page)
+		return nil
+	}
+
+	values := map[string]string{"grant_type": "authorization_code", "authorization_url": server.URL + "/authorize", "callback_url": callbackURL, "client_id": "kalo", "scope": "read", "pkce": "true"}
+	execution := executeBruRequest(context.Background(), client, NewScriptRunner(), oauth2Request(server.URL, values), nil)
+	if execution.Response.Error != "" || execution.Response.Body != "Bearer code-token" {
+		t.Fatalf("Expected the code to be exchanged for a token, got %q (%s)", execution.Response.Body, execution.Response.Error)
+	}
+
+	expected := map[string]string{"response_type": "code", "client_id": "kalo", "redirect_uri": callbackURL, "scope": "read", "code_challenge_method": "S256"}
+	for key, value := range expected {
+		if authorization.Get(key) != value {
+			t.Errorf("Expected %s=%q in the authorization URL, got %q", key, value, authorization.Get(key))
+		}
+	}
+	if authorization.Get("state") == "" || len(authorization.Get("code_challenge")) != 43 {
+		t.Errorf("Expected a random state and an S256 challenge, got %v", authorization)
+	}
+	if !strings.Contains(callbackPage, "Authorization complete") {
+		t.Errorf("Expected the browser to be told the login completed, got %q", callbackPage)
+	}
+}
+
+func TestOAuth2AuthorizationCodeStateMismatch(t *testing.T) {
+	callbackURL := loopbackCallbackURL(t)
+	tokenRequested := false
+	server := newOAuth2Server(t, func(w http.ResponseWriter, r *http.Request) {
+		tokenRequested = true
+		fmt.Fprint(w, `{"access_token": "stolen"}`)
+	})
+
+	var callbackPage string
+	client := NewHTTPClient()
+	client.OpenBrowser = func(target string) error {
+		// A redirect that was not started by this login, e.g. a forged one
+		resp, err := http.Get(callbackURL + "?code=forged&state=wrong")
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		page, _ := io.ReadAll(resp.Body)
+		callbackPage = string(page)
+		return nil
+	}
+
+	values := map[string]string{"grant_type": "authorization_code", "authorization_url": server.URL + "/authorize", "callback_url": callbackURL, "client_id": "kalo", "state": "expected-state"}
+	execution := executeBruRequest(context.Background(), client, NewScriptRunner(), oauth2Request(server.URL, values), nil)
+	if !strings.Contains(execution.Response.Error, "state mismatch in authorization response") {
+		t.Errorf("Expected the redirect to be rejected, got %q", execution.Response.Error)
+	}
+	if tokenRequested {
+		t.Error("Expected no token request for a rejected redirect")
+	}
+	if !strings.Contains(callbackPage, "Authorization failed") {
+		t.Errorf("Expected the browser to be told the login failed, got %q", callbackPage)
+	}
+}
@@ -95,40 +95,15 @@ func (f *FilterManager) ApplyJQFilter(originalResponse string) tea.Cmd {
 	originalData := originalResponse
 
 	return func() tea.Msg {
-		// Parse the jq query
-		query, err := gojq.Parse(filter)
-		if err != nil {
-			return filterMsg{filterType: JQFilter, err: fmt.Errorf("jq parse error: %v", err)}
-		}
-
 		// Parse the JSON
 		var jsonData interface{}
 		if err := json.Unmarshal([]byte(originalData), &jsonData); err != nil {
 			return filterMsg{filterType: JQFilter, err: fmt.Errorf("JSON parse error: %v", err)}
 		}
 
-		// Apply the filter
-		iter := query.Run(jsonData)
-		var results []interface{}
-		for {
-			v, ok := iter.Next()
-			if !ok {
-				break
-			}
-			if err, ok := v.(error); ok {
-				return filterMsg{filterType: JQFilter, err: fmt.Errorf("jq filter error: %v", err)}
-			}
-			results = append(results, v)
-		}
-
-		// Format the result
-		var resultData interface{}
-		if len(results) == 0 {
-			resultData = nil
-		} else if len(results) == 1 {
-			resultData = results[0]
-		} else {
-			resultData = results
+		resultData, err := RunJQ(filter, jsonData)
+		if err != nil {
+			return filterMsg{filterType: JQFilter, err: err}
 		}
 
 		// Convert back to pretty JSON
@@ -511,4 +486,38 @@ func (f *FilterManager) Reset(filterType FilterType) {
 		f.LastCollectionsFilter = ""
 		f.OriginalCollections = nil
 	}
-}
\ No newline at end of file
+}
+
+// RunJQ applies a jq filter to decoded JSON data. A single result is returned
+// as-is, multiple results as a slice and no results as nil.
+func RunJQ(filter string, data interface{}) (interface{}, error) {
+	// Parse the jq query
+	query, err := gojq.Parse(filter)
+	if err != nil {
+		return nil, fmt.Errorf("jq parse error: %v", err)
+	}
+
+	// Apply the filter
+	iter := query.Run(data)
+	var results []interface{}
+	for {
+		v, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if err, ok := v.(error); ok {
+			return nil, fmt.Errorf("jq filter error: %v", err)
+		}
+		results = append(results, v)
+	}
+
+	// Format the result
+	switch len(results) {
+	case 0:
+		return nil, nil
+	case 1:
+		return results[0], nil
+	default:
+		return results, nil
+	}
+}
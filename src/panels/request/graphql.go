@@ -0,0 +1,294 @@
+package panels
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// GraphQLSchema is the part of an introspection result used for completion
+type GraphQLSchema struct {
+	QueryType        string
+	MutationType     string
+	SubscriptionType string
+	Types            map[string]*GraphQLType
+}
+
+// GraphQLType is an object, interface or input type with its fields
+type GraphQLType struct {
+	Name   string
+	Kind   string
+	Fields []GraphQLField
+}
+
+// GraphQLField is a field and the named type it returns. List and non-null
+// wrappers are dropped since completion only needs the underlying type.
+type GraphQLField struct {
+	Name string
+	Type string
+	Args []GraphQLArgument
+}
+
+type GraphQLArgument struct {
+	Name string
+	Type string
+}
+
+// graphQLTypeRef mirrors the nested `ofType` references of introspection results
+type graphQLTypeRef struct {
+	Kind   string          `json:"kind"`
+	Name   string          `json:"name"`
+	OfType *graphQLTypeRef `json:"ofType"`
+}
+
+func (t *graphQLTypeRef) namedType() string {
+	for ref := t; ref != nil; ref = ref.OfType {
+		if ref.Name != "" {
+			return ref.Name
+		}
+	}
+	return ""
+}
+
+type graphQLInputValue struct {
+	Name string         `json:"name"`
+	Type graphQLTypeRef `json:"type"`
+}
+
+// ParseGraphQLSchema reads the response of an introspection query
+func ParseGraphQLSchema(data []byte) (*GraphQLSchema, error) {
+	var result struct {
+		Data struct {
+			Schema *struct {
+				QueryType        *struct{ Name string } `json:"queryType"`
+				MutationType     *struct{ Name string } `json:"mutationType"`
+				SubscriptionType *struct{ Name string } `json:"subscriptionType"`
+				Types            []struct {
+					Kind   string `json:"kind"`
+					Name   string `json:"name"`
+					Fields []struct {
+						Name string              `json:"name"`
+						Args []graphQLInputValue `json:"args"`
+						Type graphQLTypeRef      `json:"type"`
+					} `json:"fields"`
+					InputFields []graphQLInputValue `json:"inputFields"`
+				} `json:"types"`
+			} `json:"__schema"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("invalid introspection response: %v", err)
+	}
+	if len(result.Errors) > 0 {
+		return nil, fmt.Errorf("introspection failed: %s", result.Errors[0].Message)
+	}
+	if result.Data.Schema == nil {
+		return nil, fmt.Errorf("introspection response has no schema")
+	}
+
+	raw := result.Data.Schema
+	schema := &GraphQLSchema{Types: make(map[string]*GraphQLType)}
+	if raw.QueryType != nil {
+		schema.QueryType = raw.QueryType.Name
+	}
+	if raw.MutationType != nil {
+		schema.MutationType = raw.MutationType.Name
+	}
+	if raw.SubscriptionType != nil {
+		schema.SubscriptionType = raw.SubscriptionType.Name
+	}
+
+	for _, rawType := range raw.Types {
+		graphQLType := &GraphQLType{Name: rawType.Name, Kind: rawType.Kind}
+		for _, rawField := range rawType.Fields {
+			field := GraphQLField{Name: rawField.Name, Type: rawField.Type.namedType()}
+			for _, arg := range rawField.Args {
+				field.Args = append(field.Args, GraphQLArgument{Name: arg.Name, Type: arg.Type.namedType()})
+			}
+			graphQLType.Fields = append(graphQLType.Fields, field)
+		}
+		for _, inputField := range rawType.InputFields {
+			graphQLType.Fields = append(graphQLType.Fields, GraphQLField{Name: inputField.Name, Type: inputField.Type.namedType()})
+		}
+		schema.Types[rawType.Name] = graphQLType
+	}
+
+	return schema, nil
+}
+
+// Field looks up a field of a type
+func (s *GraphQLSchema) Field(typeName, fieldName string) *GraphQLField {
+	graphQLType := s.Types[typeName]
+	if graphQLType == nil {
+		return nil
+	}
+	for i := range graphQLType.Fields {
+		if graphQLType.Fields[i].Name == fieldName {
+			return &graphQLType.Fields[i]
+		}
+	}
+	return nil
+}
+
+// GraphQLSuggestions returns completions for the word being typed at offset
+// in a query: fields of the enclosing selection set, or arguments when the
+// cursor is inside a field's parentheses
+func GraphQLSuggestions(schema *GraphQLSchema, query string, offset int) []string {
+	if schema == nil || offset < 0 || offset > len(query) {
+		return nil
+	}
+
+	prefix := GraphQLWordBefore(query, offset)
+	context := scanGraphQLContext(schema, query[:offset-len(prefix)])
+
+	var candidates []string
+	switch {
+	case context.inArgs && context.argsField != nil && !context.inValue:
+		for _, arg := range context.argsField.Args {
+			candidates = append(candidates, arg.Name)
+		}
+	case context.inArgs:
+		return nil
+	case len(context.types) == 0:
+		candidates = []string{"query", "mutation", "subscription", "fragment"}
+	default:
+		if graphQLType := schema.Types[context.types[len(context.types)-1]]; graphQLType != nil {
+			for _, field := range graphQLType.Fields {
+				candidates = append(candidates, field.Name)
+			}
+		}
+		candidates = append(candidates, "__typename")
+	}
+
+	var filtered []string
+	lowerPrefix := strings.ToLower(prefix)
+	for _, candidate := range candidates {
+		if strings.HasPrefix(strings.ToLower(candidate), lowerPrefix) && candidate != prefix {
+			filtered = append(filtered, candidate)
+		}
+	}
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return strings.HasPrefix(filtered[i], prefix) && !strings.HasPrefix(filtered[j], prefix)
+	})
+	return filtered
+}
+
+// GraphQLWordBefore returns the partial name that ends at offset
+func GraphQLWordBefore(query string, offset int) string {
+	start := offset
+	for start > 0 && isGraphQLNameChar(query[start-1]) {
+		start--
+	}
+	return query[start:offset]
+}
+
+func isGraphQLNameChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// graphQLContext describes where in a document the cursor is
+type graphQLContext struct {
+	types     []string // Selection set types, innermost last
+	inArgs    bool
+	inValue   bool // After the ":" of an argument
+	argsField *GraphQLField
+}
+
+// scanGraphQLContext walks the text before the cursor, tracking selection
+// sets, the last selected field and whether an argument list is open
+func scanGraphQLContext(schema *GraphQLSchema, text string) graphQLContext {
+	var context graphQLContext
+	operation := schema.QueryType
+	lastField := ""
+	typeCondition := ""
+	expectTypeCondition := false
+	parenDepth := 0
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '#':
+			for i < len(text) && text[i] != '\n' {
+				i++
+			}
+		case c == '"':
+			for i++; i < len(text) && text[i] != '"'; i++ {
+				if text[i] == '\\' {
+					i++
+				}
+			}
+		case c == '(':
+			parenDepth++
+			if parenDepth == 1 {
+				context.argsField = nil
+				if len(context.types) > 0 && lastField != "" {
+					context.argsField = schema.Field(context.types[len(context.types)-1], lastField)
+				}
+			}
+			context.inValue = false
+		case c == ')':
+			if parenDepth > 0 {
+				parenDepth--
+			}
+			context.inValue = false
+		case c == ':' && parenDepth > 0:
+			context.inValue = true
+		case c == ',' && parenDepth > 0:
+			context.inValue = false
+		case c == '{' && parenDepth == 0:
+			switch {
+			case typeCondition != "":
+				context.types = append(context.types, typeCondition)
+			case len(context.types) == 0:
+				context.types = append(context.types, operation)
+			default:
+				parent := context.types[len(context.types)-1]
+				next := ""
+				if field := schema.Field(parent, lastField); field != nil {
+					next = field.Type
+				}
+				context.types = append(context.types, next)
+			}
+			typeCondition = ""
+			lastField = ""
+		case c == '}' && parenDepth == 0:
+			if len(context.types) > 0 {
+				context.types = context.types[:len(context.types)-1]
+			}
+			lastField = ""
+		case isGraphQLNameChar(c):
+			start := i
+			for i+1 < len(text) && isGraphQLNameChar(text[i+1]) {
+				i++
+			}
+			word := text[start : i+1]
+			if parenDepth > 0 {
+				// A value ends the argument, the next name is another argument
+				context.inValue = false
+				continue
+			}
+			switch {
+			case expectTypeCondition:
+				typeCondition = word
+				expectTypeCondition = false
+			case word == "on":
+				expectTypeCondition = true
+			case len(context.types) == 0 && word == "mutation":
+				operation = schema.MutationType
+			case len(context.types) == 0 && word == "subscription":
+				operation = schema.SubscriptionType
+			case len(context.types) == 0 && word == "query":
+				operation = schema.QueryType
+			default:
+				lastField = word
+			}
+		}
+	}
+
+	context.inArgs = parenDepth > 0
+	return context
+}
@@ -0,0 +1,47 @@
+package panels
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGraphQLSuggestions(t *testing.T) {
+	introspection := `{"data": {"__schema": {
+		"queryType": {"name": "Query"},
+		"types": [
+			{"kind": "OBJECT", "name": "Query", "fields": [
+				{"name": "user", "args": [{"name": "id", "type": {"kind": "NON_NULL", "ofType": {"kind": "SCALAR", "name": "ID"}}}],
+				 "type": {"kind": "OBJECT", "name": "User"}},
+				{"name": "users", "args": [], "type": {"kind": "LIST", "ofType": {"kind": "OBJECT", "name": "User"}}}
+			]},
+			{"kind": "OBJECT", "name": "User", "fields": [
+				{"name": "id", "args": [], "type": {"kind": "SCALAR", "name": "ID"}},
+				{"name": "name", "args": [], "type": {"kind": "SCALAR", "name": "String"}},
+				{"name": "nickname", "args": [], "type": {"kind": "SCALAR", "name": "String"}}
+			]}
+		]
+	}}}`
+
+	schema, err := ParseGraphQLSchema([]byte(introspection))
+	if err != nil {
+		t.Fatalf("Failed to parse introspection result: %v", err)
+	}
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"{ us", []string{"user", "users"}},
+		{"query { user(i", []string{"id"}},
+		{"query { user(id: 1) { n", []string{"name", "nickname"}},
+		{"{ users { id } user { na", []string{"name"}},
+		{"{ user(id: i", nil},
+	}
+
+	for _, test := range tests {
+		suggestions := GraphQLSuggestions(schema, test.query, len(test.query))
+		if strings.Join(suggestions, ",") != strings.Join(test.expected, ",") {
+			t.Errorf("Suggestions for %q: expected %v, got %v", test.query, test.expected, suggestions)
+		}
+	}
+}
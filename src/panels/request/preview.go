@@ -0,0 +1,93 @@
+package panels
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// RequestPreview is the current request resolved the way it will be sent,
+// shown in the Preview tab
+type RequestPreview struct {
+	Method     string
+	URL        string
+	Headers    BruParams
+	Body       string
+	Variables  []PreviewVariable
+	Unresolved []string // Placeholders that are sent as written, e.g. {{token}}
+	Blocked    bool     // Sending is refused while placeholders are unresolved
+	Notes      []string // Parts of sending the preview cannot show, e.g. scripts
+	Error      string   // Why the request cannot be built
+}
+
+// PreviewVariable is a placeholder used by the request and where its value
+// comes from
+type PreviewVariable struct {
+	Name    string
+	Value   string
+	Source  string // e.g. "environment staging" or "runtime", empty when unresolved
+	Example bool   // Value of a dynamic variable, generated again when sending
+}
+
+// renderPreviewContent lists the resolved request, highlighting placeholders
+// that stay unresolved
+func renderPreviewContent(preview *RequestPreview, sectionStyle lipgloss.Style) string {
+	if preview == nil {
+		return "  Nothing to preview"
+	}
+
+	errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("1")).Bold(true)
+	highlight := func(text string) string {
+		for _, placeholder := range preview.Unresolved {
+			// The URL is sent with braces escaped
+			escaped := strings.NewReplacer("{", "%7B", "}", "%7D").Replace(placeholder)
+			text = strings.ReplaceAll(text, placeholder, errorStyle.Render(placeholder))
+			text = strings.ReplaceAll(text, escaped, errorStyle.Render(escaped))
+		}
+		return text
+	}
+
+	var lines []string
+	if len(preview.Unresolved) > 0 {
+		warning := fmt.Sprintf("  ⚠ Unresolved: %s", strings.Join(preview.Unresolved, ", "))
+		if preview.Blocked {
+			warning += " (sending is blocked)"
+		}
+		lines = append(lines, errorStyle.Render(warning), "")
+	}
+	if preview.Error != "" {
+		lines = append(lines, errorStyle.Render("  ✗ "+preview.Error), "")
+	} else {
+		lines = append(lines, highlight(fmt.Sprintf("  %s %s", preview.Method, preview.URL)))
+		for _, header := range preview.Headers {
+			lines = append(lines, highlight(fmt.Sprintf("  %s: %s", header.Key, header.Value)))
+		}
+		if preview.Body != "" {
+			lines = append(lines, "")
+			for _, line := range strings.Split(preview.Body, "\n") {
+				lines = append(lines, highlight("  "+line))
+			}
+		}
+		lines = append(lines, "")
+	}
+
+	if len(preview.Variables) > 0 {
+		lines = append(lines, sectionStyle.Render("  Variables"))
+		for _, variable := range preview.Variables {
+			if variable.Source == "" {
+				lines = append(lines, errorStyle.Render(fmt.Sprintf("  {{%s}} is not defined", variable.Name)))
+				continue
+			}
+			if variable.Example {
+				lines = append(lines, fmt.Sprintf("  {{%s}} = %s (example, %s)", variable.Name, variable.Value, variable.Source))
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("  {{%s}} = %s (%s)", variable.Name, variable.Value, variable.Source))
+		}
+	}
+	for _, note := range preview.Notes {
+		lines = append(lines, lipgloss.NewStyle().Faint(true).Render("  "+note))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -3,10 +3,10 @@ package panels
 import (
 	"encoding/json"
 	"fmt"
-	"sort"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
+	"kalo/src/bru"
 )
 
 // renderTextCursor renders a solid colored cursor at the specified position in text
@@ -37,6 +37,8 @@ const (
 	BodySection
 	HeadersSection
 	AuthSection
+	AssertSection
+	PreviewSection
 )
 
 type QueryEditMode int
@@ -89,6 +91,59 @@ type HeaderEditState struct {
 	PendingDeletion   int // -1 means no pending deletion
 }
 
+type AssertEditMode int
+
+const (
+	AssertViewMode AssertEditMode = iota
+	AssertEditKeyMode
+	AssertEditValueMode
+	AssertAddMode
+)
+
+// AssertParameter is an assertion as edited in the request panel. A key
+// prefixed with "~" marks a disabled assertion, as in .bru files.
+type AssertParameter struct {
+	Key   string
+	Value string
+}
+
+type AssertEditState struct {
+	Mode              AssertEditMode
+	SelectedIndex     int
+	EditingKey        string
+	EditingValue      string
+	CursorPos         int
+	Parameters        []AssertParameter
+	OriginalKey       string
+	PendingDeletion   int // -1 means no pending deletion
+}
+
+type FormEditMode int
+
+const (
+	FormViewMode FormEditMode = iota
+	FormEditKeyMode
+	FormEditValueMode
+	FormAddMode
+)
+
+// FormParameter is a form body field as edited in the request panel. A key
+// prefixed with "~" marks a disabled field, as in .bru files.
+type FormParameter struct {
+	Key   string
+	Value string
+}
+
+type FormEditState struct {
+	Mode              FormEditMode
+	SelectedIndex     int
+	EditingKey        string
+	EditingValue      string
+	CursorPos         int
+	Parameters        []FormParameter
+	PendingDeletion   int // -1 means no pending deletion
+}
+
 type AuthEditMode int
 
 const (
@@ -97,6 +152,7 @@ const (
 	AuthBearerEditMode
 	AuthBasicUsernameEditMode
 	AuthBasicPasswordEditMode
+	AuthOAuth2EditMode
 )
 
 type AuthEditState struct {
@@ -106,9 +162,67 @@ type AuthEditState struct {
 	BearerToken       string
 	BasicUsername     string
 	BasicPassword     string
+	OAuth2Values      map[string]string // auth:oauth2 entries, including ones not shown in the editor
 	CursorPos         int
 }
 
+// OAuth2GrantTypes lists the grants supported by auth:oauth2
+var OAuth2GrantTypes = []string{"client_credentials", "password", "authorization_code"}
+
+// OAuth2Fields returns the auth:oauth2 keys edited for a grant type, in display order
+func OAuth2Fields(grantType string) []string {
+	switch grantType {
+	case "password":
+		return []string{"grant_type", "access_token_url", "username", "password", "client_id", "client_secret", "scope"}
+	case "authorization_code":
+		return []string{"grant_type", "authorization_url", "access_token_url", "callback_url", "client_id", "client_secret", "scope", "pkce"}
+	default:
+		return []string{"grant_type", "access_token_url", "client_id", "client_secret", "scope"}
+	}
+}
+
+// oauth2FieldLabels are the display names of auth:oauth2 keys
+var oauth2FieldLabels = map[string]string{
+	"grant_type":        "Grant Type",
+	"access_token_url":  "Access Token URL",
+	"authorization_url": "Authorization URL",
+	"callback_url":      "Callback URL",
+	"client_id":         "Client ID",
+	"client_secret":     "Client Secret",
+	"scope":             "Scope",
+	"username":          "Username",
+	"password":          "Password",
+	"pkce":              "PKCE",
+}
+
+// OAuth2GrantType returns the selected grant, defaulting to client_credentials
+func (e *AuthEditState) OAuth2GrantType() string {
+	if grantType := e.OAuth2Values["grant_type"]; grantType != "" {
+		return grantType
+	}
+	return "client_credentials"
+}
+
+// OAuth2FieldKey returns the auth:oauth2 key of the selected field
+func (e *AuthEditState) OAuth2FieldKey() string {
+	fields := OAuth2Fields(e.OAuth2GrantType())
+	if e.SelectedField < 1 || e.SelectedField > len(fields) {
+		return ""
+	}
+	return fields[e.SelectedField-1]
+}
+
+// CycleOAuth2GrantType switches to the next grant in OAuth2GrantTypes
+func (e *AuthEditState) CycleOAuth2GrantType() {
+	next := OAuth2GrantTypes[0]
+	for i, grantType := range OAuth2GrantTypes {
+		if grantType == e.OAuth2GrantType() && i+1 < len(OAuth2GrantTypes) {
+			next = OAuth2GrantTypes[i+1]
+		}
+	}
+	e.OAuth2Values["grant_type"] = next
+}
+
 type BodyEditMode int
 
 const (
@@ -124,25 +238,42 @@ type BodyEditState struct {
 	ScrollOffset      int
 	ValidationError   string
 	BracketMatches    map[int]int // line -> matching bracket line
+	
+	// GraphQL bodies edit either the query or its variables
+	EditingVars        bool
+	Suggestions        []string
+	SelectedSuggestion int
 }
 
 type BruRequest struct {
 	Meta    BruMeta           `json:"meta"`
 	HTTP    BruHTTP           `json:"http"`
-	Headers map[string]string `json:"headers,omitempty"`
-	Query   map[string]string `json:"query,omitempty"`
+	Headers BruParams         `json:"headers,omitempty"`
+	Query   BruParams         `json:"query,omitempty"`
 	Body    BruBody           `json:"body,omitempty"`
 	Auth    BruAuth           `json:"auth,omitempty"`
 	Vars    map[string]string `json:"vars,omitempty"`
+	Assertions []BruAssertion `json:"assertions,omitempty"`
+	Script  BruScript         `json:"script,omitempty"`
 	Tests   string            `json:"tests,omitempty"`
 	Docs    string            `json:"docs,omitempty"`
 	Tags    []string          `json:"tags,omitempty"`
+	Settings map[string]string `json:"settings,omitempty"` // e.g. timeout in milliseconds
+	
+	// Dir is the directory of the .bru file, used to resolve relative @file paths
+	Dir string `json:"-"`
+	
+	// Document is the parsed .bru file, used to write changes back without
+	// losing blocks, comments or formatting. Nil for requests not read from disk.
+	Document *bru.Document `json:"-"`
 	
 	// Edit state for interactive editing
 	QueryEditState  *QueryEditState  `json:"-"`
 	HeaderEditState *HeaderEditState `json:"-"`
 	AuthEditState   *AuthEditState   `json:"-"`
 	BodyEditState   *BodyEditState   `json:"-"`
+	AssertEditState *AssertEditState `json:"-"`
+	FormEditState   *FormEditState   `json:"-"`
 }
 
 type BruMeta struct {
@@ -157,31 +288,237 @@ type BruHTTP struct {
 }
 
 type BruBody struct {
-	Type string `json:"type"`
-	Data string `json:"data"`
+	Type   string         `json:"type"`
+	Data   string         `json:"data"`
+	Fields []BruFormField `json:"fields,omitempty"` // form-urlencoded and multipart-form bodies
+	Vars   string         `json:"vars,omitempty"`   // JSON variables of graphql bodies
+}
+
+// BruFormField is an entry of a body:form-urlencoded or body:multipart-form
+// block. Multipart values written as `@file(path)` upload files; several
+// files can be separated with "|".
+type BruFormField struct {
+	Key     string `json:"key"`
+	Value   string `json:"value"`
+	Enabled bool   `json:"enabled"`
+}
+
+// BruParam is an enabled entry of a headers or query block
+type BruParam struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// BruParams keeps headers or query parameters in file order. A key may
+// appear more than once, e.g. several Accept headers or repeated `tag`
+// query parameters.
+type BruParams []BruParam
+
+// Get returns the first value of key
+func (p BruParams) Get(key string) (string, bool) {
+	for _, param := range p {
+		if param.Key == key {
+			return param.Value, true
+		}
+	}
+	return "", false
+}
+
+// Values returns every value of key in order
+func (p BruParams) Values(key string) []string {
+	var values []string
+	for _, param := range p {
+		if param.Key == key {
+			values = append(values, param.Value)
+		}
+	}
+	return values
+}
+
+// Set replaces the values of key with value, keeping the position of its
+// first entry or appending it
+func (p *BruParams) Set(key, value string) {
+	params := (*p)[:0:0]
+	found := false
+	for _, param := range *p {
+		if param.Key == key {
+			if found {
+				continue
+			}
+			found = true
+			param.Value = value
+		}
+		params = append(params, param)
+	}
+	if !found {
+		params = append(params, BruParam{Key: key, Value: value})
+	}
+	*p = params
 }
 
+// Add appends an entry, keeping any earlier values of key
+func (p *BruParams) Add(key, value string) {
+	*p = append(*p, BruParam{Key: key, Value: value})
+}
+
+// Delete removes every entry of key
+func (p *BruParams) Delete(key string) {
+	params := (*p)[:0:0]
+	for _, param := range *p {
+		if param.Key != key {
+			params = append(params, param)
+		}
+	}
+	*p = params
+}
+
+// Map returns the entries as a map. Later duplicates win.
+func (p BruParams) Map() map[string]string {
+	values := make(map[string]string, len(p))
+	for _, param := range p {
+		values[param.Key] = param.Value
+	}
+	return values
+}
+
+// IsFormBody reports whether a body type is edited and sent as form fields
+func IsFormBody(bodyType string) bool {
+	return bodyType == "form-urlencoded" || bodyType == "multipart-form"
+}
+
+// FilePaths returns the paths of an `@file(...)` value
+func (f BruFormField) FilePaths() ([]string, bool) {
+	value := strings.TrimSpace(f.Value)
+	if !strings.HasPrefix(value, "@file(") || !strings.HasSuffix(value, ")") {
+		return nil, false
+	}
+
+	var paths []string
+	for _, path := range strings.Split(value[len("@file("):len(value)-1], "|") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths, true
+}
+
+// BodyTypes lists the body modes that can be selected in the request panel
+var BodyTypes = []string{"json", "text", "xml", "form-urlencoded", "multipart-form", "graphql"}
+
 type BruAuth struct {
 	Type   string            `json:"type"`
 	Values map[string]string `json:"values,omitempty"`
 }
 
+// BruAssertion is a single entry of an assert block, e.g. `res.status: eq 200`
+type BruAssertion struct {
+	Expression string `json:"expression"`
+	Operator   string `json:"operator"`
+	Value      string `json:"value,omitempty"`
+	Enabled    bool   `json:"enabled"`
+}
+
+// AssertionOperators lists the operators supported in assert blocks
+var AssertionOperators = []string{
+	"eq", "neq", "gt", "gte", "lt", "lte", "in", "notIn",
+	"contains", "notContains", "length", "matches", "notMatches",
+	"startsWith", "endsWith", "between", "isEmpty", "isNotEmpty",
+	"isNull", "isUndefined", "isDefined", "isTruthy", "isFalsy",
+	"isJson", "isNumber", "isString", "isBoolean", "isArray",
+}
+
+// ParseAssertion builds an assertion from an assert block entry. A rule that
+// does not start with a known operator is treated as an equality check.
+func ParseAssertion(expression, rule string, enabled bool) BruAssertion {
+	assertion := BruAssertion{
+		Expression: strings.TrimSpace(expression),
+		Operator:   "eq",
+		Value:      strings.TrimSpace(rule),
+		Enabled:    enabled,
+	}
+
+	fields := strings.SplitN(assertion.Value, " ", 2)
+	for _, operator := range AssertionOperators {
+		if fields[0] == operator {
+			assertion.Operator = operator
+			assertion.Value = ""
+			if len(fields) > 1 {
+				assertion.Value = strings.TrimSpace(fields[1])
+			}
+			break
+		}
+	}
+
+	return assertion
+}
+
+// Rule returns the operator and value as written in an assert block
+func (a BruAssertion) Rule() string {
+	if a.Value == "" {
+		return a.Operator
+	}
+	return a.Operator + " " + a.Value
+}
+
+// BruScript holds the script:pre-request and script:post-response blocks
+type BruScript struct {
+	PreRequest   string `json:"pre_request,omitempty"`
+	PostResponse string `json:"post_response,omitempty"`
+}
+
+// IsWebSocket reports whether the request opens a WebSocket connection
+func (r *BruRequest) IsWebSocket() bool {
+	return r.Meta.Type == "ws" || r.HTTP.Method == "WS"
+}
+
+// Clone returns a copy of the request that can be modified by scripts
+// without affecting the loaded request. Edit state is not copied.
+func (r *BruRequest) Clone() *BruRequest {
+	clone := &BruRequest{
+		Meta:   r.Meta,
+		HTTP:   r.HTTP,
+		Body:   BruBody{Type: r.Body.Type, Data: r.Body.Data, Fields: append([]BruFormField(nil), r.Body.Fields...), Vars: r.Body.Vars},
+		Dir:    r.Dir,
+		Auth:   BruAuth{Type: r.Auth.Type, Values: copyStringMap(r.Auth.Values)},
+		Script: r.Script,
+		Assertions: append([]BruAssertion(nil), r.Assertions...),
+		Tests:  r.Tests,
+		Docs:   r.Docs,
+	}
+	clone.Headers = append(BruParams(nil), r.Headers...)
+	clone.Query = append(BruParams(nil), r.Query...)
+	clone.Vars = copyStringMap(r.Vars)
+	clone.Settings = copyStringMap(r.Settings)
+	if r.Document != nil {
+		clone.Document = r.Document.Clone()
+	}
+	if r.Tags != nil {
+		clone.Tags = make([]string, len(r.Tags))
+		copy(clone.Tags, r.Tags)
+	}
+	return clone
+}
+
+func copyStringMap(values map[string]string) map[string]string {
+	copied := make(map[string]string, len(values))
+	for key, value := range values {
+		copied[key] = value
+	}
+	return copied
+}
+
 // InitializeQueryEditState initializes the query edit state for a request
 func (r *BruRequest) InitializeQueryEditState() {
 	if r.QueryEditState != nil {
 		return
 	}
 	
+	// Keep file order, a parameter may be repeated
 	var params []QueryParameter
-	for key, value := range r.Query {
-		params = append(params, QueryParameter{Key: key, Value: value})
+	for _, param := range r.Query {
+		params = append(params, QueryParameter{Key: param.Key, Value: param.Value})
 	}
 	
-	// Sort parameters for consistent display
-	sort.Slice(params, func(i, j int) bool {
-		return params[i].Key < params[j].Key
-	})
-	
 	r.QueryEditState = &QueryEditState{
 		Mode:            QueryViewMode,
 		SelectedIndex:   0,
@@ -190,16 +527,16 @@ func (r *BruRequest) InitializeQueryEditState() {
 	}
 }
 
-// SyncQueryToMap updates the Query map from the edit state
+// SyncQueryToMap updates the Query parameters from the edit state
 func (r *BruRequest) SyncQueryToMap() {
 	if r.QueryEditState == nil {
 		return
 	}
 	
-	r.Query = make(map[string]string)
+	r.Query = nil
 	for _, param := range r.QueryEditState.Parameters {
 		if param.Key != "" {
-			r.Query[param.Key] = param.Value
+			r.Query.Add(param.Key, param.Value)
 		}
 	}
 }
@@ -254,16 +591,12 @@ func (r *BruRequest) InitializeHeaderEditState() {
 		return
 	}
 	
+	// Keep file order, a header may be repeated
 	var params []HeaderParameter
-	for key, value := range r.Headers {
-		params = append(params, HeaderParameter{Key: key, Value: value})
+	for _, param := range r.Headers {
+		params = append(params, HeaderParameter{Key: param.Key, Value: param.Value})
 	}
 	
-	// Sort parameters for consistent display
-	sort.Slice(params, func(i, j int) bool {
-		return params[i].Key < params[j].Key
-	})
-	
 	r.HeaderEditState = &HeaderEditState{
 		Mode:            HeaderViewMode,
 		SelectedIndex:   0,
@@ -272,16 +605,16 @@ func (r *BruRequest) InitializeHeaderEditState() {
 	}
 }
 
-// SyncHeadersToMap updates the Headers map from the edit state
+// SyncHeadersToMap updates the Headers from the edit state
 func (r *BruRequest) SyncHeadersToMap() {
 	if r.HeaderEditState == nil {
 		return
 	}
 	
-	r.Headers = make(map[string]string)
+	r.Headers = nil
 	for _, param := range r.HeaderEditState.Parameters {
 		if param.Key != "" {
-			r.Headers[param.Key] = param.Value
+			r.Headers.Add(param.Key, param.Value)
 		}
 	}
 }
@@ -329,6 +662,109 @@ func (r *BruRequest) DeleteHeader(index int) {
 	r.SyncHeadersToMap()
 }
 
+// InitializeAssertEditState initializes the assertion edit state for a request
+func (r *BruRequest) InitializeAssertEditState() {
+	if r.AssertEditState != nil {
+		return
+	}
+	
+	// Keep file order, assertions are evaluated top to bottom
+	var params []AssertParameter
+	for _, assertion := range r.Assertions {
+		key := assertion.Expression
+		if !assertion.Enabled {
+			key = "~" + key
+		}
+		params = append(params, AssertParameter{Key: key, Value: assertion.Rule()})
+	}
+	
+	r.AssertEditState = &AssertEditState{
+		Mode:            AssertViewMode,
+		SelectedIndex:   0,
+		Parameters:      params,
+		PendingDeletion: -1,
+	}
+}
+
+// SyncAssertionsToRequest updates the Assertions from the edit state
+func (r *BruRequest) SyncAssertionsToRequest() {
+	if r.AssertEditState == nil {
+		return
+	}
+	
+	r.Assertions = nil
+	for _, param := range r.AssertEditState.Parameters {
+		key := strings.TrimSpace(param.Key)
+		enabled := !strings.HasPrefix(key, "~")
+		key = strings.TrimPrefix(key, "~")
+		if key != "" {
+			r.Assertions = append(r.Assertions, ParseAssertion(key, param.Value, enabled))
+		}
+	}
+}
+
+// InitializeFormEditState initializes the form field edit state for a request
+func (r *BruRequest) InitializeFormEditState() {
+	if r.FormEditState != nil {
+		return
+	}
+	
+	// Keep file order, duplicate keys are allowed in forms
+	var params []FormParameter
+	for _, field := range r.Body.Fields {
+		key := field.Key
+		if !field.Enabled {
+			key = "~" + key
+		}
+		params = append(params, FormParameter{Key: key, Value: field.Value})
+	}
+	
+	r.FormEditState = &FormEditState{
+		Mode:            FormViewMode,
+		SelectedIndex:   0,
+		Parameters:      params,
+		PendingDeletion: -1,
+	}
+}
+
+// SyncFormToRequest updates the body Fields from the edit state
+func (r *BruRequest) SyncFormToRequest() {
+	if r.FormEditState == nil {
+		return
+	}
+	
+	r.Body.Fields = nil
+	for _, param := range r.FormEditState.Parameters {
+		key := strings.TrimSpace(param.Key)
+		enabled := !strings.HasPrefix(key, "~")
+		key = strings.TrimPrefix(key, "~")
+		if key != "" {
+			r.Body.Fields = append(r.Body.Fields, BruFormField{Key: key, Value: param.Value, Enabled: enabled})
+		}
+	}
+}
+
+// CycleBodyType switches the body to the next mode in BodyTypes. Text content
+// and form fields are kept so switching back does not lose them.
+func (r *BruRequest) CycleBodyType() {
+	next := BodyTypes[0]
+	for i, bodyType := range BodyTypes {
+		if bodyType == r.Body.Type && i+1 < len(BodyTypes) {
+			next = BodyTypes[i+1]
+		}
+	}
+	r.Body.Type = next
+	r.BodyEditState = nil
+	r.FormEditState = nil
+	
+	// Bruno marks GraphQL requests in the meta block
+	if next == "graphql" {
+		r.Meta.Type = "graphql"
+	} else if r.Meta.Type == "graphql" {
+		r.Meta.Type = "http"
+	}
+}
+
 // InitializeAuthEditState initializes the auth edit state for a request
 func (r *BruRequest) InitializeAuthEditState() {
 	if r.AuthEditState != nil {
@@ -356,6 +792,14 @@ func (r *BruRequest) InitializeAuthEditState() {
 		}
 	}
 	
+	oauth2Values := make(map[string]string)
+	if r.Auth.Type == "oauth2" {
+		authType = "oauth2"
+		for key, value := range r.Auth.Values {
+			oauth2Values[key] = value
+		}
+	}
+	
 	r.AuthEditState = &AuthEditState{
 		Mode:          AuthViewMode,
 		SelectedField: 0,
@@ -363,6 +807,7 @@ func (r *BruRequest) InitializeAuthEditState() {
 		BearerToken:   bearerToken,
 		BasicUsername: basicUsername,
 		BasicPassword: basicPassword,
+		OAuth2Values:  oauth2Values,
 		CursorPos:     0,
 	}
 }
@@ -390,6 +835,13 @@ func (r *BruRequest) SyncAuthToRequest() {
 		}
 		r.Auth.Values["username"] = r.AuthEditState.BasicUsername
 		r.Auth.Values["password"] = r.AuthEditState.BasicPassword
+	case "oauth2":
+		r.Auth.Type = "oauth2"
+		r.Auth.Values = make(map[string]string)
+		for key, value := range r.AuthEditState.OAuth2Values {
+			r.Auth.Values[key] = value
+		}
+		r.Auth.Values["grant_type"] = r.AuthEditState.OAuth2GrantType()
 	}
 }
 
@@ -415,7 +867,7 @@ func (r *BruRequest) InitializeBodyEditState() {
 	}
 	
 	// Validate JSON if body type is JSON
-	if r.Body.Type == "json" {
+	if r.BodyEditIsJSON() {
 		r.ValidateBodyJSON()
 	}
 }
@@ -426,14 +878,50 @@ func (r *BruRequest) SyncBodyToRequest() {
 		return
 	}
 	
-	r.Body.Data = r.BodyEditState.Content
+	if r.BodyEditState.EditingVars {
+		r.Body.Vars = r.BodyEditState.Content
+	} else {
+		r.Body.Data = r.BodyEditState.Content
+	}
 	
 	// Validate JSON if body type is JSON
-	if r.Body.Type == "json" {
+	if r.BodyEditIsJSON() {
 		r.ValidateBodyJSON()
 	}
 }
 
+// ToggleGraphQLVars switches the body editor between the GraphQL query and
+// its variables, keeping the text of the part being left
+func (r *BruRequest) ToggleGraphQLVars() {
+	r.InitializeBodyEditState()
+	r.SyncBodyToRequest()
+	
+	editState := r.BodyEditState
+	editState.EditingVars = !editState.EditingVars
+	if editState.EditingVars {
+		editState.Content = r.Body.Vars
+	} else {
+		editState.Content = r.Body.Data
+	}
+	editState.CursorLine = 0
+	editState.CursorCol = 0
+	editState.ScrollOffset = 0
+	editState.Suggestions = nil
+	editState.SelectedSuggestion = 0
+	editState.ValidationError = ""
+	if r.BodyEditIsJSON() {
+		r.ValidateBodyJSON()
+	}
+}
+
+// BodyEditIsJSON reports whether the text in the body editor is JSON
+func (r *BruRequest) BodyEditIsJSON() bool {
+	if r.Body.Type == "graphql" {
+		return r.BodyEditState != nil && r.BodyEditState.EditingVars
+	}
+	return r.Body.Type == "json"
+}
+
 // ValidateBodyJSON validates the JSON content and updates validation error
 func (r *BruRequest) ValidateBodyJSON() {
 	if r.BodyEditState == nil {
@@ -455,7 +943,7 @@ func (r *BruRequest) ValidateBodyJSON() {
 }
 
 func GetRequestTabNames() []string {
-	return []string{"Query Parameters", "Request Body", "Headers", "Authorization"}
+	return []string{"Query Parameters", "Request Body", "Headers", "Authorization", "Assertions", "Preview"}
 }
 
 func GetRequestTabSection(tabIndex int) RequestSection {
@@ -468,6 +956,10 @@ func GetRequestTabSection(tabIndex int) RequestSection {
 		return HeadersSection
 	case 3:
 		return AuthSection
+	case 4:
+		return AssertSection
+	case 5:
+		return PreviewSection
 	default:
 		return QuerySection
 	}
@@ -496,7 +988,7 @@ func renderTabs(tabs []string, activeTab int, width int, focusedStyle, blurredSt
 		Render(tabsContent)
 }
 
-func RenderRequest(width, height int, currentReq *BruRequest, activePanel bool, requestCursor RequestSection, activeTab int, focusedStyle, blurredStyle, titleStyle, cursorStyle, methodStyle, urlStyle, sectionStyle, textCursorStyle lipgloss.Style) string {
+func RenderRequest(width, height int, currentReq *BruRequest, preview *RequestPreview, activePanel bool, requestCursor RequestSection, activeTab int, focusedStyle, blurredStyle, titleStyle, cursorStyle, methodStyle, urlStyle, sectionStyle, textCursorStyle lipgloss.Style) string {
 	var style lipgloss.Style
 	if activePanel {
 		style = focusedStyle
@@ -525,11 +1017,15 @@ func RenderRequest(width, height int, currentReq *BruRequest, activePanel bool,
 	case QuerySection:
 		tabContent = renderQueryContent(currentReq, activePanel, requestCursor, currentSection, cursorStyle, sectionStyle, textCursorStyle)
 	case BodySection:
-		tabContent = renderBodyContent(currentReq, activePanel, requestCursor, currentSection, cursorStyle, sectionStyle)
+		tabContent = renderBodyContent(currentReq, activePanel, requestCursor, currentSection, cursorStyle, sectionStyle, textCursorStyle)
 	case HeadersSection:
 		tabContent = renderHeadersContent(currentReq, activePanel, requestCursor, currentSection, cursorStyle, sectionStyle, textCursorStyle)
 	case AuthSection:
 		tabContent = renderAuthContent(currentReq, activePanel, requestCursor, currentSection, cursorStyle, sectionStyle)
+	case AssertSection:
+		tabContent = renderAssertContent(currentReq, activePanel, requestCursor, currentSection, cursorStyle, sectionStyle, textCursorStyle)
+	case PreviewSection:
+		tabContent = renderPreviewContent(preview, sectionStyle)
 	}
 
 	content := lipgloss.JoinVertical(lipgloss.Left, tabsRender, tabContent)
@@ -636,7 +1132,7 @@ func renderQueryContent(currentReq *BruRequest, activePanel bool, requestCursor
 	return strings.Join(lines, "\n")
 }
 
-func renderBodyContent(currentReq *BruRequest, activePanel bool, requestCursor RequestSection, currentSection RequestSection, cursorStyle, sectionStyle lipgloss.Style) string {
+func renderBodyContent(currentReq *BruRequest, activePanel bool, requestCursor RequestSection, currentSection RequestSection, cursorStyle, sectionStyle, textCursorStyle lipgloss.Style) string {
 	// Initialize edit state if needed
 	currentReq.InitializeBodyEditState()
 	editState := currentReq.BodyEditState
@@ -647,16 +1143,35 @@ func renderBodyContent(currentReq *BruRequest, activePanel bool, requestCursor R
 	if activePanel && requestCursor == currentSection {
 		switch editState.Mode {
 		case BodyViewMode:
-			lines = append(lines, "  Enter/i: Edit • Esc: Cancel")
+			if IsFormBody(currentReq.Body.Type) {
+				break
+			}
+			if currentReq.Body.Type == "graphql" {
+				lines = append(lines, "  Enter/i: Edit • v: Query/Variables • m: Body mode • Esc: Cancel")
+				break
+			}
+			lines = append(lines, "  Enter/i: Edit • m: Body mode • Esc: Cancel")
 		case BodyTextEditMode:
+			if currentReq.Body.Type == "graphql" && !editState.EditingVars {
+				lines = append(lines, "  Ctrl+S: Save • Esc: Cancel • Tab: Complete • Ctrl+N/Shift+Tab: Next/previous suggestion")
+				break
+			}
 			lines = append(lines, "  Ctrl+S: Save • Esc: Cancel • Arrow keys: Navigate")
 		}
-		lines = append(lines, "")
+		if !IsFormBody(currentReq.Body.Type) {
+			lines = append(lines, "")
+		}
 	}
 	
 	// Show body type
 	lines = append(lines, fmt.Sprintf("  Type: %s", currentReq.Body.Type))
 	
+	if IsFormBody(currentReq.Body.Type) {
+		lines = append(lines, "")
+		lines = append(lines, renderFormContent(currentReq, activePanel, requestCursor, currentSection, cursorStyle, textCursorStyle))
+		return strings.Join(lines, "\n")
+	}
+	
 	// Show validation error if exists
 	if editState.ValidationError != "" {
 		errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("1")).Bold(true)
@@ -666,6 +1181,11 @@ func renderBodyContent(currentReq *BruRequest, activePanel bool, requestCursor R
 	
 	lines = append(lines, "")
 	
+	if currentReq.Body.Type == "graphql" {
+		lines = append(lines, renderGraphQLContent(currentReq, editState, activePanel && requestCursor == currentSection, cursorStyle)...)
+		return strings.Join(lines, "\n")
+	}
+	
 	if editState.Content == "" {
 		helpText := "  No request body"
 		if activePanel && requestCursor == currentSection {
@@ -689,6 +1209,89 @@ func renderBodyContent(currentReq *BruRequest, activePanel bool, requestCursor R
 	return strings.Join(lines, "\n")
 }
 
+// renderGraphQLContent renders the query and variables of a GraphQL body.
+// While editing only the part being edited is shown, followed by completions.
+func renderGraphQLContent(currentReq *BruRequest, editState *BodyEditState, focused bool, cursorStyle lipgloss.Style) []string {
+	query := currentReq.Body.Data
+	variables := currentReq.Body.Vars
+	if editState.EditingVars {
+		variables = editState.Content
+	} else {
+		query = editState.Content
+	}
+	
+	queryTitle := "  Query:"
+	variablesTitle := "  Variables:"
+	if focused {
+		if editState.EditingVars {
+			variablesTitle = cursorStyle.Render(variablesTitle)
+		} else {
+			queryTitle = cursorStyle.Render(queryTitle)
+		}
+	}
+	
+	var lines []string
+	if editState.Mode == BodyTextEditMode && focused {
+		if editState.EditingVars {
+			lines = append(lines, variablesTitle)
+			return append(lines, renderBodyEditMode(editState, "json", cursorStyle)...)
+		}
+		lines = append(lines, queryTitle)
+		lines = append(lines, renderBodyEditMode(editState, "graphql", cursorStyle)...)
+		if len(editState.Suggestions) > 0 {
+			lines = append(lines, "")
+			lines = append(lines, "  Suggestions: "+formatGraphQLSuggestions(editState.Suggestions, editState.SelectedSuggestion))
+		}
+		return lines
+	}
+	
+	lines = append(lines, queryTitle)
+	if query == "" {
+		lines = append(lines, "  No query")
+	} else {
+		for _, line := range strings.Split(query, "\n") {
+			lines = append(lines, "  "+line)
+		}
+	}
+	
+	lines = append(lines, "")
+	lines = append(lines, variablesTitle)
+	if strings.TrimSpace(variables) == "" {
+		lines = append(lines, "  No variables")
+	} else {
+		lines = append(lines, renderJSONContent(variables, false, 0, 0)...)
+	}
+	return lines
+}
+
+// formatGraphQLSuggestions shows the first few completions with the selected one in brackets
+func formatGraphQLSuggestions(suggestions []string, selected int) string {
+	maxShow := 5
+	if len(suggestions) < maxShow {
+		maxShow = len(suggestions)
+	}
+	
+	start := 0
+	if selected >= maxShow {
+		start = selected - maxShow + 1
+	}
+	
+	var parts []string
+	for i := start; i < start+maxShow; i++ {
+		if i == selected {
+			parts = append(parts, "["+suggestions[i]+"]")
+		} else {
+			parts = append(parts, suggestions[i])
+		}
+	}
+	
+	text := strings.Join(parts, " ")
+	if len(suggestions) > maxShow {
+		text += fmt.Sprintf(" (+%d more)", len(suggestions)-maxShow)
+	}
+	return text
+}
+
 // renderBodyViewMode renders the body in view mode with syntax highlighting
 func renderBodyViewMode(editState *BodyEditState, bodyType string) []string {
 	content := editState.Content
@@ -1025,6 +1628,204 @@ func renderHeadersContent(currentReq *BruRequest, activePanel bool, requestCurso
 	return strings.Join(lines, "\n")
 }
 
+func renderAssertContent(currentReq *BruRequest, activePanel bool, requestCursor RequestSection, currentSection RequestSection, cursorStyle, sectionStyle, textCursorStyle lipgloss.Style) string {
+	// Initialize edit state if needed
+	currentReq.InitializeAssertEditState()
+	editState := currentReq.AssertEditState
+	
+	if len(editState.Parameters) == 0 && editState.Mode != AssertAddMode {
+		helpText := "  No assertions"
+		if activePanel && requestCursor == currentSection {
+			helpText += "\n\n  Press 'a' to add an assertion (e.g. res.status: eq 200)"
+		}
+		return helpText
+	}
+
+	var lines []string
+	
+	// Add help text at the top when in assertions section
+	if activePanel && requestCursor == currentSection {
+		switch editState.Mode {
+		case AssertViewMode:
+			lines = append(lines, "  ↑↓: Navigate • Enter/i: Edit • a: Add • d: Delete • t: Toggle • Esc: Cancel")
+		case AssertEditKeyMode:
+			lines = append(lines, "  Tab: Edit rule • Enter: Save • Esc: Cancel")
+		case AssertEditValueMode:
+			lines = append(lines, "  Tab: Edit expression • Enter: Save • Esc: Cancel")
+		case AssertAddMode:
+			lines = append(lines, "  Tab/Enter: Edit rule • Esc: Cancel")
+		}
+		lines = append(lines, "")
+	}
+
+	// Calculate maximum key length for alignment
+	maxKeyLength := 0
+	for _, param := range editState.Parameters {
+		if len(param.Key) > maxKeyLength {
+			maxKeyLength = len(param.Key)
+		}
+	}
+	if maxKeyLength < 12 { // Minimum width
+		maxKeyLength = 12
+	}
+	
+	// Render each assertion
+	for i, param := range editState.Parameters {
+		var line string
+		isSelected := activePanel && requestCursor == currentSection && i == editState.SelectedIndex
+		
+		// Handle different editing modes
+		switch {
+		case editState.Mode == AssertEditKeyMode && isSelected:
+			// Editing expression
+			keyWithCursor := renderTextCursor(editState.EditingKey, editState.CursorPos, textCursorStyle)
+			paddedKey := fmt.Sprintf("%-*s", maxKeyLength, keyWithCursor)
+			line = fmt.Sprintf("  %s: %s", paddedKey, param.Value)
+			line = cursorStyle.Render(line)
+			
+		case editState.Mode == AssertEditValueMode && isSelected:
+			// Editing rule
+			valueWithCursor := renderTextCursor(editState.EditingValue, editState.CursorPos, textCursorStyle)
+			paddedKey := fmt.Sprintf("%-*s", maxKeyLength, param.Key)
+			line = fmt.Sprintf("  %s: %s", paddedKey, valueWithCursor)
+			line = cursorStyle.Render(line)
+			
+		case editState.PendingDeletion == i:
+			// Show deletion confirmation
+			paddedKey := fmt.Sprintf("%-*s", maxKeyLength, param.Key)
+			line = fmt.Sprintf("  %s: %s [DELETE? y/n]", paddedKey, param.Value)
+			line = lipgloss.NewStyle().Foreground(lipgloss.Color("1")).Render(line)
+			
+		case isSelected:
+			// Selected but not editing
+			paddedKey := fmt.Sprintf("%-*s", maxKeyLength, param.Key)
+			line = fmt.Sprintf("  %s: %s", paddedKey, param.Value)
+			line = cursorStyle.Render(line)
+			
+		case strings.HasPrefix(param.Key, "~"):
+			// Disabled assertion
+			paddedKey := fmt.Sprintf("%-*s", maxKeyLength, param.Key)
+			line = fmt.Sprintf("  %s: %s", paddedKey, param.Value)
+			line = lipgloss.NewStyle().Faint(true).Render(line)
+			
+		default:
+			// Normal display
+			paddedKey := fmt.Sprintf("%-*s", maxKeyLength, param.Key)
+			line = fmt.Sprintf("  %s: %s", paddedKey, param.Value)
+		}
+		
+		lines = append(lines, line)
+	}
+	
+	// Show add assertion input if in add mode
+	if editState.Mode == AssertAddMode && activePanel && requestCursor == currentSection {
+		lines = append(lines, "")
+		keyWithCursor := renderTextCursor(editState.EditingKey, editState.CursorPos, textCursorStyle)
+		line := fmt.Sprintf("  %-*s: ", maxKeyLength, keyWithCursor)
+		lines = append(lines, cursorStyle.Render(line))
+	}
+	
+	return strings.Join(lines, "\n")
+}
+
+func renderFormContent(currentReq *BruRequest, activePanel bool, requestCursor RequestSection, currentSection RequestSection, cursorStyle, textCursorStyle lipgloss.Style) string {
+	// Initialize edit state if needed
+	currentReq.InitializeFormEditState()
+	editState := currentReq.FormEditState
+	
+	var lines []string
+	
+	// Add help text at the top when in body section
+	if activePanel && requestCursor == currentSection {
+		switch editState.Mode {
+		case FormViewMode:
+			lines = append(lines, "  ↑↓: Navigate • Enter/i: Edit • a: Add • d: Delete • t: Toggle • m: Body mode")
+		case FormEditKeyMode:
+			lines = append(lines, "  Tab: Edit value • Enter: Save • Esc: Cancel")
+		case FormEditValueMode:
+			lines = append(lines, "  Tab: Edit key • Enter: Save • Esc: Cancel")
+		case FormAddMode:
+			lines = append(lines, "  Tab/Enter: Edit value • Esc: Cancel")
+		}
+		if currentReq.Body.Type == "multipart-form" {
+			lines = append(lines, "  Use @file(path) as a value to upload a file")
+		}
+		lines = append(lines, "")
+	}
+	
+	if len(editState.Parameters) == 0 && editState.Mode != FormAddMode {
+		helpText := "  No form fields"
+		if activePanel && requestCursor == currentSection {
+			helpText += "\n\n  Press 'a' to add a field"
+		}
+		lines = append(lines, helpText)
+		return strings.Join(lines, "\n")
+	}
+
+	// Calculate maximum key length for alignment
+	maxKeyLength := 0
+	for _, param := range editState.Parameters {
+		if len(param.Key) > maxKeyLength {
+			maxKeyLength = len(param.Key)
+		}
+	}
+	if maxKeyLength < 12 { // Minimum width
+		maxKeyLength = 12
+	}
+	
+	// Render each field
+	for i, param := range editState.Parameters {
+		var line string
+		isSelected := activePanel && requestCursor == currentSection && i == editState.SelectedIndex
+		
+		switch {
+		case editState.Mode == FormEditKeyMode && isSelected:
+			keyWithCursor := renderTextCursor(editState.EditingKey, editState.CursorPos, textCursorStyle)
+			paddedKey := fmt.Sprintf("%-*s", maxKeyLength, keyWithCursor)
+			line = fmt.Sprintf("  %s: %s", paddedKey, param.Value)
+			line = cursorStyle.Render(line)
+			
+		case editState.Mode == FormEditValueMode && isSelected:
+			valueWithCursor := renderTextCursor(editState.EditingValue, editState.CursorPos, textCursorStyle)
+			paddedKey := fmt.Sprintf("%-*s", maxKeyLength, param.Key)
+			line = fmt.Sprintf("  %s: %s", paddedKey, valueWithCursor)
+			line = cursorStyle.Render(line)
+			
+		case editState.PendingDeletion == i:
+			paddedKey := fmt.Sprintf("%-*s", maxKeyLength, param.Key)
+			line = fmt.Sprintf("  %s: %s [DELETE? y/n]", paddedKey, param.Value)
+			line = lipgloss.NewStyle().Foreground(lipgloss.Color("1")).Render(line)
+			
+		case isSelected:
+			paddedKey := fmt.Sprintf("%-*s", maxKeyLength, param.Key)
+			line = fmt.Sprintf("  %s: %s", paddedKey, param.Value)
+			line = cursorStyle.Render(line)
+			
+		case strings.HasPrefix(param.Key, "~"):
+			// Disabled field
+			paddedKey := fmt.Sprintf("%-*s", maxKeyLength, param.Key)
+			line = fmt.Sprintf("  %s: %s", paddedKey, param.Value)
+			line = lipgloss.NewStyle().Faint(true).Render(line)
+			
+		default:
+			paddedKey := fmt.Sprintf("%-*s", maxKeyLength, param.Key)
+			line = fmt.Sprintf("  %s: %s", paddedKey, param.Value)
+		}
+		
+		lines = append(lines, line)
+	}
+	
+	// Show add field input if in add mode
+	if editState.Mode == FormAddMode && activePanel && requestCursor == currentSection {
+		lines = append(lines, "")
+		keyWithCursor := renderTextCursor(editState.EditingKey, editState.CursorPos, textCursorStyle)
+		line := fmt.Sprintf("  %-*s: ", maxKeyLength, keyWithCursor)
+		lines = append(lines, cursorStyle.Render(line))
+	}
+	
+	return strings.Join(lines, "\n")
+}
+
 func renderAuthContent(currentReq *BruRequest, activePanel bool, requestCursor RequestSection, currentSection RequestSection, cursorStyle, sectionStyle lipgloss.Style) string {
 	// Initialize edit state if needed
 	currentReq.InitializeAuthEditState()
@@ -1043,6 +1844,8 @@ func renderAuthContent(currentReq *BruRequest, activePanel bool, requestCursor R
 			lines = append(lines, "  Type token • Enter: Save • Esc: Cancel")
 		case AuthBasicUsernameEditMode, AuthBasicPasswordEditMode:
 			lines = append(lines, "  Type credentials • Tab: Next field • Enter: Save • Esc: Cancel")
+		case AuthOAuth2EditMode:
+			lines = append(lines, "  Type value • Enter: Save • Esc: Cancel")
 		}
 		lines = append(lines, "")
 	}
@@ -1054,18 +1857,22 @@ func renderAuthContent(currentReq *BruRequest, activePanel bool, requestCursor R
 		typeText = "Bearer Token"
 	case "basic":
 		typeText = "Basic Auth"
+	case "oauth2":
+		typeText = "OAuth 2.0"
 	}
 	
 	var typeLine string
 	if editState.Mode == AuthTypeSelectMode && activePanel && requestCursor == currentSection {
 		// Show dropdown options
-		options := []string{"None", "Bearer Token", "Basic Auth"}
+		options := []string{"None", "Bearer Token", "Basic Auth", "OAuth 2.0"}
 		selectedIndex := 0
 		switch editState.AuthType {
 		case "bearer":
 			selectedIndex = 1
 		case "basic":
 			selectedIndex = 2
+		case "oauth2":
+			selectedIndex = 3
 		}
 		
 		typeLine = "  Type: " + typeText + " ▼"
@@ -1150,6 +1957,40 @@ func renderAuthContent(currentReq *BruRequest, activePanel bool, requestCursor R
 				lines = append(lines, passLine)
 			}
 		}
+		
+	case "oauth2":
+		lines = append(lines, "")
+		
+		for i, key := range OAuth2Fields(editState.OAuth2GrantType()) {
+			value := editState.OAuth2Values[key]
+			if key == "grant_type" {
+				value = editState.OAuth2GrantType()
+			}
+			if key == "client_secret" || key == "password" {
+				value = strings.Repeat("*", len(value))
+			}
+			
+			field := i + 1
+			var fieldLine string
+			if editState.Mode == AuthOAuth2EditMode && editState.SelectedField == field && activePanel && requestCursor == currentSection {
+				valueWithCursor := renderTextCursor(value, editState.CursorPos, lipgloss.NewStyle().Background(lipgloss.Color("240")))
+				fieldLine = "  " + oauth2FieldLabels[key] + ": " + valueWithCursor
+				lines = append(lines, cursorStyle.Render(fieldLine))
+			} else {
+				fieldLine = "  " + oauth2FieldLabels[key] + ": " + value
+				isFieldSelected := activePanel && requestCursor == currentSection && editState.SelectedField == field && editState.Mode == AuthViewMode
+				if isFieldSelected {
+					lines = append(lines, cursorStyle.Render(fieldLine))
+				} else {
+					lines = append(lines, fieldLine)
+				}
+			}
+		}
+		
+		if activePanel && requestCursor == currentSection {
+			lines = append(lines, "")
+			lines = append(lines, "  Enter on Grant Type cycles grants, on PKCE toggles it")
+		}
 	}
 	
 	return strings.Join(lines, "\n")
@@ -1172,9 +2013,12 @@ func GetMaxRequestSection(currentReq *BruRequest) RequestSection {
 	if currentReq.Auth.Type != "" {
 		maxSection = AuthSection
 	}
-	if currentReq.Body.Type != "" && currentReq.Body.Data != "" {
+	if currentReq.Body.Type != "" && (currentReq.Body.Data != "" || len(currentReq.Body.Fields) > 0) {
 		maxSection = BodySection
 	}
+	if len(currentReq.Assertions) > 0 {
+		maxSection = AssertSection
+	}
 
 	return maxSection
 }
\ No newline at end of file
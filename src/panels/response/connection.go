@@ -0,0 +1,77 @@
+package panels
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	"github.com/charmbracelet/lipgloss"
+)
+
+func renderResponseConnectionContent(connectionViewport *viewport.Model, lastResponse *HTTPResponse) string {
+	connectionViewport.SetContent(RenderConnectionDetails(lastResponse))
+	return connectionViewport.View()
+}
+
+// RenderConnectionDetails describes the TLS connection of a response and the
+// certificate chain the server presented, warnings first
+func RenderConnectionDetails(resp *HTTPResponse) string {
+	if resp == nil {
+		return "No connection details available"
+	}
+	if resp.TLS == nil {
+		if resp.ServerAddr != "" {
+			return fmt.Sprintf("Plain HTTP connection to %s, no TLS", resp.ServerAddr)
+		}
+		return "No TLS connection details available"
+	}
+
+	info := resp.TLS
+	var content strings.Builder
+
+	warningStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+	for _, warning := range info.Warnings {
+		content.WriteString(warningStyle.Render("⚠ "+warning) + "\n")
+	}
+	if len(info.Warnings) > 0 {
+		content.WriteString("\n")
+	}
+
+	verified := "yes"
+	if !info.Verified {
+		verified = "no"
+	}
+	content.WriteString(fmt.Sprintf("Server name:    %s\n", info.ServerName))
+	if info.Version != "" {
+		content.WriteString(fmt.Sprintf("TLS version:    %s\n", info.Version))
+	}
+	if info.CipherSuite != "" {
+		content.WriteString(fmt.Sprintf("Cipher suite:   %s\n", info.CipherSuite))
+	}
+	if info.ALPN != "" {
+		content.WriteString(fmt.Sprintf("ALPN:           %s\n", info.ALPN))
+	}
+	content.WriteString(fmt.Sprintf("Verified:       %s\n", verified))
+	if resp.ServerAddr != "" {
+		content.WriteString(fmt.Sprintf("Remote address: %s\n", resp.ServerAddr))
+	}
+
+	for i, cert := range info.Certificates {
+		label := "Intermediate"
+		if i == 0 {
+			label = "Leaf"
+		} else if cert.Subject == cert.Issuer {
+			label = "Root"
+		}
+		content.WriteString(fmt.Sprintf("\n%s certificate\n", label))
+		content.WriteString(fmt.Sprintf("  Subject:    %s\n", cert.Subject))
+		content.WriteString(fmt.Sprintf("  Issuer:     %s\n", cert.Issuer))
+		if len(cert.SANs) > 0 {
+			content.WriteString(fmt.Sprintf("  SANs:       %s\n", strings.Join(cert.SANs, ", ")))
+		}
+		content.WriteString(fmt.Sprintf("  Valid from: %s\n", cert.NotBefore.Local().Format("2006-01-02 15:04")))
+		content.WriteString(fmt.Sprintf("  Expires:    %s\n", cert.NotAfter.Local().Format("2006-01-02 15:04")))
+	}
+
+	return strings.TrimRight(content.String(), "\n")
+}
@@ -0,0 +1,85 @@
+package panels
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Headers holds every value of each header, e.g. all Set-Cookie lines of a
+// response. Keys are canonical as in http.Header.
+type Headers map[string][]string
+
+// Get returns the first value of the header, ignoring the case of key
+func (h Headers) Get(key string) string {
+	return http.Header(h).Get(key)
+}
+
+// Values returns all values of the header, ignoring the case of key
+func (h Headers) Values(key string) []string {
+	return http.Header(h).Values(key)
+}
+
+// Set replaces the values of the header
+func (h Headers) Set(key, value string) {
+	http.Header(h).Set(key, value)
+}
+
+// Add appends a value to the header
+func (h Headers) Add(key, value string) {
+	http.Header(h).Add(key, value)
+}
+
+// Del removes the header
+func (h Headers) Del(key string) {
+	http.Header(h).Del(key)
+}
+
+// Keys returns the header names in sorted order, the order net/http writes them
+func (h Headers) Keys() []string {
+	keys := make([]string, 0, len(h))
+	for key := range h {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Clone returns a copy that can be changed independently
+func (h Headers) Clone() Headers {
+	return Headers(http.Header(h).Clone())
+}
+
+// UnmarshalJSON also reads the single value per header written to history by
+// earlier versions
+func (h *Headers) UnmarshalJSON(data []byte) error {
+	var values map[string][]string
+	if err := json.Unmarshal(data, &values); err == nil {
+		*h = values
+		return nil
+	}
+
+	var single map[string]string
+	if err := json.Unmarshal(data, &single); err != nil {
+		return err
+	}
+	*h = make(Headers, len(single))
+	for key, value := range single {
+		(*h)[key] = []string{value}
+	}
+	return nil
+}
+
+// FormatHeaders lists headers for the Response Headers tab, one line per
+// value so repeated headers such as Set-Cookie are all shown
+func FormatHeaders(headers Headers) string {
+	var content strings.Builder
+	for _, key := range headers.Keys() {
+		for _, value := range headers[key] {
+			content.WriteString(fmt.Sprintf("%s: %s\n", key, value))
+		}
+	}
+	return content.String()
+}
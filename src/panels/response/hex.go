@@ -0,0 +1,35 @@
+package panels
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// hexViewLimit is how much of a binary body the hex view shows
+const hexViewLimit = 64 * 1024
+
+// FormatHexDump shows binary data as offsets, hex bytes and the printable
+// ASCII characters, like hexdump -C. Only the first hexViewLimit bytes are
+// shown; total is the size of the whole body.
+func FormatHexDump(data []byte, total int64) string {
+	shown := data
+	if len(shown) > hexViewLimit {
+		shown = shown[:hexViewLimit]
+	}
+
+	var content strings.Builder
+	content.WriteString(hex.Dump(shown))
+	if total < int64(len(data)) {
+		total = int64(len(data))
+	}
+	if total > int64(len(shown)) {
+		content.WriteString(TruncatedNote(int64(len(shown)), total))
+	}
+	return content.String()
+}
+
+// TruncatedNote tells how much of a body is shown
+func TruncatedNote(shown, total int64) string {
+	return fmt.Sprintf("\n... showing the first %s of %s. Save the response body to keep all of it.", FormatSize(shown), FormatSize(total))
+}
@@ -13,16 +13,98 @@ type ResponseSection int
 const (
 	ResponseBodySection ResponseSection = iota
 	ResponseHeadersSection
+	ResponseTestsSection
+	ResponseTimingSection
+	ResponseConnectionSection
 )
 
 type HTTPResponse struct {
 	StatusCode   int               `json:"status_code"`
 	Status       string            `json:"status"`
-	Headers      map[string]string `json:"headers"`
+	Headers      Headers           `json:"headers"`
 	Body         string            `json:"body"`
 	ResponseTime time.Duration     `json:"response_time"`
 	Error        string            `json:"error,omitempty"`
+	ErrorType    string            `json:"error_type,omitempty"` // e.g. "tls" or "timeout"
 	IsJSON       bool              `json:"is_json"`
+	IsXML        bool              `json:"is_xml,omitempty"`
+	IsHTML       bool              `json:"is_html,omitempty"`
+	IsBinary     bool              `json:"is_binary,omitempty"`
+
+	// Body sizes as received and once Content-Encoding is undone
+	WireSize        int64          `json:"wire_size,omitempty"`
+	BodySize        int64          `json:"body_size,omitempty"`
+	ContentEncoding string         `json:"content_encoding,omitempty"`
+	DecodeError     string         `json:"decode_error,omitempty"` // Why the body is shown as received
+	Charset         string         `json:"charset,omitempty"`      // Converted to UTF-8 from
+
+	// Bodies larger than the maxBodySize setting are kept in a temp file and
+	// only their beginning is in Body
+	BodyFile        string         `json:"-"`
+	BodyTruncated   bool           `json:"body_truncated,omitempty"`
+	RawBody         []byte         `json:"-"` // Body before charset conversion and formatting
+
+	// Redirects followed before the final response
+	Redirects    []Redirect        `json:"redirects,omitempty"`
+	FinalURL     string            `json:"final_url,omitempty"`
+
+	// Timing breakdown, captured with httptrace
+	DNSTime      time.Duration     `json:"dns_time,omitempty"`
+	ConnectTime  time.Duration     `json:"connect_time,omitempty"`
+	TLSTime      time.Duration     `json:"tls_time,omitempty"`
+	SendTime     time.Duration     `json:"send_time,omitempty"`
+	WaitTime     time.Duration     `json:"wait_time,omitempty"`
+	ReceiveTime  time.Duration     `json:"receive_time,omitempty"`
+	ConnReused   bool              `json:"conn_reused,omitempty"`
+	Protocol     string            `json:"protocol,omitempty"`
+	ServerAddr   string            `json:"server_addr,omitempty"`
+	LocalAddr    string            `json:"local_addr,omitempty"`
+	Proxy        string            `json:"proxy,omitempty"` // Password redacted
+
+	// Streaming responses, read as they arrived
+	Streamed     bool              `json:"streamed,omitempty"`
+	Events       []StreamEvent     `json:"events,omitempty"`     // Parsed from text/event-stream bodies
+	DroppedEvents int              `json:"dropped_events,omitempty"` // Earlier events than the ones kept in Events
+	StreamEnd    string            `json:"stream_end,omitempty"` // "ended", "stopped" or "timed out after ..."
+
+	// TLS connection details, also set when verification fails
+	TLS          *TLSInfo          `json:"tls,omitempty"`
+}
+
+// StreamEvent is one event of a Server-Sent Events stream
+type StreamEvent struct {
+	ID       string    `json:"id,omitempty"`
+	Event    string    `json:"event"`
+	Data     string    `json:"data"`
+	Received time.Time `json:"received"`
+}
+
+// TLSInfo describes the TLS connection of an HTTPS response
+type TLSInfo struct {
+	Version      string            `json:"version"`
+	CipherSuite  string            `json:"cipher_suite,omitempty"`
+	ALPN         string            `json:"alpn,omitempty"`
+	ServerName   string            `json:"server_name"`
+	Verified     bool              `json:"verified"`
+	Certificates []CertificateInfo `json:"certificates,omitempty"` // Leaf first
+	Warnings     []string          `json:"warnings,omitempty"`
+}
+
+// CertificateInfo is one certificate of the chain presented by the server
+type CertificateInfo struct {
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	SANs      []string  `json:"sans,omitempty"` // DNS names and IP addresses
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+}
+
+// Redirect is one hop of a redirect chain
+type Redirect struct {
+	URL        string        `json:"url"`
+	StatusCode int           `json:"status_code"`
+	Location   string        `json:"location"`
+	Duration   time.Duration `json:"duration"`
 }
 
 func max(a, b int) int {
@@ -33,7 +115,7 @@ func max(a, b int) int {
 }
 
 func GetResponseTabNames() []string {
-	return []string{"Response Body", "Response Headers"}
+	return []string{"Response Body", "Response Headers", "Tests", "Timing", "Connection"}
 }
 
 func GetResponseTabSection(tabIndex int) ResponseSection {
@@ -42,6 +124,12 @@ func GetResponseTabSection(tabIndex int) ResponseSection {
 		return ResponseBodySection
 	case 1:
 		return ResponseHeadersSection
+	case 2:
+		return ResponseTestsSection
+	case 3:
+		return ResponseTimingSection
+	case 4:
+		return ResponseConnectionSection
 	default:
 		return ResponseBodySection
 	}
@@ -70,7 +158,7 @@ func renderResponseTabs(tabs []string, activeTab int, width int, focusedStyle, b
 		Render(tabsContent)
 }
 
-func RenderResponse(width, height int, activePanel bool, isLoading bool, lastResponse *HTTPResponse, statusCode int, responseCursor ResponseSection, activeTab int, headersViewport, responseViewport *viewport.Model, focusedStyle, blurredStyle, titleStyle, cursorStyle, sectionStyle, statusOkStyle lipgloss.Style, appliedJQFilter string) string {
+func RenderResponse(width, height int, activePanel bool, isLoading bool, lastResponse *HTTPResponse, statusCode int, responseCursor ResponseSection, activeTab int, headersViewport, responseViewport, testsViewport, timingViewport, connectionViewport *viewport.Model, focusedStyle, blurredStyle, titleStyle, cursorStyle, sectionStyle, statusOkStyle lipgloss.Style, appliedJQFilter string) string {
 	var style lipgloss.Style
 	if activePanel {
 		style = focusedStyle
@@ -91,6 +179,12 @@ func RenderResponse(width, height int, activePanel bool, isLoading bool, lastRes
 	headersViewport.Height = availableHeight
 	responseViewport.Width = contentWidth
 	responseViewport.Height = availableHeight
+	testsViewport.Width = contentWidth
+	testsViewport.Height = availableHeight
+	timingViewport.Width = contentWidth
+	timingViewport.Height = availableHeight
+	connectionViewport.Width = contentWidth
+	connectionViewport.Height = availableHeight
 
 
 	// Render tabs (account for panel padding and border)
@@ -101,9 +195,16 @@ func RenderResponse(width, height int, activePanel bool, isLoading bool, lastRes
 	var tabContent string
 	currentSection := GetResponseTabSection(activeTab)
 	
-	if currentSection == ResponseBodySection {
+	switch currentSection {
+	case ResponseBodySection:
 		tabContent = renderResponseBodyContent(responseViewport, activePanel, responseCursor, currentSection, cursorStyle, sectionStyle, appliedJQFilter)
-	} else {
+	case ResponseTestsSection:
+		tabContent = renderResponseTestsContent(testsViewport)
+	case ResponseTimingSection:
+		tabContent = renderResponseTimingContent(timingViewport, lastResponse)
+	case ResponseConnectionSection:
+		tabContent = renderResponseConnectionContent(connectionViewport, lastResponse)
+	default:
 		tabContent = renderResponseHeadersContent(headersViewport, activePanel, responseCursor, currentSection, cursorStyle, sectionStyle)
 	}
 
@@ -139,4 +240,8 @@ func renderResponseBodyContent(responseViewport *viewport.Model, activePanel boo
 
 func renderResponseHeadersContent(headersViewport *viewport.Model, activePanel bool, responseCursor ResponseSection, currentSection ResponseSection, cursorStyle, sectionStyle lipgloss.Style) string {
 	return headersViewport.View()
-}
\ No newline at end of file
+}
+
+func renderResponseTestsContent(testsViewport *viewport.Model) string {
+	return testsViewport.View()
+}
@@ -0,0 +1,149 @@
+package panels
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// timingPhase is one row of the timing waterfall
+type timingPhase struct {
+	label    string
+	duration time.Duration
+	color    lipgloss.Color
+}
+
+const (
+	timingLabelWidth    = 18
+	timingDurationWidth = 10
+)
+
+func renderResponseTimingContent(timingViewport *viewport.Model, lastResponse *HTTPResponse) string {
+	timingViewport.SetContent(RenderTimingWaterfall(lastResponse, timingViewport.Width))
+	return timingViewport.View()
+}
+
+// RenderTimingWaterfall draws each phase of a request as a bar offset by the
+// phases before it, scaled to the total response time
+func RenderTimingWaterfall(resp *HTTPResponse, width int) string {
+	if resp == nil || resp.ResponseTime <= 0 {
+		return "No timing available"
+	}
+
+	var phases []timingPhase
+	if len(resp.Redirects) > 0 {
+		var redirectTime time.Duration
+		for _, redirect := range resp.Redirects {
+			redirectTime += redirect.Duration
+		}
+		phases = append(phases, timingPhase{"Redirects", redirectTime, lipgloss.Color("244")})
+	}
+
+	phases = append(phases, []timingPhase{
+		{"DNS Lookup", resp.DNSTime, lipgloss.Color("39")},
+		{"TCP Connect", resp.ConnectTime, lipgloss.Color("214")},
+		{"TLS Handshake", resp.TLSTime, lipgloss.Color("170")},
+		{"Request Sent", resp.SendTime, lipgloss.Color("250")},
+		{"Waiting (TTFB)", resp.WaitTime, lipgloss.Color("42")},
+		{"Content Download", resp.ReceiveTime, lipgloss.Color("33")},
+	}...)
+
+	total := resp.ResponseTime
+	barWidth := width - timingLabelWidth - timingDurationWidth - 2
+	if barWidth < 10 {
+		barWidth = 10
+	}
+
+	var content strings.Builder
+	var offset time.Duration
+	for _, phase := range phases {
+		bar := ""
+		duration := "-"
+		if phase.duration > 0 {
+			start := int(float64(offset) / float64(total) * float64(barWidth))
+			length := int(float64(phase.duration) / float64(total) * float64(barWidth))
+			if length < 1 {
+				length = 1
+			}
+			if start+length > barWidth {
+				start = max(0, barWidth-length)
+			}
+			bar = strings.Repeat(" ", start) + lipgloss.NewStyle().Foreground(phase.color).Render(strings.Repeat("█", length)) + strings.Repeat(" ", barWidth-start-length)
+			duration = FormatDuration(phase.duration)
+			offset += phase.duration
+		} else {
+			bar = strings.Repeat(" ", barWidth)
+		}
+		content.WriteString(fmt.Sprintf("%-*s%s %*s\n", timingLabelWidth, phase.label, bar, timingDurationWidth, duration))
+	}
+
+	content.WriteString(fmt.Sprintf("%-*s%s %*s\n", timingLabelWidth, "Total", strings.Repeat(" ", barWidth), timingDurationWidth, FormatDuration(total)))
+	content.WriteString("\n")
+
+	if resp.Protocol != "" {
+		content.WriteString(fmt.Sprintf("Protocol:       %s\n", resp.Protocol))
+	}
+	if size := FormatBodySize(resp); size != "" {
+		content.WriteString(fmt.Sprintf("Body size:      %s\n", size))
+	}
+	if resp.DecodeError != "" {
+		content.WriteString(fmt.Sprintf("Decoding:       body shown as received, %s\n", resp.DecodeError))
+	}
+	if resp.Charset != "" && resp.Charset != "utf-8" {
+		content.WriteString(fmt.Sprintf("Charset:        converted from %s\n", resp.Charset))
+	}
+	if resp.ServerAddr != "" {
+		content.WriteString(fmt.Sprintf("Remote address: %s\n", resp.ServerAddr))
+	}
+	if resp.Proxy != "" {
+		content.WriteString(fmt.Sprintf("Proxy:          %s\n", resp.Proxy))
+	}
+	if resp.LocalAddr != "" {
+		content.WriteString(fmt.Sprintf("Local address:  %s\n", resp.LocalAddr))
+	}
+	if resp.ConnReused {
+		content.WriteString("Connection:     reused, no DNS, connect or TLS time\n")
+	} else if resp.ServerAddr != "" {
+		content.WriteString("Connection:     new\n")
+	}
+
+	return strings.TrimRight(content.String(), "\n")
+}
+
+// FormatBodySize describes the size of a body, with its size on the wire
+// when it was compressed
+func FormatBodySize(resp *HTTPResponse) string {
+	if resp.WireSize == 0 && resp.BodySize == 0 {
+		return ""
+	}
+	if resp.ContentEncoding == "" || resp.DecodeError != "" {
+		return FormatSize(resp.WireSize)
+	}
+	return fmt.Sprintf("%s %s, %s decoded", FormatSize(resp.WireSize), resp.ContentEncoding, FormatSize(resp.BodySize))
+}
+
+// FormatSize formats a byte count in B, KB, MB or GB
+func FormatSize(size int64) string {
+	switch {
+	case size < 1024:
+		return fmt.Sprintf("%d B", size)
+	case size < 1024*1024:
+		return fmt.Sprintf("%.1f KB", float64(size)/1024)
+	case size < 1024*1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1024*1024))
+	}
+	return fmt.Sprintf("%.1f GB", float64(size)/(1024*1024*1024))
+}
+
+// FormatDuration formats a duration the same way as the response title
+func FormatDuration(d time.Duration) string {
+	if d < time.Millisecond {
+		return fmt.Sprintf("%.2fμs", float64(d.Nanoseconds())/1000)
+	} else if d < time.Second {
+		return fmt.Sprintf("%.2fms", float64(d.Nanoseconds())/1000000)
+	}
+	return fmt.Sprintf("%.2fs", d.Seconds())
+}
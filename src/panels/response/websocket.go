@@ -0,0 +1,79 @@
+package panels
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// WebSocket message directions
+const (
+	WebSocketSent     = "sent"
+	WebSocketReceived = "received"
+	WebSocketInfo     = "info" // Connection status changes
+)
+
+// WebSocketMessage is a frame of a WebSocket conversation, or a note about
+// the connection
+type WebSocketMessage struct {
+	Direction string    `json:"direction"`
+	Text      string    `json:"text"`
+	Binary    bool      `json:"binary,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// RenderWebSocketConsole draws the message log of a WebSocket connection
+// above the line used to type messages
+func RenderWebSocketConsole(width, height int, activePanel bool, messages []WebSocketMessage, logViewport *viewport.Model, input string, focusedStyle, blurredStyle lipgloss.Style) string {
+	style := blurredStyle
+	if activePanel {
+		style = focusedStyle
+	}
+
+	logViewport.Width = width - 8
+	logViewport.Height = max(3, height-6)
+
+	following := logViewport.AtBottom()
+	logViewport.SetContent(FormatWebSocketMessages(messages))
+	if following {
+		logViewport.GotoBottom()
+	}
+
+	separator := lipgloss.NewStyle().Faint(true).Render(strings.Repeat("─", max(0, width-8)))
+	content := lipgloss.JoinVertical(lipgloss.Left, logViewport.View(), separator, input)
+
+	return style.
+		Width(width).
+		Height(height).
+		Padding(0, 1).
+		Render(content)
+}
+
+// FormatWebSocketMessages renders the log with a timestamp and an arrow for
+// the direction of each message
+func FormatWebSocketMessages(messages []WebSocketMessage) string {
+	if len(messages) == 0 {
+		return "No messages yet"
+	}
+
+	sentStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("39"))
+	receivedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+	infoStyle := lipgloss.NewStyle().Faint(true)
+
+	var content strings.Builder
+	for _, message := range messages {
+		timestamp := message.Time.Local().Format("15:04:05.000")
+		switch message.Direction {
+		case WebSocketSent:
+			content.WriteString(fmt.Sprintf("%s %s %s\n", timestamp, sentStyle.Render("→"), message.Text))
+		case WebSocketReceived:
+			content.WriteString(fmt.Sprintf("%s %s %s\n", timestamp, receivedStyle.Render("←"), message.Text))
+		default:
+			content.WriteString(infoStyle.Render(fmt.Sprintf("%s • %s", timestamp, message.Text)) + "\n")
+		}
+	}
+	return strings.TrimRight(content.String(), "\n")
+}
@@ -1,397 +1,237 @@
 package main
 
 import (
-	"bufio"
-	"fmt"
 	"io"
 	"os"
 	"path/filepath"
-	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"kalo/src/bru"
 	collections "kalo/src/panels/collections"
 	request "kalo/src/panels/request"
 )
 
 
 type BruParser struct {
-	scanner *bufio.Scanner
-	line    string
-	lineNum int
+	reader io.Reader
 }
 
 func NewBruParser(reader io.Reader) *BruParser {
 	return &BruParser{
-		scanner: bufio.NewScanner(reader),
-		lineNum: 0,
+		reader: reader,
 	}
 }
 
+// Parse reads the document and builds a request from its blocks. The
+// document is kept on the request so it can be written back without losing
+// anything kalo does not model.
 func (p *BruParser) Parse() (*request.BruRequest, error) {
-	request := &request.BruRequest{
-		Headers: make(map[string]string),
-		Query:   make(map[string]string),
-		Vars:    make(map[string]string),
-		Auth:    request.BruAuth{Values: make(map[string]string)},
-		Tags:    make([]string, 0),
+	doc, err := bru.Parse(p.reader)
+	if err != nil {
+		return nil, err
 	}
 
-	for p.nextLine() {
-		line := strings.TrimSpace(p.line)
-		
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
+	request := &request.BruRequest{
+		Vars:     make(map[string]string),
+		Settings: make(map[string]string),
+		Auth:     request.BruAuth{Values: make(map[string]string)},
+		Tags:     make([]string, 0),
+		Document: doc,
+	}
 
-		if strings.HasPrefix(line, "meta {") {
-			if err := p.parseMeta(request); err != nil {
-				return nil, fmt.Errorf("line %d: %v", p.lineNum, err)
-			}
-		} else if strings.HasPrefix(line, "get {") || strings.HasPrefix(line, "post {") || strings.HasPrefix(line, "put {") || strings.HasPrefix(line, "delete {") || strings.HasPrefix(line, "patch {") {
-			method := strings.ToUpper(strings.TrimSuffix(line, " {"))
-			request.HTTP.Method = method
-			if err := p.parseHTTP(request); err != nil {
-				return nil, fmt.Errorf("line %d: %v", p.lineNum, err)
-			}
-		} else if strings.HasPrefix(line, "headers {") {
-			if err := p.parseHeaders(request); err != nil {
-				return nil, fmt.Errorf("line %d: %v", p.lineNum, err)
-			}
-		} else if strings.HasPrefix(line, "query {") {
-			if err := p.parseQuery(request); err != nil {
-				return nil, fmt.Errorf("line %d: %v", p.lineNum, err)
-			}
-		} else if strings.HasPrefix(line, "body:") {
-			if err := p.parseBody(request, line); err != nil {
-				return nil, fmt.Errorf("line %d: %v", p.lineNum, err)
-			}
-		} else if strings.HasPrefix(line, "auth:") {
-			if err := p.parseAuth(request, line); err != nil {
-				return nil, fmt.Errorf("line %d: %v", p.lineNum, err)
-			}
-		} else if strings.HasPrefix(line, "vars {") {
-			if err := p.parseVars(request); err != nil {
-				return nil, fmt.Errorf("line %d: %v", p.lineNum, err)
-			}
-		} else if strings.HasPrefix(line, "tests {") {
-			if err := p.parseTests(request); err != nil {
-				return nil, fmt.Errorf("line %d: %v", p.lineNum, err)
-			}
-		} else if strings.HasPrefix(line, "docs {") {
-			if err := p.parseDocs(request); err != nil {
-				return nil, fmt.Errorf("line %d: %v", p.lineNum, err)
-			}
-		} else if strings.HasPrefix(line, "tags {") {
-			if err := p.parseTags(request); err != nil {
-				return nil, fmt.Errorf("line %d: %v", p.lineNum, err)
-			}
+	if block := doc.Block("meta"); block != nil {
+		p.parseMeta(request, block)
+	}
+	if block := doc.Block("tags"); block != nil {
+		p.parseTags(request, block)
+	}
+	if block := doc.MethodBlock(); block != nil {
+		p.parseHTTP(request, block)
+		if block.Name == "ws" && request.Meta.Type == "" {
+			request.Meta.Type = "ws"
 		}
 	}
+	if block := doc.Block("query"); block != nil {
+		request.Query = p.parseParamList(block)
+	} else if block := doc.Block("params:query"); block != nil {
+		request.Query = p.parseParamList(block)
+	}
+	if block := doc.Block("headers"); block != nil {
+		request.Headers = p.parseParamList(block)
+	}
+	if block := doc.Block("vars"); block != nil {
+		p.parseParams(request.Vars, block)
+	}
+	if block := doc.Block("settings"); block != nil {
+		p.parseParams(request.Settings, block)
+	}
+	p.parseBody(request, doc)
+	p.parseAuth(request, doc)
+	if block := doc.Block("assert"); block != nil {
+		p.parseAssert(request, block)
+	}
+	if block := doc.Block("script:pre-request"); block != nil {
+		request.Script.PreRequest = block.Content()
+	}
+	if block := doc.Block("script:post-response"); block != nil {
+		request.Script.PostResponse = block.Content()
+	}
+	if block := doc.Block("tests"); block != nil {
+		request.Tests = block.Content()
+	}
+	if block := doc.Block("docs"); block != nil {
+		request.Docs = block.Content()
+	}
 
 	return request, nil
 }
 
-func (p *BruParser) nextLine() bool {
-	if p.scanner.Scan() {
-		p.line = p.scanner.Text()
-		p.lineNum++
-		return true
+func (p *BruParser) parseMeta(request *request.BruRequest, block *bru.Block) {
+	request.Meta.Name, _ = block.Get("name")
+	request.Meta.Type, _ = block.Get("type")
+	if value, ok := block.Get("seq"); ok {
+		if seq, err := strconv.Atoi(value); err == nil {
+			request.Meta.Seq = seq
+		}
 	}
-	return false
 }
 
-func (p *BruParser) parseMeta(request *request.BruRequest) error {
-	for p.nextLine() {
-		line := strings.TrimSpace(p.line)
-		if line == "}" {
-			break
-		}
-		if line == "" {
-			continue
-		}
-
-		parts := strings.SplitN(line, ":", 2)
-		if len(parts) != 2 {
-			continue
-		}
-
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-		value = p.unquoteString(value)
-
-		switch key {
-		case "name":
-			request.Meta.Name = value
-		case "type":
-			request.Meta.Type = value
-		case "seq":
-			if seq, err := strconv.Atoi(value); err == nil {
-				request.Meta.Seq = seq
-			}
-		}
+func (p *BruParser) parseHTTP(request *request.BruRequest, block *bru.Block) {
+	request.HTTP.Method = strings.ToUpper(block.Name)
+	if block.Name == bru.CustomMethodBlock {
+		method, _ := block.Get("method")
+		request.HTTP.Method = strings.ToUpper(strings.TrimSpace(method))
 	}
-	return nil
+	request.HTTP.URL, _ = block.Get("url")
 }
 
-func (p *BruParser) parseHTTP(request *request.BruRequest) error {
-	for p.nextLine() {
-		line := strings.TrimSpace(p.line)
-		if line == "}" {
-			break
-		}
-		if line == "" {
+// parseParams reads the enabled entries of a vars or settings block.
+// Entries prefixed with "@" are annotations, not values.
+func (p *BruParser) parseParams(target map[string]string, block *bru.Block) {
+	for key, value := range block.Map() {
+		if strings.HasPrefix(key, "@") {
 			continue
 		}
-
-		parts := strings.SplitN(line, ":", 2)
-		if len(parts) != 2 {
-			continue
-		}
-
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-		value = p.unquoteString(value)
-
-		switch key {
-		case "url":
-			request.HTTP.URL = value
-		}
+		target[key] = value
 	}
-	return nil
 }
 
-func (p *BruParser) parseHeaders(request *request.BruRequest) error {
-	for p.nextLine() {
-		line := strings.TrimSpace(p.line)
-		if line == "}" {
-			break
-		}
-		if line == "" || strings.HasPrefix(line, "@") {
+// parseParamList reads the enabled entries of a headers or query block in
+// file order, keeping repeated keys
+func (p *BruParser) parseParamList(block *bru.Block) request.BruParams {
+	var params request.BruParams
+	for _, entry := range block.Entries {
+		if entry.Disabled || strings.HasPrefix(entry.Key, "@") {
 			continue
 		}
-
-		parts := strings.SplitN(line, ":", 2)
-		if len(parts) != 2 {
-			continue
-		}
-
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-		value = p.unquoteString(value)
-
-		request.Headers[key] = value
+		params.Add(entry.Key, entry.Value)
 	}
-	return nil
+	return params
 }
 
-func (p *BruParser) parseQuery(request *request.BruRequest) error {
-	for p.nextLine() {
-		line := strings.TrimSpace(p.line)
-		if line == "}" {
-			break
-		}
-		if line == "" || strings.HasPrefix(line, "@") {
-			continue
+// bodyMode returns the body mode selected in the method block, falling back
+// to the first body block for files written without a `body:` key
+func bodyMode(doc *bru.Document) string {
+	if block := doc.MethodBlock(); block != nil {
+		if mode, ok := block.Get("body"); ok {
+			return mode
 		}
-
-		parts := strings.SplitN(line, ":", 2)
-		if len(parts) != 2 {
-			continue
+	}
+	for _, block := range doc.BlocksWithPrefix("body:") {
+		// GraphQL variables belong to the body:graphql block
+		if block.Name != "body:graphql:vars" {
+			return strings.TrimPrefix(block.Name, "body:")
 		}
-
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-		value = p.unquoteString(value)
-
-		request.Query[key] = value
 	}
-	return nil
+	return "none"
 }
 
-func (p *BruParser) parseBody(request *request.BruRequest, headerLine string) error {
-	bodyTypeRegex := regexp.MustCompile(`body:(\w+)`)
-	matches := bodyTypeRegex.FindStringSubmatch(headerLine)
-	if len(matches) > 1 {
-		request.Body.Type = matches[1]
-	}
-
-	if !p.nextLine() {
-		return nil
-	}
-
-	line := strings.TrimSpace(p.line)
-	if line != "{" {
-		return fmt.Errorf("expected '{' after body declaration")
-	}
-
-	var bodyContent strings.Builder
-	braceCount := 1
-
-	for p.nextLine() && braceCount > 0 {
-		line := p.line
-		
-		for _, char := range line {
-			if char == '{' {
-				braceCount++
-			} else if char == '}' {
-				braceCount--
-			}
-		}
-
-		if braceCount > 0 {
-			bodyContent.WriteString(line)
-			bodyContent.WriteString("\n")
+// authMode returns the auth mode selected in the method block, falling back
+// to the first auth block for files written without an `auth:` key
+func authMode(doc *bru.Document) string {
+	if block := doc.MethodBlock(); block != nil {
+		if mode, ok := block.Get("auth"); ok {
+			return mode
 		}
 	}
-
-	content := strings.TrimSpace(bodyContent.String())
-	request.Body.Data = content
-	return nil
+	if blocks := doc.BlocksWithPrefix("auth:"); len(blocks) > 0 {
+		return strings.TrimPrefix(blocks[0].Name, "auth:")
+	}
+	return "none"
 }
 
-func (p *BruParser) parseAuth(request *request.BruRequest, headerLine string) error {
-	authTypeRegex := regexp.MustCompile(`auth:(\w+)`)
-	matches := authTypeRegex.FindStringSubmatch(headerLine)
-	if len(matches) > 1 {
-		request.Auth.Type = matches[1]
+func (p *BruParser) parseBody(req *request.BruRequest, doc *bru.Document) {
+	mode := bodyMode(doc)
+	if mode == "none" {
+		return
 	}
 
-	if !p.nextLine() {
-		return nil
+	req.Body.Type = mode
+	if mode == "graphql" {
+		if block := doc.Block("body:graphql:vars"); block != nil {
+			req.Body.Vars = block.Content()
+		}
 	}
-
-	line := strings.TrimSpace(p.line)
-	if line != "{" {
-		return nil
+	block := doc.Block("body:" + mode)
+	if block == nil {
+		return
 	}
-
-	for p.nextLine() {
-		line := strings.TrimSpace(p.line)
-		if line == "}" {
-			break
-		}
-		if line == "" {
-			continue
-		}
-
-		parts := strings.SplitN(line, ":", 2)
-		if len(parts) != 2 {
-			continue
+	if block.Kind == bru.DictBlock {
+		// Form bodies keep their field order, duplicates and disabled fields
+		for _, entry := range block.Entries {
+			req.Body.Fields = append(req.Body.Fields, request.BruFormField{
+				Key:     entry.Key,
+				Value:   entry.Value,
+				Enabled: !entry.Disabled,
+			})
 		}
-
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-		value = p.unquoteString(value)
-
-		request.Auth.Values[key] = value
+		return
 	}
-	return nil
+	req.Body.Data = block.Content()
 }
 
-func (p *BruParser) parseVars(request *request.BruRequest) error {
-	for p.nextLine() {
-		line := strings.TrimSpace(p.line)
-		if line == "}" {
-			break
-		}
-		if line == "" {
-			continue
-		}
-
-		parts := strings.SplitN(line, ":", 2)
-		if len(parts) != 2 {
-			continue
-		}
-
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-		value = p.unquoteString(value)
-
-		request.Vars[key] = value
+func (p *BruParser) parseAuth(request *request.BruRequest, doc *bru.Document) {
+	mode := authMode(doc)
+	if mode == "none" {
+		return
 	}
-	return nil
-}
-
-func (p *BruParser) parseTests(request *request.BruRequest) error {
-	var content strings.Builder
-	braceCount := 1
 
-	for p.nextLine() && braceCount > 0 {
-		line := p.line
-		
-		for _, char := range line {
-			if char == '{' {
-				braceCount++
-			} else if char == '}' {
-				braceCount--
-			}
-		}
-
-		if braceCount > 0 {
-			content.WriteString(line)
-			content.WriteString("\n")
+	request.Auth.Type = mode
+	if block := doc.Block("auth:" + mode); block != nil {
+		for key, value := range block.Map() {
+			request.Auth.Values[key] = value
 		}
 	}
-
-	request.Tests = strings.TrimSpace(content.String())
-	return nil
 }
 
-func (p *BruParser) parseDocs(request *request.BruRequest) error {
-	var content strings.Builder
-	braceCount := 1
-
-	for p.nextLine() && braceCount > 0 {
-		line := p.line
-		
-		for _, char := range line {
-			if char == '{' {
-				braceCount++
-			} else if char == '}' {
-				braceCount--
-			}
-		}
-
-		if braceCount > 0 {
-			content.WriteString(line)
-			content.WriteString("\n")
-		}
+// parseAssert reads assertions such as `res.status: eq 200`. Entries
+// prefixed with "~" are kept but disabled.
+func (p *BruParser) parseAssert(req *request.BruRequest, block *bru.Block) {
+	for _, entry := range block.Entries {
+		req.Assertions = append(req.Assertions, request.ParseAssertion(entry.Key, entry.Value, !entry.Disabled))
 	}
-
-	request.Docs = strings.TrimSpace(content.String())
-	return nil
 }
 
-func (p *BruParser) parseTags(request *request.BruRequest) error {
-	for p.nextLine() {
-		line := strings.TrimSpace(p.line)
-		if line == "}" {
-			break
-		}
-		if line == "" {
-			continue
-		}
+func (p *BruParser) parseTags(request *request.BruRequest, block *bru.Block) {
+	request.Tags = append(request.Tags, parseTagList(block.Content())...)
+}
 
-		// Tags can be on separate lines or comma-separated
-		tags := strings.Split(line, ",")
-		for _, tag := range tags {
+// parseTagList reads tags listed on separate lines or comma-separated
+func parseTagList(content string) []string {
+	var tags []string
+	for _, line := range strings.Split(content, "\n") {
+		for _, tag := range strings.Split(line, ",") {
 			tag = strings.TrimSpace(tag)
-			tag = p.unquoteString(tag)
+			if strings.HasPrefix(tag, "'") && strings.HasSuffix(tag, "'") && len(tag) >= 2 {
+				tag = tag[1 : len(tag)-1]
+			}
 			if tag != "" {
-				request.Tags = append(request.Tags, tag)
+				tags = append(tags, tag)
 			}
 		}
 	}
-	return nil
-}
-
-func (p *BruParser) unquoteString(s string) string {
-	s = strings.TrimSpace(s)
-	if strings.HasPrefix(s, "'") && strings.HasSuffix(s, "'") {
-		return s[1 : len(s)-1]
-	}
-	return s
+	return tags
 }
 
 func getMethodColor(method string) string {
@@ -406,6 +246,12 @@ func getMethodColor(method string) string {
 		return "🔴"
 	case "PATCH":
 		return "🟠"
+	case "HEAD":
+		return "🟣"
+	case "OPTIONS":
+		return "🟤"
+	case "TRACE", "CONNECT":
+		return "⚫"
 	default:
 		return "⚪"
 	}
@@ -423,8 +269,16 @@ func getMethodPriority(method string) int {
 		return 4
 	case "DELETE":
 		return 5
+	case "HEAD":
+		return 6
+	case "OPTIONS":
+		return 7
+	case "TRACE":
+		return 8
+	case "CONNECT":
+		return 9
 	default:
-		return 6 // Other methods come last
+		return 10 // Custom methods come last
 	}
 }
 
@@ -447,19 +301,22 @@ func formatRequestDisplayName(indentLevel, methodEmoji, requestName, method stri
 }
 
 type CollectionsData struct {
-	Collections []collections.CollectionItem
-	BruRequests []*request.BruRequest
+	Collections  []collections.CollectionItem
+	BruRequests  []*request.BruRequest
+	Environments map[string][]*Environment // Keyed by collection directory
 }
 
 func LoadBruFiles(collectionsDir string, width int) *CollectionsData {
 	collectionItems := []collections.CollectionItem{}
 	bruRequests := []*request.BruRequest{}
+	environments := make(map[string][]*Environment)
 
 	// Check if collections directory exists and has content
 	if _, err := os.Stat(collectionsDir); os.IsNotExist(err) {
 		return &CollectionsData{
-			Collections: collectionItems,
-			BruRequests: bruRequests,
+			Collections:  collectionItems,
+			BruRequests:  bruRequests,
+			Environments: environments,
 		}
 	}
 
@@ -467,22 +324,27 @@ func LoadBruFiles(collectionsDir string, width int) *CollectionsData {
 	dirEntries, err := os.ReadDir(collectionsDir)
 	if err != nil {
 		return &CollectionsData{
-			Collections: collectionItems,
-			BruRequests: bruRequests,
+			Collections:  collectionItems,
+			BruRequests:  bruRequests,
+			Environments: environments,
 		}
 	}
 
+	// Environments for standalone requests live in the root collections directory
+	environments[collectionsDir] = LoadEnvironments(collectionsDir)
+
 	// Group requests by collection and then by tag
 	collectionsMap := make(map[string]map[string][]*request.BruRequest)
 	requestPaths := make(map[*request.BruRequest]string)
 
 	// Add collection folders first and load their requests
 	for _, entry := range dirEntries {
-		if entry.IsDir() {
+		if entry.IsDir() && entry.Name() != environmentsDirName {
 			collectionName := entry.Name()
 			collectionPath := filepath.Join(collectionsDir, collectionName)
 			
 			collectionsMap[collectionName] = make(map[string][]*request.BruRequest)
+			environments[collectionPath] = LoadEnvironments(collectionPath)
 
 			// Load .bru files from this collection
 			collectionFiles, err := os.ReadDir(collectionPath)
@@ -491,7 +353,7 @@ func LoadBruFiles(collectionsDir string, width int) *CollectionsData {
 			}
 
 			for _, file := range collectionFiles {
-				if strings.HasSuffix(file.Name(), ".bru") {
+				if strings.HasSuffix(file.Name(), ".bru") && file.Name() != collectionFileName {
 					bruPath := filepath.Join(collectionPath, file.Name())
 					
 					bruFile, err := os.Open(bruPath)
@@ -506,6 +368,7 @@ func LoadBruFiles(collectionsDir string, width int) *CollectionsData {
 						continue
 					}
 
+					request.Dir = collectionPath
 					bruRequests = append(bruRequests, request)
 					requestPaths[request] = bruPath
 
@@ -525,7 +388,7 @@ func LoadBruFiles(collectionsDir string, width int) *CollectionsData {
 	// Add any standalone .bru files in the root collections directory
 	rootRequests := make(map[string][]*request.BruRequest)
 	for _, entry := range dirEntries {
-		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".bru") {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".bru") && entry.Name() != collectionFileName {
 			bruPath := filepath.Join(collectionsDir, entry.Name())
 			
 			bruFile, err := os.Open(bruPath)
@@ -540,6 +403,7 @@ func LoadBruFiles(collectionsDir string, width int) *CollectionsData {
 				continue
 			}
 
+			request.Dir = collectionsDir
 			bruRequests = append(bruRequests, request)
 			requestPaths[request] = bruPath
 
@@ -733,7 +597,8 @@ func LoadBruFiles(collectionsDir string, width int) *CollectionsData {
 	}
 
 	return &CollectionsData{
-		Collections: collectionItems,
-		BruRequests: bruRequests,
+		Collections:  collectionItems,
+		BruRequests:  bruRequests,
+		Environments: environments,
 	}
 }
\ No newline at end of file
@@ -2,14 +2,18 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
-	"kalo/src/panels"
+
+	panels "kalo/src/panels/request"
 )
 
+var updateGolden = flag.Bool("update", false, "rewrite golden files in testdata")
+
 func TestBruParser(t *testing.T) {
 	testFiles := []string{
 		"../examples/get-users.bru",
@@ -131,7 +135,434 @@ headers {
 		t.Errorf("Expected URL 'https://example.com/api', got %s", request.HTTP.URL)
 	}
 
-	if request.Headers["Accept"] != "application/json" {
-		t.Errorf("Expected Accept header 'application/json', got %s", request.Headers["Accept"])
+	if accept, _ := request.Headers.Get("Accept"); accept != "application/json" {
+		t.Errorf("Expected Accept header 'application/json', got %s", accept)
 	}
-}
\ No newline at end of file
+}
+func TestParseScriptBlocks(t *testing.T) {
+	bruContent := `meta {
+  name: Login
+  type: http
+  seq: 1
+}
+
+post {
+  url: https://example.com/login
+  body: none
+  auth: none
+}
+
+script:pre-request {
+  req.setHeader("X-Request-Id", "1");
+  if (true) {
+    bru.setVar("started", "yes");
+  }
+}
+
+script:post-response {
+  bru.setVar("token", res.getBody().token);
+}
+
+tests {
+  test("ok", function() {});
+}`
+
+	parser := NewBruParser(strings.NewReader(bruContent))
+	request, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse scripts: %v", err)
+	}
+
+	expectedPre := "req.setHeader(\"X-Request-Id\", \"1\");\nif (true) {\n  bru.setVar(\"started\", \"yes\");\n}"
+	if request.Script.PreRequest != expectedPre {
+		t.Errorf("Expected pre-request script %q, got %q", expectedPre, request.Script.PreRequest)
+	}
+
+	expectedPost := "bru.setVar(\"token\", res.getBody().token);"
+	if request.Script.PostResponse != expectedPost {
+		t.Errorf("Expected post-response script %q, got %q", expectedPost, request.Script.PostResponse)
+	}
+
+	if request.Tests == "" {
+		t.Errorf("Expected tests block after scripts to be parsed")
+	}
+}
+
+func TestParseAssertBlock(t *testing.T) {
+	bruContent := `meta {
+  name: Users
+  type: http
+  seq: 1
+}
+
+get {
+  url: https://example.com/users
+  body: none
+  auth: none
+}
+
+assert {
+  res.status: eq 200
+  res.body.items: isArray
+  res.body.total: 42
+  ~res.headers.content-type: contains json
+}`
+
+	parser := NewBruParser(strings.NewReader(bruContent))
+	request, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse assert block: %v", err)
+	}
+
+	if len(request.Assertions) != 4 {
+		t.Fatalf("Expected 4 assertions, got %d", len(request.Assertions))
+	}
+
+	expected := []struct {
+		expression string
+		operator   string
+		value      string
+		enabled    bool
+	}{
+		{"res.status", "eq", "200", true},
+		{"res.body.items", "isArray", "", true},
+		{"res.body.total", "eq", "42", true},
+		{"res.headers.content-type", "contains", "json", false},
+	}
+
+	for i, want := range expected {
+		got := request.Assertions[i]
+		if got.Expression != want.expression || got.Operator != want.operator || got.Value != want.value || got.Enabled != want.enabled {
+			t.Errorf("Assertion %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+}
+
+func TestParseTimeoutSetting(t *testing.T) {
+	bruContent := "get {\n  url: {{baseUrl}}/slow\n}\n\nsettings {\n  timeout: 50\n}"
+	bruReq, err := NewBruParser(strings.NewReader(bruContent)).Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	if bruReq.Settings["timeout"] != "50" {
+		t.Errorf("Expected timeout setting 50, got %q", bruReq.Settings["timeout"])
+	}
+}
+
+func TestBruRoundTrip(t *testing.T) {
+	examples, _ := filepath.Glob("../examples/*.bru")
+	fixtures, _ := filepath.Glob("testdata/roundtrip/*.bru")
+	files := append(examples, fixtures...)
+	if len(files) == 0 {
+		t.Fatal("No .bru files found for round-trip test")
+	}
+
+	for _, filename := range files {
+		t.Run(filename, func(t *testing.T) {
+			original, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatalf("Failed to read %s: %v", filename, err)
+			}
+
+			request, err := NewBruParser(strings.NewReader(string(original))).Parse()
+			if err != nil {
+				t.Fatalf("Failed to parse %s: %v", filename, err)
+			}
+
+			if written := FormatBruRequest(request); written != string(original) {
+				t.Errorf("Round trip changed %s:\n--- original\n%s\n--- written\n%s", filename, original, written)
+			}
+		})
+	}
+}
+
+func TestParseBracesInStrings(t *testing.T) {
+	original, err := os.ReadFile("testdata/roundtrip/braces.bru")
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	request, err := NewBruParser(strings.NewReader(string(original))).Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse fixture: %v", err)
+	}
+	if request.Body.Data != `{"pattern": "}"}` {
+		t.Errorf("Expected the brace in the JSON string to stay in the body, got %q", request.Body.Data)
+	}
+	if request.Script.PreRequest != "const open = \"{\";\nreq.setHeader(\"X-Open\", open);" {
+		t.Errorf("Expected the script to end at its closing brace, got %q", request.Script.PreRequest)
+	}
+	if !strings.Contains(request.Tests, `to.equal("}}")`) {
+		t.Errorf("Expected the tests block after the script, got %q", request.Tests)
+	}
+}
+
+func TestBruRoundTripEdits(t *testing.T) {
+	original, err := os.ReadFile("testdata/roundtrip/full.bru")
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	request, err := NewBruParser(strings.NewReader(string(original))).Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse fixture: %v", err)
+	}
+
+	if expand, _ := request.Query.Get("expand"); expand != "items" || len(request.Query) != 1 {
+		t.Errorf("Expected only the enabled query parameter, got %v", request.Query)
+	}
+	if request.Auth.Type != "bearer" || request.Auth.Values["token"] != "{{token}}" {
+		t.Errorf("Expected bearer auth selected by the post block, got %s %v", request.Auth.Type, request.Auth.Values)
+	}
+	if !strings.HasPrefix(request.Body.Data, "{\n  \"status\"") {
+		t.Errorf("Expected the full JSON body, got %q", request.Body.Data)
+	}
+	if request.Vars["note"] != "first line\nsecond line" {
+		t.Errorf("Expected multi-line var, got %q", request.Vars["note"])
+	}
+
+	// Edit the request the way the TUI does
+	request.Meta.Name = "Ship Order"
+	request.HTTP.Method = "PUT"
+	request.Headers.Set("Content-Type", "application/json; charset=utf-8")
+	request.Headers.Set("Accept", "application/json")
+	request.Headers.Delete("X-Request-Id")
+	request.Auth.Values["token"] = "{{adminToken}}"
+	request.Body.Data = "{\n  \"status\": \"delivered\"\n}"
+	request.Assertions = append(request.Assertions, panels.ParseAssertion("res.body.id", "isNumber", true))
+	request.Docs = ""
+
+	written := FormatBruRequest(request)
+
+	golden := "testdata/roundtrip/full.edited.golden"
+	if *updateGolden {
+		if err := os.WriteFile(golden, []byte(written), 0644); err != nil {
+			t.Fatalf("Failed to update %s: %v", golden, err)
+		}
+	}
+
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", golden, err)
+	}
+	if written != string(expected) {
+		t.Errorf("Edited output differs from %s:\n--- expected\n%s\n--- written\n%s", golden, expected, written)
+	}
+
+	// The written file must parse back to the edited request
+	reparsed, err := NewBruParser(strings.NewReader(written)).Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse written file: %v", err)
+	}
+	accept, _ := reparsed.Headers.Get("Accept")
+	if reparsed.HTTP.Method != "PUT" || accept != "application/json" || reparsed.Auth.Values["token"] != "{{adminToken}}" {
+		t.Errorf("Written file did not keep edits: %s %v %v", reparsed.HTTP.Method, reparsed.Headers, reparsed.Auth.Values)
+	}
+}
+
+func TestFormatNewBruRequest(t *testing.T) {
+	request := &panels.BruRequest{
+		Meta:    panels.BruMeta{Name: "Create", Seq: 1},
+		HTTP:    panels.BruHTTP{Method: "POST", URL: "https://example.com/items"},
+		Headers: panels.BruParams{{Key: "Content-Type", Value: "application/json"}},
+		Body:    panels.BruBody{Type: "json", Data: "{\n  \"a\": 1\n}"},
+		Auth:    panels.BruAuth{Type: "bearer", Values: map[string]string{"token": "{{token}}"}},
+		Tags:    []string{"items"},
+	}
+
+	expected := `meta {
+  name: Create
+  type: http
+  seq: 1
+}
+
+tags {
+  items
+}
+
+post {
+  url: https://example.com/items
+  auth: bearer
+  body: json
+}
+
+headers {
+  Content-Type: application/json
+}
+
+auth:bearer {
+  token: {{token}}
+}
+
+body:json {
+  {
+    "a": 1
+  }
+}
+`
+
+	if written := FormatBruRequest(request); written != expected {
+		t.Errorf("Unexpected output for new request:\n%s", written)
+	}
+}
+
+func TestMethodBlocks(t *testing.T) {
+	tests := []struct {
+		block  string
+		method string
+	}{
+		{"head {\n  url: https://example.com\n}\n", "HEAD"},
+		{"options {\n  url: https://example.com\n}\n", "OPTIONS"},
+		{"trace {\n  url: https://example.com\n}\n", "TRACE"},
+		{"connect {\n  url: https://example.com\n}\n", "CONNECT"},
+		// Other methods are kept in an http block
+		{"http {\n  method: PROPFIND\n  url: https://example.com\n}\n", "PROPFIND"},
+	}
+	for _, test := range tests {
+		t.Run(test.method, func(t *testing.T) {
+			source := "meta {\n  name: Probe\n  type: http\n  seq: 1\n}\n\n" + test.block
+			request, err := NewBruParser(strings.NewReader(source)).Parse()
+			if err != nil {
+				t.Fatalf("Failed to parse: %v", err)
+			}
+			if request.HTTP.Method != test.method {
+				t.Errorf("Expected method %s, got %q", test.method, request.HTTP.Method)
+			}
+			if written := FormatBruRequest(request); written != source {
+				t.Errorf("Unexpected output:\n%s", written)
+			}
+		})
+	}
+}
+
+func TestCustomMethodBlock(t *testing.T) {
+	source := "meta {\n  name: List\n  type: http\n  seq: 1\n}\n\nhttp {\n  method: propfind\n  url: https://example.com/dav\n}\n"
+	request, err := NewBruParser(strings.NewReader(source)).Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse custom method: %v", err)
+	}
+	if request.HTTP.Method != "PROPFIND" {
+		t.Errorf("Expected method PROPFIND, got %q", request.HTTP.Method)
+	}
+
+	request.HTTP.Method = "GET"
+	expected := "meta {\n  name: List\n  type: http\n  seq: 1\n}\n\nget {\n  url: https://example.com/dav\n}\n"
+	if written := FormatBruRequest(request); written != expected {
+		t.Errorf("Unexpected output after switching to GET:\n%s", written)
+	}
+
+	created := &panels.BruRequest{Meta: panels.BruMeta{Name: "Search", Type: "http", Seq: 1}, HTTP: panels.BruHTTP{Method: "QUERY", URL: "https://example.com"}}
+	expected = "meta {\n  name: Search\n  type: http\n  seq: 1\n}\n\nhttp {\n  method: QUERY\n  url: https://example.com\n}\n"
+	if written := FormatBruRequest(created); !strings.HasPrefix(written, expected) {
+		t.Errorf("Unexpected output for new QUERY request:\n%s", written)
+	}
+}
+
+// repeatedParamsSource repeats a query parameter and a header
+const repeatedParamsSource = "meta {\n  name: Search\n  type: http\n  seq: 1\n}\n\nget {\n  url: {{baseUrl}}/search?q=go\n}\n\nquery {\n  tag: b\n  ~tag: skipped\n  tag: a\n  limit: 10\n}\n\nheaders {\n  Accept: application/json\n  X-Trace: 1\n  Accept: text/plain\n}\n"
+
+func TestRepeatedParamsRoundTrip(t *testing.T) {
+	request, err := NewBruParser(strings.NewReader(repeatedParamsSource)).Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	if tags := request.Query.Values("tag"); len(tags) != 2 || tags[0] != "b" || tags[1] != "a" {
+		t.Errorf("Expected both tag parameters in order, got %v", request.Query)
+	}
+	if written := FormatBruRequest(request); written != repeatedParamsSource {
+		t.Errorf("Unexpected output for unchanged request:\n%s", written)
+	}
+
+	// Editing keeps the order of the other entries
+	request.InitializeQueryEditState()
+	request.UpdateQueryParameter(0, "tag", "c")
+	request.AddQueryParameter("tag", "d")
+	expected := strings.Replace(repeatedParamsSource, "  tag: b\n  ~tag: skipped\n  tag: a\n  limit: 10\n", "  tag: c\n  ~tag: skipped\n  tag: a\n  limit: 10\n  tag: d\n", 1)
+	if written := FormatBruRequest(request); written != expected {
+		t.Errorf("Unexpected output after editing query:\n%s", written)
+	}
+}
+
+func TestParseFormBody(t *testing.T) {
+	file, err := os.Open("testdata/roundtrip/form.bru")
+	if err != nil {
+		t.Fatalf("Failed to open fixture: %v", err)
+	}
+	defer file.Close()
+
+	request, err := NewBruParser(file).Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse form body: %v", err)
+	}
+
+	if request.Body.Type != "multipart-form" {
+		t.Fatalf("Expected multipart-form body, got %s", request.Body.Type)
+	}
+	if len(request.Body.Fields) != 3 {
+		t.Fatalf("Expected 3 form fields, got %d", len(request.Body.Fields))
+	}
+
+	avatar := request.Body.Fields[1]
+	paths, isFile := avatar.FilePaths()
+	if !isFile || len(paths) != 1 || paths[0] != "fixtures/avatar.png" {
+		t.Errorf("Expected avatar to upload fixtures/avatar.png, got %v", paths)
+	}
+	if request.Body.Fields[2].Enabled {
+		t.Errorf("Expected ~thumbnail to be disabled")
+	}
+}
+
+func TestParseGraphQLBody(t *testing.T) {
+	file, err := os.Open("testdata/roundtrip/graphql.bru")
+	if err != nil {
+		t.Fatalf("Failed to open fixture: %v", err)
+	}
+	defer file.Close()
+
+	request, err := NewBruParser(file).Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse graphql body: %v", err)
+	}
+
+	if request.Meta.Type != "graphql" || request.Body.Type != "graphql" {
+		t.Fatalf("Expected graphql request, got type %s with %s body", request.Meta.Type, request.Body.Type)
+	}
+	if !strings.HasPrefix(request.Body.Data, "query GetUser($id: ID!) {") {
+		t.Errorf("Unexpected query: %q", request.Body.Data)
+	}
+
+	sent, err := NewHTTPClient().ResolveRequest(request, nil, map[string]string{"baseUrl": "http://localhost", "userId": "42"})
+	if err != nil {
+		t.Fatalf("Failed to resolve graphql request: %v", err)
+	}
+	if sent.Headers.Get("Content-Type") != string(ContentTypeJSON) {
+		t.Errorf("Expected JSON content type, got %q", sent.Headers.Get("Content-Type"))
+	}
+
+	var payload struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables"`
+	}
+	if err := json.Unmarshal([]byte(sent.Body.Content), &payload); err != nil {
+		t.Fatalf("Expected JSON payload, got %q: %v", sent.Body.Content, err)
+	}
+	if payload.Query != request.Body.Data {
+		t.Errorf("Expected query to be sent unchanged, got %q", payload.Query)
+	}
+	if payload.Variables["id"] != "42" {
+		t.Errorf("Expected substituted id variable, got %v", payload.Variables["id"])
+	}
+}
+
+func TestParseWebSocketRequest(t *testing.T) {
+	source := "meta {\n  name: Chat\n  type: ws\n  seq: 1\n}\n\nws {\n  url: {{baseUrl}}/chat\n  auth: none\n}\n\nheaders {\n  Authorization: Bearer abc\n}\n"
+	request, err := NewBruParser(strings.NewReader(source)).Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse ws request: %v", err)
+	}
+	if !request.IsWebSocket() || request.HTTP.Method != "WS" {
+		t.Fatalf("Expected a WebSocket request, got %q %q", request.Meta.Type, request.HTTP.Method)
+	}
+	if written := FormatBruRequest(request); written != source {
+		t.Errorf("Unexpected output for ws request:\n%s", written)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"kalo/src/bru"
+)
+
+// ProxyConfig is the proxy configuration of a collection, read from the
+// `proxy` block of its collection.bru:
+//
+//	proxy {
+//	  url: http://proxy.corp.example.com:3128
+//	  username: jane
+//	  password: {{proxyPassword}}
+//	  bypass: localhost, *.internal.example.com, 10.0.0.0/8
+//	}
+//
+// Without a url, requests use the HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+// environment variables, still skipping the bypassed hosts.
+type ProxyConfig struct {
+	URL      *url.URL
+	Bypass   []string
+	Disabled bool // enabled: false also ignores the environment variables
+}
+
+// proxySchemes are the proxy protocols the transport supports
+var proxySchemes = map[string]bool{
+	"http":    true,
+	"https":   true,
+	"socks5":  true,
+	"socks5h": true,
+}
+
+// parseCollectionProxy reads the proxy configuration of a collection.bru,
+// passing the username and password through resolve to substitute variables.
+// It returns nil when the collection has no proxy block.
+func parseCollectionProxy(doc *bru.Document, resolve func(string) string) (*ProxyConfig, error) {
+	proxyBlock := doc.Block("proxy")
+	if proxyBlock == nil {
+		return nil, nil
+	}
+
+	config := &ProxyConfig{}
+	var username, password string
+	for _, entry := range proxyBlock.Entries {
+		if entry.Disabled {
+			continue
+		}
+		value := strings.TrimSpace(entry.Value)
+		switch entry.Key {
+		case "url":
+			proxyURL, err := url.Parse(value)
+			if err != nil || proxyURL.Host == "" || !proxySchemes[proxyURL.Scheme] {
+				return nil, fmt.Errorf("Invalid proxy url %q, use http://, https:// or socks5://host:port", value)
+			}
+			config.URL = proxyURL
+		case "username":
+			username = resolve(value)
+		case "password":
+			password = resolve(value)
+		case "bypass":
+			for _, pattern := range strings.Split(value, ",") {
+				if pattern = strings.ToLower(strings.TrimSpace(pattern)); pattern != "" {
+					config.Bypass = append(config.Bypass, pattern)
+				}
+			}
+		case "enabled":
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid proxy enabled %q", value)
+			}
+			config.Disabled = !enabled
+		}
+	}
+
+	// Separate credentials need no escaping, unlike ones in the url
+	if config.URL != nil && username != "" {
+		config.URL.User = url.UserPassword(username, password)
+	}
+
+	return config, nil
+}
+
+// cacheKey identifies a configuration so transports can be shared
+func (config *ProxyConfig) cacheKey() string {
+	proxyURL := ""
+	if config.URL != nil {
+		proxyURL = config.URL.String()
+	}
+	return fmt.Sprintf("%s|%s|%t", proxyURL, strings.Join(config.Bypass, ","), config.Disabled)
+}
+
+// proxyFunc returns the transport Proxy function for a configuration. A nil
+// configuration uses the environment variables.
+func proxyFunc(config *ProxyConfig) func(*http.Request) (*url.URL, error) {
+	if config == nil {
+		return http.ProxyFromEnvironment
+	}
+	return func(req *http.Request) (*url.URL, error) {
+		if config.Disabled || proxyBypassed(req.URL.Hostname(), config.Bypass) {
+			return nil, nil
+		}
+		if config.URL != nil {
+			return config.URL, nil
+		}
+		return http.ProxyFromEnvironment(req)
+	}
+}
+
+// proxyBypassed reports whether host matches a bypass pattern: `*` for every
+// host, a host name or IP, a `*.example.com` glob, a `.example.com` suffix or
+// a CIDR range
+func proxyBypassed(host string, patterns []string) bool {
+	host = strings.ToLower(host)
+	ip := net.ParseIP(host)
+	for _, pattern := range patterns {
+		switch {
+		case pattern == "*" || pattern == host:
+			return true
+		case strings.HasPrefix(pattern, "."):
+			if strings.HasSuffix(host, pattern) || host == pattern[1:] {
+				return true
+			}
+		case strings.Contains(pattern, "/"):
+			if _, network, err := net.ParseCIDR(pattern); err == nil && ip != nil && network.Contains(ip) {
+				return true
+			}
+		default:
+			if matched, _ := path.Match(pattern, host); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	panels "kalo/src/panels/request"
+)
+
+// newProxyServer starts a proxy that requires credentials and answers with
+// the URL it was asked for
+func newProxyServer(t *testing.T) *httptest.Server {
+	return newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Proxy-Authorization") == "" {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		fmt.Fprintf(w, "proxied %s", r.URL)
+	})
+}
+
+// proxyCollection configures the proxy for a collection, bypassing local hosts
+func proxyCollection(proxy *httptest.Server) string {
+	return fmt.Sprintf("proxy {\n  url: %s\n  username: jane\n  password: p@ss\n  bypass: localhost, 127.0.0.0/8\n}\n", proxy.URL)
+}
+
+func TestCollectionProxy(t *testing.T) {
+	proxy := newProxyServer(t)
+
+	resp := sendWithCollection(t, t.TempDir(), proxyCollection(proxy), "http://api.example.test/users")
+	if resp.Error != "" || resp.Body != "proxied http://api.example.test/users" {
+		t.Errorf("Expected the request to go through the proxy, got %q (%s)", resp.Body, resp.Error)
+	}
+	if !strings.Contains(resp.Proxy, proxy.Listener.Addr().String()) || strings.Contains(resp.Proxy, "p@ss") {
+		t.Errorf("Expected the proxy with a redacted password, got %q", resp.Proxy)
+	}
+}
+
+func TestCollectionProxyBypass(t *testing.T) {
+	proxy := newProxyServer(t)
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "direct")
+	})
+
+	resp := sendWithCollection(t, t.TempDir(), proxyCollection(proxy), server.URL)
+	if resp.Error != "" || resp.Body != "direct" || resp.Proxy != "" {
+		t.Errorf("Expected bypassed hosts to connect directly, got %q via %q", resp.Body, resp.Proxy)
+	}
+}
+
+func TestProxyBypassed(t *testing.T) {
+	patterns := []string{"*.internal.example.com", ".corp", "10.0.0.0/8"}
+	tests := map[string]bool{
+		"api.internal.example.com": true,
+		"corp":                     true,
+		"git.corp":                 true,
+		"10.1.2.3":                 true,
+		"example.com":              false,
+		"11.0.0.1":                 false,
+	}
+	for host, expected := range tests {
+		if proxyBypassed(host, patterns) != expected {
+			t.Errorf("Expected proxyBypassed(%q) to be %t", host, expected)
+		}
+	}
+}
+
+func TestProxyCredentialVariables(t *testing.T) {
+	t.Setenv("KALO_TEST_PROXY_PASSWORD", "p@ss")
+	var authorization string
+	proxy := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Proxy-Authorization")
+	})
+
+	collectionDir := t.TempDir()
+	collection := fmt.Sprintf("proxy {\n  url: %s\n  username: {{proxyUser}}\n  password: {{process.env.KALO_TEST_PROXY_PASSWORD}}\n}\n", proxy.URL)
+	if err := os.WriteFile(filepath.Join(collectionDir, collectionFileName), []byte(collection), 0644); err != nil {
+		t.Fatal(err)
+	}
+	bruReq := &panels.BruRequest{HTTP: panels.BruHTTP{Method: "GET", URL: "http://api.example.test"}, Dir: collectionDir}
+	env := &Environment{Name: "test", Vars: map[string]string{"proxyUser": "jane"}}
+	resp := sendBruRequest(context.Background(), NewHTTPClient(), bruReq, env)
+	if resp.Error != "" {
+		t.Fatalf("Request failed: %s", resp.Error)
+	}
+	if expected := "Basic " + base64.StdEncoding.EncodeToString([]byte("jane:p@ss")); authorization != expected {
+		t.Errorf("Expected the proxy credentials from the environment, got %q", authorization)
+	}
+}
+
+func TestOAuth2TokenRequestUsesProxy(t *testing.T) {
+	var proxied []string
+	proxy := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		if r.URL.Path == "/token" {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"access_token": "proxied-token", "expires_in": 3600}`)
+			return
+		}
+		fmt.Fprint(w, r.Header.Get("Authorization"))
+	})
+
+	collectionDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(collectionDir, collectionFileName), []byte("proxy {\n  url: "+proxy.URL+"\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	bruReq := &panels.BruRequest{
+		HTTP: panels.BruHTTP{Method: "GET", URL: "http://api.example.test/resource"},
+		Auth: panels.BruAuth{Type: "oauth2", Values: map[string]string{
+			"grant_type":       "client_credentials",
+			"access_token_url": "http://auth.example.test/token",
+			"client_id":        "kalo",
+		}},
+		Dir: collectionDir,
+	}
+	resp := sendBruRequest(context.Background(), NewHTTPClient(), bruReq, nil)
+	if resp.Error != "" || resp.Body != "Bearer proxied-token" {
+		t.Fatalf("Expected the token to be fetched through the proxy, got %q (%s)", resp.Body, resp.Error)
+	}
+	if strings.Join(proxied, ",") != "http://auth.example.test/token,http://api.example.test/resource" {
+		t.Errorf("Expected both requests to go through the proxy, got %v", proxied)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	request "kalo/src/panels/request"
+	response "kalo/src/panels/response"
+)
+
+// RequestExecution is the outcome of running a request with its scripts and tests
+type RequestExecution struct {
+	Sent        *HTTPRequestModel // Nil when the request could not be built
+	Response    *response.HTTPResponse
+	TestResults []TestResult
+	ScriptLogs  []ScriptLog // Console output of the request's scripts
+}
+
+// prepareBruRequest runs the pre-request script, authorizes and resolves a
+// request, returning the copy scripts ran against and what is to be sent.
+// Console output of the script goes to console, which may be nil.
+func prepareBruRequest(ctx context.Context, client *HTTPClient, runner *ScriptRunner, bruReq *request.BruRequest, env *Environment, console *ScriptConsole) (*request.BruRequest, *HTTPRequestModel, error) {
+	// Scripts may modify the request, so work on a copy of the loaded one
+	req := bruReq.Clone()
+
+	if err := runner.RunPreRequest(req.Script.PreRequest, req, env, console); err != nil {
+		return nil, nil, fmt.Errorf("Pre-request script error: %v", err)
+	}
+
+	// collection.bru is read once and passed to everything the request needs it for
+	collection, err := client.collectionConfig(req.Dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	vars := mergeVars(env, req.Vars, runner.RuntimeVars(env))
+	if err := checkUnresolvedVars(req, collection, vars); err != nil {
+		return nil, nil, err
+	}
+	if err := client.AuthorizeOAuth2(ctx, req, collection, vars, env); err != nil {
+		return nil, nil, err
+	}
+	sent, err := client.ResolveRequest(req, collection, vars)
+	if err != nil {
+		return nil, nil, err
+	}
+	sent.CookieScope = collectionScope(req, env)
+	return req, sent, nil
+}
+
+// executeBruRequest runs the pre-request script, sends the request, then runs
+// the post-response script and tests. It is shared by the TUI and `kalo run`.
+func executeBruRequest(ctx context.Context, client *HTTPClient, runner *ScriptRunner, bruReq *request.BruRequest, env *Environment) *RequestExecution {
+	console := &ScriptConsole{}
+	req, sent, err := prepareBruRequest(ctx, client, runner, bruReq, env, console)
+	if err != nil {
+		return &RequestExecution{Response: &response.HTTPResponse{Error: err.Error()}, ScriptLogs: console.Logs()}
+	}
+
+	execution := &RequestExecution{
+		Sent:     sent,
+		Response: client.SendRequest(ctx, sent),
+	}
+
+	// Scripts and tests only run against responses that actually arrived
+	if execution.Response.Error != "" {
+		execution.ScriptLogs = console.Logs()
+		return execution
+	}
+
+	vars := mergeVars(env, req.Vars, runner.RuntimeVars(env))
+	execution.TestResults = EvaluateAssertions(req.Assertions, execution.Response, vars)
+
+	if err := runner.RunPostResponse(req.Script.PostResponse, req, execution.Response, env, console); err != nil {
+		execution.TestResults = append(execution.TestResults, TestResult{
+			Name:    "post-response script",
+			Passed:  false,
+			Message: fmt.Sprintf("Script error: %v", err),
+		})
+	}
+
+	execution.TestResults = append(execution.TestResults, runner.RunTests(req.Tests, req, execution.Response, env, console)...)
+	execution.ScriptLogs = console.Logs()
+	return execution
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	request "kalo/src/panels/request"
+)
+
+// parseExecutorRequest parses a request for the given server
+func parseExecutorRequest(t *testing.T, source, serverURL string) *request.BruRequest {
+	t.Helper()
+	bruReq, err := NewBruParser(strings.NewReader(strings.ReplaceAll(source, "{{server}}", serverURL))).Parse()
+	if err != nil {
+		t.Fatalf("Failed to parse request: %v", err)
+	}
+	return bruReq
+}
+
+func TestRuntimeVarsCarryOverBetweenRequests(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			w.Write([]byte(`{"token": "abc123"}`))
+			return
+		}
+		w.Write([]byte(r.Header.Get("Authorization")))
+	})
+
+	login := parseExecutorRequest(t, `post {
+  url: {{server}}/login
+}
+
+script:post-response {
+  bru.setVar("token", res.getBody().token);
+}`, server.URL)
+	profile := parseExecutorRequest(t, `get {
+  url: {{server}}/me
+}
+
+headers {
+  Authorization: Bearer {{token}}
+}
+
+tests {
+  test("token is shared", function() {
+    expect(bru.getVar("token")).to.equal("abc123");
+  });
+}`, server.URL)
+
+	client := NewHTTPClient()
+	runner := NewScriptRunner()
+	staging := &Environment{Name: "staging", FilePath: "api/environments/staging.bru"}
+	production := &Environment{Name: "production", FilePath: "api/environments/production.bru"}
+
+	if execution := executeBruRequest(context.Background(), client, runner, login, staging); execution.Response.Error != "" {
+		t.Fatalf("Login failed: %s", execution.Response.Error)
+	}
+
+	execution := executeBruRequest(context.Background(), client, runner, profile, staging)
+	if execution.Response.Body != "Bearer abc123" {
+		t.Errorf("Expected the token from the login response to be sent, got %q", execution.Response.Body)
+	}
+	if len(execution.TestResults) != 1 || !execution.TestResults[0].Passed {
+		t.Errorf("Expected bru.getVar to see the token, got %+v", execution.TestResults)
+	}
+
+	// Another environment starts without the variables set under staging
+	execution = executeBruRequest(context.Background(), client, runner, profile, production)
+	if execution.Response.Body != "Bearer {{token}}" {
+		t.Errorf("Expected the staging token not to be sent to production, got %q", execution.Response.Body)
+	}
+	if vars := runner.RuntimeVars(production); len(vars) != 0 {
+		t.Errorf("Expected no runtime variables for production, got %v", vars)
+	}
+	if vars := runner.RuntimeVars(staging); vars["token"] != "abc123" {
+		t.Errorf("Expected staging to keep its token, got %v", vars)
+	}
+}
+
+func TestPreRequestScriptChangesSentRequest(t *testing.T) {
+	var received struct {
+		trace string
+		body  string
+	}
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received.trace = r.Header.Get("X-Trace")
+		received.body = string(body)
+	})
+
+	bruReq := parseExecutorRequest(t, `post {
+  url: {{server}}/users
+  body: json
+}
+
+headers {
+  x-trace: from-file
+}
+
+body:json {
+  {"name": "from file"}
+}
+
+script:pre-request {
+  req.setHeader("X-Trace", "from-script");
+  req.setBody({ name: "from script", id: bru.getEnvVar("userId") });
+}`, server.URL)
+
+	env := &Environment{Name: "local", Vars: map[string]string{"userId": "42"}}
+	execution := executeBruRequest(context.Background(), NewHTTPClient(), NewScriptRunner(), bruReq, env)
+	if execution.Response.Error != "" {
+		t.Fatalf("Request failed: %s", execution.Response.Error)
+	}
+
+	if received.trace != "from-script" || execution.Sent.Headers.Get("X-Trace") != "from-script" {
+		t.Errorf("Expected the header set by the script to replace the file's, got %q (recorded %q)", received.trace, execution.Sent.Headers.Get("X-Trace"))
+	}
+	var payload map[string]string
+	if err := json.Unmarshal([]byte(received.body), &payload); err != nil || payload["name"] != "from script" || payload["id"] != "42" {
+		t.Errorf("Expected the body set by the script to be sent, got %q", received.body)
+	}
+	if execution.Sent.Body.Content != received.body {
+		t.Errorf("Expected the recorded body to match what was sent, got %q", execution.Sent.Body.Content)
+	}
+
+	// The loaded request is left as written
+	if value, _ := bruReq.Headers.Get("x-trace"); value != "from-file" || !strings.Contains(bruReq.Body.Data, "from file") {
+		t.Errorf("Expected the script to work on a copy, got header %q and body %q", value, bruReq.Body.Data)
+	}
+}
@@ -112,7 +112,6 @@ func (h *RequestInputHandler) HandleInput(key tea.KeyMsg, m *model) (*model, tea
 			// Delegate other character keys to section handlers
 			return h.handleRequestSectionAction(m, key)
 		}
-		return m, nil
 	}
 
 	return m, nil
@@ -128,11 +127,16 @@ func (h *RequestInputHandler) GetFooterText(m *model) string {
 	if h.isInTextInputMode(m) {
 		// Check if we're in query or header edit mode for specific Tab behavior
 		if (m.requestCursor == request.QuerySection && m.currentReq != nil && m.currentReq.QueryEditState != nil) ||
-		   (m.requestCursor == request.HeadersSection && m.currentReq != nil && m.currentReq.HeaderEditState != nil) {
+		   (m.requestCursor == request.HeadersSection && m.currentReq != nil && m.currentReq.HeaderEditState != nil) ||
+		   (m.requestCursor == request.AssertSection && m.currentReq != nil && m.currentReq.AssertEditState != nil) ||
+		   (m.requestCursor == request.BodySection && m.currentReq != nil && request.IsFormBody(m.currentReq.Body.Type)) {
 			return "Type to edit | Enter: confirm | Esc: cancel | Tab: key/value"
 		}
 		return "Type to edit | Enter: confirm | Esc: cancel"
 	}
+	if request.GetRequestTabSection(m.requestActiveTab) == request.PreviewSection {
+		return "←/→: switch tabs | Tab: next panel"
+	}
 	return "←/→: switch tabs | ↑/↓: navigate | Enter: edit | a: add | d: delete | Tab: next panel"
 }
 
@@ -160,15 +164,33 @@ func (h *RequestInputHandler) isInTextInputMode(m *model) bool {
 		}
 	}
 	
+	// Check for assertion text input mode
+	if m.activePanel == requestPanel && m.requestCursor == request.AssertSection && m.currentReq != nil {
+		if m.currentReq.AssertEditState != nil {
+			mode := m.currentReq.AssertEditState.Mode
+			return mode == request.AssertEditKeyMode || mode == request.AssertEditValueMode || mode == request.AssertAddMode
+		}
+	}
+	
 	// Check for auth text input mode
 	if m.activePanel == requestPanel && m.requestCursor == request.AuthSection && m.currentReq != nil {
 		if m.currentReq.AuthEditState != nil {
 			mode := m.currentReq.AuthEditState.Mode
 			return mode == request.AuthTypeSelectMode || mode == request.AuthBearerEditMode || 
-				   mode == request.AuthBasicUsernameEditMode || mode == request.AuthBasicPasswordEditMode
+				   mode == request.AuthBasicUsernameEditMode || mode == request.AuthBasicPasswordEditMode ||
+				   mode == request.AuthOAuth2EditMode
 		}
 	}
 	
+	// Check for form body text input mode
+	if m.activePanel == requestPanel && m.requestCursor == request.BodySection && m.currentReq != nil && request.IsFormBody(m.currentReq.Body.Type) {
+		if m.currentReq.FormEditState != nil {
+			mode := m.currentReq.FormEditState.Mode
+			return mode == request.FormEditKeyMode || mode == request.FormEditValueMode || mode == request.FormAddMode
+		}
+		return false
+	}
+	
 	// Check for body text input mode
 	if m.activePanel == requestPanel && m.requestCursor == request.BodySection && m.currentReq != nil {
 		if m.currentReq.BodyEditState != nil {
@@ -196,6 +218,15 @@ func (h *RequestInputHandler) handleTextInputMode(m *model, msg tea.KeyMsg) (*mo
 		if m.requestCursor == request.HeadersSection && m.currentReq != nil {
 			return h.handleHeaderTextInput(m, msg)
 		}
+		if m.requestCursor == request.AssertSection && m.currentReq != nil {
+			return h.handleAssertTextInput(m, msg)
+		}
+		if m.requestCursor == request.BodySection && m.currentReq != nil && request.IsFormBody(m.currentReq.Body.Type) {
+			return h.handleFormTextInput(m, msg)
+		}
+		if m.requestCursor == request.BodySection && m.currentReq != nil && m.currentReq.Body.Type == "graphql" {
+			return h.handleBodyTextInput(m, msg)
+		}
 		// For other sections, allow panel switching
 		return m, nil
 	}
@@ -207,10 +238,16 @@ func (h *RequestInputHandler) handleTextInputMode(m *model, msg tea.KeyMsg) (*mo
 	if m.requestCursor == request.HeadersSection && m.currentReq != nil {
 		return h.handleHeaderTextInput(m, msg)
 	}
+	if m.requestCursor == request.AssertSection && m.currentReq != nil {
+		return h.handleAssertTextInput(m, msg)
+	}
 	if m.requestCursor == request.AuthSection && m.currentReq != nil {
 		return h.handleAuthTextInput(m, msg)
 	}
 	if m.requestCursor == request.BodySection && m.currentReq != nil {
+		if request.IsFormBody(m.currentReq.Body.Type) {
+			return h.handleFormTextInput(m, msg)
+		}
 		return h.handleBodyTextInput(m, msg)
 	}
 
@@ -672,6 +709,248 @@ func (h *RequestInputHandler) handleHeaderTextInput(m *model, msg tea.KeyMsg) (*
 	return m, nil
 }
 
+func (h *RequestInputHandler) handleAssertTextInput(m *model, msg tea.KeyMsg) (*model, tea.Cmd) {
+	if m.currentReq == nil || m.currentReq.AssertEditState == nil {
+		return m, nil
+	}
+
+	editState := m.currentReq.AssertEditState
+
+	switch msg.Type {
+	case tea.KeyEsc:
+		// Exit edit mode
+		editState.Mode = request.AssertViewMode
+		editState.EditingKey = ""
+		editState.EditingValue = ""
+		editState.CursorPos = 0
+		return m, nil
+
+	case tea.KeyEnter, tea.KeyTab:
+		switch editState.Mode {
+		case request.AssertEditKeyMode:
+			// Save expression edit
+			if editState.SelectedIndex < len(editState.Parameters) {
+				editState.Parameters[editState.SelectedIndex].Key = editState.EditingKey
+				m.currentReq.SyncAssertionsToRequest()
+			}
+			editState.EditingKey = ""
+			if msg.Type == tea.KeyTab {
+				// Switch to editing the rule
+				editState.Mode = request.AssertEditValueMode
+				editState.EditingValue = editState.Parameters[editState.SelectedIndex].Value
+				editState.CursorPos = len(editState.EditingValue)
+			} else {
+				editState.Mode = request.AssertViewMode
+				editState.CursorPos = 0
+			}
+		case request.AssertEditValueMode:
+			// Save rule edit
+			if editState.SelectedIndex < len(editState.Parameters) {
+				editState.Parameters[editState.SelectedIndex].Value = editState.EditingValue
+				m.currentReq.SyncAssertionsToRequest()
+			}
+			editState.EditingValue = ""
+			if msg.Type == tea.KeyTab {
+				// Switch to editing the expression
+				editState.Mode = request.AssertEditKeyMode
+				editState.EditingKey = editState.Parameters[editState.SelectedIndex].Key
+				editState.CursorPos = len(editState.EditingKey)
+			} else {
+				editState.Mode = request.AssertViewMode
+				editState.CursorPos = 0
+			}
+		case request.AssertAddMode:
+			// Add the expression, then continue with its rule
+			if editState.EditingKey != "" {
+				editState.Parameters = append(editState.Parameters, request.AssertParameter{
+					Key:   editState.EditingKey,
+					Value: "eq ",
+				})
+				editState.SelectedIndex = len(editState.Parameters) - 1
+				editState.Mode = request.AssertEditValueMode
+				editState.EditingKey = ""
+				editState.EditingValue = editState.Parameters[editState.SelectedIndex].Value
+				editState.CursorPos = len(editState.EditingValue)
+				m.currentReq.SyncAssertionsToRequest()
+			}
+		}
+		return m, nil
+
+	case tea.KeyLeft:
+		// Move cursor left
+		if editState.CursorPos > 0 {
+			editState.CursorPos--
+		}
+		return m, nil
+
+	case tea.KeyRight:
+		// Move cursor right
+		maxPos := len(editState.EditingKey)
+		if editState.Mode == request.AssertEditValueMode {
+			maxPos = len(editState.EditingValue)
+		}
+		if editState.CursorPos < maxPos {
+			editState.CursorPos++
+		}
+		return m, nil
+
+	case tea.KeyBackspace:
+		// Delete character before cursor
+		switch editState.Mode {
+		case request.AssertEditKeyMode, request.AssertAddMode:
+			if editState.CursorPos > 0 && editState.CursorPos <= len(editState.EditingKey) {
+				editState.EditingKey = editState.EditingKey[:editState.CursorPos-1] + editState.EditingKey[editState.CursorPos:]
+				editState.CursorPos--
+			}
+		case request.AssertEditValueMode:
+			if editState.CursorPos > 0 && editState.CursorPos <= len(editState.EditingValue) {
+				editState.EditingValue = editState.EditingValue[:editState.CursorPos-1] + editState.EditingValue[editState.CursorPos:]
+				editState.CursorPos--
+			}
+		}
+		return m, nil
+
+	case tea.KeySpace, tea.KeyRunes:
+		// Insert characters at cursor
+		text := string(msg.Runes)
+		if msg.Type == tea.KeySpace {
+			text = " "
+		}
+		switch editState.Mode {
+		case request.AssertEditKeyMode, request.AssertAddMode:
+			editState.EditingKey = editState.EditingKey[:editState.CursorPos] + text + editState.EditingKey[editState.CursorPos:]
+			editState.CursorPos += len(text)
+		case request.AssertEditValueMode:
+			editState.EditingValue = editState.EditingValue[:editState.CursorPos] + text + editState.EditingValue[editState.CursorPos:]
+			editState.CursorPos += len(text)
+		}
+		return m, nil
+	}
+
+	return m, nil
+}
+
+func (h *RequestInputHandler) handleFormTextInput(m *model, msg tea.KeyMsg) (*model, tea.Cmd) {
+	if m.currentReq == nil || m.currentReq.FormEditState == nil {
+		return m, nil
+	}
+
+	editState := m.currentReq.FormEditState
+
+	switch msg.Type {
+	case tea.KeyEsc:
+		// Exit edit mode
+		editState.Mode = request.FormViewMode
+		editState.EditingKey = ""
+		editState.EditingValue = ""
+		editState.CursorPos = 0
+		return m, nil
+
+	case tea.KeyEnter, tea.KeyTab:
+		switch editState.Mode {
+		case request.FormEditKeyMode:
+			// Save key edit
+			if editState.SelectedIndex < len(editState.Parameters) {
+				editState.Parameters[editState.SelectedIndex].Key = editState.EditingKey
+				m.currentReq.SyncFormToRequest()
+			}
+			editState.EditingKey = ""
+			if msg.Type == tea.KeyTab {
+				// Switch to editing the value
+				editState.Mode = request.FormEditValueMode
+				editState.EditingValue = editState.Parameters[editState.SelectedIndex].Value
+				editState.CursorPos = len(editState.EditingValue)
+			} else {
+				editState.Mode = request.FormViewMode
+				editState.CursorPos = 0
+			}
+		case request.FormEditValueMode:
+			// Save value edit
+			if editState.SelectedIndex < len(editState.Parameters) {
+				editState.Parameters[editState.SelectedIndex].Value = editState.EditingValue
+				m.currentReq.SyncFormToRequest()
+			}
+			editState.EditingValue = ""
+			if msg.Type == tea.KeyTab {
+				// Switch to editing the key
+				editState.Mode = request.FormEditKeyMode
+				editState.EditingKey = editState.Parameters[editState.SelectedIndex].Key
+				editState.CursorPos = len(editState.EditingKey)
+			} else {
+				editState.Mode = request.FormViewMode
+				editState.CursorPos = 0
+			}
+		case request.FormAddMode:
+			// Add the key, then continue with its value
+			if editState.EditingKey != "" {
+				editState.Parameters = append(editState.Parameters, request.FormParameter{
+					Key:   editState.EditingKey,
+					Value: "",
+				})
+				editState.SelectedIndex = len(editState.Parameters) - 1
+				editState.Mode = request.FormEditValueMode
+				editState.EditingKey = ""
+				editState.EditingValue = editState.Parameters[editState.SelectedIndex].Value
+				editState.CursorPos = len(editState.EditingValue)
+				m.currentReq.SyncFormToRequest()
+			}
+		}
+		return m, nil
+
+	case tea.KeyLeft:
+		// Move cursor left
+		if editState.CursorPos > 0 {
+			editState.CursorPos--
+		}
+		return m, nil
+
+	case tea.KeyRight:
+		// Move cursor right
+		maxPos := len(editState.EditingKey)
+		if editState.Mode == request.FormEditValueMode {
+			maxPos = len(editState.EditingValue)
+		}
+		if editState.CursorPos < maxPos {
+			editState.CursorPos++
+		}
+		return m, nil
+
+	case tea.KeyBackspace:
+		// Delete character before cursor
+		switch editState.Mode {
+		case request.FormEditKeyMode, request.FormAddMode:
+			if editState.CursorPos > 0 && editState.CursorPos <= len(editState.EditingKey) {
+				editState.EditingKey = editState.EditingKey[:editState.CursorPos-1] + editState.EditingKey[editState.CursorPos:]
+				editState.CursorPos--
+			}
+		case request.FormEditValueMode:
+			if editState.CursorPos > 0 && editState.CursorPos <= len(editState.EditingValue) {
+				editState.EditingValue = editState.EditingValue[:editState.CursorPos-1] + editState.EditingValue[editState.CursorPos:]
+				editState.CursorPos--
+			}
+		}
+		return m, nil
+
+	case tea.KeySpace, tea.KeyRunes:
+		// Insert characters at cursor
+		text := string(msg.Runes)
+		if msg.Type == tea.KeySpace {
+			text = " "
+		}
+		switch editState.Mode {
+		case request.FormEditKeyMode, request.FormAddMode:
+			editState.EditingKey = editState.EditingKey[:editState.CursorPos] + text + editState.EditingKey[editState.CursorPos:]
+			editState.CursorPos += len(text)
+		case request.FormEditValueMode:
+			editState.EditingValue = editState.EditingValue[:editState.CursorPos] + text + editState.EditingValue[editState.CursorPos:]
+			editState.CursorPos += len(text)
+		}
+		return m, nil
+	}
+
+	return m, nil
+}
+
 func (h *RequestInputHandler) handleRequestSectionAction(m *model, msg tea.KeyMsg) (*model, tea.Cmd) {
 	if m.currentReq == nil {
 		return m, nil
@@ -686,6 +965,8 @@ func (h *RequestInputHandler) handleRequestSectionAction(m *model, msg tea.KeyMs
 		return h.handleHeadersSectionAction(m, msg)
 	case request.AuthSection:
 		return h.handleAuthSectionAction(m, msg)
+	case request.AssertSection:
+		return h.handleAssertSectionAction(m, msg)
 	default:
 		return m, nil
 	}
@@ -776,6 +1057,10 @@ func (h *RequestInputHandler) handleQuerySectionAction(m *model, msg tea.KeyMsg)
 }
 
 func (h *RequestInputHandler) handleBodySectionAction(m *model, msg tea.KeyMsg) (*model, tea.Cmd) {
+	if request.IsFormBody(m.currentReq.Body.Type) {
+		return h.handleFormSectionAction(m, msg)
+	}
+
 	// Initialize edit state if needed
 	m.currentReq.InitializeBodyEditState()
 	editState := m.currentReq.BodyEditState
@@ -804,6 +1089,16 @@ func (h *RequestInputHandler) handleBodySectionAction(m *model, msg tea.KeyMsg)
 					editState.CursorCol = len(lines[len(lines)-1])
 				}
 				return m, nil
+			case "m":
+				// Switch body mode
+				m.currentReq.CycleBodyType()
+				return m, nil
+			case "v":
+				// Switch between the GraphQL query and its variables
+				if m.currentReq.Body.Type == "graphql" {
+					m.currentReq.ToggleGraphQLVars()
+				}
+				return m, nil
 			}
 		}
 	}
@@ -896,6 +1191,196 @@ func (h *RequestInputHandler) handleHeadersSectionAction(m *model, msg tea.KeyMs
 }
 
 
+func (h *RequestInputHandler) handleAssertSectionAction(m *model, msg tea.KeyMsg) (*model, tea.Cmd) {
+	// Initialize edit state if needed
+	m.currentReq.InitializeAssertEditState()
+	editState := m.currentReq.AssertEditState
+
+	// Handle navigation and actions in view mode
+	if editState.Mode == request.AssertViewMode {
+		switch msg.Type {
+		case tea.KeyUp:
+			if editState.SelectedIndex > 0 {
+				editState.SelectedIndex--
+			}
+			return m, nil
+		case tea.KeyDown:
+			if editState.SelectedIndex < len(editState.Parameters)-1 {
+				editState.SelectedIndex++
+			}
+			return m, nil
+		case tea.KeyEnter:
+			// Enter edit mode for expression
+			if len(editState.Parameters) == 0 {
+				editState.Mode = request.AssertAddMode
+				editState.EditingKey = ""
+				editState.EditingValue = ""
+				editState.CursorPos = 0
+			} else if editState.SelectedIndex < len(editState.Parameters) {
+				editState.Mode = request.AssertEditKeyMode
+				editState.EditingKey = editState.Parameters[editState.SelectedIndex].Key
+				editState.CursorPos = len(editState.EditingKey)
+			}
+			return m, nil
+		case tea.KeyRunes:
+			switch string(msg.Runes) {
+			case "i":
+				// Enter edit mode for expression
+				if len(editState.Parameters) == 0 {
+					editState.Mode = request.AssertAddMode
+					editState.EditingKey = ""
+					editState.EditingValue = ""
+					editState.CursorPos = 0
+				} else if editState.SelectedIndex < len(editState.Parameters) {
+					editState.Mode = request.AssertEditKeyMode
+					editState.EditingKey = editState.Parameters[editState.SelectedIndex].Key
+					editState.CursorPos = len(editState.EditingKey)
+				}
+				return m, nil
+			case "a":
+				// Add new assertion
+				editState.Mode = request.AssertAddMode
+				editState.EditingKey = ""
+				editState.EditingValue = ""
+				editState.CursorPos = 0
+				return m, nil
+			case "t":
+				// Toggle assertion between enabled and disabled
+				if editState.SelectedIndex < len(editState.Parameters) {
+					param := &editState.Parameters[editState.SelectedIndex]
+					if strings.HasPrefix(param.Key, "~") {
+						param.Key = strings.TrimPrefix(param.Key, "~")
+					} else {
+						param.Key = "~" + param.Key
+					}
+					m.currentReq.SyncAssertionsToRequest()
+				}
+				return m, nil
+			case "d":
+				// Delete assertion
+				if editState.SelectedIndex < len(editState.Parameters) {
+					editState.PendingDeletion = editState.SelectedIndex
+				}
+				return m, nil
+			case "y":
+				// Confirm deletion
+				if editState.PendingDeletion >= 0 && editState.PendingDeletion < len(editState.Parameters) {
+					editState.Parameters = append(editState.Parameters[:editState.PendingDeletion], editState.Parameters[editState.PendingDeletion+1:]...)
+					editState.PendingDeletion = -1
+					if editState.SelectedIndex >= len(editState.Parameters) && len(editState.Parameters) > 0 {
+						editState.SelectedIndex = len(editState.Parameters) - 1
+					}
+					m.currentReq.SyncAssertionsToRequest()
+				}
+				return m, nil
+			case "n":
+				// Cancel deletion
+				editState.PendingDeletion = -1
+				return m, nil
+			}
+		}
+	}
+
+	return m, nil
+}
+
+
+func (h *RequestInputHandler) handleFormSectionAction(m *model, msg tea.KeyMsg) (*model, tea.Cmd) {
+	// Initialize edit state if needed
+	m.currentReq.InitializeFormEditState()
+	editState := m.currentReq.FormEditState
+
+	// Handle navigation and actions in view mode
+	if editState.Mode == request.FormViewMode {
+		switch msg.Type {
+		case tea.KeyUp:
+			if editState.SelectedIndex > 0 {
+				editState.SelectedIndex--
+			}
+			return m, nil
+		case tea.KeyDown:
+			if editState.SelectedIndex < len(editState.Parameters)-1 {
+				editState.SelectedIndex++
+			}
+			return m, nil
+		case tea.KeyEnter:
+			// Enter edit mode for key
+			if len(editState.Parameters) == 0 {
+				editState.Mode = request.FormAddMode
+				editState.EditingKey = ""
+				editState.EditingValue = ""
+				editState.CursorPos = 0
+			} else if editState.SelectedIndex < len(editState.Parameters) {
+				editState.Mode = request.FormEditKeyMode
+				editState.EditingKey = editState.Parameters[editState.SelectedIndex].Key
+				editState.CursorPos = len(editState.EditingKey)
+			}
+			return m, nil
+		case tea.KeyRunes:
+			switch string(msg.Runes) {
+			case "i":
+				// Enter edit mode for key
+				if len(editState.Parameters) == 0 {
+					editState.Mode = request.FormAddMode
+					editState.EditingKey = ""
+					editState.EditingValue = ""
+					editState.CursorPos = 0
+				} else if editState.SelectedIndex < len(editState.Parameters) {
+					editState.Mode = request.FormEditKeyMode
+					editState.EditingKey = editState.Parameters[editState.SelectedIndex].Key
+					editState.CursorPos = len(editState.EditingKey)
+				}
+				return m, nil
+			case "m":
+				// Switch body mode
+				m.currentReq.CycleBodyType()
+				return m, nil
+			case "a":
+				// Add new field
+				editState.Mode = request.FormAddMode
+				editState.EditingKey = ""
+				editState.EditingValue = ""
+				editState.CursorPos = 0
+				return m, nil
+			case "t":
+				// Toggle field between enabled and disabled
+				if editState.SelectedIndex < len(editState.Parameters) {
+					param := &editState.Parameters[editState.SelectedIndex]
+					if strings.HasPrefix(param.Key, "~") {
+						param.Key = strings.TrimPrefix(param.Key, "~")
+					} else {
+						param.Key = "~" + param.Key
+					}
+					m.currentReq.SyncFormToRequest()
+				}
+				return m, nil
+			case "d":
+				// Delete field
+				if editState.SelectedIndex < len(editState.Parameters) {
+					editState.PendingDeletion = editState.SelectedIndex
+				}
+				return m, nil
+			case "y":
+				// Confirm deletion
+				if editState.PendingDeletion >= 0 && editState.PendingDeletion < len(editState.Parameters) {
+					editState.Parameters = append(editState.Parameters[:editState.PendingDeletion], editState.Parameters[editState.PendingDeletion+1:]...)
+					editState.PendingDeletion = -1
+					if editState.SelectedIndex >= len(editState.Parameters) && len(editState.Parameters) > 0 {
+						editState.SelectedIndex = len(editState.Parameters) - 1
+					}
+					m.currentReq.SyncFormToRequest()
+				}
+				return m, nil
+			case "n":
+				// Cancel deletion
+				editState.PendingDeletion = -1
+				return m, nil
+			}
+		}
+	}
+
+	return m, nil
+}
 
 func (h *RequestInputHandler) handleAuthTextInput(m *model, msg tea.KeyMsg) (*model, tea.Cmd) {
 	if m.currentReq == nil || m.currentReq.AuthEditState == nil {
@@ -926,6 +1411,9 @@ func (h *RequestInputHandler) handleAuthTextInput(m *model, msg tea.KeyMsg) (*mo
 		case request.AuthBasicPasswordEditMode:
 			editState.Mode = request.AuthViewMode
 			m.currentReq.SyncAuthToRequest()
+		case request.AuthOAuth2EditMode:
+			editState.Mode = request.AuthViewMode
+			m.currentReq.SyncAuthToRequest()
 		}
 		editState.CursorPos = 0
 		return m, nil
@@ -953,6 +1441,8 @@ func (h *RequestInputHandler) handleAuthTextInput(m *model, msg tea.KeyMsg) (*mo
 					editState.AuthType = "none"
 				case "basic":
 					editState.AuthType = "bearer"
+				case "oauth2":
+					editState.AuthType = "basic"
 				}
 			} else { // KeyDown
 				switch editState.AuthType {
@@ -960,6 +1450,8 @@ func (h *RequestInputHandler) handleAuthTextInput(m *model, msg tea.KeyMsg) (*mo
 					editState.AuthType = "bearer"
 				case "bearer":
 					editState.AuthType = "basic"
+				case "basic":
+					editState.AuthType = "oauth2"
 				}
 			}
 		}
@@ -982,6 +1474,8 @@ func (h *RequestInputHandler) handleAuthTextInput(m *model, msg tea.KeyMsg) (*mo
 			maxPos = len(editState.BasicUsername)
 		case request.AuthBasicPasswordEditMode:
 			maxPos = len(editState.BasicPassword)
+		case request.AuthOAuth2EditMode:
+			maxPos = len(editState.OAuth2Values[editState.OAuth2FieldKey()])
 		}
 		if editState.CursorPos < maxPos {
 			editState.CursorPos++
@@ -1006,6 +1500,13 @@ func (h *RequestInputHandler) handleAuthTextInput(m *model, msg tea.KeyMsg) (*mo
 				editState.BasicPassword = editState.BasicPassword[:editState.CursorPos-1] + editState.BasicPassword[editState.CursorPos:]
 				editState.CursorPos--
 			}
+		case request.AuthOAuth2EditMode:
+			key := editState.OAuth2FieldKey()
+			value := editState.OAuth2Values[key]
+			if editState.CursorPos > 0 && editState.CursorPos <= len(value) {
+				editState.OAuth2Values[key] = value[:editState.CursorPos-1] + value[editState.CursorPos:]
+				editState.CursorPos--
+			}
 		}
 		return m, nil
 
@@ -1023,6 +1524,11 @@ func (h *RequestInputHandler) handleAuthTextInput(m *model, msg tea.KeyMsg) (*mo
 			case request.AuthBasicPasswordEditMode:
 				editState.BasicPassword = editState.BasicPassword[:editState.CursorPos] + char + editState.BasicPassword[editState.CursorPos:]
 				editState.CursorPos++
+			case request.AuthOAuth2EditMode:
+				key := editState.OAuth2FieldKey()
+				value := editState.OAuth2Values[key]
+				editState.OAuth2Values[key] = value[:editState.CursorPos] + char + value[editState.CursorPos:]
+				editState.CursorPos++
 			}
 		}
 		return m, nil
@@ -1046,6 +1552,8 @@ func (h *RequestInputHandler) handleAuthSectionAction(m *model, msg tea.KeyMsg)
 				maxField = 1 // type, token
 			case "basic":
 				maxField = 2 // type, username, password
+			case "oauth2":
+				maxField = len(request.OAuth2Fields(editState.OAuth2GrantType())) // type, oauth2 fields
 			}
 			if editState.SelectedField > 0 {
 				editState.SelectedField--
@@ -1060,6 +1568,8 @@ func (h *RequestInputHandler) handleAuthSectionAction(m *model, msg tea.KeyMsg)
 				maxField = 1 // type, token
 			case "basic":
 				maxField = 2 // type, username, password
+			case "oauth2":
+				maxField = len(request.OAuth2Fields(editState.OAuth2GrantType())) // type, oauth2 fields
 			}
 			if editState.SelectedField < maxField {
 				editState.SelectedField++
@@ -1089,6 +1599,9 @@ func (h *RequestInputHandler) handleAuthSectionAction(m *model, msg tea.KeyMsg)
 					editState.CursorPos = len(editState.BasicPassword)
 				}
 			}
+			if editState.AuthType == "oauth2" && editState.SelectedField > 0 {
+				h.editOAuth2Field(m, editState)
+			}
 			return m, nil
 		case tea.KeyRunes:
 			switch string(msg.Runes) {
@@ -1111,6 +1624,9 @@ func (h *RequestInputHandler) handleAuthSectionAction(m *model, msg tea.KeyMsg)
 						editState.CursorPos = len(editState.BasicPassword)
 					}
 				}
+				if editState.AuthType == "oauth2" && editState.SelectedField > 0 {
+					h.editOAuth2Field(m, editState)
+				}
 				return m, nil
 			}
 		}
@@ -1120,6 +1636,27 @@ func (h *RequestInputHandler) handleAuthSectionAction(m *model, msg tea.KeyMsg)
 }
 
 // handleBodyTextInput handles text input for the body editor
+// editOAuth2Field starts editing the selected auth:oauth2 field. The grant
+// type and PKCE are switched in place instead of typed.
+func (h *RequestInputHandler) editOAuth2Field(m *model, editState *request.AuthEditState) {
+	switch key := editState.OAuth2FieldKey(); key {
+	case "grant_type":
+		editState.CycleOAuth2GrantType()
+		m.currentReq.SyncAuthToRequest()
+	case "pkce":
+		if editState.OAuth2Values[key] == "true" {
+			editState.OAuth2Values[key] = "false"
+		} else {
+			editState.OAuth2Values[key] = "true"
+		}
+		m.currentReq.SyncAuthToRequest()
+	case "":
+	default:
+		editState.Mode = request.AuthOAuth2EditMode
+		editState.CursorPos = len(editState.OAuth2Values[key])
+	}
+}
+
 func (h *RequestInputHandler) handleBodyTextInput(m *model, msg tea.KeyMsg) (*model, tea.Cmd) {
 	if m.currentReq == nil || m.currentReq.BodyEditState == nil {
 		return m, nil
@@ -1127,6 +1664,26 @@ func (h *RequestInputHandler) handleBodyTextInput(m *model, msg tea.KeyMsg) (*mo
 
 	editState := m.currentReq.BodyEditState
 
+	// GraphQL suggestions follow the query as it is typed
+	defer m.updateGraphQLSuggestions()
+	if len(editState.Suggestions) > 0 {
+		switch msg.Type {
+		case tea.KeyTab:
+			completeGraphQLSuggestion(editState)
+			return m, nil
+		case tea.KeyCtrlN:
+			editState.SelectedSuggestion = (editState.SelectedSuggestion + 1) % len(editState.Suggestions)
+			return m, nil
+		case tea.KeyShiftTab:
+			if editState.SelectedSuggestion > 0 {
+				editState.SelectedSuggestion--
+			} else {
+				editState.SelectedSuggestion = len(editState.Suggestions) - 1 // Wrap to last
+			}
+			return m, nil
+		}
+	}
+
 	switch msg.Type {
 	case tea.KeyEsc:
 		// Exit edit mode
@@ -1135,7 +1692,7 @@ func (h *RequestInputHandler) handleBodyTextInput(m *model, msg tea.KeyMsg) (*mo
 
 	case tea.KeyCtrlS:
 		// Format JSON if body type is JSON
-		if m.currentReq.Body.Type == "json" {
+		if m.currentReq.BodyEditIsJSON() {
 			content := strings.TrimSpace(editState.Content)
 			if content != "" {
 				var jsonData interface{}
@@ -1180,7 +1737,7 @@ func (h *RequestInputHandler) handleBodyTextInput(m *model, msg tea.KeyMsg) (*mo
 		}
 		
 		// Validate JSON if body type is JSON
-		if m.currentReq.Body.Type == "json" {
+		if m.currentReq.BodyEditIsJSON() {
 			m.currentReq.ValidateBodyJSON()
 		}
 		return m, nil
@@ -1282,7 +1839,7 @@ func (h *RequestInputHandler) handleBodyTextInput(m *model, msg tea.KeyMsg) (*mo
 		}
 		
 		// Validate JSON if body type is JSON
-		if m.currentReq.Body.Type == "json" {
+		if m.currentReq.BodyEditIsJSON() {
 			m.currentReq.ValidateBodyJSON()
 		}
 		return m, nil
@@ -1310,7 +1867,7 @@ func (h *RequestInputHandler) handleBodyTextInput(m *model, msg tea.KeyMsg) (*mo
 		}
 		
 		// Validate JSON if body type is JSON
-		if m.currentReq.Body.Type == "json" {
+		if m.currentReq.BodyEditIsJSON() {
 			m.currentReq.ValidateBodyJSON()
 		}
 		return m, nil
@@ -1334,7 +1891,7 @@ func (h *RequestInputHandler) handleBodyTextInput(m *model, msg tea.KeyMsg) (*mo
 			}
 			
 			// Validate JSON if body type is JSON
-			if m.currentReq.Body.Type == "json" {
+			if m.currentReq.BodyEditIsJSON() {
 				m.currentReq.ValidateBodyJSON()
 			}
 		}
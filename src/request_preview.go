@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	request "kalo/src/panels/request"
+)
+
+// requestPlaceholders lists the {{ }} placeholders in the parts of a request
+// that ResolveRequest substitutes, in order and without repeats
+func requestPlaceholders(bruReq *request.BruRequest) []string {
+	texts := []string{bruReq.HTTP.URL}
+	for _, param := range bruReq.Query {
+		texts = append(texts, param.Value)
+	}
+	for _, param := range bruReq.Headers {
+		texts = append(texts, param.Value)
+	}
+	texts = append(texts, bruReq.Body.Data, bruReq.Body.Vars)
+	for _, field := range bruReq.Body.Fields {
+		if field.Enabled {
+			texts = append(texts, field.Key, field.Value)
+		}
+	}
+	if bruReq.Auth.Type != "" {
+		keys := make([]string, 0, len(bruReq.Auth.Values))
+		for key := range bruReq.Auth.Values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			texts = append(texts, bruReq.Auth.Values[key])
+		}
+	}
+
+	var placeholders []string
+	seen := make(map[string]bool)
+	for _, text := range texts {
+		for _, placeholder := range templatePattern.FindAllString(text, -1) {
+			if !seen[placeholder] {
+				seen[placeholder] = true
+				placeholders = append(placeholders, placeholder)
+			}
+		}
+	}
+	return placeholders
+}
+
+// unresolvedPlaceholders returns the placeholders of a request that would be
+// sent as written because a variable, environment variable or filter is unknown
+func unresolvedPlaceholders(bruReq *request.BruRequest, vars map[string]string) []string {
+	var unresolved []string
+	for _, placeholder := range requestPlaceholders(bruReq) {
+		if _, ok := evaluateTemplate(placeholder[2:len(placeholder)-2], vars); !ok {
+			unresolved = append(unresolved, placeholder)
+		}
+	}
+	return unresolved
+}
+
+// blockUnresolvedVars reads the blockUnresolvedVars setting, which refuses to
+// send requests with unresolved placeholders
+func blockUnresolvedVars(bruReq *request.BruRequest, collection *CollectionConfig) (bool, error) {
+	value := strings.TrimSpace(requestSettings(bruReq, collection)["blockUnresolvedVars"])
+	if value == "" {
+		return false, nil
+	}
+	block, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("Invalid blockUnresolvedVars setting %q", value)
+	}
+	return block, nil
+}
+
+// checkUnresolvedVars fails when a request that blocks unresolved
+// placeholders still has some
+func checkUnresolvedVars(bruReq *request.BruRequest, collection *CollectionConfig, vars map[string]string) error {
+	block, err := blockUnresolvedVars(bruReq, collection)
+	if err != nil || !block {
+		return err
+	}
+	if unresolved := unresolvedPlaceholders(bruReq, vars); len(unresolved) > 0 {
+		return fmt.Errorf("Unresolved variables: %s", strings.Join(unresolved, ", "))
+	}
+	return nil
+}
+
+// variableSource tells where the value of a placeholder comes from, in the
+// order mergeVars applies them. It is empty when nothing defines it.
+func variableSource(expr string, env *Environment, requestVars, runtimeVars map[string]string) string {
+	name := strings.TrimSpace(expr)
+	names := []string{name}
+	if stages, ok := parseTemplate(name); ok && len(stages[0]) == 1 && !stages[0][0].quoted {
+		names = append(names, stages[0][0].text)
+	}
+
+	for _, key := range names {
+		if _, exists := runtimeVars[key]; exists {
+			return "runtime"
+		}
+		if _, exists := requestVars[key]; exists {
+			return "request vars"
+		}
+		if env != nil {
+			if _, exists := env.Vars[key]; exists {
+				return "environment " + env.Name
+			}
+		}
+		if _, exists := dynamicVariables[key]; exists {
+			return "dynamic, new value when sent"
+		}
+		if strings.HasPrefix(key, "process.env.") {
+			return "process environment"
+		}
+	}
+	return ""
+}
+
+// dynamicPreviewNote explains the values dynamic variables show in a preview
+const dynamicPreviewNote = "Dynamic variables show example values; new ones are generated when sending"
+
+// buildRequestPreview resolves a request without sending it, for the
+// Preview tab. Scripts and OAuth2 token requests only run when sending.
+func buildRequestPreview(client *HTTPClient, bruReq *request.BruRequest, env *Environment, runtimeVars map[string]string) *request.RequestPreview {
+	vars := mergeVars(env, bruReq.Vars, runtimeVars)
+	preview := &request.RequestPreview{}
+
+	for _, placeholder := range requestPlaceholders(bruReq) {
+		expr := strings.TrimSpace(placeholder[2 : len(placeholder)-2])
+		value, ok := evaluateTemplate(expr, vars)
+		source := variableSource(expr, env, bruReq.Vars, runtimeVars)
+		if !ok {
+			// e.g. a defined variable piped into an unknown filter
+			source = ""
+			preview.Unresolved = append(preview.Unresolved, placeholder)
+		}
+		example := ok && strings.HasPrefix(source, "dynamic")
+		preview.Variables = append(preview.Variables, request.PreviewVariable{Name: expr, Value: value, Source: source, Example: example})
+		if example && !slices.Contains(preview.Notes, dynamicPreviewNote) {
+			preview.Notes = append(preview.Notes, dynamicPreviewNote)
+		}
+	}
+
+	collection, err := client.collectionConfig(bruReq.Dir)
+	if err != nil {
+		preview.Error = err.Error()
+		return preview
+	}
+	block, err := blockUnresolvedVars(bruReq, collection)
+	if err != nil {
+		preview.Error = err.Error()
+		return preview
+	}
+	preview.Blocked = block && len(preview.Unresolved) > 0
+
+	if strings.TrimSpace(bruReq.Script.PreRequest) != "" {
+		preview.Notes = append(preview.Notes, "The pre-request script runs when sending and may change this request")
+	}
+	if bruReq.Auth.Type == "oauth2" {
+		preview.Notes = append(preview.Notes, "The OAuth2 access token is fetched when sending")
+	}
+
+	sent, err := client.ResolveRequest(bruReq, collection, vars)
+	if err != nil {
+		preview.Error = err.Error()
+		return preview
+	}
+	preview.Method = string(sent.Method)
+	preview.URL = sent.URL
+	for _, key := range sent.Headers.Keys() {
+		for _, value := range sent.Headers[key] {
+			preview.Headers = append(preview.Headers, request.BruParam{Key: key, Value: value})
+		}
+	}
+	if sent.Body != nil {
+		preview.Body = describePreviewBody(sent.Body)
+	}
+	return preview
+}
+
+// describePreviewBody shows a resolved body. Multipart bodies are listed part
+// by part since boundaries are only chosen when sending.
+func describePreviewBody(body *RequestBody) string {
+	if body.Type != "multipart-form" {
+		return body.Content
+	}
+
+	var lines []string
+	for _, part := range multipartParts(body) {
+		if part.File != nil {
+			lines = append(lines, fmt.Sprintf("%s: @file(%s)", part.Name, part.File.FilePath))
+		} else {
+			lines = append(lines, fmt.Sprintf("%s: %s", part.Name, part.Value))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// previewSource is what the cached preview of the Preview tab was built from
+type previewSource struct {
+	request *request.BruRequest
+	env     *Environment
+}
+
+// refreshPreview builds the preview when the Preview tab is shown for a
+// request or environment it was not built for. Building resolves the request
+// and reads collection.bru, so View only shows the cached preview.
+func (m *model) refreshPreview() {
+	if m.currentReq == nil || request.GetRequestTabSection(m.requestActiveTab) != request.PreviewSection {
+		m.preview = nil
+		return
+	}
+	env := m.activeEnvironment()
+	source := previewSource{request: m.currentReq, env: env}
+	if m.preview != nil && m.previewSource == source {
+		return
+	}
+	m.preview = buildRequestPreview(m.httpClient, m.currentReq, env, m.scriptRunner.RuntimeVars(env))
+	m.previewSource = source
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	panels "kalo/src/panels/request"
+)
+
+// previewEnvironment defines BASE_URL and a userId the request overrides
+var previewEnvironment = &Environment{Name: "staging", Vars: map[string]string{"BASE_URL": "https://api.example.com", "userId": "1"}}
+
+// newPreviewRequest uses a variable from every source and one that is missing
+func newPreviewRequest() *panels.BruRequest {
+	return &panels.BruRequest{
+		HTTP:    panels.BruHTTP{Method: "GET", URL: "{{BASE_URL}}/users/{{userId}}"},
+		Query:   panels.BruParams{{Key: "q", Value: "{{missing}}"}},
+		Headers: panels.BruParams{{Key: "Authorization", Value: "Bearer {{token}}"}, {Key: "X-Request-Id", Value: "{{$guid}}"}},
+		Vars:    map[string]string{"userId": "42"},
+	}
+}
+
+func TestRequestPreview(t *testing.T) {
+	preview := buildRequestPreview(NewHTTPClient(), newPreviewRequest(), previewEnvironment, map[string]string{"token": "abc"})
+	if preview.Error != "" || preview.Method != "GET" || preview.URL != "https://api.example.com/users/42?q=%7B%7Bmissing%7D%7D" {
+		t.Fatalf("Expected the resolved URL, got %q (%s)", preview.URL, preview.Error)
+	}
+	if len(preview.Unresolved) != 1 || preview.Unresolved[0] != "{{missing}}" || preview.Blocked {
+		t.Errorf("Expected {{missing}} to be unresolved without blocking, got %v", preview.Unresolved)
+	}
+}
+
+func TestPreviewVariableSources(t *testing.T) {
+	preview := buildRequestPreview(NewHTTPClient(), newPreviewRequest(), previewEnvironment, map[string]string{"token": "abc"})
+	sources := make(map[string]string)
+	for _, variable := range preview.Variables {
+		sources[variable.Name] = variable.Source
+	}
+	expected := map[string]string{
+		"BASE_URL": "environment staging",
+		"userId":   "request vars",
+		"token":    "runtime",
+		"$guid":    "dynamic, new value when sent",
+		"missing":  "",
+	}
+	for name, source := range expected {
+		if sources[name] != source {
+			t.Errorf("Expected %s to come from %q, got %q", name, source, sources[name])
+		}
+	}
+}
+
+func TestBlockUnresolvedVars(t *testing.T) {
+	req := newPreviewRequest()
+	req.Settings = map[string]string{"blockUnresolvedVars": "true"}
+	if preview := buildRequestPreview(NewHTTPClient(), req, previewEnvironment, nil); !preview.Blocked {
+		t.Errorf("Expected the preview to show sending as blocked")
+	}
+
+	// Sending is refused while placeholders are unresolved
+	_, _, err := prepareBruRequest(context.Background(), NewHTTPClient(), NewScriptRunner(), req, previewEnvironment, nil)
+	if err == nil || err.Error() != "Unresolved variables: {{missing}}, {{token}}" {
+		t.Errorf("Expected sending to be blocked, got %v", err)
+	}
+	req.Vars["missing"] = "found"
+	req.Vars["token"] = "abc"
+	if _, sent, err := prepareBruRequest(context.Background(), NewHTTPClient(), NewScriptRunner(), req, previewEnvironment, nil); err != nil || !strings.HasSuffix(sent.URL, "?q=found") {
+		t.Errorf("Expected the request to be sent once resolved, got %v", err)
+	}
+}
+
+func TestPreviewMultipartBody(t *testing.T) {
+	body := &RequestBody{Type: "multipart-form", Parts: []FormPart{
+		{Name: "tag", Value: "b"},
+		{Name: "file", File: &FileUpload{FieldName: "file", FilePath: "notes.txt"}},
+		{Name: "tag", Value: "a"},
+	}}
+	if preview := describePreviewBody(body); preview != "tag: b\nfile: @file(notes.txt)\ntag: a" {
+		t.Errorf("Expected every part in order, got %q", preview)
+	}
+}
+
+func TestPreviewLabelsDynamicExamples(t *testing.T) {
+	preview := buildRequestPreview(NewHTTPClient(), newPreviewRequest(), previewEnvironment, nil)
+	for _, variable := range preview.Variables {
+		if variable.Example != (variable.Name == "$guid") {
+			t.Errorf("Expected only $guid to be an example value, got %+v", variable)
+		}
+	}
+	notes := strings.Join(preview.Notes, "\n")
+	if strings.Count(notes, dynamicPreviewNote) != 1 {
+		t.Errorf("Expected a note about example values, got %q", notes)
+	}
+}
+
+func TestRefreshPreviewCaches(t *testing.T) {
+	previewTab := -1
+	for i := range panels.GetRequestTabNames() {
+		if panels.GetRequestTabSection(i) == panels.PreviewSection {
+			previewTab = i
+		}
+	}
+	m := &model{httpClient: NewHTTPClient(), scriptRunner: NewScriptRunner(), currentReq: newPreviewRequest()}
+
+	m.refreshPreview()
+	if m.preview != nil {
+		t.Fatal("Expected no preview while another tab is shown")
+	}
+
+	m.requestActiveTab = previewTab
+	m.refreshPreview()
+	built := m.preview
+	if built == nil {
+		t.Fatal("Expected a preview on the Preview tab")
+	}
+	m.refreshPreview()
+	if m.preview != built {
+		t.Error("Expected the preview to be kept while nothing changed")
+	}
+
+	m.currentReq = newPreviewRequest()
+	m.refreshPreview()
+	if m.preview == built || m.preview == nil {
+		t.Error("Expected the preview to be built again for another request")
+	}
+}
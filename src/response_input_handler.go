@@ -20,6 +20,10 @@ func (h *ResponseInputHandler) HandleInput(key tea.KeyMsg, m *model) (*model, te
 		return h.handleJQFilterInput(m, key)
 	}
 
+	if m.wsConsole != nil && !m.isLoading {
+		return h.handleWebSocketInput(key, m)
+	}
+
 	switch key.Type {
 	case tea.KeyLeft:
 		if m.responseActiveTab > 0 {
@@ -37,34 +41,22 @@ func (h *ResponseInputHandler) HandleInput(key tea.KeyMsg, m *model) (*model, te
 		}
 		return m, nil
 	case tea.KeyUp:
-		if m.responseCursor == response.ResponseHeadersSection {
-			m.headersViewport.LineUp(1)
-		}
+		m.activeResponseViewport().LineUp(1)
 		return m, nil
 	case tea.KeyDown:
-		if m.responseCursor == response.ResponseHeadersSection {
-			m.headersViewport.LineDown(1)
-		}
+		m.activeResponseViewport().LineDown(1)
 		return m, nil
 	case tea.KeyCtrlD, tea.KeyPgDown:
-		if m.responseCursor == response.ResponseHeadersSection {
-			m.headersViewport.HalfViewDown()
-		}
+		m.activeResponseViewport().HalfViewDown()
 		return m, nil
 	case tea.KeyCtrlU, tea.KeyPgUp:
-		if m.responseCursor == response.ResponseHeadersSection {
-			m.headersViewport.HalfViewUp()
-		}
+		m.activeResponseViewport().HalfViewUp()
 		return m, nil
 	case tea.KeyHome:
-		if m.responseCursor == response.ResponseHeadersSection {
-			m.headersViewport.GotoTop()
-		}
+		m.activeResponseViewport().GotoTop()
 		return m, nil
 	case tea.KeyEnd:
-		if m.responseCursor == response.ResponseHeadersSection {
-			m.headersViewport.GotoBottom()
-		}
+		m.activeResponseViewport().GotoBottom()
 		return m, nil
 	case tea.KeyCtrlR:
 		// Reset jq filter
@@ -93,25 +85,25 @@ func (h *ResponseInputHandler) HandleInput(key tea.KeyMsg, m *model) (*model, te
 			}
 			return m, nil
 		case "k":
-			if m.responseCursor == response.ResponseHeadersSection {
-				m.headersViewport.LineUp(1)
-			}
+			m.activeResponseViewport().LineUp(1)
 			return m, nil
 		case "j":
-			if m.responseCursor == response.ResponseHeadersSection {
-				m.headersViewport.LineDown(1)
-			}
+			m.activeResponseViewport().LineDown(1)
 			return m, nil
 		case "g":
-			if m.responseCursor == response.ResponseHeadersSection {
-				m.headersViewport.GotoTop()
-			}
+			m.activeResponseViewport().GotoTop()
 			return m, nil
 		case "G":
-			if m.responseCursor == response.ResponseHeadersSection {
-				m.headersViewport.GotoBottom()
-			}
+			m.activeResponseViewport().GotoBottom()
 			return m, nil
+		case "r":
+			// Reconnect to a stopped or ended stream
+			return m, m.resumeStream()
+		case "s":
+			if m.streaming || (m.lastResponse != nil && m.lastResponse.Streamed) {
+				return m, m.executeCommand("save_stream")
+			}
+			return m, m.executeCommand("save_body")
 		case "/":
 			// Start jq filter
 			m.responseCursor = response.ResponseBodySection
@@ -124,6 +116,32 @@ func (h *ResponseInputHandler) HandleInput(key tea.KeyMsg, m *model) (*model, te
 	return m, nil
 }
 
+// handleWebSocketInput types into the WebSocket console's input line. Enter
+// sends the line; the arrow keys scroll the message log.
+func (h *ResponseInputHandler) handleWebSocketInput(key tea.KeyMsg, m *model) (*model, tea.Cmd) {
+	console := m.wsConsole
+	switch key.Type {
+	case tea.KeyEnter:
+		return m, m.sendWebSocketMessage()
+	case tea.KeyUp:
+		console.log.LineUp(1)
+		return m, nil
+	case tea.KeyDown:
+		console.log.LineDown(1)
+		return m, nil
+	case tea.KeyPgUp:
+		console.log.HalfViewUp()
+		return m, nil
+	case tea.KeyPgDown:
+		console.log.HalfViewDown()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	console.input, cmd = console.input.Update(key)
+	return m, cmd
+}
+
 // CanHandleInput returns true if response panel can handle input
 func (h *ResponseInputHandler) CanHandleInput(m *model) bool {
 	return m.activePanel == responsePanel
@@ -135,6 +153,21 @@ func (h *ResponseInputHandler) GetFooterText(m *model) string {
 		return "←/→: switch tabs | /: filter | Ctrl+R: reset filter | Tab: next panel (filtered)"
 	}
 	
+	if m.wsConsole != nil {
+		if m.wsConsole.session == nil {
+			return "Enter: send (disconnected, run the request to reconnect) | ↑/↓: scroll | Tab: next panel"
+		}
+		return "Enter: send | Ctrl+X: disconnect | ↑/↓: scroll | Tab: next panel"
+	}
+
+	if m.lastResponse != nil && m.lastResponse.Streamed {
+		return "←/→: switch tabs | /: jq filter | r: resume stream | s: save stream | Tab: next panel"
+	}
+
+	if m.lastResponse != nil {
+		return "←/→: switch tabs | /: jq filter | s: save body | Tab: next panel"
+	}
+
 	return "←/→: switch tabs | /: jq filter | Tab: next panel"
 }
 
@@ -0,0 +1,669 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+	request "kalo/src/panels/request"
+	response "kalo/src/panels/response"
+)
+
+// scriptTimeout bounds how long a single script may run before it is interrupted
+const scriptTimeout = 5 * time.Second
+
+// ScriptRunner executes the JavaScript blocks of .bru files in an embedded runtime.
+// Runtime variables set with bru.setVar persist across requests for the
+// lifetime of the runner. They are kept per environment, so switching
+// environments does not carry a token from one server to another.
+type ScriptRunner struct {
+	runtimeVars map[string]map[string]string // Keyed by runtimeScope
+	mu          sync.Mutex
+}
+
+// NewScriptRunner creates a new script runner
+func NewScriptRunner() *ScriptRunner {
+	return &ScriptRunner{
+		runtimeVars: make(map[string]map[string]string),
+	}
+}
+
+// runtimeScope identifies the environment runtime variables belong to. The
+// environment file is specific to its collection; requests sent without an
+// environment share one scope.
+func runtimeScope(env *Environment) string {
+	if env == nil {
+		return ""
+	}
+	return env.FilePath
+}
+
+// RuntimeVars returns a copy of the variables scripts set while env was active
+func (s *ScriptRunner) RuntimeVars(env *Environment) map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	scoped := s.runtimeVars[runtimeScope(env)]
+	vars := make(map[string]string, len(scoped))
+	for key, value := range scoped {
+		vars[key] = value
+	}
+	return vars
+}
+
+// ScriptConsole collects what scripts write with console.log and friends. A nil
+// console discards the output.
+type ScriptConsole struct {
+	logs []ScriptLog
+	mu   sync.Mutex
+}
+
+// Logs returns the captured output in the order it was written
+func (c *ScriptConsole) Logs() []ScriptLog {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]ScriptLog(nil), c.logs...)
+}
+
+func (c *ScriptConsole) write(level string, args []goja.Value) {
+	if c == nil {
+		return
+	}
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		switch {
+		case arg == nil || goja.IsUndefined(arg):
+			parts[i] = "undefined"
+		case goja.IsNull(arg):
+			parts[i] = "null"
+		default:
+			parts[i] = scriptValueString(arg)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.logs = append(c.logs, ScriptLog{Level: level, Message: strings.Join(parts, " ")})
+}
+
+// RunPreRequest executes a script:pre-request block. The script may modify the
+// request through `req` before variables are substituted.
+func (s *ScriptRunner) RunPreRequest(script string, req *request.BruRequest, env *Environment, console *ScriptConsole) error {
+	if strings.TrimSpace(script) == "" {
+		return nil
+	}
+
+	vm, err := s.newScriptVM(req, nil, env, console)
+	if err != nil {
+		return err
+	}
+	return runScript(vm, script)
+}
+
+// RunPostResponse executes a script:post-response block against a response
+func (s *ScriptRunner) RunPostResponse(script string, req *request.BruRequest, resp *response.HTTPResponse, env *Environment, console *ScriptConsole) error {
+	if strings.TrimSpace(script) == "" || resp == nil {
+		return nil
+	}
+
+	vm, err := s.newScriptVM(req, resp, env, console)
+	if err != nil {
+		return err
+	}
+	return runScript(vm, script)
+}
+
+// RunTests executes a tests block against a response and collects the results
+// of every test() call. Script errors are reported as a failed result.
+func (s *ScriptRunner) RunTests(script string, req *request.BruRequest, resp *response.HTTPResponse, env *Environment, console *ScriptConsole) []TestResult {
+	if strings.TrimSpace(script) == "" || resp == nil {
+		return nil
+	}
+
+	vm, err := s.newScriptVM(req, resp, env, console)
+	if err != nil {
+		return []TestResult{{Name: "tests", Passed: false, Message: err.Error()}}
+	}
+
+	var results []TestResult
+	vm.Set("test", func(name string, fn goja.Callable) {
+		result := TestResult{Name: name, Passed: true}
+		if fn != nil {
+			if _, err := fn(goja.Undefined()); err != nil {
+				result.Passed = false
+				result.Message, result.Expected, result.Actual = describeScriptError(err)
+			}
+		}
+		results = append(results, result)
+	})
+
+	if err := runScript(vm, script); err != nil {
+		results = append(results, TestResult{
+			Name:    "tests",
+			Passed:  false,
+			Message: fmt.Sprintf("Script error: %v", err),
+		})
+	}
+
+	return results
+}
+
+// newScriptVM creates a runtime with the chai prelude and the `req`, `res`,
+// `bru` and `console` objects. A nil response leaves `res` undefined.
+func (s *ScriptRunner) newScriptVM(req *request.BruRequest, resp *response.HTTPResponse, env *Environment, console *ScriptConsole) (*goja.Runtime, error) {
+	vm := goja.New()
+	vm.SetFieldNameMapper(goja.TagFieldNameMapper("json", true))
+
+	if _, err := vm.RunString(chaiPrelude); err != nil {
+		return nil, fmt.Errorf("Failed to initialize script runtime: %v", err)
+	}
+
+	if req != nil {
+		if err := vm.Set("req", newScriptRequest(vm, req)); err != nil {
+			return nil, err
+		}
+	}
+	if resp != nil {
+		if err := vm.Set("res", newScriptResponse(vm, resp)); err != nil {
+			return nil, err
+		}
+	}
+	if err := vm.Set("bru", s.newScriptBru(vm, req, env)); err != nil {
+		return nil, err
+	}
+	if err := vm.Set("console", newScriptConsole(vm, console)); err != nil {
+		return nil, err
+	}
+
+	return vm, nil
+}
+
+// runScript runs a script with a timeout and describes any error it throws
+func runScript(vm *goja.Runtime, script string) error {
+	timer := time.AfterFunc(scriptTimeout, func() {
+		vm.Interrupt("script timed out")
+	})
+	defer timer.Stop()
+
+	if _, err := vm.RunString(script); err != nil {
+		message, _, _ := describeScriptError(err)
+		return errors.New(message)
+	}
+	return nil
+}
+
+// newScriptBru builds the `bru` object used to share variables between requests
+func (s *ScriptRunner) newScriptBru(vm *goja.Runtime, req *request.BruRequest, env *Environment) *goja.Object {
+	obj := vm.NewObject()
+	scope := runtimeScope(env)
+
+	obj.Set("setVar", func(name string, value goja.Value) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.runtimeVars[scope] == nil {
+			s.runtimeVars[scope] = make(map[string]string)
+		}
+		s.runtimeVars[scope][name] = scriptValueString(value)
+	})
+	obj.Set("getVar", func(name string) interface{} {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if value, exists := s.runtimeVars[scope][name]; exists {
+			return value
+		}
+		return nil
+	})
+	obj.Set("hasVar", func(name string) bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		_, exists := s.runtimeVars[scope][name]
+		return exists
+	})
+	obj.Set("deleteVar", func(name string) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.runtimeVars[scope], name)
+	})
+	obj.Set("getEnvVar", func(name string) interface{} {
+		if env != nil {
+			if value, exists := env.Vars[name]; exists {
+				return value
+			}
+		}
+		return nil
+	})
+	obj.Set("getEnvName", func() interface{} {
+		if env != nil {
+			return env.Name
+		}
+		return nil
+	})
+	obj.Set("getRequestVar", func(name string) interface{} {
+		if req != nil {
+			if value, exists := req.Vars[name]; exists {
+				return value
+			}
+		}
+		return nil
+	})
+
+	return obj
+}
+
+// newScriptConsole builds the `console` object. warn and error keep their level;
+// info and debug are recorded as log.
+func newScriptConsole(vm *goja.Runtime, console *ScriptConsole) *goja.Object {
+	obj := vm.NewObject()
+
+	for _, level := range []string{"log", "info", "debug", "warn", "error"} {
+		recorded := level
+		if level == "info" || level == "debug" {
+			recorded = "log"
+		}
+		obj.Set(level, func(call goja.FunctionCall) goja.Value {
+			console.write(recorded, call.Arguments)
+			return goja.Undefined()
+		})
+	}
+
+	return obj
+}
+
+// newScriptRequest builds the `req` object. Setters modify the given request.
+func newScriptRequest(vm *goja.Runtime, req *request.BruRequest) *goja.Object {
+	obj := vm.NewObject()
+
+	obj.Set("getUrl", func() string { return req.HTTP.URL })
+	obj.Set("setUrl", func(url string) { req.HTTP.URL = url })
+	obj.Set("getMethod", func() string { return req.HTTP.Method })
+	obj.Set("setMethod", func(method string) { req.HTTP.Method = strings.ToUpper(method) })
+	obj.Set("getName", func() string { return req.Meta.Name })
+	obj.Set("getHeaders", func() map[string]string { return req.Headers.Map() })
+	obj.Set("getHeader", func(name string) interface{} {
+		for _, param := range req.Headers {
+			if strings.EqualFold(param.Key, name) {
+				return param.Value
+			}
+		}
+		return nil
+	})
+	obj.Set("setHeader", func(name string, value goja.Value) {
+		// Replaces every value of the header, whatever its case
+		for _, param := range req.Headers {
+			if strings.EqualFold(param.Key, name) && param.Key != name {
+				req.Headers.Delete(param.Key)
+			}
+		}
+		req.Headers.Set(name, scriptValueString(value))
+	})
+	obj.Set("deleteHeader", func(name string) {
+		for _, param := range req.Headers {
+			if strings.EqualFold(param.Key, name) {
+				req.Headers.Delete(param.Key)
+			}
+		}
+	})
+	obj.Set("getBody", func() interface{} {
+		if request.IsFormBody(req.Body.Type) {
+			fields := make(map[string]interface{})
+			for _, field := range req.Body.Fields {
+				if field.Enabled {
+					fields[field.Key] = field.Value
+				}
+			}
+			return fields
+		}
+		if req.Body.Type == "graphql" {
+			return map[string]interface{}{
+				"query":     req.Body.Data,
+				"variables": parseScriptBody(req.Body.Vars),
+			}
+		}
+		return parseScriptBody(req.Body.Data)
+	})
+	obj.Set("setBody", func(value goja.Value) {
+		if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+			req.Body.Data = ""
+			return
+		}
+		if _, isObject := value.(*goja.Object); isObject {
+			data, err := json.MarshalIndent(value.Export(), "", "  ")
+			if err != nil {
+				panic(vm.NewGoError(err))
+			}
+			req.Body.Data = string(data)
+			if req.Body.Type == "" {
+				req.Body.Type = "json"
+			}
+			return
+		}
+		req.Body.Data = value.String()
+		if req.Body.Type == "" {
+			req.Body.Type = "text"
+		}
+	})
+
+	return obj
+}
+
+// scriptValueString converts a JS value to the string stored in variables and headers
+func scriptValueString(value goja.Value) string {
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return ""
+	}
+	if _, isObject := value.(*goja.Object); isObject {
+		if data, err := json.Marshal(value.Export()); err == nil {
+			return string(data)
+		}
+	}
+	return value.String()
+}
+
+// newScriptResponse builds the `res` object exposed to scripts
+func newScriptResponse(vm *goja.Runtime, resp *response.HTTPResponse) *goja.Object {
+	body := parseScriptBody(resp.Body)
+	responseTime := float64(resp.ResponseTime.Microseconds()) / 1000
+
+	headers := scriptHeaders(resp.Headers)
+
+	obj := vm.NewObject()
+	obj.Set("status", resp.StatusCode)
+	obj.Set("statusText", resp.Status)
+	obj.Set("headers", headers)
+	obj.Set("body", body)
+	obj.Set("responseTime", responseTime)
+
+	obj.Set("getStatus", func() int { return resp.StatusCode })
+	obj.Set("getStatusText", func() string { return resp.Status })
+	obj.Set("getHeaders", func() map[string]interface{} { return headers })
+	obj.Set("getHeader", func(name string) interface{} {
+		if value, exists := headers[strings.ToLower(name)]; exists {
+			return value
+		}
+		return nil
+	})
+	obj.Set("getBody", func() interface{} { return body })
+	obj.Set("getResponseTime", func() float64 { return responseTime })
+
+	return obj
+}
+
+// scriptHeaders lowercases header names for scripts and assertions. Repeated
+// headers such as set-cookie are arrays, as in Node.
+func scriptHeaders(headers response.Headers) map[string]interface{} {
+	values := make(map[string]interface{}, len(headers))
+	for key, headerValues := range headers {
+		if len(headerValues) == 1 {
+			values[strings.ToLower(key)] = headerValues[0]
+		} else {
+			list := make([]interface{}, len(headerValues))
+			for i, value := range headerValues {
+				list[i] = value
+			}
+			values[strings.ToLower(key)] = list
+		}
+	}
+	return values
+}
+
+// parseScriptBody returns the body as a JS-friendly value, decoding JSON when possible
+func parseScriptBody(body string) interface{} {
+	var data interface{}
+	if err := json.Unmarshal([]byte(body), &data); err == nil {
+		return data
+	}
+	return body
+}
+
+// describeScriptError extracts a message and any expected/actual values from a script error
+func describeScriptError(err error) (string, interface{}, interface{}) {
+	switch e := err.(type) {
+	case *goja.Exception:
+		if obj, ok := e.Value().(*goja.Object); ok {
+			message := obj.Get("message")
+			if message != nil && !goja.IsUndefined(message) {
+				return message.String(), exportValue(obj.Get("expected")), exportValue(obj.Get("actual"))
+			}
+		}
+		return e.Value().String(), nil, nil
+	case *goja.InterruptedError:
+		return fmt.Sprintf("%v", e.Value()), nil, nil
+	default:
+		return err.Error(), nil, nil
+	}
+}
+
+func exportValue(value goja.Value) interface{} {
+	if value == nil || goja.IsUndefined(value) {
+		return nil
+	}
+	return value.Export()
+}
+
+// chaiPrelude implements the subset of chai's expect() API used by Bruno tests
+const chaiPrelude = `
+function AssertionError(message, expected, actual) {
+  this.name = "AssertionError";
+  this.message = message;
+  this.expected = expected;
+  this.actual = actual;
+}
+AssertionError.prototype = Object.create(Error.prototype);
+
+function __inspect(value) {
+  if (typeof value === "string") return JSON.stringify(value);
+  if (value === undefined) return "undefined";
+  try { return JSON.stringify(value); } catch (e) { return String(value); }
+}
+
+function __deepEqual(a, b) {
+  if (a === b) return true;
+  if (typeof a !== "object" || typeof b !== "object" || a === null || b === null) return false;
+  if (Array.isArray(a) !== Array.isArray(b)) return false;
+  var keysA = Object.keys(a), keysB = Object.keys(b);
+  if (keysA.length !== keysB.length) return false;
+  for (var i = 0; i < keysA.length; i++) {
+    if (!__deepEqual(a[keysA[i]], b[keysA[i]])) return false;
+  }
+  return true;
+}
+
+function __typeOf(value) {
+  if (value === null) return "null";
+  if (Array.isArray(value)) return "array";
+  return typeof value;
+}
+
+function Assertion(value) {
+  this._value = value;
+  this._negate = false;
+  this._deep = false;
+  this._any = false;
+  this._all = false;
+}
+
+["to", "be", "been", "is", "that", "which", "and", "has", "have", "with", "at", "of", "same", "but", "does", "still", "also", "own"].forEach(function (word) {
+  Object.defineProperty(Assertion.prototype, word, { get: function () { return this; } });
+});
+
+Object.defineProperty(Assertion.prototype, "not", { get: function () { this._negate = !this._negate; return this; } });
+Object.defineProperty(Assertion.prototype, "deep", { get: function () { this._deep = true; return this; } });
+Object.defineProperty(Assertion.prototype, "any", { get: function () { this._any = true; this._all = false; return this; } });
+Object.defineProperty(Assertion.prototype, "all", { get: function () { this._all = true; this._any = false; return this; } });
+
+Assertion.prototype._assert = function (passed, message, negatedMessage, expected) {
+  if (this._negate) passed = !passed;
+  if (!passed) {
+    throw new AssertionError(this._negate ? negatedMessage : message, expected, this._value);
+  }
+  return this;
+};
+
+Assertion.prototype.equal = function (expected) {
+  var passed = this._deep ? __deepEqual(this._value, expected) : this._value === expected;
+  return this._assert(passed,
+    "expected " + __inspect(this._value) + " to equal " + __inspect(expected),
+    "expected " + __inspect(this._value) + " to not equal " + __inspect(expected), expected);
+};
+Assertion.prototype.equals = Assertion.prototype.equal;
+Assertion.prototype.eq = Assertion.prototype.equal;
+
+Assertion.prototype.eql = function (expected) {
+  return this._assert(__deepEqual(this._value, expected),
+    "expected " + __inspect(this._value) + " to deeply equal " + __inspect(expected),
+    "expected " + __inspect(this._value) + " to not deeply equal " + __inspect(expected), expected);
+};
+
+Assertion.prototype.above = function (n) {
+  return this._assert(this._value > n,
+    "expected " + __inspect(this._value) + " to be above " + n,
+    "expected " + __inspect(this._value) + " to be at most " + n, n);
+};
+Assertion.prototype.gt = Assertion.prototype.above;
+Assertion.prototype.greaterThan = Assertion.prototype.above;
+
+Assertion.prototype.least = function (n) {
+  return this._assert(this._value >= n,
+    "expected " + __inspect(this._value) + " to be at least " + n,
+    "expected " + __inspect(this._value) + " to be below " + n, n);
+};
+Assertion.prototype.gte = Assertion.prototype.least;
+
+Assertion.prototype.below = function (n) {
+  return this._assert(this._value < n,
+    "expected " + __inspect(this._value) + " to be below " + n,
+    "expected " + __inspect(this._value) + " to be at least " + n, n);
+};
+Assertion.prototype.lt = Assertion.prototype.below;
+Assertion.prototype.lessThan = Assertion.prototype.below;
+
+Assertion.prototype.most = function (n) {
+  return this._assert(this._value <= n,
+    "expected " + __inspect(this._value) + " to be at most " + n,
+    "expected " + __inspect(this._value) + " to be above " + n, n);
+};
+Assertion.prototype.lte = Assertion.prototype.most;
+
+Assertion.prototype.within = function (low, high) {
+  return this._assert(this._value >= low && this._value <= high,
+    "expected " + __inspect(this._value) + " to be within " + low + ".." + high,
+    "expected " + __inspect(this._value) + " to not be within " + low + ".." + high, [low, high]);
+};
+
+Assertion.prototype.a = function (type) {
+  var actual = __typeOf(this._value);
+  return this._assert(actual === type.toLowerCase(),
+    "expected " + __inspect(this._value) + " to be a " + type,
+    "expected " + __inspect(this._value) + " not to be a " + type, type);
+};
+Assertion.prototype.an = Assertion.prototype.a;
+
+Assertion.prototype.include = function (item) {
+  var value = this._value, passed = false;
+  if (typeof value === "string") {
+    passed = value.indexOf(item) !== -1;
+  } else if (Array.isArray(value)) {
+    for (var i = 0; i < value.length; i++) {
+      if (this._deep ? __deepEqual(value[i], item) : value[i] === item) { passed = true; break; }
+    }
+  } else if (value && typeof value === "object") {
+    passed = true;
+    for (var key in item) {
+      if (!__deepEqual(value[key], item[key])) { passed = false; break; }
+    }
+  }
+  return this._assert(passed,
+    "expected " + __inspect(value) + " to include " + __inspect(item),
+    "expected " + __inspect(value) + " to not include " + __inspect(item), item);
+};
+Assertion.prototype.includes = Assertion.prototype.include;
+Assertion.prototype.contain = Assertion.prototype.include;
+Assertion.prototype.contains = Assertion.prototype.include;
+
+Assertion.prototype.property = function (name, expected) {
+  var value = this._value;
+  var has = value !== null && value !== undefined && Object.prototype.hasOwnProperty.call(Object(value), name);
+  if (arguments.length > 1) {
+    var matches = has && (this._deep ? __deepEqual(value[name], expected) : value[name] === expected);
+    return this._assert(matches,
+      "expected " + __inspect(value) + " to have property " + __inspect(name) + " of " + __inspect(expected),
+      "expected " + __inspect(value) + " to not have property " + __inspect(name) + " of " + __inspect(expected), expected);
+  }
+  this._assert(has,
+    "expected " + __inspect(value) + " to have property " + __inspect(name),
+    "expected " + __inspect(value) + " to not have property " + __inspect(name), name);
+  return has ? new Assertion(value[name]) : this;
+};
+
+Assertion.prototype.lengthOf = function (n) {
+  var length = this._value === null || this._value === undefined ? undefined : this._value.length;
+  return this._assert(length === n,
+    "expected " + __inspect(this._value) + " to have a length of " + n + " but got " + length,
+    "expected " + __inspect(this._value) + " to not have a length of " + n, n);
+};
+Assertion.prototype.length = Assertion.prototype.lengthOf;
+
+Assertion.prototype.match = function (re) {
+  return this._assert(re.test(String(this._value)),
+    "expected " + __inspect(this._value) + " to match " + re,
+    "expected " + __inspect(this._value) + " not to match " + re, String(re));
+};
+Assertion.prototype.matches = Assertion.prototype.match;
+
+Assertion.prototype.oneOf = function (list) {
+  return this._assert(list.indexOf(this._value) !== -1,
+    "expected " + __inspect(this._value) + " to be one of " + __inspect(list),
+    "expected " + __inspect(this._value) + " to not be one of " + __inspect(list), list);
+};
+
+// keys checks that every key is present. With .any one is enough; with .all
+// the object must have no other keys.
+Assertion.prototype.keys = function () {
+  var keys = Array.isArray(arguments[0]) ? arguments[0] : Array.prototype.slice.call(arguments);
+  var value = this._value || {}, found = 0;
+  for (var i = 0; i < keys.length; i++) {
+    if (Object.prototype.hasOwnProperty.call(value, keys[i])) found++;
+  }
+  var passed = this._any ? found > 0 : found === keys.length;
+  if (this._all) passed = passed && Object.keys(value).length === keys.length;
+  var which = this._any ? "any of keys " : this._all ? "all of keys " : "keys ";
+  return this._assert(passed,
+    "expected " + __inspect(value) + " to have " + which + __inspect(keys),
+    "expected " + __inspect(value) + " to not have " + which + __inspect(keys), keys);
+};
+Assertion.prototype.key = Assertion.prototype.keys;
+
+function __defineFlag(name, check, description) {
+  Object.defineProperty(Assertion.prototype, name, {
+    get: function () {
+      return this._assert(check(this._value),
+        "expected " + __inspect(this._value) + " to be " + description,
+        "expected " + __inspect(this._value) + " to not be " + description, undefined);
+    }
+  });
+}
+
+__defineFlag("ok", function (v) { return !!v; }, "truthy");
+__defineFlag("true", function (v) { return v === true; }, "true");
+__defineFlag("false", function (v) { return v === false; }, "false");
+__defineFlag("null", function (v) { return v === null; }, "null");
+__defineFlag("undefined", function (v) { return v === undefined; }, "undefined");
+__defineFlag("exist", function (v) { return v !== null && v !== undefined; }, "defined");
+__defineFlag("empty", function (v) {
+  if (v === null || v === undefined) return true;
+  if (typeof v === "string" || Array.isArray(v)) return v.length === 0;
+  if (typeof v === "object") return Object.keys(v).length === 0;
+  return false;
+}, "empty");
+
+function expect(value) {
+  return new Assertion(value);
+}
+`
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	request "kalo/src/panels/request"
+	response "kalo/src/panels/response"
+)
+
+// scriptResponse is the response the script tests run against
+var scriptResponse = &response.HTTPResponse{
+	StatusCode: 200,
+	Status:     "200 OK",
+	Headers:    response.Headers{"Content-Type": {"application/json"}},
+	Body:       `{"id": 1, "name": "Ada", "tags": ["admin", "dev"]}`,
+}
+
+func TestRunTestsResults(t *testing.T) {
+	script := `
+test("status is 200", function() {
+  expect(res.getStatus()).to.equal(200);
+});
+test("name is Grace", function() {
+  expect(res.getBody().name).to.equal("Grace");
+});
+test("no body", function() {});
+undefinedFunction();
+test("never registered", function() {});
+`
+	results := NewScriptRunner().RunTests(script, &request.BruRequest{}, scriptResponse, nil, nil)
+	if len(results) != 4 {
+		t.Fatalf("Expected 3 test results and a script error, got %+v", results)
+	}
+
+	if !results[0].Passed || results[0].Name != "status is 200" {
+		t.Errorf("Expected a passing status test, got %+v", results[0])
+	}
+
+	failed := results[1]
+	if failed.Passed || failed.Message != `expected "Ada" to equal "Grace"` {
+		t.Errorf("Expected a failed name test with chai's message, got %+v", failed)
+	}
+	if failed.Expected != "Grace" || failed.Actual != "Ada" {
+		t.Errorf("Expected the failure to record expected and actual values, got %v and %v", failed.Expected, failed.Actual)
+	}
+
+	if !results[2].Passed {
+		t.Errorf("Expected an empty test to pass, got %+v", results[2])
+	}
+
+	scriptError := results[3]
+	if scriptError.Passed || scriptError.Name != "tests" || scriptError.Message != "Script error: undefinedFunction is not defined" {
+		t.Errorf("Expected the script error to be reported as a failed result, got %+v", scriptError)
+	}
+}
+
+func TestRunTestsSkipsWithoutResponse(t *testing.T) {
+	if results := NewScriptRunner().RunTests(`test("x", function() {});`, &request.BruRequest{}, nil, nil, nil); results != nil {
+		t.Errorf("Expected no results without a response, got %+v", results)
+	}
+}
+
+func TestScriptConsole(t *testing.T) {
+	script := `
+console.log("status", res.getStatus(), res.getBody().tags);
+console.info("missing", undefined, null);
+console.warn("slow response");
+console.error({ code: "E1" });
+`
+	console := &ScriptConsole{}
+	if err := NewScriptRunner().RunPostResponse(script, &request.BruRequest{}, scriptResponse, nil, console); err != nil {
+		t.Fatalf("Failed to run script: %v", err)
+	}
+
+	expected := []ScriptLog{
+		{Level: "log", Message: `status 200 ["admin","dev"]`},
+		{Level: "log", Message: "missing undefined null"},
+		{Level: "warn", Message: "slow response"},
+		{Level: "error", Message: `{"code":"E1"}`},
+	}
+	logs := console.Logs()
+	if len(logs) != len(expected) {
+		t.Fatalf("Expected %d log lines, got %+v", len(expected), logs)
+	}
+	for i, want := range expected {
+		if logs[i] != want {
+			t.Errorf("Line %d: expected %+v, got %+v", i, want, logs[i])
+		}
+	}
+
+	// Without a console the output is discarded rather than failing the script
+	if err := NewScriptRunner().RunPreRequest(`console.log("dropped");`, &request.BruRequest{}, nil, nil); err != nil {
+		t.Errorf("Expected console calls to work without a console, got %v", err)
+	}
+}
+
+func TestChaiChainWords(t *testing.T) {
+	passing := []string{
+		`expect(res.getStatus()).to.be.a("number").and.also.equal(200)`,
+		`expect(res.getBody()).to.have.own.property("name").that.is.a("string")`,
+		`expect(res.getBody()).to.have.all.keys("id", "name", "tags")`,
+		`expect(res.getBody()).to.have.any.keys("missing", "id")`,
+		`expect(res.getBody()).to.not.have.all.keys("id", "name")`,
+		`expect(res.getBody()).to.not.have.any.keys("missing", "other")`,
+		`expect(res.getBody().tags).to.deep.equal(["admin", "dev"]).but.still.have.lengthOf(2)`,
+		`expect(res.getBody()).to.have.key("id")`,
+	}
+	failing := map[string]string{
+		`expect(res.getBody()).to.have.all.keys("id", "name")`:       `to have all of keys ["id","name"]`,
+		`expect(res.getBody()).to.have.any.keys("missing", "other")`: `to have any of keys ["missing","other"]`,
+	}
+
+	runner := NewScriptRunner()
+	for _, assertion := range passing {
+		results := runner.RunTests(`test("t", function() { `+assertion+`; });`, &request.BruRequest{}, scriptResponse, nil, nil)
+		if len(results) != 1 || !results[0].Passed {
+			t.Errorf("Expected %s to pass, got %+v", assertion, results)
+		}
+	}
+	for assertion, message := range failing {
+		results := runner.RunTests(`test("t", function() { `+assertion+`; });`, &request.BruRequest{}, scriptResponse, nil, nil)
+		if len(results) != 1 || results[0].Passed || !strings.Contains(results[0].Message, message) {
+			t.Errorf("Expected %s to fail with %q, got %+v", assertion, message, results)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"kalo/src/bru"
+	response "kalo/src/panels/response"
+)
+
+// TLSConfig is the tls configuration of a collection, read from the `tls`
+// and `tls:client-certs` blocks of its collection.bru:
+//
+//	tls {
+//	  ca: certs/internal-ca.pem
+//	  minVersion: 1.2
+//	  serverName: api.internal
+//	  insecureSkipVerify: false
+//	}
+//
+//	tls:client-certs {
+//	  *.internal.example.com: certs/client.pem | certs/client-key.pem
+//	}
+type TLSConfig struct {
+	CAFiles            []string
+	ClientCerts        []ClientCertificate // Checked in file order
+	MinVersion         uint16
+	ServerName         string // Overrides the SNI name and the name the server certificate is verified against
+	InsecureSkipVerify bool
+}
+
+// ClientCertificate is a client certificate used for hosts matching a pattern
+// such as `*.example.com`
+type ClientCertificate struct {
+	HostPattern string
+	CertFile    string
+	KeyFile     string
+}
+
+// tlsVersions maps the minVersion setting to crypto/tls versions
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// parseCollectionTLS reads the tls configuration of the collection.bru in
+// collectionPath. Relative paths are resolved against the collection. It
+// returns nil when the collection has no tls configuration.
+func parseCollectionTLS(collectionPath string, doc *bru.Document) (*TLSConfig, error) {
+	tlsBlock := doc.Block("tls")
+	certsBlock := doc.Block("tls:client-certs")
+	if tlsBlock == nil && certsBlock == nil {
+		return nil, nil
+	}
+
+	resolve := func(file string) string {
+		file = strings.TrimSpace(file)
+		if file != "" && !filepath.IsAbs(file) {
+			file = filepath.Join(collectionPath, file)
+		}
+		return file
+	}
+
+	config := &TLSConfig{}
+	if tlsBlock != nil {
+		for _, entry := range tlsBlock.Entries {
+			if entry.Disabled {
+				continue
+			}
+			value := strings.TrimSpace(entry.Value)
+			switch entry.Key {
+			case "ca":
+				// Several CA bundles can be listed with repeated ca entries
+				config.CAFiles = append(config.CAFiles, resolve(value))
+			case "minVersion":
+				version, ok := tlsVersions[strings.TrimPrefix(value, "TLS")]
+				if !ok {
+					return nil, fmt.Errorf("Invalid tls minVersion %q, use 1.0, 1.1, 1.2 or 1.3", value)
+				}
+				config.MinVersion = version
+			case "serverName":
+				config.ServerName = value
+			case "insecureSkipVerify":
+				insecure, err := strconv.ParseBool(value)
+				if err != nil {
+					return nil, fmt.Errorf("Invalid tls insecureSkipVerify %q", value)
+				}
+				config.InsecureSkipVerify = insecure
+			}
+		}
+	}
+
+	if certsBlock != nil {
+		for _, entry := range certsBlock.Entries {
+			if entry.Disabled {
+				continue
+			}
+			// A single file holds both the certificate and its key
+			files := strings.SplitN(entry.Value, "|", 2)
+			cert := ClientCertificate{HostPattern: strings.ToLower(strings.TrimSpace(entry.Key)), CertFile: resolve(files[0])}
+			cert.KeyFile = cert.CertFile
+			if len(files) == 2 {
+				cert.KeyFile = resolve(files[1])
+			}
+			config.ClientCerts = append(config.ClientCerts, cert)
+		}
+	}
+
+	return config, nil
+}
+
+// cacheKey identifies a configuration so transports can be shared
+func (config *TLSConfig) cacheKey() string {
+	return fmt.Sprintf("%+v", *config)
+}
+
+// loadedClientCertificate is a ClientCertificate with its key pair loaded
+type loadedClientCertificate struct {
+	hostPattern string
+	certificate tls.Certificate
+}
+
+// tlsHostKey is the context key under which collectionTransport stores the
+// host of a request, so the client certificate can be chosen per host even
+// when the connection goes through a proxy
+type tlsHostKey struct{}
+
+// newTLSClientConfig builds a tls.Config that verifies servers against the
+// configured CAs and presents the client certificate matching each host
+func newTLSClientConfig(config *TLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         config.MinVersion,
+		ServerName:         config.ServerName,
+		InsecureSkipVerify: config.InsecureSkipVerify,
+	}
+
+	if len(config.CAFiles) > 0 {
+		// Custom CAs are trusted in addition to the system ones
+		roots, err := x509.SystemCertPool()
+		if err != nil || roots == nil {
+			roots = x509.NewCertPool()
+		}
+		for _, file := range config.CAFiles {
+			pem, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to read CA bundle: %v", err)
+			}
+			if !roots.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("No certificates found in CA bundle %s", file)
+			}
+		}
+		tlsConfig.RootCAs = roots
+	}
+
+	var certs []loadedClientCertificate
+	for _, cert := range config.ClientCerts {
+		certificate, err := tls.LoadX509KeyPair(cert.CertFile, cert.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to load client certificate for %s: %v", cert.HostPattern, err)
+		}
+		certs = append(certs, loadedClientCertificate{hostPattern: cert.HostPattern, certificate: certificate})
+	}
+
+	if len(certs) > 0 {
+		tlsConfig.GetClientCertificate = func(info *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			host, _ := info.Context().Value(tlsHostKey{}).(string)
+			for _, cert := range certs {
+				if matched, _ := path.Match(cert.hostPattern, strings.ToLower(host)); matched {
+					return &cert.certificate, nil
+				}
+			}
+			// An empty certificate tells the server we have none
+			return &tls.Certificate{}, nil
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+// describeTLSError explains why a TLS handshake failed. It returns an empty
+// string for errors that are not TLS failures.
+func describeTLSError(err error) string {
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidCert x509.CertificateInvalidError
+	var recordHeaderErr tls.RecordHeaderError
+	var opErr *net.OpError
+
+	switch {
+	case errors.As(err, &unknownAuthority):
+		return "TLS handshake failed: the server certificate is signed by an unknown authority. Add the CA with `ca` in the collection's tls block, or set `insecureSkipVerify: true` to skip verification"
+	case errors.As(err, &hostnameErr):
+		return fmt.Sprintf("TLS handshake failed: the server certificate is not valid for %s. Set `serverName` in the tls block if the server uses another name", hostnameErr.Host)
+	case errors.As(err, &invalidCert):
+		return fmt.Sprintf("TLS handshake failed: the server certificate is invalid (%v)", invalidCert)
+	case errors.As(err, &recordHeaderErr):
+		return "TLS handshake failed: the server did not answer with TLS. Check that the URL should use https"
+	case errors.As(err, &opErr) && opErr.Op == "remote error":
+		return fmt.Sprintf("TLS handshake failed: the server rejected the connection (%v). It may require a client certificate from the tls:client-certs block", opErr.Err)
+	case strings.Contains(err.Error(), "tls: "):
+		return fmt.Sprintf("TLS handshake failed: %v", err)
+	}
+	return ""
+}
+
+// certificateExpiryWarning is how soon before expiry a certificate is flagged
+const certificateExpiryWarning = 14 * 24 * time.Hour
+
+// describeTLSConnection captures the negotiated parameters and certificate
+// chain of a TLS connection to host for the Connection tab
+func describeTLSConnection(state *tls.ConnectionState, host string) *response.TLSInfo {
+	serverName := state.ServerName
+	if serverName == "" {
+		serverName = host
+	}
+	info := &response.TLSInfo{
+		Version:     tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		ALPN:        state.NegotiatedProtocol,
+		ServerName:  serverName,
+		Verified:    len(state.VerifiedChains) > 0,
+	}
+	if !info.Verified {
+		info.Warnings = append(info.Warnings, "The certificate chain was not verified because insecureSkipVerify is set")
+	}
+	addCertificates(info, state.PeerCertificates)
+	return info
+}
+
+// describeFailedTLS captures the certificates a server presented when
+// verifying them failed. It returns nil for other errors.
+func describeFailedTLS(err error, host string) *response.TLSInfo {
+	var verifyErr *tls.CertificateVerificationError
+	if !errors.As(err, &verifyErr) {
+		return nil
+	}
+	info := &response.TLSInfo{ServerName: host}
+	addCertificates(info, verifyErr.UnverifiedCertificates)
+	return info
+}
+
+// addCertificates records a certificate chain, warning about certificates that
+// are expired or expire soon and a leaf that does not cover the server name
+func addCertificates(info *response.TLSInfo, certs []*x509.Certificate) {
+	now := time.Now()
+	for i, cert := range certs {
+		sans := append([]string{}, cert.DNSNames...)
+		for _, ip := range cert.IPAddresses {
+			sans = append(sans, ip.String())
+		}
+		info.Certificates = append(info.Certificates, response.CertificateInfo{
+			Subject:   cert.Subject.String(),
+			Issuer:    cert.Issuer.String(),
+			SANs:      sans,
+			NotBefore: cert.NotBefore,
+			NotAfter:  cert.NotAfter,
+		})
+
+		name := cert.Subject.CommonName
+		if name == "" {
+			name = cert.Subject.String()
+		}
+		expires := cert.NotAfter.Local().Format("2006-01-02")
+		switch {
+		case now.After(cert.NotAfter):
+			info.Warnings = append(info.Warnings, fmt.Sprintf("Certificate %q expired on %s", name, expires))
+		case now.Before(cert.NotBefore):
+			info.Warnings = append(info.Warnings, fmt.Sprintf("Certificate %q is not valid before %s", name, cert.NotBefore.Local().Format("2006-01-02")))
+		case cert.NotAfter.Sub(now) < certificateExpiryWarning:
+			days := int(cert.NotAfter.Sub(now).Hours() / 24)
+			info.Warnings = append(info.Warnings, fmt.Sprintf("Certificate %q expires in %d days, on %s", name, days, expires))
+		}
+
+		if i == 0 && info.ServerName != "" {
+			if err := cert.VerifyHostname(info.ServerName); err != nil {
+				info.Warnings = append(info.Warnings, fmt.Sprintf("The certificate does not match %s, it covers %s", info.ServerName, strings.Join(sans, ", ")))
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	panels "kalo/src/panels/request"
+	response "kalo/src/panels/response"
+)
+
+// newTLSTestServer starts an HTTPS server with a self-signed certificate and
+// a collection whose ca.pem trusts it
+func newTLSTestServer(t *testing.T) (*httptest.Server, string) {
+	t.Helper()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "secure")
+	}))
+	t.Cleanup(server.Close)
+
+	collectionDir := t.TempDir()
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(filepath.Join(collectionDir, "ca.pem"), caPEM, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return server, collectionDir
+}
+
+// sendWithCollection writes collection.bru and sends a GET request from it
+func sendWithCollection(t *testing.T, collectionDir, collection, url string) *response.HTTPResponse {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(collectionDir, collectionFileName), []byte(collection), 0644); err != nil {
+		t.Fatal(err)
+	}
+	bruReq := &panels.BruRequest{HTTP: panels.BruHTTP{Method: "GET", URL: url}, Dir: collectionDir}
+	resp := sendBruRequest(context.Background(), NewHTTPClient(), bruReq, nil)
+	return resp
+}
+
+func TestCollectionTLS(t *testing.T) {
+	server, collectionDir := newTLSTestServer(t)
+	tests := []struct {
+		name       string
+		collection string
+		expected   string // part of the error, empty for success
+	}{
+		{"unknown authority", "", "unknown authority"},
+		{"collection CA", "tls {\n  ca: ca.pem\n  minVersion: 1.2\n}\n", ""},
+		{"insecure", "tls {\n  insecureSkipVerify: true\n}\n", ""},
+		{"invalid version", "tls {\n  minVersion: 1.4\n}\n", "Invalid tls minVersion"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := sendWithCollection(t, collectionDir, test.collection, server.URL)
+			if test.expected == "" && (resp.Error != "" || resp.Body != "secure") {
+				t.Errorf("Expected the request to succeed, got %q", resp.Error)
+			}
+			if test.expected != "" && !strings.Contains(resp.Error, test.expected) {
+				t.Errorf("Expected an error containing %q, got %q", test.expected, resp.Error)
+			}
+		})
+	}
+}
+
+func TestTLSConnectionDetails(t *testing.T) {
+	server, collectionDir := newTLSTestServer(t)
+
+	resp := sendWithCollection(t, collectionDir, "", server.URL)
+	if resp.ErrorType != string(ErrorTLS) || resp.TLS == nil || len(resp.TLS.Certificates) != 1 || resp.TLS.Verified {
+		t.Errorf("Expected the unverified certificate to be captured, got %+v (%s)", resp.TLS, resp.ErrorType)
+	}
+
+	resp = sendWithCollection(t, collectionDir, "tls {\n  ca: ca.pem\n}\n", server.URL)
+	if resp.TLS == nil || !resp.TLS.Verified || resp.TLS.Version == "" || len(resp.TLS.Warnings) != 0 {
+		t.Errorf("Expected verified connection details without warnings, got %+v", resp.TLS)
+	} else if sans := resp.TLS.Certificates[0].SANs; !strings.Contains(strings.Join(sans, ","), "127.0.0.1") {
+		t.Errorf("Expected the leaf SANs to include 127.0.0.1, got %v", sans)
+	}
+
+	resp = sendWithCollection(t, collectionDir, "tls {\n  insecureSkipVerify: true\n}\n", server.URL)
+	if resp.TLS == nil || resp.TLS.Verified || len(resp.TLS.Warnings) != 1 {
+		t.Errorf("Expected an unverified connection warning, got %+v", resp.TLS)
+	}
+}
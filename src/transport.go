@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"net/http"
+)
+
+// collectionTransport is the transport shared by the requests of collections
+// with the same tls and proxy configuration
+type collectionTransport struct {
+	*http.Transport
+}
+
+// RoundTrip records the request's host in its context, where the TLS client
+// certificate callback looks it up
+func (t *collectionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := context.WithValue(req.Context(), tlsHostKey{}, req.URL.Hostname())
+	return t.Transport.RoundTrip(req.WithContext(ctx))
+}
+
+// proxyFor returns the proxy a request is sent through, with its password
+// redacted, or an empty string for a direct connection
+func (t *collectionTransport) proxyFor(req *http.Request) string {
+	proxyURL, err := t.Proxy(req)
+	if err != nil || proxyURL == nil {
+		return ""
+	}
+	return proxyURL.Redacted()
+}
+
+// newCollectionTransport builds a transport from a collection's tls and proxy
+// configuration, either of which may be nil
+func newCollectionTransport(tlsConfig *TLSConfig, proxyConfig *ProxyConfig) (*collectionTransport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxyFunc(proxyConfig)
+	transport.DisableCompression = true // SendRequest decodes bodies itself, see acceptEncoding
+
+	if tlsConfig != nil {
+		clientConfig, err := newTLSClientConfig(tlsConfig)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = clientConfig
+	}
+
+	return &collectionTransport{transport}, nil
+}
+
+// sentTransport returns the transport for a resolved request. Requests
+// replayed from history no longer carry their collection config, so it is
+// looked up again.
+func (c *HTTPClient) sentTransport(sent *HTTPRequestModel) (*collectionTransport, error) {
+	collection := sent.Collection
+	if collection == nil {
+		var err error
+		if collection, err = c.collectionConfig(sent.CollectionDir); err != nil {
+			return nil, err
+		}
+	}
+	return c.transportFor(collection, sent.CollectionVars)
+}
+
+// transportFor returns the transport for a collection, building and caching
+// one per distinct tls and proxy configuration. Proxy credentials may use
+// {{variables}} from vars. A nil collection has neither.
+func (c *HTTPClient) transportFor(collection *CollectionConfig, vars map[string]string) (*collectionTransport, error) {
+	var tlsConfig *TLSConfig
+	if collection != nil {
+		if collection.tlsErr != nil {
+			return nil, collection.tlsErr
+		}
+		tlsConfig = collection.TLS
+	}
+	proxyConfig, err := collection.proxy(func(text string) string {
+		return c.substituteVars(text, vars)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	key := ""
+	if tlsConfig != nil {
+		key += tlsConfig.cacheKey()
+	}
+	key += "|"
+	if proxyConfig != nil {
+		key += proxyConfig.cacheKey()
+	}
+
+	c.transportsMu.Lock()
+	defer c.transportsMu.Unlock()
+
+	if transport, exists := c.transports[key]; exists {
+		return transport, nil
+	}
+	transport, err := newCollectionTransport(tlsConfig, proxyConfig)
+	if err != nil {
+		return nil, err
+	}
+	c.transports[key] = transport
+	return transport, nil
+}
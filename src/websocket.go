@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	response "kalo/src/panels/response"
+)
+
+// webSocketManagedHeaders are set by the WebSocket handshake itself
+var webSocketManagedHeaders = map[string]bool{
+	"Upgrade":                  true,
+	"Connection":               true,
+	"Sec-Websocket-Key":        true,
+	"Sec-Websocket-Version":    true,
+	"Sec-Websocket-Extensions": true,
+}
+
+// DialWebSocket opens a WebSocket connection for a resolved request, using
+// its headers, the collection's tls and proxy settings and the cookie jar.
+// http and https URLs are dialled as ws and wss.
+func (c *HTTPClient) DialWebSocket(ctx context.Context, sent *HTTPRequestModel) (*WebSocketSession, error) {
+	wsURL, err := url.Parse(sent.URL)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid URL: %v", err)
+	}
+	switch wsURL.Scheme {
+	case "http":
+		wsURL.Scheme = "ws"
+	case "https":
+		wsURL.Scheme = "wss"
+	case "ws", "wss":
+	default:
+		return nil, fmt.Errorf("Invalid WebSocket URL %q, use ws:// or wss://", sent.URL)
+	}
+
+	transport, err := c.sentTransport(sent)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := sent.Timeout
+	if timeout <= 0 {
+		timeout = defaultRequestTimeout
+	}
+	dialer := &websocket.Dialer{
+		Proxy:            transport.Proxy,
+		TLSClientConfig:  transport.TLSClientConfig,
+		HandshakeTimeout: timeout,
+	}
+	if c.Cookies != nil && sent.CookieScope != "" {
+		dialer.Jar = c.Cookies.Jar(sent.CookieScope, !sent.DisableCookies)
+	}
+
+	header := http.Header{}
+	for key, values := range sent.Headers {
+		if !webSocketManagedHeaders[http.CanonicalHeaderKey(key)] {
+			header[key] = values
+		}
+	}
+
+	// The client certificate is chosen by host, as for HTTP requests
+	ctx = context.WithValue(ctx, tlsHostKey{}, wsURL.Hostname())
+	conn, resp, err := dialer.DialContext(ctx, wsURL.String(), header)
+	if dialer.Jar != nil {
+		c.Cookies.Flush()
+	}
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("WebSocket handshake failed: %s", resp.Status)
+		}
+		message, _ := describeRequestError(ctx, err, timeout)
+		return nil, errors.New(message)
+	}
+
+	session := &WebSocketSession{
+		URL:    wsURL.String(),
+		conn:   conn,
+		events: make(chan WebSocketEvent, 64),
+	}
+	go session.read()
+	return session, nil
+}
+
+// WebSocketSession is an open WebSocket connection. Received frames and the
+// end of the connection are delivered on Events.
+type WebSocketSession struct {
+	URL     string
+	conn    *websocket.Conn
+	events  chan WebSocketEvent
+	writeMu sync.Mutex
+	closing bool
+}
+
+// WebSocketEvent is a received frame, or the end of the connection when
+// Closed is set
+type WebSocketEvent struct {
+	Message *response.WebSocketMessage
+	Closed  bool
+	Reason  string
+}
+
+// Events returns the channel of received frames, closed after the final
+// Closed event
+func (s *WebSocketSession) Events() <-chan WebSocketEvent {
+	return s.events
+}
+
+func (s *WebSocketSession) read() {
+	defer close(s.events)
+	for {
+		messageType, data, err := s.conn.ReadMessage()
+		if err != nil {
+			s.events <- WebSocketEvent{Closed: true, Reason: s.describeClose(err)}
+			s.conn.Close()
+			return
+		}
+
+		message := &response.WebSocketMessage{Direction: response.WebSocketReceived, Time: time.Now()}
+		if messageType == websocket.BinaryMessage {
+			message.Binary = true
+			message.Text = fmt.Sprintf("%d bytes of binary data", len(data))
+		} else {
+			message.Text = string(data)
+		}
+		s.events <- WebSocketEvent{Message: message}
+	}
+}
+
+// describeClose explains why the connection ended
+func (s *WebSocketSession) describeClose(err error) string {
+	s.writeMu.Lock()
+	closing := s.closing
+	s.writeMu.Unlock()
+	if closing {
+		return "closed"
+	}
+
+	var closeErr *websocket.CloseError
+	if errors.As(err, &closeErr) {
+		reason := fmt.Sprintf("closed by server with code %d", closeErr.Code)
+		if closeErr.Text != "" {
+			reason += ": " + closeErr.Text
+		}
+		return reason
+	}
+	return fmt.Sprintf("connection lost: %v", err)
+}
+
+// Send writes a text frame. JSON is sent as text, as browsers do.
+func (s *WebSocketSession) Send(text string) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if err := s.conn.WriteMessage(websocket.TextMessage, []byte(text)); err != nil {
+		return fmt.Errorf("Failed to send: %v", err)
+	}
+	return nil
+}
+
+// Close starts the closing handshake. The connection ends once the server
+// answers, or after a second if it does not.
+func (s *WebSocketSession) Close() {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if s.closing {
+		return
+	}
+	s.closing = true
+	deadline := time.Now().Add(time.Second)
+	s.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), deadline)
+	s.conn.SetReadDeadline(deadline)
+}
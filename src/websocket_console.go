@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	response "kalo/src/panels/response"
+)
+
+// webSocketConsole replaces the response panel for WebSocket requests. It
+// stays after the connection closes so the conversation can still be read.
+type webSocketConsole struct {
+	session  *WebSocketSession // Nil once closed
+	url      string
+	status   string // "connected" or why the connection ended
+	messages []response.WebSocketMessage
+	input    textinput.Model
+	log      viewport.Model
+}
+
+// webSocketOpenedMsg reports the outcome of connecting a WebSocket request
+type webSocketOpenedMsg struct {
+	requestID int
+	session   *WebSocketSession
+	err       error
+}
+
+// webSocketEventMsg delivers a received frame or the end of a connection
+type webSocketEventMsg struct {
+	session *WebSocketSession
+	event   WebSocketEvent
+}
+
+// webSocketSendFailedMsg reports a frame the console could not send
+type webSocketSendFailedMsg struct {
+	session *WebSocketSession
+	err     error
+}
+
+// connectWebSocket opens a connection for the current ws request after
+// running its pre-request script and auth, like any other request
+func (m *model) connectWebSocket() tea.Cmd {
+	m.closeWebSocket()
+	m.wsConsole = nil
+
+	ctx := m.startLoading()
+	requestID := m.loadingID
+	req := m.currentReq
+	env := m.activeEnvironment()
+
+	cmd := func() tea.Msg {
+		_, sent, err := prepareBruRequest(ctx, m.httpClient, m.scriptRunner, req, env, nil)
+		if err != nil {
+			return webSocketOpenedMsg{requestID: requestID, err: err}
+		}
+		session, err := m.httpClient.DialWebSocket(ctx, sent)
+		return webSocketOpenedMsg{requestID: requestID, session: session, err: err}
+	}
+
+	return tea.Batch(cmd, m.loadingTick())
+}
+
+// openWebSocketConsole shows the console for a new connection
+func (m *model) openWebSocketConsole(session *WebSocketSession) tea.Cmd {
+	input := textinput.New()
+	input.Prompt = "> "
+	input.Placeholder = "Type a text or JSON message and press Enter"
+	input.CharLimit = 0
+	input.Focus()
+
+	m.wsConsole = &webSocketConsole{
+		session: session,
+		url:     session.URL,
+		status:  "connected",
+		input:   input,
+		log:     viewport.New(30, 5),
+	}
+	m.wsConsole.note("Connected to " + session.URL)
+	m.activePanel = responsePanel
+	return waitForWebSocketEvent(session)
+}
+
+// waitForWebSocketEvent waits for the next frame of a connection
+func waitForWebSocketEvent(session *WebSocketSession) tea.Cmd {
+	return func() tea.Msg {
+		event, ok := <-session.Events()
+		if !ok {
+			return nil
+		}
+		return webSocketEventMsg{session: session, event: event}
+	}
+}
+
+// handleWebSocketEvent logs a received frame, or the end of the connection
+func (m *model) handleWebSocketEvent(msg webSocketEventMsg) tea.Cmd {
+	console := m.wsConsole
+	if console == nil || console.session != msg.session {
+		return nil
+	}
+
+	if msg.event.Closed {
+		console.session = nil
+		console.status = msg.event.Reason
+		console.note("Connection " + msg.event.Reason)
+		return nil
+	}
+	console.messages = append(console.messages, *msg.event.Message)
+	return waitForWebSocketEvent(msg.session)
+}
+
+// sendWebSocketMessage sends the console's input line as a text frame
+func (m *model) sendWebSocketMessage() tea.Cmd {
+	console := m.wsConsole
+	if console == nil || console.session == nil || console.input.Value() == "" {
+		return nil
+	}
+
+	// Logged before writing so replies cannot appear above it
+	text := console.input.Value()
+	console.input.SetValue("")
+	console.messages = append(console.messages, response.WebSocketMessage{Direction: response.WebSocketSent, Text: text, Time: time.Now()})
+	session := console.session
+	return func() tea.Msg {
+		if err := session.Send(text); err != nil {
+			return webSocketSendFailedMsg{session: session, err: err}
+		}
+		return nil
+	}
+}
+
+// closeWebSocket closes the console's connection, if it is still open
+func (m *model) closeWebSocket() bool {
+	if m.wsConsole == nil || m.wsConsole.session == nil {
+		return false
+	}
+	m.wsConsole.session.Close()
+	m.wsConsole.status = "closing"
+	return true
+}
+
+// note logs a change of the connection status
+func (c *webSocketConsole) note(text string) {
+	c.messages = append(c.messages, response.WebSocketMessage{Direction: response.WebSocketInfo, Text: text, Time: time.Now()})
+}
+
+// webSocketTitle describes the connection for the response title
+func (m *model) webSocketTitle() string {
+	console := m.wsConsole
+	statusStyle := currentTheme.StatusOkStyle
+	if console.session == nil {
+		statusStyle = lipgloss.NewStyle().Background(lipgloss.Color("196")).Foreground(lipgloss.Color("0")).Padding(0, 1)
+	} else if console.status != "connected" {
+		statusStyle = lipgloss.NewStyle().Background(lipgloss.Color("214")).Foreground(lipgloss.Color("0")).Padding(0, 1)
+	}
+
+	frames := 0
+	for _, message := range console.messages {
+		if message.Direction != response.WebSocketInfo {
+			frames++
+		}
+	}
+	return fmt.Sprintf(" WebSocket %s • %s • %d messages ", statusStyle.Render(console.status), console.url, frames)
+}
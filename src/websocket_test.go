@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	response "kalo/src/panels/response"
+)
+
+// newEchoServer accepts WebSocket connections authorized with "Bearer abc"
+// and echoes every message back
+func newEchoServer(t *testing.T) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	return newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer abc" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.WriteMessage(messageType, append([]byte("echo: "), data...))
+		}
+	})
+}
+
+func TestWebSocketHandshakeFailure(t *testing.T) {
+	server := newEchoServer(t)
+	_, err := NewHTTPClient().DialWebSocket(context.Background(), &HTTPRequestModel{URL: server.URL})
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected a failed handshake, got %v", err)
+	}
+}
+
+func TestWebSocketSession(t *testing.T) {
+	server := newEchoServer(t)
+	sent := &HTTPRequestModel{URL: server.URL, Headers: response.Headers{"Authorization": {"Bearer abc"}}}
+	session, err := NewHTTPClient().DialWebSocket(context.Background(), sent)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	if !strings.HasPrefix(session.URL, "ws://") {
+		t.Errorf("Expected an http URL to be dialled as ws, got %s", session.URL)
+	}
+
+	if err := session.Send(`{"hello": "world"}`); err != nil {
+		t.Fatalf("Failed to send: %v", err)
+	}
+	event := <-session.Events()
+	if event.Message == nil || event.Message.Direction != response.WebSocketReceived || event.Message.Text != `echo: {"hello": "world"}` {
+		t.Errorf("Expected the echoed message, got %+v", event)
+	}
+
+	session.Close()
+	event = <-session.Events()
+	if !event.Closed || event.Reason != "closed" {
+		t.Errorf("Expected the connection to be closed, got %+v", event)
+	}
+}